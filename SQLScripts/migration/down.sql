@@ -1,3 +1,19 @@
+DROP TABLE IF EXISTS hydroponic_system.ingest_receipts;
+DROP TABLE IF EXISTS hydroponic_system.readings;
+DROP TABLE IF EXISTS hydroponic_system.metrics;
+DROP TABLE IF EXISTS hydroponic_system.farm_invitations;
+DROP TABLE IF EXISTS hydroponic_system.farm_members;
+DROP TABLE IF EXISTS hydroponic_system.device_nonces;
+DROP TABLE IF EXISTS hydroponic_system.device_credentials;
+DROP TABLE IF EXISTS hydroponic_system.api_token_farms;
+DROP TABLE IF EXISTS hydroponic_system.api_tokens;
+DROP TABLE IF EXISTS hydroponic_system.mfa_recovery_codes;
+DROP TABLE IF EXISTS hydroponic_system.login_throttles;
+DROP TABLE IF EXISTS hydroponic_system.email_verification_tokens;
+DROP TABLE IF EXISTS hydroponic_system.password_reset_tokens;
+DROP TABLE IF EXISTS hydroponic_system.password_histories;
+DROP TABLE IF EXISTS hydroponic_system.refresh_tokens;
+DROP TABLE IF EXISTS hydroponic_system.sessions;
 DROP TABLE IF EXISTS public.tank_trans;
 DROP TABLE IF EXISTS public.growth_hist;
 DROP TABLE IF EXISTS public.growth_plans;
@@ -5,6 +21,7 @@ DROP TABLE IF EXISTS public.system_units;
 DROP TABLE IF EXISTS public.farms;
 DROP TABLE IF EXISTS public.profiles;
 DROP TABLE IF EXISTS public.accounts;
+DROP TABLE IF EXISTS hydroponic_system.organizations;
 
 
 
@@ -2,21 +2,40 @@ CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
 
 create schema hydroponic_system;
 
+CREATE TABLE hydroponic_system.organizations (
+	id uuid DEFAULT public.uuid_generate_v4(),
+	"name" varchar NOT NULL,
+	created_at timestamptz NULL,
+	updated_at timestamptz NULL,
+	deleted_at timestamptz NULL,
+	CONSTRAINT organizations_pkey PRIMARY KEY (id)
+);
+
 CREATE TABLE hydroponic_system.accounts (
 	id uuid DEFAULT public.uuid_generate_v4(),
 	username varchar NOT NULL,
 	email varchar NOT NULL,
 	"password" varchar NOT NULL,
 	"role" varchar NOT NULL,
+	organization_id uuid NOT NULL,
+	organization_role varchar NOT NULL DEFAULT 'member',
+	email_verified_at timestamptz NULL,
+	mfa_secret varchar NULL,
+	mfa_enabled_at timestamptz NULL,
+	mfa_last_step int8 NULL,
+	deletion_scheduled_at timestamptz NULL,
 	created_at timestamptz NULL,
 	updated_at timestamptz NULL,
 	deleted_at timestamptz NULL,
-	CONSTRAINT accounts_pkey PRIMARY KEY (id)
+	CONSTRAINT accounts_pkey PRIMARY KEY (id),
+	CONSTRAINT accounts_role_check CHECK ("role" IN ('owner', 'operator', 'viewer')),
+	CONSTRAINT accounts_organization_role_check CHECK (organization_role IN ('admin', 'member'))
 );
 
 CREATE TABLE hydroponic_system.profiles (
 	id uuid DEFAULT public.uuid_generate_v4(),
 	account_id uuid NOT NULL, 
+	organization_id uuid NOT NULL,
 	"name" varchar NOT NULL,
 	address varchar NOT NULL,
 	created_at timestamptz NULL,
@@ -28,6 +47,7 @@ CREATE TABLE hydroponic_system.profiles (
 CREATE TABLE hydroponic_system.farms (
 	id uuid DEFAULT public.uuid_generate_v4(),
 	profile_id uuid NOT NULL, 
+	organization_id uuid NOT NULL,
 	"name" varchar NOT NULL,
 	address varchar NOT NULL,
 	created_at timestamptz NULL,
@@ -67,6 +87,7 @@ CREATE TABLE hydroponic_system.growth_plans (
 	CONSTRAINT growth_plans_pkey PRIMARY KEY (id)
 );
 
+-- growth_hist is superseded by readings and no longer written
 CREATE TABLE hydroponic_system.growth_hist(
 	id uuid DEFAULT public.uuid_generate_v4(),
 	farm_id uuid NOT NULL, 
@@ -107,6 +128,200 @@ CREATE TABLE hydroponic_system.aggregations (
 	CONSTRAINT aggregation_pkey PRIMARY KEY (id)
 );
 
+CREATE TABLE hydroponic_system.sessions (
+	id uuid DEFAULT public.uuid_generate_v4(),
+	account_id uuid NOT NULL,
+	user_agent varchar NULL,
+	ip_address varchar NULL,
+	last_seen_at timestamptz NOT NULL,
+	expires_at timestamptz NOT NULL,
+	revoked_at timestamptz NULL,
+	created_at timestamptz NULL,
+	CONSTRAINT sessions_pkey PRIMARY KEY (id)
+);
+
+CREATE TABLE hydroponic_system.refresh_tokens (
+	id uuid DEFAULT public.uuid_generate_v4(),
+	account_id uuid NOT NULL,
+	family_id uuid NOT NULL,
+	token_hash varchar NOT NULL,
+	replaced_by uuid NULL,
+	expires_at timestamptz NOT NULL,
+	revoked_at timestamptz NULL,
+	created_at timestamptz NULL,
+	CONSTRAINT refresh_tokens_pkey PRIMARY KEY (id)
+);
+
+CREATE TABLE hydroponic_system.password_histories (
+	id uuid DEFAULT public.uuid_generate_v4(),
+	account_id uuid NOT NULL,
+	password_hash varchar NOT NULL,
+	created_at timestamptz NULL,
+	CONSTRAINT password_histories_pkey PRIMARY KEY (id)
+);
+
+CREATE TABLE hydroponic_system.password_reset_tokens (
+	id uuid DEFAULT public.uuid_generate_v4(),
+	account_id uuid NOT NULL,
+	token_hash varchar NOT NULL,
+	expires_at timestamptz NOT NULL,
+	used_at timestamptz NULL,
+	created_at timestamptz NULL,
+	CONSTRAINT password_reset_tokens_pkey PRIMARY KEY (id),
+	CONSTRAINT password_reset_tokens_token_hash_key UNIQUE (token_hash)
+);
+
+CREATE TABLE hydroponic_system.email_verification_tokens (
+	id uuid DEFAULT public.uuid_generate_v4(),
+	account_id uuid NOT NULL,
+	token_hash varchar NOT NULL,
+	expires_at timestamptz NOT NULL,
+	used_at timestamptz NULL,
+	created_at timestamptz NULL,
+	CONSTRAINT email_verification_tokens_pkey PRIMARY KEY (id),
+	CONSTRAINT email_verification_tokens_token_hash_key UNIQUE (token_hash)
+);
+
+CREATE TABLE hydroponic_system.mfa_recovery_codes (
+	id uuid DEFAULT public.uuid_generate_v4(),
+	account_id uuid NOT NULL,
+	code_hash varchar NOT NULL,
+	used_at timestamptz NULL,
+	created_at timestamptz NULL,
+	CONSTRAINT mfa_recovery_codes_pkey PRIMARY KEY (id)
+);
+
+CREATE TABLE hydroponic_system.api_tokens (
+	id uuid DEFAULT public.uuid_generate_v4(),
+	account_id uuid NOT NULL,
+	"name" varchar NOT NULL,
+	token_prefix varchar NOT NULL,
+	token_hash varchar NOT NULL,
+	permission varchar NOT NULL,
+	scoped bool NOT NULL DEFAULT false,
+	last_used_at timestamptz NULL,
+	last_used_ip varchar NULL,
+	expires_at timestamptz NULL,
+	revoked_at timestamptz NULL,
+	created_at timestamptz NULL,
+	CONSTRAINT api_tokens_pkey PRIMARY KEY (id),
+	CONSTRAINT api_tokens_token_hash_key UNIQUE (token_hash),
+	CONSTRAINT api_tokens_permission_check CHECK (permission IN ('read', 'write'))
+);
+
+CREATE TABLE hydroponic_system.api_token_farms (
+	api_token_id uuid NOT NULL,
+	farm_id uuid NOT NULL,
+	CONSTRAINT api_token_farms_pkey PRIMARY KEY (api_token_id, farm_id)
+);
+
+CREATE TABLE hydroponic_system.device_credentials (
+	id uuid DEFAULT public.uuid_generate_v4(),
+	system_unit_id uuid NOT NULL,
+	key_id varchar NOT NULL,
+	secret_ciphertext varchar NOT NULL,
+	last_used_at timestamptz NULL,
+	revoked_at timestamptz NULL,
+	created_at timestamptz NULL,
+	CONSTRAINT device_credentials_pkey PRIMARY KEY (id),
+	CONSTRAINT device_credentials_key_id_key UNIQUE (key_id)
+);
+
+CREATE TABLE hydroponic_system.device_nonces (
+	key_id varchar NOT NULL,
+	nonce varchar NOT NULL,
+	created_at timestamptz NOT NULL,
+	CONSTRAINT device_nonces_pkey PRIMARY KEY (key_id, nonce)
+);
+
+CREATE TABLE hydroponic_system.farm_members (
+	id uuid DEFAULT public.uuid_generate_v4(),
+	farm_id uuid NOT NULL,
+	account_id uuid NOT NULL,
+	"role" varchar NOT NULL,
+	created_at timestamptz NULL,
+	updated_at timestamptz NULL,
+	CONSTRAINT farm_members_pkey PRIMARY KEY (id),
+	CONSTRAINT farm_members_farm_account_key UNIQUE (farm_id, account_id),
+	CONSTRAINT farm_members_role_check CHECK ("role" IN ('owner', 'operator', 'viewer'))
+);
+
+CREATE TABLE hydroponic_system.farm_invitations (
+	id uuid DEFAULT public.uuid_generate_v4(),
+	farm_id uuid NOT NULL,
+	email varchar NOT NULL,
+	"role" varchar NOT NULL,
+	token_hash varchar NOT NULL,
+	invited_by uuid NOT NULL,
+	expires_at timestamptz NOT NULL,
+	accepted_at timestamptz NULL,
+	accepted_by uuid NULL,
+	revoked_at timestamptz NULL,
+	created_at timestamptz NULL,
+	CONSTRAINT farm_invitations_pkey PRIMARY KEY (id),
+	CONSTRAINT farm_invitations_token_hash_key UNIQUE (token_hash),
+	CONSTRAINT farm_invitations_role_check CHECK ("role" IN ('owner', 'operator', 'viewer'))
+);
+
+CREATE TABLE hydroponic_system.metrics (
+	"name" varchar NOT NULL,
+	unit varchar NOT NULL,
+	min_value float8 NULL,
+	max_value float8 NULL,
+	description varchar NULL,
+	created_at timestamptz NULL,
+	updated_at timestamptz NULL,
+	CONSTRAINT metrics_pkey PRIMARY KEY ("name"),
+	CONSTRAINT metrics_range_check CHECK (min_value IS NULL OR max_value IS NULL OR min_value <= max_value)
+);
+
+-- readings taken together share a sample_id. measured_at is the device clock
+-- after the skew policy, received_at the server clock; skew_flagged marks
+-- readings whose device time was out of bounds.
+CREATE TABLE hydroponic_system.readings (
+	id uuid DEFAULT public.uuid_generate_v4(),
+	sample_id uuid NOT NULL,
+	farm_id uuid NOT NULL,
+	system_id uuid NOT NULL,
+	metric varchar NOT NULL,
+	value float8 NOT NULL,
+	measured_at timestamptz NOT NULL,
+	received_at timestamptz NOT NULL,
+	skew_flagged bool NOT NULL DEFAULT false,
+	created_at timestamptz NULL,
+	updated_at timestamptz NULL,
+	deleted_at timestamptz NULL,
+	CONSTRAINT readings_pkey PRIMARY KEY (id),
+	CONSTRAINT readings_sample_metric_key UNIQUE (sample_id, metric)
+);
+
+-- one row per readings sample or tank transaction sent with a message_id or
+-- a sequence number. The unique keys deduplicate retries from the same system
+-- unit, the stored response is returned again for a replayed request.
+CREATE TABLE hydroponic_system.ingest_receipts (
+	id uuid DEFAULT public.uuid_generate_v4(),
+	system_id uuid NOT NULL,
+	kind varchar NOT NULL,
+	message_id varchar NULL,
+	"sequence" int8 NULL,
+	request_hash varchar NOT NULL,
+	response jsonb NOT NULL,
+	created_at timestamptz NULL,
+	CONSTRAINT ingest_receipts_pkey PRIMARY KEY (id),
+	CONSTRAINT ingest_receipts_system_message_key UNIQUE (system_id, message_id),
+	CONSTRAINT ingest_receipts_system_sequence_key UNIQUE (system_id, "sequence"),
+	CONSTRAINT ingest_receipts_key_check CHECK (message_id IS NOT NULL OR "sequence" IS NOT NULL),
+	CONSTRAINT ingest_receipts_kind_check CHECK (kind IN ('readings', 'tank_trans'))
+);
+
+CREATE TABLE hydroponic_system.login_throttles (
+	throttle_key varchar NOT NULL,
+	failed_count int4 NOT NULL DEFAULT 0,
+	last_failed_at timestamptz NOT NULL,
+	locked_until timestamptz NULL,
+	CONSTRAINT login_throttles_pkey PRIMARY KEY (throttle_key)
+);
+
 create schema super_admin;
 
 CREATE TABLE super_admin.accounts (
@@ -132,6 +347,9 @@ CREATE TABLE super_admin.system_logs(
 	CONSTRAINT system_logs_pkey PRIMARY KEY (id)
 );
 
+ALTER TABLE ONLY hydroponic_system.accounts ADD CONSTRAINT fk_accounts_organizations FOREIGN KEY (organization_id) REFERENCES hydroponic_system.organizations(id) ON UPDATE CASCADE;
+ALTER TABLE ONLY hydroponic_system.profiles ADD CONSTRAINT fk_profiles_organizations FOREIGN KEY (organization_id) REFERENCES hydroponic_system.organizations(id) ON UPDATE CASCADE;
+ALTER TABLE ONLY hydroponic_system.farms ADD CONSTRAINT fk_farms_organizations FOREIGN KEY (organization_id) REFERENCES hydroponic_system.organizations(id) ON UPDATE CASCADE;
 ALTER TABLE ONLY hydroponic_system.profiles ADD CONSTRAINT fk_profiles_accounts FOREIGN KEY (account_id) REFERENCES hydroponic_system.accounts(id) ON UPDATE CASCADE ON DELETE SET NULL;
 ALTER TABLE ONLY hydroponic_system.farms ADD CONSTRAINT fk_farms_profiles FOREIGN KEY (profile_id) REFERENCES hydroponic_system.profiles(id) ON UPDATE CASCADE ON DELETE SET NULL;
 ALTER TABLE ONLY hydroponic_system.system_units ADD CONSTRAINT fk_system_units_farms FOREIGN KEY (farm_id) REFERENCES hydroponic_system.farms(id) ON UPDATE CASCADE ON DELETE SET NULL;
@@ -144,9 +362,81 @@ ALTER TABLE ONLY hydroponic_system.tank_trans ADD CONSTRAINT fk_tank_trans_syste
 ALTER TABLE ONLY hydroponic_system.tank_trans ADD CONSTRAINT fk_tank_trans_farms FOREIGN KEY (farm_id) REFERENCES hydroponic_system.farms(id) ON UPDATE CASCADE ON DELETE SET NULL;
 ALTER TABLE ONLY hydroponic_system.aggregations ADD CONSTRAINT fk_aggregation_system FOREIGN KEY (system_id) REFERENCES hydroponic_system.system_units(id) ON UPDATE CASCADE ON DELETE SET NULL;
 ALTER TABLE ONLY hydroponic_system.aggregations ADD CONSTRAINT fk_aggregation_farms FOREIGN KEY (farm_id) REFERENCES hydroponic_system.farms(id) ON UPDATE CASCADE ON DELETE SET NULL;
+ALTER TABLE ONLY hydroponic_system.sessions ADD CONSTRAINT fk_sessions_accounts FOREIGN KEY (account_id) REFERENCES hydroponic_system.accounts(id) ON UPDATE CASCADE ON DELETE CASCADE;
+ALTER TABLE ONLY hydroponic_system.refresh_tokens ADD CONSTRAINT fk_refresh_tokens_accounts FOREIGN KEY (account_id) REFERENCES hydroponic_system.accounts(id) ON UPDATE CASCADE ON DELETE CASCADE;
+ALTER TABLE ONLY hydroponic_system.password_histories ADD CONSTRAINT fk_password_histories_accounts FOREIGN KEY (account_id) REFERENCES hydroponic_system.accounts(id) ON UPDATE CASCADE ON DELETE CASCADE;
+ALTER TABLE ONLY hydroponic_system.password_reset_tokens ADD CONSTRAINT fk_password_reset_tokens_accounts FOREIGN KEY (account_id) REFERENCES hydroponic_system.accounts(id) ON UPDATE CASCADE ON DELETE CASCADE;
+ALTER TABLE ONLY hydroponic_system.email_verification_tokens ADD CONSTRAINT fk_email_verification_tokens_accounts FOREIGN KEY (account_id) REFERENCES hydroponic_system.accounts(id) ON UPDATE CASCADE ON DELETE CASCADE;
+ALTER TABLE ONLY hydroponic_system.mfa_recovery_codes ADD CONSTRAINT fk_mfa_recovery_codes_accounts FOREIGN KEY (account_id) REFERENCES hydroponic_system.accounts(id) ON UPDATE CASCADE ON DELETE CASCADE;
+ALTER TABLE ONLY hydroponic_system.api_tokens ADD CONSTRAINT fk_api_tokens_accounts FOREIGN KEY (account_id) REFERENCES hydroponic_system.accounts(id) ON UPDATE CASCADE ON DELETE CASCADE;
+ALTER TABLE ONLY hydroponic_system.api_token_farms ADD CONSTRAINT fk_api_token_farms_api_tokens FOREIGN KEY (api_token_id) REFERENCES hydroponic_system.api_tokens(id) ON UPDATE CASCADE ON DELETE CASCADE;
+ALTER TABLE ONLY hydroponic_system.api_token_farms ADD CONSTRAINT fk_api_token_farms_farms FOREIGN KEY (farm_id) REFERENCES hydroponic_system.farms(id) ON UPDATE CASCADE ON DELETE CASCADE;
+ALTER TABLE ONLY hydroponic_system.device_credentials ADD CONSTRAINT fk_device_credentials_system_units FOREIGN KEY (system_unit_id) REFERENCES hydroponic_system.system_units(id) ON UPDATE CASCADE ON DELETE CASCADE;
+ALTER TABLE ONLY hydroponic_system.farm_members ADD CONSTRAINT fk_farm_members_farms FOREIGN KEY (farm_id) REFERENCES hydroponic_system.farms(id) ON UPDATE CASCADE ON DELETE CASCADE;
+ALTER TABLE ONLY hydroponic_system.farm_members ADD CONSTRAINT fk_farm_members_accounts FOREIGN KEY (account_id) REFERENCES hydroponic_system.accounts(id) ON UPDATE CASCADE ON DELETE CASCADE;
+ALTER TABLE ONLY hydroponic_system.farm_invitations ADD CONSTRAINT fk_farm_invitations_farms FOREIGN KEY (farm_id) REFERENCES hydroponic_system.farms(id) ON UPDATE CASCADE ON DELETE CASCADE;
+ALTER TABLE ONLY hydroponic_system.farm_invitations ADD CONSTRAINT fk_farm_invitations_accounts FOREIGN KEY (invited_by) REFERENCES hydroponic_system.accounts(id) ON UPDATE CASCADE ON DELETE CASCADE;
+ALTER TABLE ONLY hydroponic_system.readings ADD CONSTRAINT fk_readings_system FOREIGN KEY (system_id) REFERENCES hydroponic_system.system_units(id) ON UPDATE CASCADE ON DELETE SET NULL;
+ALTER TABLE ONLY hydroponic_system.readings ADD CONSTRAINT fk_readings_farms FOREIGN KEY (farm_id) REFERENCES hydroponic_system.farms(id) ON UPDATE CASCADE ON DELETE SET NULL;
+ALTER TABLE ONLY hydroponic_system.readings ADD CONSTRAINT fk_readings_metrics FOREIGN KEY (metric) REFERENCES hydroponic_system.metrics("name") ON UPDATE CASCADE;
+ALTER TABLE ONLY hydroponic_system.ingest_receipts ADD CONSTRAINT fk_ingest_receipts_system FOREIGN KEY (system_id) REFERENCES hydroponic_system.system_units(id) ON UPDATE CASCADE ON DELETE CASCADE;
 
 CREATE INDEX idx_growth_hist_farm_system_date
 ON hydroponic_system.growth_hist (farm_id, system_id, created_at);
 
 CREATE INDEX idx_tank_trans_farm_system_date
-ON hydroponic_system.tank_trans (farm_id, system_id, created_at);
\ No newline at end of file
+ON hydroponic_system.tank_trans (farm_id, system_id, created_at);
+
+CREATE INDEX idx_refresh_tokens_family
+ON hydroponic_system.refresh_tokens (family_id);
+
+CREATE INDEX idx_sessions_account
+ON hydroponic_system.sessions (account_id) WHERE revoked_at IS NULL;
+
+CREATE INDEX idx_password_histories_account
+ON hydroponic_system.password_histories (account_id, created_at);
+
+CREATE UNIQUE INDEX idx_accounts_email_lower
+ON hydroponic_system.accounts (lower(email)) WHERE deleted_at IS NULL;
+
+CREATE INDEX idx_mfa_recovery_codes_account
+ON hydroponic_system.mfa_recovery_codes (account_id) WHERE used_at IS NULL;
+
+CREATE INDEX idx_api_tokens_account
+ON hydroponic_system.api_tokens (account_id) WHERE revoked_at IS NULL;
+
+CREATE UNIQUE INDEX idx_device_credentials_active_unit
+ON hydroponic_system.device_credentials (system_unit_id) WHERE revoked_at IS NULL;
+
+CREATE INDEX idx_device_nonces_created_at
+ON hydroponic_system.device_nonces (created_at);
+
+CREATE INDEX idx_farm_members_account
+ON hydroponic_system.farm_members (account_id);
+
+CREATE INDEX idx_farm_invitations_farm
+ON hydroponic_system.farm_invitations (farm_id) WHERE accepted_at IS NULL AND revoked_at IS NULL;
+
+CREATE INDEX idx_accounts_organization
+ON hydroponic_system.accounts (organization_id) WHERE deleted_at IS NULL;
+
+CREATE INDEX idx_farms_organization
+ON hydroponic_system.farms (organization_id) WHERE deleted_at IS NULL;
+
+CREATE INDEX idx_accounts_deletion_scheduled
+ON hydroponic_system.accounts (deletion_scheduled_at) WHERE deletion_scheduled_at IS NOT NULL AND deleted_at IS NULL;
+
+CREATE INDEX idx_readings_farm_system_metric_date
+ON hydroponic_system.readings (farm_id, system_id, metric, measured_at);
+
+INSERT INTO hydroponic_system.metrics ("name", unit, min_value, max_value, description, created_at) VALUES
+	('ppm', 'ppm', 0, 5000, 'Total dissolved solids', now()),
+	('ph', 'pH', 0, 14, 'Nutrient solution acidity', now()),
+	('ec', 'mS/cm', 0, 20, 'Electrical conductivity', now()),
+	('water_temperature', '°C', 0, 50, 'Nutrient solution temperature', now()),
+	('air_temperature', '°C', -20, 60, 'Air temperature', now()),
+	('humidity', '%', 0, 100, 'Relative air humidity', now()),
+	('dissolved_oxygen', 'mg/L', 0, 30, 'Dissolved oxygen', now()),
+	('reservoir_level', '%', 0, 100, 'Reservoir fill level', now()),
+	('light_intensity', 'lux', 0, 200000, 'Light intensity', now())
+ON CONFLICT ("name") DO NOTHING;
@@ -1,9 +1,18 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/constant"
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/handler"
@@ -14,6 +23,10 @@ import (
 	dbstore "github.com/Ayasibp/be-smart-farming-hydroponic/internal/store/db"
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/hasher"
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/mailer"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/mqtt"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/passwordpolicy"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/secretcipher"
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/tokenprovider"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -39,9 +52,19 @@ func main() {
 		}
 	}
 
-	handlers, middlewares := prepare()
+	handlers, middlewares, readingQueue, mqttServer, err := prepare()
+	if err != nil {
+		return
+	}
 
 	srv := gin.Default()
+	err = srv.SetTrustedProxies(trustedProxies())
+	if err != nil {
+		logger.Error("main", "Invalid trusted proxies", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
 	srv.Use(middleware.CORS())
 
 	routes.Build(srv, handlers, middlewares)
@@ -58,14 +81,56 @@ func main() {
 			"port": port,
 		})
 
-	if err := srv.Run(fmt.Sprintf(":%s", port)); err != nil {
-		logger.Error("main", "Error running Gin server", map[string]string{
-			"error": err.Error(),
-		})
+	httpServer := &http.Server{
+		Addr:    fmt.Sprintf(":%s", port),
+		Handler: srv,
+	}
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- httpServer.ListenAndServe()
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("main", "Error running Gin server", map[string]string{
+				"error": err.Error(),
+			})
+		}
+	case <-quit:
+		logger.Info("main", "Shutting down server...", nil)
+
+		shutdownTimeout := 30 * time.Second
+		if value, err := strconv.Atoi(os.Getenv(constant.EnvKeyShutdownTimeout)); err == nil {
+			shutdownTimeout = time.Duration(value) * time.Second
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		if err := httpServer.Shutdown(ctx); err != nil {
+			logger.Error("main", "Error shutting down Gin server", map[string]string{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	if mqttServer != nil {
+		mqttServer.Close()
+	}
+	// requests and messages are done, so every queued sample can still be stored
+	if readingQueue != nil {
+		readingQueue.Close()
 	}
 }
 
-func prepare() (handlers routes.Handlers, middlewares routes.Middlewares) {
+var errMqttTLSRequired = errors.New("MQTT_TLS_CERT and MQTT_TLS_KEY are required, or set MQTT_ALLOW_INSECURE")
+
+// prepare wires the dependencies of the server. It logs and returns the first
+// configuration error, and the server does not start.
+func prepare() (handlers routes.Handlers, middlewares routes.Middlewares, readingQueue service.ReadingQueue, mqttServer *mqtt.Server, err error) {
 	logger.Info("main", "Initializing dependencies...", nil)
 
 	appName := os.Getenv(constant.EnvKeyAppName)
@@ -89,13 +154,147 @@ func prepare() (handlers routes.Handlers, middlewares routes.Middlewares) {
 		return
 	}
 
-	jwtProvider := tokenprovider.NewJWT(appName, jwtSecret, refreshTokenDuration, accessTokenDuration)
-	middlewares = routes.Middlewares{
-		Auth: middleware.CreateAuth(jwtProvider),
+	acceptLegacyHS256, _ := strconv.ParseBool(os.Getenv(constant.EnvKeyJWTAcceptLegacyHS256))
+	jwtKeys, err := tokenprovider.NewKeySet(tokenprovider.KeySetConfig{
+		Algorithm:         os.Getenv(constant.EnvKeyJWTAlgorithm),
+		Secret:            jwtSecret,
+		KeysDir:           os.Getenv(constant.EnvKeyJWTKeysDir),
+		SigningKeyId:      os.Getenv(constant.EnvKeyJWTSigningKeyId),
+		AcceptLegacyHS256: acceptLegacyHS256,
+	})
+	if err != nil {
+		logger.Error("main", "Invalid JWT key configuration", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	jwtProvider := tokenprovider.NewJWT(appName, constant.AudienceUser, jwtKeys, refreshTokenDuration, accessTokenDuration)
+	superJwtProvider := tokenprovider.NewJWT(appName+constant.SuperAdminIssuerSuffix, constant.AudienceSuperAdmin, jwtKeys, refreshTokenDuration, accessTokenDuration)
+
+	// TOTP seeds and device keys are encrypted at rest; reusing the JWT
+	// secret keeps existing deployments working but a dedicated key is
+	// preferred. EdDSA and RS256 deployments have no JWT secret and must set
+	// one.
+	mfaSecretKey := os.Getenv(constant.EnvKeyMfaSecretKey)
+	if mfaSecretKey == "" {
+		mfaSecretKey = jwtSecret
+	}
+	secretCipher, err := secretcipher.NewAESGCM(mfaSecretKey)
+	if err != nil {
+		logger.Error("main", "Invalid secret encryption key", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	passwordPolicy := passwordpolicy.NewDefault()
+	if value, err := strconv.Atoi(os.Getenv(constant.EnvKeyPasswordMinLength)); err == nil {
+		passwordPolicy.MinLength = value
+	}
+	if value, err := strconv.Atoi(os.Getenv(constant.EnvKeyPasswordMinClasses)); err == nil {
+		passwordPolicy.MinCharClasses = value
+	}
+	if value, err := strconv.Atoi(os.Getenv(constant.EnvKeyPasswordHistorySize)); err == nil {
+		passwordPolicy.HistorySize = value
+	}
+
+	passwordResetTTL := 30 * time.Minute
+	if value, err := strconv.Atoi(os.Getenv(constant.EnvKeyPasswordResetTTL)); err == nil {
+		passwordResetTTL = time.Duration(value) * time.Minute
+	}
+
+	emailVerifyTTL := 24 * time.Hour
+	if value, err := strconv.Atoi(os.Getenv(constant.EnvKeyEmailVerifyTTL)); err == nil {
+		emailVerifyTTL = time.Duration(value) * time.Minute
+	}
+
+	farmInvitationTTL := 7 * 24 * time.Hour
+	if value, err := strconv.Atoi(os.Getenv(constant.EnvKeyFarmInvitationTTL)); err == nil {
+		farmInvitationTTL = time.Duration(value) * time.Minute
+	}
+
+	accountDeletionGrace := 30 * 24 * time.Hour
+	if value, err := strconv.Atoi(os.Getenv(constant.EnvKeyAccountDeletionGrace)); err == nil {
+		accountDeletionGrace = time.Duration(value) * time.Minute
+	}
+
+	impersonationTTL := 15 * time.Minute
+	if value, err := strconv.Atoi(os.Getenv(constant.EnvKeyImpersonationTTL)); err == nil {
+		impersonationTTL = time.Duration(value) * time.Minute
+	}
+
+	loginThrottlePolicy := service.DefaultLoginThrottlePolicy()
+	if value, err := strconv.Atoi(os.Getenv(constant.EnvKeyLoginMaxAttempts)); err == nil {
+		loginThrottlePolicy.MaxAccountAttempts = value
+	}
+	if value, err := strconv.Atoi(os.Getenv(constant.EnvKeyLoginMaxIpAttempts)); err == nil {
+		loginThrottlePolicy.MaxIpAttempts = value
+	}
+
+	readingSkewPolicy := service.DefaultClockSkewPolicy()
+	switch mode := os.Getenv(constant.EnvKeyReadingSkewPolicy); mode {
+	case service.SkewPolicyReject, service.SkewPolicyClamp, service.SkewPolicyFlag:
+		readingSkewPolicy.Mode = mode
+	}
+	if value, err := strconv.Atoi(os.Getenv(constant.EnvKeyReadingMaxFutureSkew)); err == nil {
+		readingSkewPolicy.MaxFuture = time.Duration(value) * time.Minute
+	}
+	if value, err := strconv.Atoi(os.Getenv(constant.EnvKeyReadingMaxPastAge)); err == nil {
+		readingSkewPolicy.MaxPast = time.Duration(value) * time.Minute
+	}
+
+	readingQueuePolicy := service.DefaultReadingQueuePolicy()
+	if value, err := strconv.Atoi(os.Getenv(constant.EnvKeyReadingQueueSize)); err == nil {
+		readingQueuePolicy.Capacity = value
+	}
+	if value, err := strconv.Atoi(os.Getenv(constant.EnvKeyReadingQueueWorkers)); err == nil {
+		readingQueuePolicy.Workers = value
+	}
+	if value, err := strconv.Atoi(os.Getenv(constant.EnvKeyReadingQueueBatch)); err == nil {
+		readingQueuePolicy.BatchSize = value
+	}
+	if value, err := strconv.Atoi(os.Getenv(constant.EnvKeyReadingQueueFlush)); err == nil {
+		readingQueuePolicy.FlushInterval = time.Duration(value) * time.Millisecond
+	}
+
+	var appMailer mailer.Mailer
+	switch os.Getenv(constant.EnvKeyMailer) {
+	case "smtp":
+		appMailer = mailer.NewSMTP(
+			os.Getenv(constant.EnvKeySMTPHost),
+			os.Getenv(constant.EnvKeySMTPPort),
+			os.Getenv(constant.EnvKeySMTPUsername),
+			os.Getenv(constant.EnvKeySMTPPassword),
+			os.Getenv(constant.EnvKeyMailFrom),
+		)
+	default:
+		appMailer = mailer.NewFile(os.Getenv(constant.EnvKeyMailFilePath))
+	}
+
+	var passwordHasher hasher.Hasher
+	switch os.Getenv(constant.EnvKeyPasswordHasher) {
+	case "bcrypt":
+		bcryptCost := 10
+		if value, err := strconv.Atoi(os.Getenv(constant.EnvKeyBcryptCost)); err == nil {
+			bcryptCost = value
+		}
+		passwordHasher = hasher.NewBcrypt(bcryptCost)
+	default:
+		argon2Params := hasher.DefaultArgon2idParams
+		if value, err := strconv.ParseUint(os.Getenv(constant.EnvKeyArgon2Memory), 10, 32); err == nil {
+			argon2Params.Memory = uint32(value)
+		}
+		if value, err := strconv.ParseUint(os.Getenv(constant.EnvKeyArgon2Iterations), 10, 32); err == nil {
+			argon2Params.Iterations = uint32(value)
+		}
+		if value, err := strconv.ParseUint(os.Getenv(constant.EnvKeyArgon2Parallelism), 10, 8); err == nil {
+			argon2Params.Parallelism = uint8(value)
+		}
+		passwordHasher = hasher.NewArgon2id(argon2Params)
 	}
 
 	db := dbstore.Get()
-	hasher := hasher.NewBcrypt(10)
 
 	logger.Info("main", "Initializing repositories...", nil)
 	accountRepo := repository.NewAuthRepository(db)
@@ -103,46 +302,119 @@ func prepare() (handlers routes.Handlers, middlewares routes.Middlewares) {
 	farmRepo := repository.NewFarmRepository(db)
 	systemUnitRepo := repository.NewSystemUnitRepository(db)
 	growthHistRepo := repository.NewGrowthHistRepository(db)
+	metricRepo := repository.NewMetricRepository(db)
+	ingestReceiptRepo := repository.NewIngestReceiptRepository(db)
 	systemLogRepo := repository.NewSystemLogRepository(db)
 	superAccountRepo := repository.NewSuperAccountRepository(db)
 	unitIdRepo := repository.NewUnitIdRepository(db)
 	tankTransRepo := repository.NewTankTransRepository(db)
 	aggregationRepo := repository.NewAggregationRepository(db)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	passwordResetRepo := repository.NewPasswordResetRepository(db)
+	emailVerifyRepo := repository.NewEmailVerificationRepository(db)
+	loginThrottleRepo := repository.NewLoginThrottleRepository(db)
+	mfaRepo := repository.NewMfaRepository(db)
+	apiTokenRepo := repository.NewApiTokenRepository(db)
+	deviceCredentialRepo := repository.NewDeviceCredentialRepository(db)
+	farmMemberRepo := repository.NewFarmMemberRepository(db)
+	organizationRepo := repository.NewOrganizationRepository(db)
+	accountDataRepo := repository.NewAccountDataRepository(db)
 
 	logger.Info("main", "Initializing services...", nil)
+	loginThrottleService := service.NewLoginThrottleService(service.LoginThrottleServiceConfig{
+		LoginThrottleRepo: loginThrottleRepo,
+		SystemLogRepo:     systemLogRepo,
+		Policy:            loginThrottlePolicy,
+	})
+	mfaService := service.NewMfaService(service.MfaServiceConfig{
+		AccountRepo:   accountRepo,
+		MfaRepo:       mfaRepo,
+		SecretCipher:  secretCipher,
+		Hasher:        passwordHasher,
+		JwtProvider:   jwtProvider,
+		LoginThrottle: loginThrottleService,
+		Issuer:        appName,
+	})
+	farmMemberService := service.NewFarmMemberService(service.FarmMemberServiceConfig{
+		FarmMemberRepo: farmMemberRepo,
+		FarmRepo:       farmRepo,
+		Mailer:         appMailer,
+		FrontEndBase:   os.Getenv(constant.EnvFrontEndBase),
+		InvitationTTL:  farmInvitationTTL,
+	})
 	accountService := service.NewAccountService(service.AccountServiceConfig{
-		AccountRepo: accountRepo,
-		ProfileRepo: profileRepo,
-		Hasher:      hasher,
-		JwtProvider: jwtProvider,
+		AccountRepo:       accountRepo,
+		ProfileRepo:       profileRepo,
+		RefreshTokenRepo:  refreshTokenRepo,
+		SessionRepo:       sessionRepo,
+		Hasher:            passwordHasher,
+		JwtProvider:       jwtProvider,
+		PasswordPolicy:    passwordPolicy,
+		PasswordResetRepo: passwordResetRepo,
+		Mailer:            appMailer,
+		PasswordResetTTL:  passwordResetTTL,
+		FrontEndBase:      os.Getenv(constant.EnvFrontEndBase),
+		EmailVerifyRepo:   emailVerifyRepo,
+		EmailVerifyTTL:    emailVerifyTTL,
+		LoginThrottle:     loginThrottleService,
+		MfaService:        mfaService,
+		FarmMemberService: farmMemberService,
 	})
 	superAccountService := service.NewSuperAccountService(service.SuperAccountServiceConfig{
 		SuperAccountRepo: superAccountRepo,
-		Hasher:           hasher,
+		Hasher:           passwordHasher,
+		JwtProvider:      superJwtProvider,
+		LoginThrottle:    loginThrottleService,
 	})
 	profileService := service.NewProfileService(service.ProfileServiceConfig{
 		ProfileRepo: profileRepo,
 		AccountRepo: accountRepo,
 	})
 	farmService := service.NewFarmService(service.FarmServiceConfig{
-		FarmRepo:    farmRepo,
-		ProfileRepo: profileRepo,
+		FarmRepo:       farmRepo,
+		FarmMemberRepo: farmMemberRepo,
+		ProfileRepo:    profileRepo,
+	})
+	deviceService := service.NewDeviceService(service.DeviceServiceConfig{
+		DeviceCredentialRepo: deviceCredentialRepo,
+		SecretCipher:         secretCipher,
 	})
 	systemUnitService := service.NewSystemUnitService(service.SystemUnitServiceConfig{
-		SystemUnitRepo: systemUnitRepo,
-		FarmRepo:       farmRepo,
-		UnitKeyRepo:    unitIdRepo,
+		SystemUnitRepo:    systemUnitRepo,
+		FarmMemberRepo:    farmMemberRepo,
+		UnitKeyRepo:       unitIdRepo,
+		IngestReceiptRepo: ingestReceiptRepo,
+		DeviceService:     deviceService,
 	})
+	if readingQueuePolicy.Capacity > 0 {
+		readingQueue = service.NewReadingQueue(service.ReadingQueueConfig{
+			GrowthHistRepo: growthHistRepo,
+			Policy:         readingQueuePolicy,
+		})
+	}
 	growthHistService := service.NewGrowthHistService(service.GrowthHistServiceConfig{
-		GrowthHistRepo:  growthHistRepo,
-		FarmRepo:        farmRepo,
-		SystemUnitRepo:  systemUnitRepo,
-		AggregationRepo: aggregationRepo,
+		GrowthHistRepo:    growthHistRepo,
+		IngestReceiptRepo: ingestReceiptRepo,
+		MetricRepo:        metricRepo,
+		FarmMemberRepo:    farmMemberRepo,
+		SystemUnitRepo:    systemUnitRepo,
+		AggregationRepo:   aggregationRepo,
+		ReadingQueue:      readingQueue,
+		SkewPolicy:        readingSkewPolicy,
 	})
-	tankTransService := service.NewTankTransService(service.TankTransServiceConfig{
-		TankTransRepo:  tankTransRepo,
-		FarmRepo:       farmRepo,
+	readingIngestService := service.NewReadingIngestService(service.ReadingIngestServiceConfig{
+		GrowthHistRepo: growthHistRepo,
+		MetricRepo:     metricRepo,
+		FarmMemberRepo: farmMemberRepo,
 		SystemUnitRepo: systemUnitRepo,
+		SkewPolicy:     readingSkewPolicy,
+	})
+	tankTransService := service.NewTankTransService(service.TankTransServiceConfig{
+		TankTransRepo:     tankTransRepo,
+		IngestReceiptRepo: ingestReceiptRepo,
+		FarmMemberRepo:    farmMemberRepo,
+		SystemUnitRepo:    systemUnitRepo,
 	})
 	aggregationService := service.NewAggregationService(service.AggregationServiceConfig{
 		AggregatoionRepo: aggregationRepo,
@@ -150,16 +422,53 @@ func prepare() (handlers routes.Handlers, middlewares routes.Middlewares) {
 		SystemUnitRepo:   systemUnitRepo,
 		GrowthHistRepo:   growthHistRepo,
 	})
+	organizationService := service.NewOrganizationService(service.OrganizationServiceConfig{
+		OrganizationRepo: organizationRepo,
+		FarmRepo:         farmRepo,
+		SystemUnitRepo:   systemUnitRepo,
+	})
+	accountDataService := service.NewAccountDataService(service.AccountDataServiceConfig{
+		AccountDataRepo: accountDataRepo,
+		AccountRepo:     accountRepo,
+		SessionRepo:     sessionRepo,
+		Mailer:          appMailer,
+		GracePeriod:     accountDeletionGrace,
+	})
 	systemLogService := service.NewSystemLogService(service.SystemLogServiceConfig{
 		SystemLogRepo: systemLogRepo,
 	})
+	impersonationService := service.NewImpersonationService(service.ImpersonationServiceConfig{
+		AccountRepo:      accountRepo,
+		JwtProvider:      jwtProvider,
+		SystemLogService: systemLogService,
+		TokenTTL:         impersonationTTL,
+	})
 	unitIdService := service.NewUnitIdService(service.UnitIdServiceConfig{
 		UnitIdRepo: unitIdRepo,
 	})
+	sessionService := service.NewSessionService(service.SessionServiceConfig{
+		SessionRepo: sessionRepo,
+	})
+	apiTokenService := service.NewApiTokenService(service.ApiTokenServiceConfig{
+		ApiTokenRepo: apiTokenRepo,
+		AccountRepo:  accountRepo,
+		FarmRepo:     farmRepo,
+	})
+
+	middlewares = routes.Middlewares{
+		Auth:        middleware.CreateAuth(jwtProvider, sessionService, apiTokenService, impersonationService),
+		SessionAuth: middleware.CreateAuth(jwtProvider, sessionService, nil, nil),
+		SuperAuth:   middleware.CreateSuperAuth(superJwtProvider),
+		Stepup:      middleware.CreateStepup(jwtProvider),
+		SuperStepup: middleware.CreateStepup(superJwtProvider),
+		DeviceAuth:  middleware.CreateDeviceAuth(deviceService),
+	}
 
 	logger.Info("main", "Initializing handlers...", nil)
 	accountHandler := handler.NewAccountHandler(handler.AccountHandlerConfig{
 		AccountService: accountService,
+		SessionService: sessionService,
+		MfaService:     mfaService,
 		TokenProvider:  jwtProvider,
 	})
 	profileHandler := handler.NewProfileHandler(handler.ProfileHandlerConfig{
@@ -177,13 +486,19 @@ func prepare() (handlers routes.Handlers, middlewares routes.Middlewares) {
 		GrowthHistService: growthHistService,
 		SystemLogService:  systemLogService,
 	})
+	readingIngestHandler := handler.NewReadingIngestHandler(handler.ReadingIngestHandlerConfig{
+		ReadingIngestService: readingIngestService,
+		ReadingQueue:         readingQueue,
+		SystemLogService:     systemLogService,
+	})
 	aggregationHandler := handler.NewAggregationHandler(handler.AggregationHandlerConfig{
 		AggregationService: aggregationService,
 		SystemLogService:   systemLogService,
 	})
 	superAccountHandler := handler.NewSuperAccountHandler(handler.SuperAccountHandlerConfig{
-		SuperAccountService: superAccountService,
-		SystemLogService:    systemLogService,
+		SuperAccountService:  superAccountService,
+		ImpersonationService: impersonationService,
+		SystemLogService:     systemLogService,
 	})
 	tankTransHandler := handler.NewTankTransHandler(handler.TankTransHandlerConfig{
 		TankTransService: tankTransService,
@@ -194,25 +509,139 @@ func prepare() (handlers routes.Handlers, middlewares routes.Middlewares) {
 		SystemLogService: systemLogService,
 	})
 
+	apiTokenHandler := handler.NewApiTokenHandler(handler.ApiTokenHandlerConfig{
+		ApiTokenService:  apiTokenService,
+		SystemLogService: systemLogService,
+	})
+	farmMemberHandler := handler.NewFarmMemberHandler(handler.FarmMemberHandlerConfig{
+		FarmMemberService: farmMemberService,
+		SystemLogService:  systemLogService,
+	})
+	organizationHandler := handler.NewOrganizationHandler(handler.OrganizationHandlerConfig{
+		OrganizationService: organizationService,
+		SystemLogService:    systemLogService,
+	})
+	accountDataHandler := handler.NewAccountDataHandler(handler.AccountDataHandlerConfig{
+		AccountDataService: accountDataService,
+		SystemLogService:   systemLogService,
+	})
+	keyHandler := handler.NewKeyHandler(handler.KeyHandlerConfig{
+		KeySet: jwtKeys,
+	})
+	mqttHandler := handler.NewMqttHandler(handler.MqttHandlerConfig{
+		GrowthHistService: growthHistService,
+		TankTransService:  tankTransService,
+		DeviceService:     deviceService,
+		SystemLogService:  systemLogService,
+	})
+
 	cronJob := middleware.NewCorn(
 		middleware.CronJobConfig{
-			AggregateService: aggregationService,
+			AggregateService:   aggregationService,
+			DeviceService:      deviceService,
+			AccountDataService: accountDataService,
 		},
 	)
 	cronJob.CreateAggregationEachMonth()
+	cronJob.CleanupDeviceNonces()
+	cronJob.PurgeDeletedAccounts()
 
 	handlers = routes.Handlers{
-		Account:      accountHandler,
-		Profile:      profileHandler,
-		Farm:         farmHandler,
-		SystemUnit:   systemUnitHandler,
-		GrowthHist:   growthHistHandler,
-		SuperAccount: superAccountHandler,
-		UnitId:       unitIdHandler,
-		TankTrans:    tankTransHandler,
-		Aggregation:  aggregationHandler,
+		Account:       accountHandler,
+		Profile:       profileHandler,
+		Farm:          farmHandler,
+		SystemUnit:    systemUnitHandler,
+		GrowthHist:    growthHistHandler,
+		SuperAccount:  superAccountHandler,
+		UnitId:        unitIdHandler,
+		TankTrans:     tankTransHandler,
+		Aggregation:   aggregationHandler,
+		Key:           keyHandler,
+		ApiToken:      apiTokenHandler,
+		FarmMember:    farmMemberHandler,
+		Organization:  organizationHandler,
+		AccountData:   accountDataHandler,
+		ReadingIngest: readingIngestHandler,
+	}
+
+	if mqttAddr := os.Getenv(constant.EnvKeyMqttAddr); mqttAddr != "" {
+		mqttServer, err = startMqtt(mqttAddr, mqttHandler)
+		if err != nil {
+			logger.Error("main", "Error starting MQTT gateway", map[string]string{
+				"error": err.Error(),
+			})
+			return
+		}
 	}
 
 	logger.Info("main", "Application initialized successfully.", nil)
-	return
+	return handlers, middlewares, readingQueue, mqttServer, nil
+}
+
+// startMqtt serves the MQTT gateway for devices. Devices send their secret as
+// the MQTT password, so TLS is required unless plain TCP is explicitly
+// allowed, e.g. behind a TLS terminating proxy.
+func startMqtt(addr string, mqttHandler *handler.MqttHandler) (*mqtt.Server, error) {
+	certFile := os.Getenv(constant.EnvKeyMqttTLSCert)
+	keyFile := os.Getenv(constant.EnvKeyMqttTLSKey)
+	useTLS := certFile != "" || keyFile != ""
+	allowInsecure, _ := strconv.ParseBool(os.Getenv(constant.EnvKeyMqttAllowInsecure))
+
+	var tlsConfig *tls.Config
+	switch {
+	case useTLS:
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		}
+	case allowInsecure:
+		logger.Warn("main", "MQTT gateway runs without TLS, device secrets are sent in clear text", map[string]string{
+			"addr": addr,
+		})
+	default:
+		return nil, errMqttTLSRequired
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+
+	mqttServer := mqtt.NewServer(mqtt.Config{
+		Authenticate: mqttHandler.Authenticate,
+		Handle:       mqttHandler.Handle,
+	})
+	go func() {
+		if err := mqttServer.Serve(listener); err != nil {
+			logger.Error("main", "Error running MQTT gateway", map[string]string{
+				"error": err.Error(),
+			})
+		}
+	}()
+
+	logger.Info("main", "MQTT gateway is starting...", map[string]string{
+		"addr": addr,
+		"tls":  strconv.FormatBool(useTLS),
+	})
+	return mqttServer, nil
+}
+
+// trustedProxies lists the proxies whose X-Forwarded-For is believed. None by
+// default, so a client cannot pick the IP that the login throttle, sessions
+// and API tokens record for it.
+func trustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv(constant.EnvKeyTrustedProxies), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
 }
@@ -0,0 +1,10 @@
+package constant
+
+const (
+	// ApiTokenPrefix marks personal API tokens so the auth middleware can tell
+	// them apart from JWTs without parsing them.
+	ApiTokenPrefix string = "hpt_"
+
+	ApiTokenPermissionRead  string = "read"
+	ApiTokenPermissionWrite string = "write"
+)
@@ -1,6 +1,11 @@
 package constant
 
 var (
-	TypeUserClaim   = "user"
-	TypeStepupClaim = "stepup"
+	TypeUserClaim    = "user"
+	TypeRefreshClaim = "refresh"
+	TypeStepupClaim  = "stepup"
+	TypeMfaClaim     = "mfa"
+	// TypeImpersonationClaim marks access tokens a super admin obtained for
+	// another account. They have no session and cannot be refreshed.
+	TypeImpersonationClaim = "impersonation"
 )
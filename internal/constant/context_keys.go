@@ -1,7 +1,16 @@
 package constant
 
 const (
-	ContextKeyUser   string = "user_ctx"
-	ContextKeyOtp    string = "otp_ctx"
-	ContextKeyStepup string = "stepup_ctx"
+	ContextKeyUser    string = "user_ctx"
+	ContextKeySession string = "session_ctx"
+	ContextKeyOtp     string = "otp_ctx"
+	ContextKeyStepup  string = "stepup_ctx"
+	// ContextKeyApiToken holds the *dto.ApiTokenAuth of requests made with a
+	// personal API token instead of a session JWT.
+	ContextKeyApiToken string = "api_token_ctx"
+	// ContextKeyDevice holds the *dto.DeviceAuth of signed device requests.
+	ContextKeyDevice string = "device_ctx"
+	// ContextKeyImpersonation holds the *dto.ImpersonationAuth of requests
+	// made with a super admin impersonation token.
+	ContextKeyImpersonation string = "impersonation_ctx"
 )
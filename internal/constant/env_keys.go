@@ -6,7 +6,47 @@ var (
 	EnvKeyRefreshTokenDuration = "REFRESH_TOKEN_DURATION"
 	EnvKeyAccessTokenDuration  = "ACCESS_TOKEN_DURATION"
 	EnvKeyJWTSecret            = "JWT_SECRET"
+	EnvKeyJWTAlgorithm         = "JWT_ALGORITHM"
+	EnvKeyJWTKeysDir           = "JWT_KEYS_DIR"
+	EnvKeyJWTSigningKeyId      = "JWT_SIGNING_KEY_ID"
+	EnvKeyJWTAcceptLegacyHS256 = "JWT_ACCEPT_LEGACY_HS256"
+	EnvKeyMfaSecretKey         = "MFA_SECRET_KEY"
 	EnvKeyAppName              = "APP_NAME"
 	EnvKeyCloudinaryURL        = "CLOUDINARY_URL"
 	EnvFrontEndBase            = "FRONT_END_BASE"
+	EnvKeyPasswordMinLength    = "PASSWORD_MIN_LENGTH"
+	EnvKeyPasswordMinClasses   = "PASSWORD_MIN_CHAR_CLASSES"
+	EnvKeyPasswordHistorySize  = "PASSWORD_HISTORY_SIZE"
+	EnvKeyPasswordResetTTL     = "PASSWORD_RESET_TOKEN_DURATION"
+	EnvKeyEmailVerifyTTL       = "EMAIL_VERIFICATION_TOKEN_DURATION"
+	EnvKeyFarmInvitationTTL    = "FARM_INVITATION_TOKEN_DURATION"
+	EnvKeyAccountDeletionGrace = "ACCOUNT_DELETION_GRACE_PERIOD"
+	EnvKeyImpersonationTTL     = "IMPERSONATION_TOKEN_DURATION"
+	EnvKeyLoginMaxAttempts     = "LOGIN_MAX_ATTEMPTS"
+	EnvKeyLoginMaxIpAttempts   = "LOGIN_MAX_IP_ATTEMPTS"
+	EnvKeyTrustedProxies       = "TRUSTED_PROXIES"
+	EnvKeyReadingSkewPolicy    = "READING_SKEW_POLICY"
+	EnvKeyReadingMaxFutureSkew = "READING_MAX_FUTURE_SKEW"
+	EnvKeyReadingMaxPastAge    = "READING_MAX_PAST_AGE"
+	EnvKeyReadingQueueSize     = "READING_QUEUE_SIZE"
+	EnvKeyReadingQueueWorkers  = "READING_QUEUE_WORKERS"
+	EnvKeyReadingQueueBatch    = "READING_QUEUE_BATCH_SIZE"
+	EnvKeyReadingQueueFlush    = "READING_QUEUE_FLUSH_INTERVAL"
+	EnvKeyShutdownTimeout      = "SHUTDOWN_TIMEOUT"
+	EnvKeyMqttAddr             = "MQTT_ADDR"
+	EnvKeyMqttTLSCert          = "MQTT_TLS_CERT"
+	EnvKeyMqttTLSKey           = "MQTT_TLS_KEY"
+	EnvKeyMqttAllowInsecure    = "MQTT_ALLOW_INSECURE"
+	EnvKeyMailer               = "MAILER"
+	EnvKeyPasswordHasher       = "PASSWORD_HASHER"
+	EnvKeyBcryptCost           = "BCRYPT_COST"
+	EnvKeyArgon2Memory         = "ARGON2_MEMORY"
+	EnvKeyArgon2Iterations     = "ARGON2_ITERATIONS"
+	EnvKeyArgon2Parallelism    = "ARGON2_PARALLELISM"
+	EnvKeyMailFrom             = "MAIL_FROM"
+	EnvKeyMailFilePath         = "MAIL_FILE_PATH"
+	EnvKeySMTPHost             = "SMTP_HOST"
+	EnvKeySMTPPort             = "SMTP_PORT"
+	EnvKeySMTPUsername         = "SMTP_USERNAME"
+	EnvKeySMTPPassword         = "SMTP_PASSWORD"
 )
@@ -2,4 +2,11 @@ package constant
 
 const (
 	Issuer string = "issuer-test"
+
+	// SuperAdminIssuerSuffix is appended to the app name to form the issuer of
+	// super admin tokens.
+	SuperAdminIssuerSuffix string = "-super-admin"
+
+	AudienceUser       string = "hydroponic-user"
+	AudienceSuperAdmin string = "hydroponic-super-admin"
 )
@@ -0,0 +1,7 @@
+package constant
+
+// Metric catalog names the legacy growth history endpoints read and write.
+const (
+	MetricPpm string = "ppm"
+	MetricPh  string = "ph"
+)
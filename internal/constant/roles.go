@@ -0,0 +1,20 @@
+package constant
+
+const (
+	RoleOwner    string = "owner"
+	RoleOperator string = "operator"
+	RoleViewer   string = "viewer"
+
+	// RoleSuperAdmin is only carried by super admin tokens and is never stored
+	// on farmer accounts.
+	RoleSuperAdmin string = "super_admin"
+)
+
+var Roles = []string{RoleOwner, RoleOperator, RoleViewer}
+
+// Organization roles. Admins manage the organization and see every farm in
+// it; members only see the farms they belong to.
+const (
+	OrgRoleAdmin  string = "admin"
+	OrgRoleMember string = "member"
+)
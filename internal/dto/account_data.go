@@ -0,0 +1,14 @@
+package dto
+
+import "time"
+
+// AccountExportFile is a rendered account export ready to be downloaded.
+type AccountExportFile struct {
+	FileName    string
+	ContentType string
+	Data        []byte
+}
+
+type AccountDeletionResponse struct {
+	DeletionScheduledAt time.Time `json:"deletion_scheduled_at"`
+}
@@ -0,0 +1,48 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type CreateApiTokenBody struct {
+	Name       string      `json:"name" binding:"required"`
+	Permission string      `json:"permission" binding:"required,oneof=read write"`
+	FarmIds    []uuid.UUID `json:"farm_ids"`
+	// ExpiresInDays of zero creates a token that never expires.
+	ExpiresInDays int `json:"expires_in_days" binding:"min=0"`
+}
+
+type ApiTokenResponse struct {
+	ID          uuid.UUID   `json:"id"`
+	Name        string      `json:"name"`
+	TokenPrefix string      `json:"token_prefix"`
+	Permission  string      `json:"permission"`
+	Scoped      bool        `json:"scoped"`
+	FarmIds     []uuid.UUID `json:"farm_ids"`
+	LastUsedAt  *time.Time  `json:"last_used_at"`
+	LastUsedIp  string      `json:"last_used_ip"`
+	ExpiresAt   *time.Time  `json:"expires_at"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+// CreateApiTokenResponse is the only response that contains the raw token.
+type CreateApiTokenResponse struct {
+	*ApiTokenResponse
+	Token string `json:"token"`
+}
+
+// ApiTokenAuth is the identity behind a request authenticated with an API
+// token.
+type ApiTokenAuth struct {
+	TokenID          uuid.UUID
+	AccountID        uuid.UUID
+	Username         string
+	Role             string
+	OrganizationID   uuid.UUID
+	OrganizationRole string
+	EmailVerified    bool
+	ReadOnly         bool
+	FarmIds          []uuid.UUID
+}
@@ -0,0 +1,42 @@
+package dto
+
+import (
+	"slices"
+
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/constant"
+	"github.com/google/uuid"
+)
+
+// Caller identifies the authenticated account a service call is made on behalf of.
+type Caller struct {
+	AccountID        uuid.UUID
+	Role             string
+	OrganizationID   uuid.UUID
+	OrganizationRole string
+	// FarmIds narrows access to these farms for callers using a farm scoped
+	// API token. Nil means no restriction beyond the account; an empty, non
+	// nil slice allows no farm.
+	FarmIds []uuid.UUID
+	// ReadOnly caps the caller to viewer on every farm, whatever its
+	// membership role. Set for read API tokens and read only impersonation.
+	ReadOnly bool
+}
+
+func (c *Caller) CanAccessFarm(farmId uuid.UUID) bool {
+	return c.FarmIds == nil || slices.Contains(c.FarmIds, farmId)
+}
+
+// IsOrganizationAdmin reports whether the caller may manage its organization.
+// Read only callers never can.
+func (c *Caller) IsOrganizationAdmin() bool {
+	return !c.ReadOnly && c.OrganizationRole == constant.OrgRoleAdmin
+}
+
+// FarmRole is the role the caller acts with on a farm where its account is a
+// member with memberRole.
+func (c *Caller) FarmRole(memberRole string) string {
+	if c.ReadOnly {
+		return constant.RoleViewer
+	}
+	return memberRole
+}
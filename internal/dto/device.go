@@ -0,0 +1,49 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DeviceCredentialResponse is shown once, when a system unit is provisioned
+// or its credential is rotated.
+type DeviceCredentialResponse struct {
+	KeyId  string `json:"key_id"`
+	Secret string `json:"secret"`
+}
+
+// DeviceSignedRequest is what the device auth middleware extracts from a
+// request before checking its signature.
+type DeviceSignedRequest struct {
+	KeyId     string
+	Timestamp string
+	Nonce     string
+	Signature string
+	Method    string
+	Path      string
+	Body      []byte
+}
+
+// DeviceAuth identifies the system unit behind a verified device request.
+type DeviceAuth struct {
+	CredentialID uuid.UUID
+	KeyId        string
+	SystemUnitID uuid.UUID
+	FarmID       uuid.UUID
+	UnitKey      uuid.UUID
+}
+
+type DeviceGrowthHist struct {
+	Ppm        float64    `json:"ppm" binding:"required"`
+	Ph         float64    `json:"ph" binding:"required"`
+	MeasuredAt *time.Time `json:"measured_at"`
+	IngestKey
+}
+
+type DeviceTankTransaction struct {
+	WaterVolume int `json:"water_volume" binding:"required"`
+	AVolume     int `json:"a_volume" binding:"required"`
+	BVolume     int `json:"b_volume" binding:"required"`
+	IngestKey
+}
@@ -0,0 +1,38 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type FarmMemberResponse struct {
+	FarmID    uuid.UUID `json:"farm_id"`
+	AccountID uuid.UUID `json:"account_id"`
+	Username  string    `json:"username,omitempty"`
+	Email     string    `json:"email,omitempty"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type UpdateFarmMemberBody struct {
+	Role string `json:"role" binding:"required,oneof=owner operator viewer"`
+}
+
+type InviteFarmMemberBody struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required,oneof=owner operator viewer"`
+}
+
+type FarmInvitationResponse struct {
+	ID        uuid.UUID `json:"id"`
+	FarmID    uuid.UUID `json:"farm_id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type AcceptFarmInvitationBody struct {
+	Token string `json:"token" binding:"required"`
+}
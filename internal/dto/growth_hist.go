@@ -12,6 +12,9 @@ type GrowthHist struct {
 	SystemId uuid.UUID `json:"system_id" binding:"required"`
 	Ppm      float64   `json:"ppm" binding:"required"`
 	Ph       float64   `json:"ph" binding:"required"`
+	// MeasuredAt is the device clock when the sample was taken.
+	MeasuredAt *time.Time `json:"measured_at"`
+	IngestKey
 }
 type GrowthHistResponse struct {
 	ID       uuid.UUID `json:"id" binding:"required"`
@@ -26,6 +29,8 @@ type GetGrowthFilter struct {
 	Period    string    `json:"period" binding:"required"`
 	StartDate time.Time `json:"start_date" binding:"required"`
 	EndDate   time.Time `json:"end_date" binding:"required"`
+	// Metrics limits the query to these catalog names, empty means all.
+	Metrics []string `json:"metrics"`
 }
 type GetGrowthAggregationResp struct {
 	Period        string                     `json:"period" binding:"required"`
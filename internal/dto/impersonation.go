@@ -0,0 +1,33 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ImpersonateBody struct {
+	AccountID uuid.UUID `json:"account_id" binding:"required"`
+	// Reason is recorded in the system log with every impersonated request.
+	Reason string `json:"reason" binding:"required"`
+	// AllowWrite lifts the default read only restriction.
+	AllowWrite bool `json:"allow_write"`
+}
+
+type ImpersonateResponse struct {
+	ImpersonationID uuid.UUID `json:"impersonation_id"`
+	AccountID       uuid.UUID `json:"account_id"`
+	Username        string    `json:"username"`
+	AccessToken     string    `json:"access_token"`
+	ReadOnly        bool      `json:"read_only"`
+	ExpiresAt       time.Time `json:"expires_at"`
+}
+
+// ImpersonationAuth is the identity behind a request authenticated with an
+// impersonation token.
+type ImpersonationAuth struct {
+	ImpersonationID uuid.UUID
+	ActorID         uuid.UUID
+	AccountID       uuid.UUID
+	ReadOnly        bool
+}
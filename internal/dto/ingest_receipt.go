@@ -0,0 +1,26 @@
+package dto
+
+import (
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
+	"github.com/google/uuid"
+)
+
+// IngestKey makes a write safe to retry. MessageId is generated by the
+// client, Sequence counts up per system unit; either one identifies the
+// request among those of the same system unit, and a replay gets the original
+// response back.
+type IngestKey struct {
+	MessageId string `json:"message_id" binding:"omitempty,max=128"`
+	Sequence  *int64 `json:"sequence" binding:"omitempty,min=0"`
+}
+
+// SequenceGapReport lists the sequence numbers a system unit skipped between
+// the first and the last one received.
+type SequenceGapReport struct {
+	SystemId      uuid.UUID            `json:"system_id"`
+	FirstSequence *int64               `json:"first_sequence"`
+	LastSequence  *int64               `json:"last_sequence"`
+	Received      int64                `json:"received"`
+	Missing       int64                `json:"missing"`
+	Gaps          []*model.SequenceGap `json:"gaps"`
+}
@@ -1,13 +1,49 @@
 package dto
 
 type LoginBody struct {
-	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
+	Username  string `json:"username" binding:"required"`
+	Password  string `json:"password" binding:"required"`
+	UserAgent string `json:"-"`
+	IpAddress string `json:"-"`
 }
 type LoginResponse struct {
-	AccesToken   string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
+	AccesToken   string `json:"access_token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	// MfaRequired is set instead of the tokens when the account has MFA on.
+	// The client then posts a code to /auth/login/mfa with MfaToken.
+	MfaRequired bool   `json:"mfa_required,omitempty"`
+	MfaToken    string `json:"mfa_token,omitempty"`
+}
+type SuperLoginResponse struct {
+	AccesToken string `json:"access_token"`
 }
 type RefreshTokenResponse struct {
 	AccesToken string `json:"access_token"`
 }
+
+type ChangePasswordBody struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+type ForgotPasswordBody struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordBody struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+type VerifyEmailBody struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type UnlockLoginBody struct {
+	Username  string `json:"username"`
+	IpAddress string `json:"ip_address"`
+}
+
+type UnlockLoginResponse struct {
+	Cleared int64 `json:"cleared"`
+}
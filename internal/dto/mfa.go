@@ -0,0 +1,35 @@
+package dto
+
+type MfaEnrollResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type MfaCodeBody struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+	IpAddress    string `json:"-"`
+}
+
+type MfaRecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type LoginMfaBody struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+	MfaToken     string `json:"-"`
+	UserAgent    string `json:"-"`
+	IpAddress    string `json:"-"`
+}
+
+// StepupBody carries a TOTP code, or the password for accounts without MFA.
+type StepupBody struct {
+	Code      string `json:"code"`
+	Password  string `json:"password"`
+	IpAddress string `json:"-"`
+}
+
+type StepupResponse struct {
+	StepupToken string `json:"stepup_token"`
+}
@@ -0,0 +1,30 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type OrganizationResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type UpdateOrganizationBody struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type OrganizationMemberResponse struct {
+	AccountID uuid.UUID `json:"account_id"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type UpdateOrganizationMemberBody struct {
+	Role string `json:"role" binding:"required,oneof=admin member"`
+}
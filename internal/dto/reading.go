@@ -0,0 +1,90 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
+	"github.com/google/uuid"
+)
+
+// CreateReadingsBody is one sample: a value per metric catalog name, e.g.
+// {"ec": 1.8, "water_temperature": 21.5}.
+type CreateReadingsBody struct {
+	FarmId   uuid.UUID          `json:"farm_id" binding:"required"`
+	SystemId uuid.UUID          `json:"system_id" binding:"required"`
+	Readings map[string]float64 `json:"readings" binding:"required"`
+	// MeasuredAt is the device clock when the sample was taken. It defaults
+	// to the time the server receives it.
+	MeasuredAt *time.Time `json:"measured_at"`
+	IngestKey
+}
+
+type DeviceReadingsBody struct {
+	Readings   map[string]float64 `json:"readings" binding:"required"`
+	MeasuredAt *time.Time         `json:"measured_at"`
+	IngestKey
+}
+
+type ReadingsResponse struct {
+	SampleId    uuid.UUID          `json:"sample_id"`
+	FarmId      uuid.UUID          `json:"farm_id"`
+	SystemId    uuid.UUID          `json:"system_id"`
+	Readings    map[string]float64 `json:"readings"`
+	MeasuredAt  time.Time          `json:"measured_at"`
+	ReceivedAt  time.Time          `json:"received_at"`
+	SkewFlagged bool               `json:"skew_flagged"`
+}
+
+type GetReadingAggregationResp struct {
+	Period        string                   `json:"period"`
+	AggregateData []*model.MetricAggregate `json:"aggregate_data"`
+}
+
+type GetReadingDataResp struct {
+	StartDate time.Time              `json:"start_date"`
+	EndDate   time.Time              `json:"end_date"`
+	Data      []*model.ReadingFilter `json:"data"`
+}
+
+// BulkReadingRow is one NDJSON line of a bulk upload.
+type BulkReadingRow struct {
+	FarmId     uuid.UUID          `json:"farm_id"`
+	SystemId   uuid.UUID          `json:"system_id"`
+	Readings   map[string]float64 `json:"readings"`
+	MeasuredAt *time.Time         `json:"measured_at"`
+}
+
+type BulkRowResult struct {
+	Line        int        `json:"line"`
+	Status      string     `json:"status"`
+	SampleId    *uuid.UUID `json:"sample_id,omitempty"`
+	SkewFlagged bool       `json:"skew_flagged,omitempty"`
+	Reason      string     `json:"reason,omitempty"`
+}
+
+type BulkIngestResponse struct {
+	Accepted int              `json:"accepted"`
+	Rejected int              `json:"rejected"`
+	Readings int64            `json:"readings"`
+	Rows     []*BulkRowResult `json:"rows"`
+}
+
+// ReadingQueueStats shows how far the write queue is behind. Depth counts
+// samples waiting for a worker, Unflushed adds those in a batch being
+// written. LastLatencyMs is how long the oldest sample of the last batch
+// waited until it was stored.
+type ReadingQueueStats struct {
+	Depth         int   `json:"depth"`
+	Capacity      int   `json:"capacity"`
+	Workers       int   `json:"workers"`
+	Unflushed     int64 `json:"unflushed"`
+	Enqueued      int64 `json:"enqueued"`
+	Rejected      int64 `json:"rejected"`
+	Stored        int64 `json:"stored"`
+	Failed        int64 `json:"failed"`
+	Batches       int64 `json:"batches"`
+	LastFlushMs   int64 `json:"last_flush_ms"`
+	AvgFlushMs    int64 `json:"avg_flush_ms"`
+	MaxFlushMs    int64 `json:"max_flush_ms"`
+	LastLatencyMs int64 `json:"last_latency_ms"`
+}
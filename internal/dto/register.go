@@ -6,9 +6,10 @@ import (
 
 type RegisterBody struct {
 	UserName string `json:"username" binding:"required"`
-	Email    string `json:"email" binding:"required"`
+	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required"`
-	Role     string `json:"role" binding:"required"`
+	// InvitationToken joins a farm right after registering.
+	InvitationToken string `json:"invitation_token"`
 }
 
 type RegisterSuperUserBody struct {
@@ -20,7 +21,10 @@ type RegisterResponse struct {
 	UserID   uuid.UUID        `json:"user_id"`
 	Username string           `json:"username"`
 	Role     string           `json:"role"`
+	OrganizationID   uuid.UUID `json:"organization_id"`
+	OrganizationRole string    `json:"organization_role"`
 	ProfileResponse *ProfileResponse `json:"profile_response"`
+	FarmMember      *FarmMemberResponse `json:"farm_member,omitempty"`
 }
 
 type RegisterSuperUserResponse struct {
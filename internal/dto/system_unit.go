@@ -15,6 +15,8 @@ type CreateSystemUnitResponse struct {
 	TankVolume  int       `json:"tank_volume" binding:"required"`
 	TankAVolume int       `json:"tank_a_volume" binding:"required"`
 	TankBVolume int       `json:"tank_b_volume" binding:"required"`
+	// DeviceCredential is only returned when the unit is provisioned.
+	DeviceCredential *DeviceCredentialResponse `json:"device_credential,omitempty"`
 }
 type SystemUnitResponse struct {
 	ID          uuid.UUID `json:"id" binding:"required"`
@@ -8,6 +8,7 @@ type TankTransaction struct {
 	WaterVolume      int       `json:"water_volume" binding:"required"`
 	AVolume       int       `json:"a_volume" binding:"required"`
 	BVolume       int       `json:"b_volume" binding:"required"`
+	IngestKey
 }
 
 type TankTransactionResponse struct {
@@ -6,8 +6,15 @@ var (
 	InvalidBearerFormat = errors.New("Invalid Authorization Bearer Format")
 	InvalidToken        = errors.New("Invalid Token")
 	InvalidIssuer       = errors.New("Invalid Token Issuer")
+	InvalidAudience     = errors.New("Invalid Token Audience")
+	RefreshTokenReused  = errors.New("Refresh token reuse detected, please login again")
+	EmptyRefreshToken   = errors.New("Empty refresh token")
+	InvalidSession      = errors.New("Session expired or revoked")
 	InvalidIDParam      = errors.New("Invalid ID Parameter")
 
+	ErrorOnGettingSessions = errors.New("Error on getting sessions")
+	ErrorOnRevokingSession = errors.New("Error on revoking session")
+
 	ForbiddenAccess = errors.New("user is forbidden to access this resource")
 
 	InvalidRequestBody = errors.New("invalid request body")
@@ -22,9 +29,42 @@ var (
 	PasswordDoesntMatch           = errors.New("password doesn't match")
 	PasswordContainUsername       = errors.New("password must not contain username")
 	PasswordSameAsBefore          = errors.New("Password cannot be same as before")
+	PasswordTooShort              = errors.New("password is too short")
+	PasswordTooWeak               = errors.New("password must mix upper case, lower case, digits and symbols")
+	PasswordTooCommon             = errors.New("password is too common")
+	ErrorChangingPassword         = errors.New("Error Changing Password")
+	InvalidPasswordResetToken     = errors.New("password reset token is invalid or expired")
+	InvalidEmailVerifyToken       = errors.New("email verification token is invalid or expired")
+	EmailNotVerified              = errors.New("email is not verified, account is read-only")
+	EmailAlreadyVerified          = errors.New("email is already verified")
+	ErrorSendingVerification      = errors.New("Error Sending Email Verification")
+	ErrorRequestingPasswordReset  = errors.New("Error Requesting Password Reset")
 	UsernamePasswordIncorrect     = errors.New("username or password incorrect")
+	TooManyLoginAttempts          = errors.New("too many failed login attempts, try again later")
+	ErrorUnlockingLogin           = errors.New("Error Unlocking Login")
 	ErrorGeneratingHashedPassword = errors.New("Error Generating Hashed Password")
 	ErrorCreatingAccount          = errors.New("Error Creating Account")
+	ErrorGeneratingToken          = errors.New("Error Generating Token")
+
+	InvalidExportFormat             = errors.New("export format must be json or csv")
+	ErrorExportingAccount           = errors.New("Error Exporting Account Data")
+	AccountDeletionAlreadyScheduled = errors.New("account deletion is already scheduled")
+	AccountDeletionNotScheduled     = errors.New("account deletion is not scheduled")
+
+	MfaAlreadyEnabled     = errors.New("MFA is already enabled")
+	MfaNotEnrolled        = errors.New("MFA enrollment has not been started")
+	MfaNotEnabled         = errors.New("MFA is not enabled")
+	InvalidMfaCode        = errors.New("invalid MFA code")
+	ErrorEnrollingMfa     = errors.New("Error Enrolling MFA")
+	ErrorDisablingMfa     = errors.New("Error Disabling MFA")
+	InvalidApiToken       = errors.New("API token is invalid, expired or revoked")
+	InvalidApiTokenID     = errors.New("invalid API token ID")
+	ErrorCreatingApiToken = errors.New("Error Creating API Token")
+	ApiTokenReadOnly      = errors.New("API token is read-only")
+	StepupRequired        = errors.New("step-up authentication required")
+	ErrorGeneratingStepup = errors.New("Error Generating Step-up Token")
+	ImpersonationReadOnly = errors.New("impersonation token is read-only")
+	ErrorImpersonating    = errors.New("Error Starting Impersonation")
 
 	InvalidAccountId          = errors.New("Invalid Account Id")
 	ErrorOnCreatingNewProfile = errors.New("Error on Creating new profile")
@@ -34,10 +74,19 @@ var (
 	InvalidProfileID          = errors.New("invalid Profile ID")
 	ProfileAlreadyCreated     = errors.New("Profile already created")
 
-	ErrorOnCreatingNewFarm = errors.New("Error on Creating new farm")
-	ErrorOnDeletingFarm    = errors.New("Error on Deleting farm")
-	InvalidFarmIDParam     = errors.New("invalid Farm ID param")
-	InvalidFarmID          = errors.New("invalid Farm ID")
+	InvalidOrganizationID     = errors.New("invalid organization ID")
+	InvalidOrganizationMember = errors.New("invalid organization member")
+	LastOrganizationAdmin     = errors.New("an organization must keep at least one admin")
+
+	ErrorOnCreatingNewFarm  = errors.New("Error on Creating new farm")
+	ErrorOnDeletingFarm     = errors.New("Error on Deleting farm")
+	InvalidFarmIDParam      = errors.New("invalid Farm ID param")
+	InvalidFarmID           = errors.New("invalid Farm ID")
+	InvalidFarmMember       = errors.New("invalid farm member")
+	LastFarmOwner           = errors.New("a farm must keep at least one owner")
+	InvalidFarmInvitation   = errors.New("farm invitation is invalid, expired or already used")
+	InvalidFarmInvitationID = errors.New("invalid farm invitation ID")
+	ErrorInvitingFarmMember = errors.New("Error Inviting Farm Member")
 
 	ErrorOnCreatingNewSystemUnit = errors.New("Error on Creating new system unit")
 	ErrorOnDeletingSystemUnit    = errors.New("Error on Deleting system unit")
@@ -47,6 +96,15 @@ var (
 	InvalidUnitKey      = errors.New("invalid Unit Key")
 	InvalidUnitKeyParam = errors.New("invalid Unit Key param")
 
+	InvalidDeviceSignature        = errors.New("invalid device signature")
+	DeviceRequestExpired          = errors.New("device request timestamp is outside the allowed window")
+	DeviceNonceReused             = errors.New("device request nonce has already been used")
+	InvalidDeviceSecret           = errors.New("invalid unit key or device secret")
+	InvalidDeviceTopic            = errors.New("topic does not belong to the device")
+	DeviceCredentialRevoked       = errors.New("device credential has been rotated or revoked")
+	ErrorCheckingDeviceCredential = errors.New("Error Checking Device Credential")
+	ErrorProvisioningDevice       = errors.New("Error Provisioning Device Credential")
+
 	InvalidId = errors.New("Invalid Account Id")
 
 	ErrorOnCreatingNewTankTrans = errors.New("Error on Creating new tank transaction")
@@ -60,4 +118,36 @@ var (
 	InvalidValuePeriodQueryParams = errors.New("Invalid Period Value")
 	StartDateExceedEndDate        = errors.New("start_date exceed end_date")
 	ErrorOnGettingAggregatedData  = errors.New("error on getting aggregated data")
+
+	EmptyReadings              = errors.New("at least one reading is required")
+	InvalidMetric              = errors.New("unknown metric")
+	MetricValueOutOfRange      = errors.New("metric value is out of range")
+	MetricValueNotFinite       = errors.New("metric value must be a finite number")
+	ErrorOnGettingMetrics      = errors.New("error on getting metrics")
+	MeasuredAtOutOfRange       = errors.New("measured_at is too far from the server time")
+	IngestKeyReused            = errors.New("message_id or sequence was already used for a different request")
+	ErrorCheckingIngestKey     = errors.New("error on checking message_id or sequence")
+	ErrorOnGettingSequenceGaps = errors.New("error on getting sequence gaps")
+	ReadingQueueFull           = errors.New("too many readings waiting to be stored, retry later")
+	ReadingQueueClosed         = errors.New("readings are not accepted while the server shuts down")
+	ReadingQueueDisabled       = errors.New("reading queue is disabled")
+
+	InvalidBulkFormat      = errors.New("bulk format must be ndjson or csv")
+	InvalidBulkRow         = errors.New("row could not be parsed")
+	InvalidBulkCsvHeader   = errors.New("csv header must have farm_id, system_id and at least one metric column")
+	EmptyBulkBody          = errors.New("bulk body has no rows")
+	TooManyBulkRows        = errors.New("bulk body has too many rows")
+	BulkBodyTooLarge       = errors.New("bulk body is too large")
+	ErrorIngestingReadings = errors.New("Error Ingesting Readings")
+
+	InvalidLineProtocol     = errors.New("line is not valid line protocol")
+	InvalidLineTimestamp    = errors.New("line timestamp is not a valid integer for the precision")
+	InvalidLinePrecision    = errors.New("precision must be one of ns, us, ms, s, m or h")
+	UnknownMeasurement      = errors.New("unknown measurement, use readings or a metric name")
+	InvalidMetricField      = errors.New("a metric measurement takes a single value field")
+	NonNumericField         = errors.New("field values must be numeric")
+	MissingSystemUnitTag    = errors.New("line needs a unit_key or system_id tag")
+	AmbiguousUnitKey        = errors.New("unit_key matches several system units, add a system_id tag")
+	InvalidGzipBody         = errors.New("body is not valid gzip")
+	UnsupportedBodyEncoding = errors.New("content encoding must be gzip or identity")
 )
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/service"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/response"
+	"github.com/gin-gonic/gin"
+)
+
+type AccountDataHandler struct {
+	accountDataService service.AccountDataService
+	systemLogService   service.SystemLogService
+}
+
+type AccountDataHandlerConfig struct {
+	AccountDataService service.AccountDataService
+	SystemLogService   service.SystemLogService
+}
+
+func NewAccountDataHandler(config AccountDataHandlerConfig) *AccountDataHandler {
+	return &AccountDataHandler{
+		accountDataService: config.AccountDataService,
+		systemLogService:   config.SystemLogService,
+	}
+}
+
+// ExportAccount downloads the caller's data, as JSON by default or as a zip
+// of CSV files with ?format=csv.
+func (h *AccountDataHandler) ExportAccount(c *gin.Context) {
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	file, err := h.accountDataService.ExportAccount(caller, c.Query("format"))
+	if err != nil {
+		logger.Error("accountDataHandler", "Failed to export account data", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, errorStatus(err), err.Error())
+		return
+	}
+
+	err = h.systemLogService.CreateSystemLog("Export Account Data: " + "{Account:" + caller.AccountID.String() + "}")
+	if err != nil {
+		logger.Error("accountDataHandler", "Failed to log system event", map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="`+file.FileName+`"`)
+	c.Data(200, file.ContentType, file.Data)
+}
+
+func (h *AccountDataHandler) RequestDeletion(c *gin.Context) {
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.accountDataService.RequestDeletion(caller)
+	if err != nil {
+		logger.Error("accountDataHandler", "Failed to schedule account deletion", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, errorStatus(err), err.Error())
+		return
+	}
+
+	err = h.systemLogService.CreateSystemLog("Request Account Deletion: " + "{Account:" + caller.AccountID.String() + "}")
+	if err != nil {
+		logger.Error("accountDataHandler", "Failed to log system event", map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	response.JSON(c, 200, "Account Deletion Scheduled", resp)
+}
+
+func (h *AccountDataHandler) CancelDeletion(c *gin.Context) {
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	err = h.accountDataService.CancelDeletion(caller)
+	if err != nil {
+		logger.Error("accountDataHandler", "Failed to cancel account deletion", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, errorStatus(err), err.Error())
+		return
+	}
+
+	err = h.systemLogService.CreateSystemLog("Cancel Account Deletion: " + "{Account:" + caller.AccountID.String() + "}")
+	if err != nil {
+		logger.Error("accountDataHandler", "Failed to log system event", map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	response.JSON(c, 200, "Account Deletion Cancelled", nil)
+}
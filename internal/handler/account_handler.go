@@ -2,6 +2,7 @@ package handler
 
 import (
 	"encoding/hex"
+	"errors"
 	"net/http"
 
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/dto"
@@ -16,17 +17,23 @@ import (
 
 type AccountHandler struct {
 	accountService service.AccountService
+	sessionService service.SessionService
+	mfaService     service.MfaService
 	tokenProvider  tokenprovider.JWTTokenProvider
 }
 
 type AccountHandlerConfig struct {
 	AccountService service.AccountService
+	SessionService service.SessionService
+	MfaService     service.MfaService
 	TokenProvider  tokenprovider.JWTTokenProvider
 }
 
 func NewAccountHandler(config AccountHandlerConfig) *AccountHandler {
 	return &AccountHandler{
 		accountService: config.AccountService,
+		sessionService: config.SessionService,
+		mfaService:     config.MfaService,
 		tokenProvider:  config.TokenProvider,
 	}
 }
@@ -71,7 +78,14 @@ func (h *AccountHandler) Login(c *gin.Context) {
 		return
 	}
 
+	loginBody.UserAgent = c.Request.UserAgent()
+	loginBody.IpAddress = c.ClientIP()
+
 	resp, err := h.accountService.Login(&loginBody)
+	if errors.Is(err, errs.TooManyLoginAttempts) {
+		response.Error(c, http.StatusTooManyRequests, err.Error())
+		return
+	}
 	if err != nil {
 		logger.Error("AccountHandler", "Failed to login", map[string]string{
 			"error": err.Error(),
@@ -80,33 +94,374 @@ func (h *AccountHandler) Login(c *gin.Context) {
 		return
 	}
 
+	if resp.MfaRequired {
+		response.JSON(c, 200, "MFA code required", resp)
+		return
+	}
+
+	setTokenCookies(c, resp)
+
+	response.JSON(c, 200, "Login success", resp)
+}
+
+// LoginMfa takes the mfa token returned by Login in the OtpToken header and a
+// TOTP or recovery code in the body.
+func (h *AccountHandler) LoginMfa(c *gin.Context) {
+	var loginMfaBody dto.LoginMfaBody
+
+	if err := c.ShouldBindJSON(&loginMfaBody); err != nil {
+		response.Error(c, 400, errs.InvalidRequestBody.Error())
+		return
+	}
+
+	loginMfaBody.MfaToken = c.Request.Header.Get("OtpToken")
+	loginMfaBody.UserAgent = c.Request.UserAgent()
+	loginMfaBody.IpAddress = c.ClientIP()
+
+	resp, err := h.accountService.LoginMfa(&loginMfaBody)
+	if errors.Is(err, errs.TooManyLoginAttempts) {
+		response.Error(c, http.StatusTooManyRequests, err.Error())
+		return
+	}
+	if errors.Is(err, errs.InvalidToken) {
+		response.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+	if err != nil {
+		logger.Error("AccountHandler", "Failed to complete MFA login", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, 400, err.Error())
+		return
+	}
+
+	setTokenCookies(c, resp)
+
+	response.JSON(c, 200, "Login success", resp)
+}
+
+func (h *AccountHandler) Refresh(c *gin.Context) {
+	refreshToken, err := h.tokenProvider.ExtractToken(c.Request.Header.Get("Authorization"))
+	if err != nil {
+		cookieToken, cookieErr := c.Cookie("refresh-token")
+		if cookieErr != nil || cookieToken == "" {
+			logger.Error("AccountHandler", "Failed to extract refresh token", map[string]string{
+				"error": err.Error(),
+			})
+			response.Error(c, 400, errs.EmptyRefreshToken.Error())
+			return
+		}
+		refreshToken = cookieToken
+	}
+
+	resp, err := h.accountService.Refresh(refreshToken)
+	if errors.Is(err, errs.InvalidToken) || errors.Is(err, errs.RefreshTokenReused) {
+		logger.Error("AccountHandler Refresh", "Rejected refresh token", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+	if err != nil {
+		logger.Error("AccountHandler Refresh", "Failed to renew access token", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, 400, err.Error())
+		return
+	}
+
+	setTokenCookies(c, resp)
+
+	response.JSON(c, 200, "Renew Access Token Success", resp)
+}
+
+func setTokenCookies(c *gin.Context, resp *dto.LoginResponse) {
 	c.SetSameSite(http.SameSiteLaxMode)
 	c.SetCookie("refresh-token", resp.RefreshToken, 3600*24*30, "", "", true, true)
 	c.SetCookie("access-token", resp.AccesToken, 3600*24*30, "", "/", true, true)
+}
 
-	response.JSON(c, 200, "Login success", resp)
+func (h *AccountHandler) Logout(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		response.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	sessionId, err := getSessionId(c)
+	if err != nil {
+		response.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	resp, err := h.sessionService.Logout(sessionId, userId)
+	if err != nil {
+		logger.Error("AccountHandler", "Failed to logout", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, 400, err.Error())
+		return
+	}
+
+	clearTokenCookies(c)
+
+	response.JSON(c, 200, "Logout Success", resp)
 }
 
-func (h *AccountHandler) Refresh(c *gin.Context) {
-	authHeader := c.Request.Header.Get("Authorization")
+func (h *AccountHandler) LogoutAll(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		response.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
 
-	refreshToken, err := h.tokenProvider.ExtractToken(authHeader)
+	resp, err := h.sessionService.LogoutAll(userId)
 	if err != nil {
-		logger.Error("AccountHandler", "Failed to extract refresh token", map[string]string{
+		logger.Error("AccountHandler", "Failed to logout all sessions", map[string]string{
 			"error": err.Error(),
 		})
 		response.Error(c, 400, err.Error())
 		return
 	}
 
-	token, err := h.tokenProvider.RenewAccessToken(refreshToken)
+	clearTokenCookies(c)
+
+	response.JSON(c, 200, "Logout All Sessions Success", resp)
+}
+
+func (h *AccountHandler) GetSessions(c *gin.Context) {
+	userId, err := getUserId(c)
 	if err != nil {
-		logger.Error("AccountHandler Refresh", "Failed to renew access token", map[string]string{
+		response.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	sessionId, err := getSessionId(c)
+	if err != nil {
+		response.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	resp, err := h.sessionService.GetActiveSessions(userId, sessionId)
+	if err != nil {
+		logger.Error("AccountHandler", "Failed to get sessions", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, 400, err.Error())
+		return
+	}
+
+	response.JSON(c, 200, "Get Sessions Success", resp)
+}
+
+func (h *AccountHandler) ChangePassword(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		response.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var changePasswordBody dto.ChangePasswordBody
+	if err := c.ShouldBindJSON(&changePasswordBody); err != nil {
+		response.Error(c, 400, errs.InvalidRequestBody.Error())
+		return
+	}
+
+	err = h.accountService.ChangePassword(userId, &changePasswordBody)
+	if err != nil {
+		logger.Error("AccountHandler", "Failed to change password", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, 400, err.Error())
+		return
+	}
+
+	response.JSON(c, 200, "Change Password Success", nil)
+}
+
+func (h *AccountHandler) ForgotPassword(c *gin.Context) {
+	var forgotPasswordBody dto.ForgotPasswordBody
+	if err := c.ShouldBindJSON(&forgotPasswordBody); err != nil {
+		response.Error(c, 400, errs.InvalidRequestBody.Error())
+		return
+	}
+
+	err := h.accountService.ForgotPassword(&forgotPasswordBody)
+	if err != nil {
+		logger.Error("AccountHandler", "Failed to request password reset", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, 400, err.Error())
+		return
+	}
+
+	response.JSON(c, 200, "If the email is registered, a reset link has been sent", nil)
+}
+
+func (h *AccountHandler) ResetPassword(c *gin.Context) {
+	var resetPasswordBody dto.ResetPasswordBody
+	if err := c.ShouldBindJSON(&resetPasswordBody); err != nil {
+		response.Error(c, 400, errs.InvalidRequestBody.Error())
+		return
+	}
+
+	err := h.accountService.ResetPassword(&resetPasswordBody)
+	if err != nil {
+		logger.Error("AccountHandler", "Failed to reset password", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, 400, err.Error())
+		return
+	}
+
+	clearTokenCookies(c)
+
+	response.JSON(c, 200, "Reset Password Success", nil)
+}
+
+func (h *AccountHandler) VerifyEmail(c *gin.Context) {
+	var verifyEmailBody dto.VerifyEmailBody
+	if err := c.ShouldBindJSON(&verifyEmailBody); err != nil {
+		response.Error(c, 400, errs.InvalidRequestBody.Error())
+		return
+	}
+
+	err := h.accountService.VerifyEmail(&verifyEmailBody)
+	if err != nil {
+		logger.Error("AccountHandler", "Failed to verify email", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, 400, err.Error())
+		return
+	}
+
+	response.JSON(c, 200, "Verify Email Success", nil)
+}
+
+func (h *AccountHandler) ResendVerification(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		response.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	err = h.accountService.ResendVerification(userId)
+	if err != nil {
+		logger.Error("AccountHandler", "Failed to resend verification email", map[string]string{
 			"error": err.Error(),
 		})
 		response.Error(c, 400, err.Error())
 		return
 	}
 
-	response.JSON(c, 200, "Renew Access Token Success", token)
+	response.JSON(c, 200, "Verification Email Sent", nil)
+}
+
+func clearTokenCookies(c *gin.Context) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie("refresh-token", "", -1, "", "", true, true)
+	c.SetCookie("access-token", "", -1, "", "/", true, true)
+}
+
+func (h *AccountHandler) EnrollMfa(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		response.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	resp, err := h.mfaService.Enroll(userId)
+	if err != nil {
+		logger.Error("AccountHandler", "Failed to enroll MFA", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, 400, err.Error())
+		return
+	}
+
+	response.JSON(c, 200, "Enroll MFA Success", resp)
+}
+
+func (h *AccountHandler) ActivateMfa(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		response.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var mfaCodeBody dto.MfaCodeBody
+	if err := c.ShouldBindJSON(&mfaCodeBody); err != nil {
+		response.Error(c, 400, errs.InvalidRequestBody.Error())
+		return
+	}
+	mfaCodeBody.IpAddress = c.ClientIP()
+
+	resp, err := h.mfaService.Activate(userId, &mfaCodeBody)
+	if err != nil {
+		logger.Error("AccountHandler", "Failed to activate MFA", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, errorStatus(err), err.Error())
+		return
+	}
+
+	response.JSON(c, 200, "Activate MFA Success", resp)
+}
+
+func (h *AccountHandler) DisableMfa(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		response.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var mfaCodeBody dto.MfaCodeBody
+	if err := c.ShouldBindJSON(&mfaCodeBody); err != nil {
+		response.Error(c, 400, errs.InvalidRequestBody.Error())
+		return
+	}
+	mfaCodeBody.IpAddress = c.ClientIP()
+
+	err = h.mfaService.Disable(userId, &mfaCodeBody)
+	if err != nil {
+		logger.Error("AccountHandler", "Failed to disable MFA", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, errorStatus(err), err.Error())
+		return
+	}
+
+	response.JSON(c, 200, "Disable MFA Success", nil)
+}
+
+func (h *AccountHandler) StepUp(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		response.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	sessionId, err := getSessionId(c)
+	if err != nil {
+		response.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var stepupBody dto.StepupBody
+	if err := c.ShouldBindJSON(&stepupBody); err != nil {
+		response.Error(c, 400, errs.InvalidRequestBody.Error())
+		return
+	}
+	stepupBody.IpAddress = c.ClientIP()
+
+	resp, err := h.mfaService.StepUp(userId, sessionId, &stepupBody)
+	if err != nil {
+		logger.Error("AccountHandler", "Failed to step up", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, errorStatus(err), err.Error())
+		return
+	}
+
+	response.JSON(c, 200, "Step-up Success", resp)
 }
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/dto"
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/service"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/response"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type ApiTokenHandler struct {
+	apiTokenService  service.ApiTokenService
+	systemLogService service.SystemLogService
+}
+
+type ApiTokenHandlerConfig struct {
+	ApiTokenService  service.ApiTokenService
+	SystemLogService service.SystemLogService
+}
+
+func NewApiTokenHandler(config ApiTokenHandlerConfig) *ApiTokenHandler {
+	return &ApiTokenHandler{
+		apiTokenService:  config.ApiTokenService,
+		systemLogService: config.SystemLogService,
+	}
+}
+
+func (h *ApiTokenHandler) CreateApiToken(c *gin.Context) {
+	var createApiTokenBody dto.CreateApiTokenBody
+	if err := c.ShouldBindJSON(&createApiTokenBody); err != nil {
+		logger.Error("apiTokenHandler", "Invalid request body", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, 400, errs.InvalidRequestBody.Error())
+		return
+	}
+
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.apiTokenService.CreateApiToken(caller, &createApiTokenBody)
+	if err != nil {
+		logger.Error("apiTokenHandler", "Failed to create API token", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, errorStatus(err), err.Error())
+		return
+	}
+
+	err = h.systemLogService.CreateSystemLog("Create API Token: " + "{ID:" + resp.ID.String() + "}")
+	if err != nil {
+		logger.Error("apiTokenHandler", "Failed to log system event", map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	response.JSON(c, 201, "Create API Token Success", resp)
+}
+
+func (h *ApiTokenHandler) GetApiTokens(c *gin.Context) {
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.apiTokenService.GetApiTokens(caller)
+	if err != nil {
+		logger.Error("apiTokenHandler", "Failed to get API tokens", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, 400, err.Error())
+		return
+	}
+
+	response.JSON(c, 200, "Get API Tokens Success", resp)
+}
+
+func (h *ApiTokenHandler) RevokeApiToken(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("tokenId"))
+	if err != nil {
+		response.Error(c, 400, errs.InvalidIDParam.Error())
+		return
+	}
+
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	err = h.apiTokenService.RevokeApiToken(caller, &id)
+	if errors.Is(err, errs.InvalidApiTokenID) {
+		response.Error(c, 404, err.Error())
+		return
+	}
+	if err != nil {
+		logger.Error("apiTokenHandler", "Failed to revoke API token", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, 400, err.Error())
+		return
+	}
+
+	err = h.systemLogService.CreateSystemLog("Revoke API Token: " + "{ID:" + id.String() + "}")
+	if err != nil {
+		logger.Error("apiTokenHandler", "Failed to log system event", map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	response.JSON(c, 200, "Revoke API Token Success", nil)
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/constant"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/dto"
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/tokenprovider"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// getUserClaims returns the claims that the auth middleware stored for the caller.
+func getUserClaims(c *gin.Context) (*tokenprovider.UserClaims, error) {
+	value, ok := c.Get(constant.ContextKeyUser)
+	if !ok {
+		return nil, errs.InvalidToken
+	}
+
+	claims, ok := value.(tokenprovider.UserClaims)
+	if !ok {
+		return nil, errs.InvalidToken
+	}
+
+	return &claims, nil
+}
+
+func getUserId(c *gin.Context) (*uuid.UUID, error) {
+	claims, err := getUserClaims(c)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return nil, errs.InvalidToken
+	}
+
+	return &id, nil
+}
+
+func getSessionId(c *gin.Context) (*uuid.UUID, error) {
+	value, ok := c.Get(constant.ContextKeySession)
+	if !ok {
+		return nil, errs.InvalidSession
+	}
+
+	id, ok := value.(uuid.UUID)
+	if !ok {
+		return nil, errs.InvalidSession
+	}
+
+	return &id, nil
+}
+
+// getCaller builds the identity that services use to scope data access.
+func getCaller(c *gin.Context) (*dto.Caller, error) {
+	claims, err := getUserClaims(c)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return nil, errs.InvalidToken
+	}
+
+	organizationId, err := uuid.Parse(claims.OrganizationID)
+	if err != nil {
+		return nil, errs.InvalidToken
+	}
+
+	caller := &dto.Caller{
+		AccountID:        id,
+		Role:             claims.Role,
+		OrganizationID:   organizationId,
+		OrganizationRole: claims.OrganizationRole,
+		ReadOnly:         claims.ReadOnly,
+	}
+	if value, ok := c.Get(constant.ContextKeyApiToken); ok {
+		if apiToken, ok := value.(*dto.ApiTokenAuth); ok {
+			caller.FarmIds = apiToken.FarmIds
+			caller.ReadOnly = apiToken.ReadOnly
+		}
+	}
+
+	return caller, nil
+}
+
+// getDevice returns the system unit that the device auth middleware verified.
+func getDevice(c *gin.Context) (*dto.DeviceAuth, error) {
+	value, ok := c.Get(constant.ContextKeyDevice)
+	if !ok {
+		return nil, errs.InvalidDeviceSignature
+	}
+
+	device, ok := value.(*dto.DeviceAuth)
+	if !ok {
+		return nil, errs.InvalidDeviceSignature
+	}
+
+	return device, nil
+}
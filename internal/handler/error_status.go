@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+)
+
+// errorStatus maps a service error to the HTTP status returned to the client.
+// Resources that are missing or owned by another tenant answer with 404,
+// members whose farm role is too weak with 403.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, errs.InvalidProfileID),
+		errors.Is(err, errs.InvalidFarmID),
+		errors.Is(err, errs.InvalidSystemUnitID),
+		errors.Is(err, errs.InvalidFarmMember),
+		errors.Is(err, errs.InvalidFarmInvitationID),
+		errors.Is(err, errs.InvalidOrganizationID),
+		errors.Is(err, errs.InvalidOrganizationMember):
+		return http.StatusNotFound
+	case errors.Is(err, errs.ForbiddenAccess):
+		return http.StatusForbidden
+	case errors.Is(err, errs.LastFarmOwner),
+		errors.Is(err, errs.LastOrganizationAdmin),
+		errors.Is(err, errs.AccountDeletionAlreadyScheduled),
+		errors.Is(err, errs.AccountDeletionNotScheduled),
+		errors.Is(err, errs.IngestKeyReused):
+		return http.StatusConflict
+	case errors.Is(err, errs.TooManyBulkRows),
+		errors.Is(err, errs.BulkBodyTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, errs.UnsupportedBodyEncoding):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, errs.ReadingQueueFull),
+		errors.Is(err, errs.TooManyLoginAttempts):
+		return http.StatusTooManyRequests
+	case errors.Is(err, errs.ReadingQueueClosed):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusBadRequest
+	}
+}
@@ -45,12 +45,18 @@ func (h *FarmHandler) CreateFarm(c *gin.Context) {
 		"name": createFarmBody.Name,
 	})
 
-	resp, err := h.farmService.CreateFarm(createFarmBody)
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.farmService.CreateFarm(caller, createFarmBody)
 	if err != nil {
 		logger.Error("farmHandler", "Failed to create farm", map[string]string{
 			"error": err.Error(),
 		})
-		response.Error(c, 400, err.Error())
+		response.Error(c, errorStatus(err), err.Error())
 		return
 	}
 
@@ -72,12 +78,18 @@ func (h *FarmHandler) CreateFarm(c *gin.Context) {
 func (h *FarmHandler) GetFarms(c *gin.Context) {
 	logger.Info("farmHandler", "Fetching all farms", nil)
 
-	resp, err := h.farmService.GetFarms()
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.farmService.GetFarms(caller)
 	if err != nil {
 		logger.Error("farmHandler", "Failed to fetch farms", map[string]string{
 			"error": err.Error(),
 		})
-		response.Error(c, 400, err.Error())
+		response.Error(c, errorStatus(err), err.Error())
 		return
 	}
 
@@ -99,12 +111,18 @@ func (h *FarmHandler) GetFarmDetails(c *gin.Context) {
 		"farmID": farmId,
 	})
 
-	resp, err := h.farmService.GetFarmDetails(&id)
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.farmService.GetFarmDetails(caller, &id)
 	if err != nil {
 		logger.Error("farmHandler", "Failed to fetch farm details", map[string]string{
 			"error": err.Error(),
 		})
-		response.Error(c, 400, err.Error())
+		response.Error(c, errorStatus(err), err.Error())
 		return
 	}
 
@@ -136,12 +154,18 @@ func (h *FarmHandler) UpdateFarm(c *gin.Context) {
 		"farmID": paramId,
 	})
 
-	resp, err := h.farmService.UpdateFarm(&id, updateFarmBody)
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.farmService.UpdateFarm(caller, &id, updateFarmBody)
 	if err != nil {
 		logger.Error("farmHandler", "Failed to update farm", map[string]string{
 			"error": err.Error(),
 		})
-		response.Error(c, 400, err.Error())
+		response.Error(c, errorStatus(err), err.Error())
 		return
 	}
 	err = h.systemLogService.CreateSystemLog("Update Farm: " + "{ID:" + hex.EncodeToString(resp.ID[:]) + "}")
@@ -171,12 +195,18 @@ func (h *FarmHandler) DeleteFarm(c *gin.Context) {
 		"farmID": farmId,
 	})
 
-	resp, err := h.farmService.DeleteFarm(&id)
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.farmService.DeleteFarm(caller, &id)
 	if err != nil {
 		logger.Error("farmHandler", "Failed to delete farm", map[string]string{
 			"error": err.Error(),
 		})
-		response.Error(c, 400, err.Error())
+		response.Error(c, errorStatus(err), err.Error())
 		return
 	}
 	err = h.systemLogService.CreateSystemLog("Delete Farm: " + "{ID:" + hex.EncodeToString(resp.ID[:]) + "}")
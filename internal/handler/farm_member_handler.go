@@ -0,0 +1,276 @@
+package handler
+
+import (
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/dto"
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/service"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/response"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type FarmMemberHandler struct {
+	farmMemberService service.FarmMemberService
+	systemLogService  service.SystemLogService
+}
+
+type FarmMemberHandlerConfig struct {
+	FarmMemberService service.FarmMemberService
+	SystemLogService  service.SystemLogService
+}
+
+func NewFarmMemberHandler(config FarmMemberHandlerConfig) *FarmMemberHandler {
+	return &FarmMemberHandler{
+		farmMemberService: config.FarmMemberService,
+		systemLogService:  config.SystemLogService,
+	}
+}
+
+func (h *FarmMemberHandler) GetMembers(c *gin.Context) {
+	farmId, err := uuid.Parse(c.Param("farmId"))
+	if err != nil {
+		response.Error(c, 400, errs.InvalidFarmIDParam.Error())
+		return
+	}
+
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.farmMemberService.GetMembers(caller, &farmId)
+	if err != nil {
+		logger.Error("farmMemberHandler", "Failed to get farm members", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, errorStatus(err), err.Error())
+		return
+	}
+
+	response.JSON(c, 200, "Get Farm Members Success", resp)
+}
+
+func (h *FarmMemberHandler) UpdateMemberRole(c *gin.Context) {
+	farmId, err := uuid.Parse(c.Param("farmId"))
+	if err != nil {
+		response.Error(c, 400, errs.InvalidFarmIDParam.Error())
+		return
+	}
+
+	accountId, err := uuid.Parse(c.Param("accountId"))
+	if err != nil {
+		response.Error(c, 400, errs.InvalidIDParam.Error())
+		return
+	}
+
+	var updateMemberBody dto.UpdateFarmMemberBody
+	if err := c.ShouldBindJSON(&updateMemberBody); err != nil {
+		logger.Error("farmMemberHandler", "Invalid request body", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, 400, errs.InvalidRequestBody.Error())
+		return
+	}
+
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.farmMemberService.UpdateMemberRole(caller, &farmId, &accountId, &updateMemberBody)
+	if err != nil {
+		logger.Error("farmMemberHandler", "Failed to update farm member", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, errorStatus(err), err.Error())
+		return
+	}
+
+	err = h.systemLogService.CreateSystemLog("Update Farm Member: " + "{Farm:" + farmId.String() + ", Account:" + accountId.String() + ", Role:" + resp.Role + "}")
+	if err != nil {
+		logger.Error("farmMemberHandler", "Failed to log system event", map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	response.JSON(c, 200, "Update Farm Member Success", resp)
+}
+
+func (h *FarmMemberHandler) RemoveMember(c *gin.Context) {
+	farmId, err := uuid.Parse(c.Param("farmId"))
+	if err != nil {
+		response.Error(c, 400, errs.InvalidFarmIDParam.Error())
+		return
+	}
+
+	accountId, err := uuid.Parse(c.Param("accountId"))
+	if err != nil {
+		response.Error(c, 400, errs.InvalidIDParam.Error())
+		return
+	}
+
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.farmMemberService.RemoveMember(caller, &farmId, &accountId)
+	if err != nil {
+		logger.Error("farmMemberHandler", "Failed to remove farm member", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, errorStatus(err), err.Error())
+		return
+	}
+
+	err = h.systemLogService.CreateSystemLog("Remove Farm Member: " + "{Farm:" + farmId.String() + ", Account:" + accountId.String() + "}")
+	if err != nil {
+		logger.Error("farmMemberHandler", "Failed to log system event", map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	response.JSON(c, 200, "Remove Farm Member Success", resp)
+}
+
+func (h *FarmMemberHandler) InviteMember(c *gin.Context) {
+	farmId, err := uuid.Parse(c.Param("farmId"))
+	if err != nil {
+		response.Error(c, 400, errs.InvalidFarmIDParam.Error())
+		return
+	}
+
+	var inviteMemberBody dto.InviteFarmMemberBody
+	if err := c.ShouldBindJSON(&inviteMemberBody); err != nil {
+		logger.Error("farmMemberHandler", "Invalid request body", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, 400, errs.InvalidRequestBody.Error())
+		return
+	}
+
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.farmMemberService.InviteMember(caller, &farmId, &inviteMemberBody)
+	if err != nil {
+		logger.Error("farmMemberHandler", "Failed to invite farm member", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, errorStatus(err), err.Error())
+		return
+	}
+
+	err = h.systemLogService.CreateSystemLog("Invite Farm Member: " + "{Farm:" + farmId.String() + ", Invitation:" + resp.ID.String() + ", Role:" + resp.Role + "}")
+	if err != nil {
+		logger.Error("farmMemberHandler", "Failed to log system event", map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	response.JSON(c, 201, "Invite Farm Member Success", resp)
+}
+
+func (h *FarmMemberHandler) GetInvitations(c *gin.Context) {
+	farmId, err := uuid.Parse(c.Param("farmId"))
+	if err != nil {
+		response.Error(c, 400, errs.InvalidFarmIDParam.Error())
+		return
+	}
+
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.farmMemberService.GetInvitations(caller, &farmId)
+	if err != nil {
+		logger.Error("farmMemberHandler", "Failed to get farm invitations", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, errorStatus(err), err.Error())
+		return
+	}
+
+	response.JSON(c, 200, "Get Farm Invitations Success", resp)
+}
+
+func (h *FarmMemberHandler) RevokeInvitation(c *gin.Context) {
+	farmId, err := uuid.Parse(c.Param("farmId"))
+	if err != nil {
+		response.Error(c, 400, errs.InvalidFarmIDParam.Error())
+		return
+	}
+
+	invitationId, err := uuid.Parse(c.Param("invitationId"))
+	if err != nil {
+		response.Error(c, 400, errs.InvalidIDParam.Error())
+		return
+	}
+
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	err = h.farmMemberService.RevokeInvitation(caller, &farmId, &invitationId)
+	if err != nil {
+		logger.Error("farmMemberHandler", "Failed to revoke farm invitation", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, errorStatus(err), err.Error())
+		return
+	}
+
+	err = h.systemLogService.CreateSystemLog("Revoke Farm Invitation: " + "{Farm:" + farmId.String() + ", Invitation:" + invitationId.String() + "}")
+	if err != nil {
+		logger.Error("farmMemberHandler", "Failed to log system event", map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	response.JSON(c, 200, "Revoke Farm Invitation Success", nil)
+}
+
+// AcceptInvitation joins the farm of an invitation with the signed in
+// account. New users pass the token when registering instead.
+func (h *FarmMemberHandler) AcceptInvitation(c *gin.Context) {
+	var acceptInvitationBody dto.AcceptFarmInvitationBody
+	if err := c.ShouldBindJSON(&acceptInvitationBody); err != nil {
+		logger.Error("farmMemberHandler", "Invalid request body", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, 400, errs.InvalidRequestBody.Error())
+		return
+	}
+
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.farmMemberService.AcceptInvitation(caller, &acceptInvitationBody)
+	if err != nil {
+		response.Error(c, errorStatus(err), err.Error())
+		return
+	}
+
+	err = h.systemLogService.CreateSystemLog("Accept Farm Invitation: " + "{Farm:" + resp.FarmID.String() + ", Account:" + caller.AccountID.String() + "}")
+	if err != nil {
+		logger.Error("farmMemberHandler", "Failed to log system event", map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	response.JSON(c, 200, "Accept Farm Invitation Success", resp)
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"encoding/hex"
+	"strings"
 	"time"
 
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/dto"
@@ -40,12 +41,18 @@ func (h *GrowthHistHandler) CreateGrowthHist(c *gin.Context) {
 		return
 	}
 
-	resp, err := h.growthHistService.CreateGrowthHist(createGrowthHistBody)
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.growthHistService.CreateGrowthHist(caller, createGrowthHistBody)
 	if err != nil {
 		logger.Error("growthHistHandler", "Failed to create growth history", map[string]string{
 			"error": err.Error(),
 		})
-		response.Error(c, 400, err.Error())
+		response.Error(c, errorStatus(err), err.Error())
 		return
 	}
 
@@ -57,6 +64,41 @@ func (h *GrowthHistHandler) CreateGrowthHist(c *gin.Context) {
 	response.JSON(c, 201, "Create Growth History Success", resp)
 }
 
+func (h *GrowthHistHandler) CreateDeviceGrowthHist(c *gin.Context) {
+	var createGrowthHistBody *dto.DeviceGrowthHist
+
+	if err := c.ShouldBindJSON(&createGrowthHistBody); err != nil {
+		logger.Error("growthHistHandler", "Invalid request body", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, 400, errs.InvalidRequestBody.Error())
+		return
+	}
+
+	device, err := getDevice(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.growthHistService.CreateDeviceGrowthHist(device, createGrowthHistBody)
+	if err != nil {
+		logger.Error("growthHistHandler", "Failed to create growth history", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, errorStatus(err), err.Error())
+		return
+	}
+
+	h.systemLogService.CreateSystemLog("Create Growth History: " + "{ID:" + hex.EncodeToString(resp.ID[:]) + ", Device:" + device.KeyId + "}")
+	logger.Info("growthHistHandler", "Growth history created by device", map[string]string{
+		"ID":    hex.EncodeToString(resp.ID[:]),
+		"keyId": device.KeyId,
+	})
+
+	response.JSON(c, 201, "Create Growth History Success", resp)
+}
+
 func (h *GrowthHistHandler) GetGrowthHistAggregationByFilter(c *gin.Context) {
 	period := c.Query("period")
 	startDate := c.Query("start_date")
@@ -77,7 +119,13 @@ func (h *GrowthHistHandler) GetGrowthHistAggregationByFilter(c *gin.Context) {
 		return
 	}
 
-	resp, err := h.growthHistService.GetGrowthHistAggregationByFilter(&dto.GetGrowthFilter{
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.growthHistService.GetGrowthHistAggregationByFilter(caller, &dto.GetGrowthFilter{
 		FarmId:    farmId,
 		SystemId:  systemId,
 		StartDate: startDateVal,
@@ -88,7 +136,7 @@ func (h *GrowthHistHandler) GetGrowthHistAggregationByFilter(c *gin.Context) {
 		logger.Error("growthHistHandler", "Failed to fetch growth history aggregation", map[string]string{
 			"error": err.Error(),
 		})
-		response.Error(c, 400, err.Error())
+		response.Error(c, errorStatus(err), err.Error())
 		return
 	}
 	logger.Info("growthHistHandler", "Fetched growth history aggregation", map[string]string{
@@ -117,7 +165,13 @@ func (h *GrowthHistHandler) GetGrowthHistByFilter(c *gin.Context) {
 		return
 	}
 
-	resp, err := h.growthHistService.GetGrowthHistByFilter(&dto.GetGrowthFilter{
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.growthHistService.GetGrowthHistByFilter(caller, &dto.GetGrowthFilter{
 		FarmId:    farmId,
 		SystemId:  systemId,
 		StartDate: startDateVal,
@@ -128,13 +182,185 @@ func (h *GrowthHistHandler) GetGrowthHistByFilter(c *gin.Context) {
 		logger.Error("growthHistHandler", "Failed to fetch growth history", map[string]string{
 			"error": err.Error(),
 		})
-		response.Error(c, 400, err.Error())
+		response.Error(c, errorStatus(err), err.Error())
 		return
 	}
 	logger.Info("growthHistHandler", "Fetched growth history successfully", nil)
 	response.JSON(c, 200, "Get Growth History Success", resp)
 }
 
+func (h *GrowthHistHandler) GetMetrics(c *gin.Context) {
+	resp, err := h.growthHistService.GetMetrics()
+	if err != nil {
+		logger.Error("growthHistHandler", "Failed to fetch metrics", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, errorStatus(err), err.Error())
+		return
+	}
+
+	response.JSON(c, 200, "Get Metrics Success", resp)
+}
+
+func (h *GrowthHistHandler) CreateReadings(c *gin.Context) {
+	var createReadingsBody *dto.CreateReadingsBody
+
+	if err := c.ShouldBindJSON(&createReadingsBody); err != nil {
+		logger.Error("growthHistHandler", "Invalid request body", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, 400, errs.InvalidRequestBody.Error())
+		return
+	}
+
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.growthHistService.CreateReadings(caller, createReadingsBody)
+	if err != nil {
+		logger.Error("growthHistHandler", "Failed to create readings", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, errorStatus(err), err.Error())
+		return
+	}
+
+	h.systemLogService.CreateSystemLog("Create Readings: " + "{Sample:" + resp.SampleId.String() + "}")
+	logger.Info("growthHistHandler", "Readings created", map[string]string{
+		"sampleId": resp.SampleId.String(),
+	})
+
+	response.JSON(c, 201, "Create Readings Success", resp)
+}
+
+func (h *GrowthHistHandler) CreateDeviceReadings(c *gin.Context) {
+	var createReadingsBody *dto.DeviceReadingsBody
+
+	if err := c.ShouldBindJSON(&createReadingsBody); err != nil {
+		logger.Error("growthHistHandler", "Invalid request body", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, 400, errs.InvalidRequestBody.Error())
+		return
+	}
+
+	device, err := getDevice(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.growthHistService.CreateDeviceReadings(device, createReadingsBody)
+	if err != nil {
+		logger.Error("growthHistHandler", "Failed to create readings", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, errorStatus(err), err.Error())
+		return
+	}
+
+	h.systemLogService.CreateSystemLog("Create Readings: " + "{Sample:" + resp.SampleId.String() + ", Device:" + device.KeyId + "}")
+	logger.Info("growthHistHandler", "Readings created by device", map[string]string{
+		"sampleId": resp.SampleId.String(),
+		"keyId":    device.KeyId,
+	})
+
+	response.JSON(c, 201, "Create Readings Success", resp)
+}
+
+func (h *GrowthHistHandler) GetReadingAggregationByFilter(c *gin.Context) {
+	period := c.Query("period")
+	startDate := c.Query("start_date")
+	endDate := c.Query("end_date")
+	farmId := c.Query("farm_id")
+	systemId := c.Query("system_id")
+
+	var startDateVal time.Time
+	var endDateVal time.Time
+
+	checkerFlag, err := getGrowthHistQueryParamsValidator(&period, &farmId, &systemId, &startDate, &endDate, &startDateVal, &endDateVal)
+
+	if !checkerFlag {
+		logger.Error("growthHistHandler", "Invalid query parameters", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, 400, err.Error())
+		return
+	}
+
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.growthHistService.GetReadingAggregationByFilter(caller, &dto.GetGrowthFilter{
+		FarmId:    farmId,
+		SystemId:  systemId,
+		StartDate: startDateVal,
+		EndDate:   endDateVal,
+		Period:    period,
+		Metrics:   getMetricsQuery(c),
+	})
+	if err != nil {
+		logger.Error("growthHistHandler", "Failed to fetch reading aggregation", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, errorStatus(err), err.Error())
+		return
+	}
+
+	response.JSON(c, 200, "Get "+resp.Period+" Aggregate Readings Success", resp.AggregateData)
+}
+
+func (h *GrowthHistHandler) GetReadingsByFilter(c *gin.Context) {
+	period := "custom"
+	startDate := c.Query("start_date")
+	endDate := c.Query("end_date")
+	farmId := c.Query("farm_id")
+	systemId := c.Query("system_id")
+
+	var startDateVal time.Time
+	var endDateVal time.Time
+
+	checkerFlag, err := getGrowthHistQueryParamsValidator(&period, &farmId, &systemId, &startDate, &endDate, &startDateVal, &endDateVal)
+
+	if !checkerFlag {
+		logger.Error("growthHistHandler", "Invalid query parameters", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, 400, err.Error())
+		return
+	}
+
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.growthHistService.GetReadingsByFilter(caller, &dto.GetGrowthFilter{
+		FarmId:    farmId,
+		SystemId:  systemId,
+		StartDate: startDateVal,
+		EndDate:   endDateVal,
+		Period:    period,
+		Metrics:   getMetricsQuery(c),
+	})
+	if err != nil {
+		logger.Error("growthHistHandler", "Failed to fetch readings", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, errorStatus(err), err.Error())
+		return
+	}
+
+	response.JSON(c, 200, "Get Readings Success", resp)
+}
+
 func (h *GrowthHistHandler) GenerateDummyData(c *gin.Context) {
 	var createGrowthHistBody *dto.GrowthHistDummyDataBody
 
@@ -145,12 +371,18 @@ func (h *GrowthHistHandler) GenerateDummyData(c *gin.Context) {
 		response.Error(c, 400, errs.InvalidRequestBody.Error())
 		return
 	}
-	resp, err := h.growthHistService.GenerateDummyData(createGrowthHistBody)
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.growthHistService.GenerateDummyData(caller, createGrowthHistBody)
 	if err != nil {
 		logger.Error("growthHistHandler", "Failed to generate dummy data", map[string]string{
 			"error": err.Error(),
 		})
-		response.Error(c, 400, err.Error())
+		response.Error(c, errorStatus(err), err.Error())
 		return
 	}
 	logger.Info("growthHistHandler", "Dummy data generated successfully", nil)
@@ -191,3 +423,15 @@ func getGrowthHistQueryParamsValidator(period *string, farmId *string, systemId
 
 	return true, nil
 }
+
+// getMetricsQuery reads the comma separated metrics query param.
+func getMetricsQuery(c *gin.Context) []string {
+	var metrics []string
+	for _, metric := range strings.Split(c.Query("metrics"), ",") {
+		metric = strings.TrimSpace(metric)
+		if metric != "" {
+			metrics = append(metrics, metric)
+		}
+	}
+	return metrics
+}
@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/tokenprovider"
+	"github.com/gin-gonic/gin"
+)
+
+type KeyHandler struct {
+	keySet tokenprovider.KeySet
+}
+
+type KeyHandlerConfig struct {
+	KeySet tokenprovider.KeySet
+}
+
+func NewKeyHandler(config KeyHandlerConfig) *KeyHandler {
+	return &KeyHandler{
+		keySet: config.KeySet,
+	}
+}
+
+// GetJWKS publishes the public verification keys. The body is a plain JWK Set
+// rather than the usual response envelope so standard JWT libraries can read
+// it directly.
+func (h *KeyHandler) GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.keySet.JWKS())
+}
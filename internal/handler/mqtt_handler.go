@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/dto"
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/service"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/mqtt"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+)
+
+// Topics a device publishes to, under farms/{unit_key}/.
+const (
+	mqttTopicReadings = "readings"
+	mqttTopicTank     = "tank"
+)
+
+// mqttCredentialCheckInterval bounds how long a connection keeps publishing
+// after its credential was rotated or revoked.
+const mqttCredentialCheckInterval = 30 * time.Second
+
+// mqttSession is the state of a device connection. Messages of a connection
+// are handled one at a time, so it needs no lock.
+type mqttSession struct {
+	device    *dto.DeviceAuth
+	checkedAt time.Time
+}
+
+// MqttHandler maps messages of the MQTT gateway to the same services as the
+// signed device endpoints. A device connects with its unit key as user name
+// and its device secret as password.
+type MqttHandler struct {
+	growthHistService service.GrowthHistService
+	tankTransService  service.TankTransService
+	deviceService     service.DeviceService
+	systemLogService  service.SystemLogService
+}
+
+type MqttHandlerConfig struct {
+	GrowthHistService service.GrowthHistService
+	TankTransService  service.TankTransService
+	DeviceService     service.DeviceService
+	SystemLogService  service.SystemLogService
+}
+
+func NewMqttHandler(config MqttHandlerConfig) *MqttHandler {
+	return &MqttHandler{
+		growthHistService: config.GrowthHistService,
+		tankTransService:  config.TankTransService,
+		deviceService:     config.DeviceService,
+		systemLogService:  config.SystemLogService,
+	}
+}
+
+// Authenticate resolves the system unit behind a CONNECT.
+func (h *MqttHandler) Authenticate(clientId string, username string, password []byte) (interface{}, error) {
+	unitKey, err := uuid.Parse(username)
+	if err != nil {
+		logger.Warn("mqttHandler", "Invalid unit key", map[string]string{
+			"clientId": clientId,
+		})
+		return nil, mqtt.ErrBadCredentials
+	}
+
+	device, err := h.deviceService.AuthenticateUnit(unitKey, string(password))
+	if errors.Is(err, errs.InvalidDeviceSecret) {
+		return nil, mqtt.ErrBadCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("mqttHandler", "Device connected", map[string]string{
+		"clientId": clientId,
+		"keyId":    device.KeyId,
+	})
+	return &mqttSession{device: device, checkedAt: time.Now()}, nil
+}
+
+// Handle stores a message published to farms/{unit_key}/readings or
+// farms/{unit_key}/tank. Messages that can never be stored are logged and
+// acknowledged; only failures worth a retry are returned, which leaves the
+// message unacknowledged.
+func (h *MqttHandler) Handle(client interface{}, topic string, payload []byte) error {
+	session, ok := client.(*mqttSession)
+	if !ok {
+		return errs.InvalidDeviceSecret
+	}
+	device := session.device
+
+	// a revoked credential closes the connection, and the device cannot
+	// connect again with it
+	if time.Since(session.checkedAt) > mqttCredentialCheckInterval {
+		err := h.deviceService.CheckCredential(device)
+		if err != nil {
+			return err
+		}
+		session.checkedAt = time.Now()
+	}
+
+	err := h.handle(device, topic, payload)
+	if err == nil {
+		return nil
+	}
+
+	logger.Error("mqttHandler", "Failed to handle message", map[string]string{
+		"topic": topic,
+		"keyId": device.KeyId,
+		"error": err.Error(),
+	})
+	if isRetryable(err) {
+		return err
+	}
+	return nil
+}
+
+func (h *MqttHandler) handle(device *dto.DeviceAuth, topic string, payload []byte) error {
+	parts := strings.Split(topic, "/")
+	if len(parts) != 3 || parts[0] != "farms" || parts[1] != device.UnitKey.String() {
+		return errs.InvalidDeviceTopic
+	}
+
+	switch parts[2] {
+	case mqttTopicReadings:
+		var input dto.DeviceReadingsBody
+		err := decodeMqttPayload(payload, &input)
+		if err != nil {
+			return err
+		}
+
+		resp, err := h.growthHistService.CreateDeviceReadings(device, &input)
+		if err != nil {
+			return err
+		}
+
+		h.systemLogService.CreateSystemLog("Create Readings: " + "{Sample:" + resp.SampleId.String() + ", Device:" + device.KeyId + ", Via:mqtt}")
+		logger.Info("mqttHandler", "Readings created by device", map[string]string{
+			"sampleId": resp.SampleId.String(),
+			"keyId":    device.KeyId,
+		})
+	case mqttTopicTank:
+		var input dto.DeviceTankTransaction
+		err := decodeMqttPayload(payload, &input)
+		if err != nil {
+			return err
+		}
+
+		resp, err := h.tankTransService.CreateDeviceTankTrans(device, &input)
+		if err != nil {
+			return err
+		}
+
+		h.systemLogService.CreateSystemLog("Create Tank Transaction: " + "{ID:" + hex.EncodeToString(resp.ID[:]) + ", Device:" + device.KeyId + ", Via:mqtt}")
+		logger.Info("mqttHandler", "Tank transaction created by device", map[string]string{
+			"TransactionID": hex.EncodeToString(resp.ID[:]),
+			"KeyID":         device.KeyId,
+		})
+	default:
+		return errs.InvalidDeviceTopic
+	}
+	return nil
+}
+
+// decodeMqttPayload reads a JSON payload and checks its binding tags, as
+// ShouldBindJSON does for HTTP bodies.
+func decodeMqttPayload(payload []byte, obj interface{}) error {
+	err := json.Unmarshal(payload, obj)
+	if err == nil {
+		err = binding.Validator.ValidateStruct(obj)
+	}
+	if err != nil {
+		return errs.InvalidRequestBody
+	}
+	return nil
+}
+
+// isRetryable tells the failures of the server, which may pass, from those of
+// the message itself.
+func isRetryable(err error) bool {
+	switch {
+	case errors.Is(err, errs.ReadingQueueFull),
+		errors.Is(err, errs.ReadingQueueClosed),
+		errors.Is(err, errs.ErrorCheckingIngestKey),
+		errors.Is(err, errs.ErrorOnCreatingNewGrowthHist),
+		errors.Is(err, errs.ErrorOnCreatingNewTankTrans):
+		return true
+	}
+	return false
+}
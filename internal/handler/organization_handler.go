@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/dto"
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/service"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/response"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type OrganizationHandler struct {
+	organizationService service.OrganizationService
+	systemLogService    service.SystemLogService
+}
+
+type OrganizationHandlerConfig struct {
+	OrganizationService service.OrganizationService
+	SystemLogService    service.SystemLogService
+}
+
+func NewOrganizationHandler(config OrganizationHandlerConfig) *OrganizationHandler {
+	return &OrganizationHandler{
+		organizationService: config.OrganizationService,
+		systemLogService:    config.SystemLogService,
+	}
+}
+
+func (h *OrganizationHandler) GetOrganization(c *gin.Context) {
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.organizationService.GetOrganization(caller)
+	if err != nil {
+		logger.Error("organizationHandler", "Failed to get organization", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, errorStatus(err), err.Error())
+		return
+	}
+
+	response.JSON(c, 200, "Get Organization Success", resp)
+}
+
+func (h *OrganizationHandler) UpdateOrganization(c *gin.Context) {
+	var updateOrganizationBody dto.UpdateOrganizationBody
+	if err := c.ShouldBindJSON(&updateOrganizationBody); err != nil {
+		logger.Error("organizationHandler", "Invalid request body", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, 400, errs.InvalidRequestBody.Error())
+		return
+	}
+
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.organizationService.UpdateOrganization(caller, &updateOrganizationBody)
+	if err != nil {
+		logger.Error("organizationHandler", "Failed to update organization", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, errorStatus(err), err.Error())
+		return
+	}
+
+	err = h.systemLogService.CreateSystemLog("Update Organization: " + "{ID:" + resp.ID.String() + ", Name:" + resp.Name + "}")
+	if err != nil {
+		logger.Error("organizationHandler", "Failed to log system event", map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	response.JSON(c, 200, "Update Organization Success", resp)
+}
+
+func (h *OrganizationHandler) GetMembers(c *gin.Context) {
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.organizationService.GetMembers(caller)
+	if err != nil {
+		logger.Error("organizationHandler", "Failed to get organization members", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, errorStatus(err), err.Error())
+		return
+	}
+
+	response.JSON(c, 200, "Get Organization Members Success", resp)
+}
+
+func (h *OrganizationHandler) UpdateMemberRole(c *gin.Context) {
+	accountId, err := uuid.Parse(c.Param("accountId"))
+	if err != nil {
+		response.Error(c, 400, errs.InvalidIDParam.Error())
+		return
+	}
+
+	var updateMemberBody dto.UpdateOrganizationMemberBody
+	if err := c.ShouldBindJSON(&updateMemberBody); err != nil {
+		logger.Error("organizationHandler", "Invalid request body", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, 400, errs.InvalidRequestBody.Error())
+		return
+	}
+
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.organizationService.UpdateMemberRole(caller, &accountId, &updateMemberBody)
+	if err != nil {
+		logger.Error("organizationHandler", "Failed to update organization member", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, errorStatus(err), err.Error())
+		return
+	}
+
+	err = h.systemLogService.CreateSystemLog("Update Organization Member: " + "{Organization:" + caller.OrganizationID.String() + ", Account:" + accountId.String() + ", Role:" + resp.Role + "}")
+	if err != nil {
+		logger.Error("organizationHandler", "Failed to log system event", map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	response.JSON(c, 200, "Update Organization Member Success", resp)
+}
+
+func (h *OrganizationHandler) GetFarms(c *gin.Context) {
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.organizationService.GetFarms(caller)
+	if err != nil {
+		logger.Error("organizationHandler", "Failed to get organization farms", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, errorStatus(err), err.Error())
+		return
+	}
+
+	response.JSON(c, 200, "Get Organization Farms Success", resp)
+}
+
+func (h *OrganizationHandler) GetSystemUnits(c *gin.Context) {
+	var systemUnitFilter *dto.SystemUnitFilter
+	if farmIds := c.Query("farm_ids"); farmIds != "" {
+		systemUnitFilter = &dto.SystemUnitFilter{FarmIds: farmIds}
+	}
+
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.organizationService.GetSystemUnits(caller, systemUnitFilter)
+	if err != nil {
+		logger.Error("organizationHandler", "Failed to get organization system units", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, errorStatus(err), err.Error())
+		return
+	}
+
+	response.JSON(c, 200, "Get Organization System Units Success", resp)
+}
@@ -36,12 +36,18 @@ func (h *ProfileHandler) CreateProfile(c *gin.Context) {
 		return
 	}
 
-	resp, err := h.profileService.CreateProfile(createProfileBody)
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.profileService.CreateProfile(caller, createProfileBody)
 	if err != nil {
 		logger.Error("profileHandler", "Error creating profile", map[string]string{
 			"error": err.Error(),
 		})
-		response.Error(c, 400, err.Error())
+		response.Error(c, errorStatus(err), err.Error())
 		return
 	}
 
@@ -59,12 +65,18 @@ func (h *ProfileHandler) GetProfileDetails(c *gin.Context) {
 		response.Error(c, 400, errs.InvalidProfileIDParam.Error())
 		return
 	}
-	resp, err := h.profileService.GetProfileDetails(&id)
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.profileService.GetProfileDetails(caller, &id)
 	if err != nil {
 		logger.Error("profileHandler", "Error fetching profile details", map[string]string{
 			"error": err.Error(),
 		})
-		response.Error(c, 400, err.Error())
+		response.Error(c, errorStatus(err), err.Error())
 		return
 	}
 
@@ -73,12 +85,18 @@ func (h *ProfileHandler) GetProfileDetails(c *gin.Context) {
 
 func (h *ProfileHandler) GetProfiles(c *gin.Context) {
 	logger.Info("profileHandler", "Fetching all profiles", nil)
-	resp, err := h.profileService.GetProfiles()
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.profileService.GetProfiles(caller)
 	if err != nil {
 		logger.Error("profileHandler", "Error fetching profiles", map[string]string{
 			"error": err.Error(),
 		})
-		response.Error(c, 400, err.Error())
+		response.Error(c, errorStatus(err), err.Error())
 		return
 	}
 
@@ -107,12 +125,18 @@ func (h *ProfileHandler) UpdateProfile(c *gin.Context) {
 		return
 	}
 
-	resp, err := h.profileService.UpdateProfile(&id, updateProfileBody)
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.profileService.UpdateProfile(caller, &id, updateProfileBody)
 	if err != nil {
 		logger.Error("profileHandler", "Error updating profile", map[string]string{
 			"error": err.Error(),
 		})
-		response.Error(c, 400, err.Error())
+		response.Error(c, errorStatus(err), err.Error())
 		return
 	}
 
@@ -130,12 +154,18 @@ func (h *ProfileHandler) DeleteProfile(c *gin.Context) {
 		response.Error(c, 400, errs.InvalidProfileIDParam.Error())
 		return
 	}
-	resp, err := h.profileService.DeleteProfile(&id)
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.profileService.DeleteProfile(caller, &id)
 	if err != nil {
 		logger.Error("profileHandler", "Error deleting profile", map[string]string{
 			"error": err.Error(),
 		})
-		response.Error(c, 400, err.Error())
+		response.Error(c, errorStatus(err), err.Error())
 		return
 	}
 
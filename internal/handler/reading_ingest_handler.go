@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/service"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/response"
+	"github.com/gin-gonic/gin"
+)
+
+// bulkMaxBodySize bounds a bulk upload.
+const bulkMaxBodySize = 10 << 20
+
+type ReadingIngestHandler struct {
+	readingIngestService service.ReadingIngestService
+	readingQueue         service.ReadingQueue
+	systemLogService     service.SystemLogService
+}
+
+type ReadingIngestHandlerConfig struct {
+	ReadingIngestService service.ReadingIngestService
+	ReadingQueue         service.ReadingQueue
+	SystemLogService     service.SystemLogService
+}
+
+func NewReadingIngestHandler(config ReadingIngestHandlerConfig) *ReadingIngestHandler {
+	return &ReadingIngestHandler{
+		readingIngestService: config.ReadingIngestService,
+		readingQueue:         config.ReadingQueue,
+		systemLogService:     config.SystemLogService,
+	}
+}
+
+// GetQueueStats reports the depth and flush times of the reading write queue.
+func (h *ReadingIngestHandler) GetQueueStats(c *gin.Context) {
+	if h.readingQueue == nil {
+		response.Error(c, 404, errs.ReadingQueueDisabled.Error())
+		return
+	}
+
+	response.JSON(c, 200, "Get Reading Queue Stats Success", h.readingQueue.Stats())
+}
+
+// IngestReadings takes NDJSON or CSV, picked by ?format= or the Content-Type,
+// and answers with a report of the accepted and rejected rows.
+func (h *ReadingIngestHandler) IngestReadings(c *gin.Context) {
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, bulkMaxBodySize))
+	if err != nil {
+		response.Error(c, http.StatusRequestEntityTooLarge, errs.BulkBodyTooLarge.Error())
+		return
+	}
+
+	resp, err := h.readingIngestService.IngestReadings(caller, bulkFormat(c), bytes.NewReader(body))
+	if err != nil {
+		logger.Error("readingIngestHandler", "Failed to ingest readings", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, errorStatus(err), err.Error())
+		return
+	}
+
+	h.systemLogService.CreateSystemLog("Ingest Readings: " + "{Account:" + caller.AccountID.String() +
+		", Accepted:" + strconv.Itoa(resp.Accepted) + ", Rejected:" + strconv.Itoa(resp.Rejected) + "}")
+
+	response.JSON(c, 200, "Ingest Readings Success", resp)
+}
+
+// WriteLineProtocol is the /write endpoint of InfluxDB, so Telegraf and
+// sensor loggers can send readings as line protocol, gzipped or not. As
+// InfluxDB does, it answers 204 when every line is stored and 400 with the
+// rejected lines otherwise; the valid lines are stored either way.
+func (h *ReadingIngestHandler) WriteLineProtocol(c *gin.Context) {
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	body, err := readWriteBody(c)
+	if err != nil {
+		logger.Error("readingIngestHandler", "Invalid line protocol body", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, errorStatus(err), err.Error())
+		return
+	}
+
+	resp, err := h.readingIngestService.IngestLineProtocol(caller, c.Query("precision"), bytes.NewReader(body))
+	if err != nil {
+		logger.Error("readingIngestHandler", "Failed to write line protocol", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, errorStatus(err), err.Error())
+		return
+	}
+
+	h.systemLogService.CreateSystemLog("Write Line Protocol: " + "{Account:" + caller.AccountID.String() +
+		", Accepted:" + strconv.Itoa(resp.Accepted) + ", Rejected:" + strconv.Itoa(resp.Rejected) + "}")
+
+	if resp.Rejected > 0 {
+		for _, row := range resp.Rows {
+			if row.Status == service.BulkRowRejected {
+				response.JSON(c, 400, "partial write: line "+strconv.Itoa(row.Line)+": "+row.Reason, resp)
+				return
+			}
+		}
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// readWriteBody reads a line protocol body, inflating it when it is sent with
+// Content-Encoding gzip. The limit applies to both sizes.
+func readWriteBody(c *gin.Context) ([]byte, error) {
+	var body io.Reader = http.MaxBytesReader(c.Writer, c.Request.Body, bulkMaxBodySize)
+
+	encoding := c.GetHeader("Content-Encoding")
+	switch encoding {
+	case "", "identity":
+	case "gzip":
+		reader, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, errs.InvalidGzipBody
+		}
+		defer reader.Close()
+		body = reader
+	default:
+		return nil, errs.UnsupportedBodyEncoding
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, bulkMaxBodySize+1))
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) || len(data) > bulkMaxBodySize {
+		return nil, errs.BulkBodyTooLarge
+	}
+	if err != nil && encoding == "gzip" {
+		return nil, errs.InvalidGzipBody
+	}
+	if err != nil {
+		return nil, errs.InvalidRequestBody
+	}
+	return data, nil
+}
+
+func bulkFormat(c *gin.Context) string {
+	if format := c.Query("format"); format != "" {
+		return format
+	}
+
+	switch c.ContentType() {
+	case "text/csv":
+		return service.BulkFormatCSV
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return service.BulkFormatNDJSON
+	}
+	return ""
+}
@@ -12,19 +12,22 @@ import (
 )
 
 type SuperAccountHandler struct {
-	superAccountService service.SuperAccountService
-	systemLogService    service.SystemLogService
+	superAccountService  service.SuperAccountService
+	impersonationService service.ImpersonationService
+	systemLogService     service.SystemLogService
 }
 
 type SuperAccountHandlerConfig struct {
-	SuperAccountService service.SuperAccountService
-	SystemLogService    service.SystemLogService
+	SuperAccountService  service.SuperAccountService
+	ImpersonationService service.ImpersonationService
+	SystemLogService     service.SystemLogService
 }
 
 func NewSuperAccountHandler(config SuperAccountHandlerConfig) *SuperAccountHandler {
 	return &SuperAccountHandler{
-		superAccountService: config.SuperAccountService,
-		systemLogService:    config.SystemLogService,
+		superAccountService:  config.SuperAccountService,
+		impersonationService: config.ImpersonationService,
+		systemLogService:     config.SystemLogService,
 	}
 }
 
@@ -64,3 +67,99 @@ func (h *SuperAccountHandler) CreateSuperUser(c *gin.Context) {
 
 	response.JSON(c, 201, "Register Success", resp)
 }
+
+func (h *SuperAccountHandler) Login(c *gin.Context) {
+	var loginBody dto.LoginBody
+
+	if err := c.ShouldBindJSON(&loginBody); err != nil {
+		response.Error(c, 400, errs.InvalidRequestBody.Error())
+		return
+	}
+
+	resp, err := h.superAccountService.Login(&loginBody)
+	if err != nil {
+		logger.Error("superAccountHandler", "Failed to login super user", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, 400, err.Error())
+		return
+	}
+
+	response.JSON(c, 200, "Login success", resp)
+}
+
+func (h *SuperAccountHandler) UnlockLogin(c *gin.Context) {
+	var unlockLoginBody dto.UnlockLoginBody
+
+	if err := c.ShouldBindJSON(&unlockLoginBody); err != nil {
+		response.Error(c, 400, errs.InvalidRequestBody.Error())
+		return
+	}
+
+	resp, err := h.superAccountService.UnlockLogin(&unlockLoginBody)
+	if err != nil {
+		logger.Error("superAccountHandler", "Failed to unlock login", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, 400, err.Error())
+		return
+	}
+
+	response.JSON(c, 200, "Unlock Login Success", resp)
+}
+
+func (h *SuperAccountHandler) StepUp(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	sessionId, err := getSessionId(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	var stepupBody dto.StepupBody
+	if err := c.ShouldBindJSON(&stepupBody); err != nil {
+		response.Error(c, 400, errs.InvalidRequestBody.Error())
+		return
+	}
+
+	resp, err := h.superAccountService.StepUp(userId, sessionId, &stepupBody)
+	if err != nil {
+		logger.Error("superAccountHandler", "Failed to step up", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, 400, err.Error())
+		return
+	}
+
+	response.JSON(c, 200, "Step-up Success", resp)
+}
+
+func (h *SuperAccountHandler) Impersonate(c *gin.Context) {
+	userId, err := getUserId(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	var impersonateBody dto.ImpersonateBody
+	if err := c.ShouldBindJSON(&impersonateBody); err != nil {
+		response.Error(c, 400, errs.InvalidRequestBody.Error())
+		return
+	}
+
+	resp, err := h.impersonationService.Impersonate(*userId, &impersonateBody)
+	if err != nil {
+		logger.Error("superAccountHandler", "Failed to impersonate account", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, 400, err.Error())
+		return
+	}
+
+	response.JSON(c, 200, "Impersonate Success", resp)
+}
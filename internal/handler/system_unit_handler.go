@@ -43,12 +43,18 @@ func (h *SystemUnitHandler) CreateSystemUnit(c *gin.Context) {
 		return
 	}
 
-	resp, err := h.systemUnitService.CreateSystemUnit(createSystemUnitBody)
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.systemUnitService.CreateSystemUnit(caller, createSystemUnitBody)
 	if err != nil {
 		logger.Error("systemUnitHandler", "Failed to create system unit", map[string]string{
 			"error": err.Error(),
 		})
-		response.Error(c, 400, err.Error())
+		response.Error(c, errorStatus(err), err.Error())
 		return
 	}
 
@@ -83,6 +89,9 @@ func (h *SystemUnitHandler) GetSystemUnits(c *gin.Context) {
 	jsonBody := string(bodyAsByteArray)
 	if len(jsonBody) == 0 {
 		systemUnitFilter = nil
+		if farmIds := c.Query("farm_ids"); farmIds != "" {
+			systemUnitFilter = &dto.SystemUnitFilter{FarmIds: farmIds}
+		}
 	} else {
 		c.Request.Body = io.NopCloser(bytes.NewBuffer(bodyAsByteArray))
 		if err := c.ShouldBindJSON(&systemUnitFilter); err != nil {
@@ -94,12 +103,18 @@ func (h *SystemUnitHandler) GetSystemUnits(c *gin.Context) {
 		}
 	}
 
-	resp, err := h.systemUnitService.GetSystemUnits(systemUnitFilter)
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.systemUnitService.GetSystemUnits(caller, systemUnitFilter)
 	if err != nil {
 		logger.Error("systemUnitHandler", "Failed to retrieve system units", map[string]string{
 			"error": err.Error(),
 		})
-		response.Error(c, 400, err.Error())
+		response.Error(c, errorStatus(err), err.Error())
 		return
 	}
 
@@ -129,12 +144,18 @@ func (h *SystemUnitHandler) UpdateSystemUnit(c *gin.Context) {
 		return
 	}
 
-	resp, err := h.systemUnitService.UpdateSystemUnit(&id, updateSystemUnitBody)
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.systemUnitService.UpdateSystemUnit(caller, &id, updateSystemUnitBody)
 	if err != nil {
 		logger.Error("systemUnitHandler", "Failed to update system unit", map[string]string{
 			"error": err.Error(),
 		})
-		response.Error(c, 400, err.Error())
+		response.Error(c, errorStatus(err), err.Error())
 		return
 	}
 
@@ -167,12 +188,18 @@ func (h *SystemUnitHandler) DeleteSystemIdById(c *gin.Context) {
 		return
 	}
 
-	resp, err := h.systemUnitService.DeleteSystemUnitById(&id)
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.systemUnitService.DeleteSystemUnitById(caller, &id)
 	if err != nil {
 		logger.Error("systemUnitHandler", "Failed to delete system unit", map[string]string{
 			"error": err.Error(),
 		})
-		response.Error(c, 400, err.Error())
+		response.Error(c, errorStatus(err), err.Error())
 		return
 	}
 
@@ -191,3 +218,74 @@ func (h *SystemUnitHandler) DeleteSystemIdById(c *gin.Context) {
 
 	response.JSON(c, 201, "Delete System Id Success", resp)
 }
+
+func (h *SystemUnitHandler) RotateDeviceCredential(c *gin.Context) {
+	logger.Info("systemUnitHandler", "Starting RotateDeviceCredential process", nil)
+
+	paramId := c.Param("systemId")
+	id, paramErr := uuid.Parse(paramId)
+	if paramErr != nil {
+		logger.Error("systemUnitHandler", "Invalid system unit ID parameter", map[string]string{
+			"error": paramErr.Error(),
+		})
+		response.Error(c, 400, errs.InvalidSystemUnitIDParam.Error())
+		return
+	}
+
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.systemUnitService.RotateDeviceCredential(caller, &id)
+	if err != nil {
+		logger.Error("systemUnitHandler", "Failed to rotate device credential", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, errorStatus(err), err.Error())
+		return
+	}
+
+	err = h.systemLogService.CreateSystemLog("Rotate Device Credential: " + "{ID:" + hex.EncodeToString(id[:]) + ", Key:" + resp.KeyId + "}")
+	if err != nil {
+		logger.Error("systemUnitHandler", "Failed to log system event", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, 400, err.Error())
+		return
+	}
+
+	response.JSON(c, 201, "Rotate Device Credential Success", resp)
+}
+
+func (h *SystemUnitHandler) GetSequenceGaps(c *gin.Context) {
+	logger.Info("systemUnitHandler", "Starting GetSequenceGaps process", nil)
+
+	paramId := c.Param("systemId")
+	id, paramErr := uuid.Parse(paramId)
+	if paramErr != nil {
+		logger.Error("systemUnitHandler", "Invalid system unit ID parameter", map[string]string{
+			"error": paramErr.Error(),
+		})
+		response.Error(c, 400, errs.InvalidSystemUnitIDParam.Error())
+		return
+	}
+
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.systemUnitService.GetSequenceGaps(caller, &id)
+	if err != nil {
+		logger.Error("systemUnitHandler", "Failed to get sequence gaps", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, errorStatus(err), err.Error())
+		return
+	}
+
+	response.JSON(c, 200, "Get Sequence Gaps Success", resp)
+}
@@ -40,12 +40,18 @@ func (h *TankTransHandler) CreateTankTransaction(c *gin.Context) {
 		return
 	}
 
-	resp, err := h.tankTransService.CreateTankTrans(createTankTransBody)
+	caller, err := getCaller(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.tankTransService.CreateTankTrans(caller, createTankTransBody)
 	if err != nil {
 		logger.Error("tankTransHandler", "Failed to create tank transaction", map[string]string{
 			"error": err.Error(),
 		})
-		response.Error(c, 400, err.Error())
+		response.Error(c, errorStatus(err), err.Error())
 		return
 	}
 
@@ -64,3 +70,47 @@ func (h *TankTransHandler) CreateTankTransaction(c *gin.Context) {
 
 	response.JSON(c, 201, "Create Tank Transaction Success", resp)
 }
+
+func (h *TankTransHandler) CreateDeviceTankTransaction(c *gin.Context) {
+	logger.Info("tankTransHandler", "Starting CreateDeviceTankTransaction process", nil)
+
+	var createTankTransBody *dto.DeviceTankTransaction
+	if err := c.ShouldBindJSON(&createTankTransBody); err != nil {
+		logger.Error("tankTransHandler", "Invalid request body", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, 400, errs.InvalidRequestBody.Error())
+		return
+	}
+
+	device, err := getDevice(c)
+	if err != nil {
+		response.Error(c, 401, err.Error())
+		return
+	}
+
+	resp, err := h.tankTransService.CreateDeviceTankTrans(device, createTankTransBody)
+	if err != nil {
+		logger.Error("tankTransHandler", "Failed to create tank transaction", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, errorStatus(err), err.Error())
+		return
+	}
+
+	logger.Info("tankTransHandler", "Successfully created tank transaction", map[string]string{
+		"TransactionID": hex.EncodeToString(resp.ID[:]),
+		"KeyID":         device.KeyId,
+	})
+
+	err = h.systemLogService.CreateSystemLog("Create Tank Transaction: " + "{ID:" + hex.EncodeToString(resp.ID[:]) + ", Device:" + device.KeyId + "}")
+	if err != nil {
+		logger.Error("tankTransHandler", "Failed to log system event", map[string]string{
+			"error": err.Error(),
+		})
+		response.Error(c, 400, err.Error())
+		return
+	}
+
+	response.JSON(c, 201, "Create Tank Transaction Success", resp)
+}
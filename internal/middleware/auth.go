@@ -3,15 +3,23 @@ package middleware
 import (
 	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/constant"
 	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/service"
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/response"
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/tokenprovider"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
-func CreateAuth(tokenChecker tokenprovider.JWTTokenProvider) gin.HandlerFunc {
+// CreateAuth authenticates session JWTs. When apiTokenService is set it also
+// accepts personal API tokens; those requests carry no session, so routes
+// that need one (step-up, account management) reject them. When
+// impersonationService is set it also accepts super admin impersonation
+// tokens and records every request made with them.
+func CreateAuth(tokenChecker tokenprovider.JWTTokenProvider, sessionService service.SessionService, apiTokenService service.ApiTokenService, impersonationService service.ImpersonationService) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		authHeader := ctx.Request.Header.Get("Authorization")
 		tokenStr, err := tokenChecker.ExtractToken(authHeader)
@@ -20,8 +28,45 @@ func CreateAuth(tokenChecker tokenprovider.JWTTokenProvider) gin.HandlerFunc {
 			return
 		}
 
+		if apiTokenService != nil && strings.HasPrefix(tokenStr, constant.ApiTokenPrefix) {
+			apiToken, err := apiTokenService.Authenticate(tokenStr, ctx.ClientIP())
+			if err != nil {
+				response.Error(ctx, http.StatusUnauthorized, err.Error())
+				return
+			}
+
+			ctx.Set(constant.ContextKeyUser, tokenprovider.UserClaims{
+				UserID:           apiToken.AccountID.String(),
+				Username:         apiToken.Username,
+				Role:             apiToken.Role,
+				OrganizationID:   apiToken.OrganizationID.String(),
+				OrganizationRole: apiToken.OrganizationRole,
+				EmailVerified:    apiToken.EmailVerified,
+				ReadOnly:         apiToken.ReadOnly,
+			})
+			ctx.Set(constant.ContextKeyApiToken, apiToken)
+			ctx.Next()
+			return
+		}
+
 		claims, err := tokenChecker.ValidateToken(tokenStr)
-		if errors.Is(err, errs.InvalidToken) || errors.Is(err, errs.InvalidIssuer) {
+		if errors.Is(err, errs.InvalidToken) && impersonationService != nil {
+			impersonationClaims, impersonation, impersonationErr := impersonationService.Authenticate(tokenStr)
+			if impersonationErr == nil {
+				// refuse the request rather than serve it unaudited
+				err = impersonationService.RecordRequest(impersonation, ctx.Request.Method, ctx.Request.URL.RequestURI(), ctx.ClientIP())
+				if err != nil {
+					response.UnknownError(ctx, err)
+					return
+				}
+
+				ctx.Set(constant.ContextKeyUser, impersonationClaims.UserClaims)
+				ctx.Set(constant.ContextKeyImpersonation, impersonation)
+				ctx.Next()
+				return
+			}
+		}
+		if errors.Is(err, errs.InvalidToken) || errors.Is(err, errs.InvalidIssuer) || errors.Is(err, errs.InvalidAudience) {
 			response.Error(ctx, http.StatusUnauthorized, err.Error())
 			return
 		}
@@ -31,7 +76,26 @@ func CreateAuth(tokenChecker tokenprovider.JWTTokenProvider) gin.HandlerFunc {
 			return
 		}
 
+		sessionId, err := uuid.Parse(claims.ID)
+		if err != nil {
+			response.Error(ctx, http.StatusUnauthorized, errs.InvalidToken.Error())
+			return
+		}
+
+		userId, err := uuid.Parse(claims.UserID)
+		if err != nil {
+			response.Error(ctx, http.StatusUnauthorized, errs.InvalidToken.Error())
+			return
+		}
+
+		err = sessionService.ValidateSession(&sessionId, &userId, ctx.ClientIP())
+		if err != nil {
+			response.Error(ctx, http.StatusUnauthorized, err.Error())
+			return
+		}
+
 		ctx.Set(constant.ContextKeyUser, claims.UserClaims)
+		ctx.Set(constant.ContextKeySession, sessionId)
 		ctx.Next()
 	}
 }
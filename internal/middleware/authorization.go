@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/constant"
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/response"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/tokenprovider"
+	"github.com/gin-gonic/gin"
+)
+
+// RoutePermissions maps "METHOD /route/path" (as reported by gin's FullPath)
+// to the roles allowed to call it.
+type RoutePermissions map[string][]string
+
+// CreateAuthorization must run after CreateAuth. Routes missing from the
+// permission table are denied, and accounts with an unverified email, read
+// API tokens and read only impersonation tokens may only use GET routes.
+func CreateAuthorization(permissions RoutePermissions) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		value, ok := ctx.Get(constant.ContextKeyUser)
+		if !ok {
+			response.Error(ctx, http.StatusUnauthorized, errs.InvalidToken.Error())
+			return
+		}
+
+		claims, ok := value.(tokenprovider.UserClaims)
+		if !ok {
+			response.Error(ctx, http.StatusUnauthorized, errs.InvalidToken.Error())
+			return
+		}
+
+		roles, ok := permissions[ctx.Request.Method+" "+ctx.FullPath()]
+		if !ok || !slices.Contains(roles, claims.Role) {
+			response.Error(ctx, http.StatusForbidden, errs.ForbiddenAccess.Error())
+			return
+		}
+
+		if !claims.EmailVerified && ctx.Request.Method != http.MethodGet {
+			response.Error(ctx, http.StatusForbidden, errs.EmailNotVerified.Error())
+			return
+		}
+
+		if claims.ReadOnly && ctx.Request.Method != http.MethodGet {
+			if _, ok := ctx.Get(constant.ContextKeyApiToken); ok {
+				response.Error(ctx, http.StatusForbidden, errs.ApiTokenReadOnly.Error())
+				return
+			}
+			response.Error(ctx, http.StatusForbidden, errs.ImpersonationReadOnly.Error())
+			return
+		}
+
+		ctx.Next()
+	}
+}
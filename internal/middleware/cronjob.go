@@ -9,19 +9,27 @@ import (
 
 type CronJob interface {
 	CreateAggregationEachMonth()
+	CleanupDeviceNonces()
+	PurgeDeletedAccounts()
 }
 
 type cronJob struct {
-	aggregateService service.AggregationService
+	aggregateService   service.AggregationService
+	deviceService      service.DeviceService
+	accountDataService service.AccountDataService
 }
 
 type CronJobConfig struct {
-	AggregateService service.AggregationService
+	AggregateService   service.AggregationService
+	DeviceService      service.DeviceService
+	AccountDataService service.AccountDataService
 }
 
 func NewCorn(config CronJobConfig) CronJob {
 	return &cronJob{
-		aggregateService: config.AggregateService,
+		aggregateService:   config.AggregateService,
+		deviceService:      config.DeviceService,
+		accountDataService: config.AccountDataService,
 	}
 }
 
@@ -34,3 +42,24 @@ func (c cronJob) CreateAggregationEachMonth() {
 
 	scheduler.StartAsync()
 }
+
+func (c cronJob) CleanupDeviceNonces() {
+	scheduler := gocron.NewScheduler(time.UTC)
+
+	scheduler.Every(10).Minutes().Do(func() {
+		c.deviceService.DeleteExpiredNonces()
+	})
+
+	scheduler.StartAsync()
+}
+
+// PurgeDeletedAccounts closes the accounts whose deletion grace period is over.
+func (c cronJob) PurgeDeletedAccounts() {
+	scheduler := gocron.NewScheduler(time.UTC)
+
+	scheduler.Every(1).Hour().Do(func() {
+		c.accountDataService.PurgeDueAccounts()
+	})
+
+	scheduler.StartAsync()
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/constant"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/dto"
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/service"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/response"
+	"github.com/gin-gonic/gin"
+)
+
+// maxDeviceBodySize bounds how much of a device request is buffered to hash
+// it before the signature is known to be valid.
+const maxDeviceBodySize = 1 << 20
+
+// CreateDeviceAuth authenticates system units by the HMAC signature described
+// on DeviceService.Authenticate. The body is read for hashing and restored for
+// the handler.
+func CreateDeviceAuth(deviceService service.DeviceService) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		body, err := io.ReadAll(io.LimitReader(ctx.Request.Body, maxDeviceBodySize+1))
+		if err != nil {
+			response.Error(ctx, http.StatusBadRequest, errs.InvalidRequestBody.Error())
+			return
+		}
+		if len(body) > maxDeviceBodySize {
+			response.Error(ctx, http.StatusRequestEntityTooLarge, errs.InvalidRequestBody.Error())
+			return
+		}
+		ctx.Request.Body = io.NopCloser(bytes.NewBuffer(body))
+
+		device, err := deviceService.Authenticate(&dto.DeviceSignedRequest{
+			KeyId:     ctx.Request.Header.Get("X-Device-Key"),
+			Timestamp: ctx.Request.Header.Get("X-Device-Timestamp"),
+			Nonce:     ctx.Request.Header.Get("X-Device-Nonce"),
+			Signature: ctx.Request.Header.Get("X-Device-Signature"),
+			Method:    ctx.Request.Method,
+			Path:      ctx.Request.URL.Path,
+			Body:      body,
+		})
+		if errors.Is(err, errs.InvalidDeviceSignature) || errors.Is(err, errs.DeviceRequestExpired) || errors.Is(err, errs.DeviceNonceReused) {
+			response.Error(ctx, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		if err != nil {
+			response.UnknownError(ctx, err)
+			return
+		}
+
+		ctx.Set(constant.ContextKeyDevice, device)
+		ctx.Next()
+	}
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/constant"
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/response"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/tokenprovider"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CreateStepup guards destructive routes. It must run after an auth
+// middleware and requires a step-up token in the Stepup header that was issued
+// for the same user and session as the access token.
+func CreateStepup(tokenChecker tokenprovider.JWTTokenProvider) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		tokenStr := ctx.Request.Header.Get("Stepup")
+		if tokenStr == "" {
+			response.Error(ctx, http.StatusForbidden, errs.StepupRequired.Error())
+			return
+		}
+
+		claims, err := tokenChecker.ValidateStepupToken(tokenStr)
+		if err != nil {
+			response.Error(ctx, http.StatusForbidden, errs.StepupRequired.Error())
+			return
+		}
+
+		value, _ := ctx.Get(constant.ContextKeyUser)
+		userClaims, ok := value.(tokenprovider.UserClaims)
+		if !ok || userClaims.UserID != claims.UserID {
+			response.Error(ctx, http.StatusForbidden, errs.StepupRequired.Error())
+			return
+		}
+
+		value, _ = ctx.Get(constant.ContextKeySession)
+		sessionId, ok := value.(uuid.UUID)
+		if !ok || sessionId.String() != claims.ID {
+			response.Error(ctx, http.StatusForbidden, errs.StepupRequired.Error())
+			return
+		}
+
+		ctx.Set(constant.ContextKeyStepup, claims.UserClaims)
+		ctx.Next()
+	}
+}
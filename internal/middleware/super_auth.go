@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/constant"
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/response"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/tokenprovider"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CreateSuperAuth only lets through access tokens issued by the super admin
+// token provider. Farmer tokens fail the issuer and audience checks.
+func CreateSuperAuth(tokenChecker tokenprovider.JWTTokenProvider) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authHeader := ctx.Request.Header.Get("Authorization")
+		tokenStr, err := tokenChecker.ExtractToken(authHeader)
+		if errors.Is(err, errs.InvalidBearerFormat) {
+			response.Error(ctx, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		claims, err := tokenChecker.ValidateToken(tokenStr)
+		if errors.Is(err, errs.InvalidToken) || errors.Is(err, errs.InvalidIssuer) || errors.Is(err, errs.InvalidAudience) {
+			response.Error(ctx, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		if err != nil {
+			response.UnknownError(ctx, err)
+			return
+		}
+
+		if claims.Role != constant.RoleSuperAdmin {
+			response.Error(ctx, http.StatusForbidden, errs.ForbiddenAccess.Error())
+			return
+		}
+
+		// Super admin tokens have no session row; the jti still binds step-up
+		// tokens to the access token they were requested with.
+		sessionId, err := uuid.Parse(claims.ID)
+		if err != nil {
+			response.Error(ctx, http.StatusUnauthorized, errs.InvalidToken.Error())
+			return
+		}
+
+		ctx.Set(constant.ContextKeyUser, claims.UserClaims)
+		ctx.Set(constant.ContextKeySession, sessionId)
+		ctx.Next()
+	}
+}
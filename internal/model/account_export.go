@@ -0,0 +1,33 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AccountExport is everything stored about an account: the account itself, its
+// profiles and the farms it owns with their system units and readings.
+type AccountExport struct {
+	Account      *AccountExportAccount `json:"account"`
+	Profiles     []*Profile            `json:"profiles"`
+	Farms        []*Farm               `json:"farms"`
+	SystemUnits  []*SystemUnit         `json:"system_units"`
+	Readings     []*Reading            `json:"readings"`
+	TankTrans    []*TankTran           `json:"tank_trans"`
+	Aggregations []*Aggregation        `json:"aggregations"`
+}
+
+// AccountExportAccount is the account row without its credentials.
+type AccountExportAccount struct {
+	ID                  uuid.UUID  `json:"id"`
+	Username            string     `json:"username"`
+	Email               string     `json:"email"`
+	Role                string     `json:"role"`
+	OrganizationId      uuid.UUID  `json:"organization_id"`
+	OrganizationRole    string     `json:"organization_role"`
+	EmailVerifiedAt     *time.Time `json:"email_verified_at"`
+	MfaEnabledAt        *time.Time `json:"mfa_enabled_at"`
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at"`
+	CreatedAt           time.Time  `json:"created_at"`
+}
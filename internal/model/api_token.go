@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ApiToken struct {
+	ID          uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	AccountId   uuid.UUID  `json:"account_id" gorm:"type:uuid;not null"`
+	Name        string     `json:"name" gorm:"type:varchar;not null"`
+	TokenPrefix string     `json:"token_prefix" gorm:"type:varchar;not null"`
+	TokenHash   string     `json:"-" gorm:"type:varchar;not null;unique"`
+	Permission  string     `json:"permission" gorm:"type:varchar;not null"`
+	Scoped      bool       `json:"scoped" gorm:"not null"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	LastUsedIp  string     `json:"last_used_ip" gorm:"type:varchar"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	// FarmIds restricts a scoped token to these farms, an unscoped token may
+	// reach every farm of the account. Stored in api_token_farms, whose rows
+	// go away with their farm, so a scoped token can end up with none.
+	FarmIds []uuid.UUID `json:"farm_ids" gorm:"-"`
+}
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type DeviceCredential struct {
+	ID               uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	SystemUnitId     uuid.UUID  `json:"system_unit_id" gorm:"type:uuid;not null"`
+	KeyId            string     `json:"key_id" gorm:"type:varchar;not null;unique"`
+	SecretCiphertext string     `json:"-" gorm:"type:varchar;not null"`
+	LastUsedAt       *time.Time `json:"last_used_at"`
+	RevokedAt        *time.Time `json:"revoked_at"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// DeviceCredentialJoined is an active credential together with the farm of
+// its system unit.
+type DeviceCredentialJoined struct {
+	ID               uuid.UUID  `json:"id"`
+	SystemUnitId     uuid.UUID  `json:"system_unit_id"`
+	FarmId           uuid.UUID  `json:"farm_id"`
+	UnitKey          uuid.UUID  `json:"unit_key"`
+	KeyId            string     `json:"key_id"`
+	SecretCiphertext string     `json:"-"`
+	LastUsedAt       *time.Time `json:"last_used_at"`
+}
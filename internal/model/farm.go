@@ -8,11 +8,12 @@ import (
 )
 
 type Farm struct {
-	ID        uuid.UUID      `json:"id" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
-	ProfileId uuid.UUID      `json:"profile_id" gorm:"type:uuid;default:uuid_generate_v4()"`
-	Name      string         `json:"name" gorm:"type:varchar;not null;unique"`
-	Address   string         `json:"address" gorm:"type:varchar;not null"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"deleted_at"`
+	ID             uuid.UUID      `json:"id" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	ProfileId      uuid.UUID      `json:"profile_id" gorm:"type:uuid;default:uuid_generate_v4()"`
+	OrganizationId uuid.UUID      `json:"organization_id" gorm:"type:uuid;not null"`
+	Name           string         `json:"name" gorm:"type:varchar;not null;unique"`
+	Address        string         `json:"address" gorm:"type:varchar;not null"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `json:"deleted_at"`
 }
@@ -0,0 +1,37 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type FarmMember struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	FarmId    uuid.UUID `json:"farm_id" gorm:"type:uuid;not null"`
+	AccountId uuid.UUID `json:"account_id" gorm:"type:uuid;not null"`
+	Role      string    `json:"role" gorm:"type:varchar;not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// FarmMemberJoined is a member together with the account it belongs to.
+type FarmMemberJoined struct {
+	FarmMember
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+type FarmInvitation struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	FarmId     uuid.UUID  `json:"farm_id" gorm:"type:uuid;not null"`
+	Email      string     `json:"email" gorm:"type:varchar;not null"`
+	Role       string     `json:"role" gorm:"type:varchar;not null"`
+	TokenHash  string     `json:"-" gorm:"type:varchar;not null;unique"`
+	InvitedBy  uuid.UUID  `json:"invited_by" gorm:"type:uuid;not null"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at"`
+	AcceptedBy *uuid.UUID `json:"accepted_by" gorm:"type:uuid"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
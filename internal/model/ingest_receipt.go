@@ -0,0 +1,34 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IngestReceipt records a readings sample or tank transaction that was sent
+// with a client message id or a per-device sequence number, together with the
+// response it produced.
+type IngestReceipt struct {
+	ID          uuid.UUID `json:"id" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	SystemId    uuid.UUID `json:"system_id" gorm:"type:uuid;not null"`
+	Kind        string    `json:"kind" gorm:"type:varchar;not null"`
+	MessageId   *string   `json:"message_id" gorm:"type:varchar"`
+	Sequence    *int64    `json:"sequence"`
+	RequestHash string    `json:"-" gorm:"type:varchar;not null"`
+	Response    []byte    `json:"-" gorm:"type:jsonb;not null"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// SequenceGap is an inclusive range of sequence numbers never received.
+type SequenceGap struct {
+	From int64 `json:"from"`
+	To   int64 `json:"to"`
+}
+
+// SequenceStats summarizes the sequence numbers received from a system unit.
+type SequenceStats struct {
+	First    *int64 `json:"first"`
+	Last     *int64 `json:"last"`
+	Received int64  `json:"received"`
+}
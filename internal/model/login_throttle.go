@@ -0,0 +1,10 @@
+package model
+
+import "time"
+
+type LoginThrottle struct {
+	ThrottleKey  string     `json:"throttle_key" gorm:"type:varchar;primaryKey"`
+	FailedCount  int        `json:"failed_count"`
+	LastFailedAt time.Time  `json:"last_failed_at"`
+	LockedUntil  *time.Time `json:"locked_until"`
+}
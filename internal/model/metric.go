@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// Metric is a catalog entry for something a system unit can measure. Readings
+// outside [MinValue, MaxValue] are rejected; a nil bound is open.
+type Metric struct {
+	Name        string    `json:"name" gorm:"column:name;type:varchar;primaryKey"`
+	Unit        string    `json:"unit" gorm:"type:varchar;not null"`
+	MinValue    *float64  `json:"min_value" gorm:"type:float"`
+	MaxValue    *float64  `json:"max_value" gorm:"type:float"`
+	Description string    `json:"description" gorm:"type:varchar"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (m *Metric) InRange(value float64) bool {
+	if m.MinValue != nil && value < *m.MinValue {
+		return false
+	}
+	if m.MaxValue != nil && value > *m.MaxValue {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Organization struct {
+	ID        uuid.UUID      `json:"id" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	Name      string         `json:"name" gorm:"type:varchar;not null"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at"`
+}
+
+// OrganizationMember is an account as listed to organization admins.
+type OrganizationMember struct {
+	AccountId        uuid.UUID `json:"account_id"`
+	Username         string    `json:"username"`
+	Email            string    `json:"email"`
+	OrganizationRole string    `json:"organization_role"`
+	CreatedAt        time.Time `json:"created_at"`
+}
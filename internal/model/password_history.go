@@ -0,0 +1,14 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type PasswordHistory struct {
+	ID           uuid.UUID `json:"id" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	AccountId    uuid.UUID `json:"account_id" gorm:"type:uuid;not null"`
+	PasswordHash string    `json:"-" gorm:"type:varchar;not null"`
+	CreatedAt    time.Time `json:"created_at"`
+}
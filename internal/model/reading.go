@@ -0,0 +1,52 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Reading is one metric value of a sample. All readings sent in one request
+// share the sample id. MeasuredAt is when the device took the sample,
+// ReceivedAt when the server stored it.
+type Reading struct {
+	ID          uuid.UUID      `json:"id" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	SampleId    uuid.UUID      `json:"sample_id" gorm:"type:uuid;not null"`
+	FarmId      uuid.UUID      `json:"farm_id" gorm:"type:uuid;not null"`
+	SystemId    uuid.UUID      `json:"system_id" gorm:"type:uuid;not null"`
+	Metric      string         `json:"metric" gorm:"type:varchar;not null"`
+	Value       float64        `json:"value" gorm:"type:float;not null"`
+	MeasuredAt  time.Time      `json:"measured_at" gorm:"not null"`
+	ReceivedAt  time.Time      `json:"received_at" gorm:"not null"`
+	SkewFlagged bool           `json:"skew_flagged" gorm:"not null"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"deleted_at"`
+}
+
+// ReadingSample is a sample waiting in the write queue, with the receipt to
+// store alongside it when the client sent retry keys.
+type ReadingSample struct {
+	Readings []*Reading
+	Receipt  *IngestReceipt
+}
+
+type ReadingFilter struct {
+	SampleId    uuid.UUID `json:"sample_id" gorm:"column:sample_id;type:uuid;"`
+	Metric      string    `json:"metric" gorm:"column:metric;type:varchar;"`
+	Value       float64   `json:"value" gorm:"column:value;type:float;"`
+	MeasuredAt  time.Time `json:"measured_at" gorm:"column:measured_at;"`
+	ReceivedAt  time.Time `json:"received_at" gorm:"column:received_at;"`
+	SkewFlagged bool      `json:"skew_flagged" gorm:"column:skew_flagged;"`
+}
+
+type MetricAggregate struct {
+	Metric    string  `json:"metric" gorm:"column:metric;type:varchar;"`
+	Unit      string  `json:"unit" gorm:"column:unit;type:varchar;"`
+	Total     float64 `json:"total" gorm:"column:total;type:float;"`
+	TotalData int64   `json:"totalData" gorm:"column:total_data;type:integer;"`
+	Min       float64 `json:"min" gorm:"column:min;type:float;"`
+	Max       float64 `json:"max" gorm:"column:max;type:float;"`
+	Avg       float64 `json:"avg" gorm:"column:avg;type:float;"`
+}
@@ -0,0 +1,18 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type RefreshToken struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	AccountId  uuid.UUID  `json:"account_id" gorm:"type:uuid;not null"`
+	FamilyId   uuid.UUID  `json:"family_id" gorm:"type:uuid;not null"`
+	TokenHash  string     `json:"-" gorm:"type:varchar;not null"`
+	ReplacedBy *uuid.UUID `json:"replaced_by" gorm:"type:uuid"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -0,0 +1,18 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Session struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	AccountId  uuid.UUID  `json:"account_id" gorm:"type:uuid;not null"`
+	UserAgent  string     `json:"user_agent" gorm:"type:varchar"`
+	IpAddress  string     `json:"ip_address" gorm:"type:varchar"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
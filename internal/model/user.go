@@ -8,12 +8,20 @@ import (
 )
 
 type User struct {
-	ID        uuid.UUID      `json:"id" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
-	Username  string         `json:"username" gorm:"type:varchar;not null; unique"`
-	Password  string         `json:"password" gorm:"type:varchar; not null"`
-	Email     string         `json:"email" gorm:"type:varchar; not null"`
-	Role      string         `json:"role" gorm:"type:varchar"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+	ID               uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	Username         string     `json:"username" gorm:"type:varchar;not null; unique"`
+	Password         string     `json:"password" gorm:"type:varchar; not null"`
+	Email            string     `json:"email" gorm:"type:varchar; not null"`
+	Role             string     `json:"role" gorm:"type:varchar"`
+	OrganizationId   uuid.UUID  `json:"organization_id" gorm:"type:uuid;not null"`
+	OrganizationRole string     `json:"organization_role" gorm:"type:varchar;not null"`
+	EmailVerifiedAt  *time.Time `json:"email_verified_at"`
+	MfaSecret        string     `json:"-"`
+	MfaEnabledAt     *time.Time `json:"mfa_enabled_at"`
+	// DeletionScheduledAt is set while the account waits out the deletion
+	// grace period.
+	DeletionScheduledAt *time.Time     `json:"deletion_scheduled_at"`
+	CreatedAt           time.Time      `json:"created_at"`
+	UpdatedAt           time.Time      `json:"updated_at"`
+	DeletedAt           gorm.DeletedAt `json:"deleted_at" gorm:"index"`
 }
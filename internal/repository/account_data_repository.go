@@ -0,0 +1,326 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/constant"
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type AccountDataRepository interface {
+	GetAccountExport(accountId uuid.UUID) (*model.AccountExport, error)
+	ScheduleAccountDeletion(accountId uuid.UUID, scheduledAt time.Time) error
+	CancelAccountDeletion(accountId uuid.UUID) error
+	GetAccountsDueForDeletion(now time.Time, limit int) ([]uuid.UUID, error)
+	PurgeAccount(accountId uuid.UUID, now time.Time) error
+}
+
+type accountDataRepository struct {
+	db *gorm.DB
+}
+
+func NewAccountDataRepository(db *gorm.DB) AccountDataRepository {
+	return &accountDataRepository{
+		db: db,
+	}
+}
+
+// ownedFarmIdsSQL selects the live farms the account owns, alone or together
+// with other owners. It takes the account id as parameter.
+const ownedFarmIdsSQL = `SELECT owned_m.farm_id
+	FROM hydroponic_system.farm_members owned_m
+	JOIN hydroponic_system.farms owned_f ON owned_f.id = owned_m.farm_id
+	WHERE owned_m.account_id = ?
+	AND owned_m.role = 'owner'
+	AND owned_f.deleted_at IS NULL`
+
+// GetAccountExport reads every row that belongs to the account in a single
+// repeatable read transaction, so the export is a consistent snapshot.
+func (r *accountDataRepository) GetAccountExport(accountId uuid.UUID) (*model.AccountExport, error) {
+	logger.Info("accountDataRepository", "Exporting account data", map[string]string{
+		"account_id": accountId.String(),
+	})
+
+	export := &model.AccountExport{}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`SET TRANSACTION ISOLATION LEVEL REPEATABLE READ READ ONLY`).Error
+		if err != nil {
+			return err
+		}
+
+		res := tx.Raw(`SELECT id, username, email, role, organization_id, organization_role, email_verified_at, mfa_enabled_at, deletion_scheduled_at, created_at
+					   FROM hydroponic_system.accounts
+					   WHERE id = ? AND deleted_at IS NULL`,
+			accountId).Scan(&export.Account)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errs.InvalidAccountId
+		}
+
+		queries := []struct {
+			sqlScript string
+			dest      interface{}
+		}{
+			{`SELECT * FROM hydroponic_system.profiles
+			  WHERE account_id = ? AND deleted_at IS NULL
+			  ORDER BY created_at`, &export.Profiles},
+			{`SELECT * FROM hydroponic_system.farms
+			  WHERE id IN (` + ownedFarmIdsSQL + `)
+			  ORDER BY created_at`, &export.Farms},
+			{`SELECT * FROM hydroponic_system.system_units
+			  WHERE farm_id IN (` + ownedFarmIdsSQL + `) AND deleted_at IS NULL
+			  ORDER BY created_at`, &export.SystemUnits},
+			{`SELECT * FROM hydroponic_system.readings
+			  WHERE farm_id IN (` + ownedFarmIdsSQL + `) AND deleted_at IS NULL
+			  ORDER BY measured_at, sample_id, metric`, &export.Readings},
+			{`SELECT * FROM hydroponic_system.tank_trans
+			  WHERE farm_id IN (` + ownedFarmIdsSQL + `) AND deleted_at IS NULL
+			  ORDER BY created_at`, &export.TankTrans},
+			{`SELECT * FROM hydroponic_system.aggregations
+			  WHERE farm_id IN (` + ownedFarmIdsSQL + `) AND deleted_at IS NULL
+			  ORDER BY "time"`, &export.Aggregations},
+		}
+		for _, query := range queries {
+			err := tx.Raw(query.sqlScript, accountId).Scan(query.dest).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		logger.Error("accountDataRepository", "Failed to export account data", map[string]string{
+			"account_id": accountId.String(),
+			"error":      err.Error(),
+		})
+		return nil, err
+	}
+
+	return export, nil
+}
+
+func (r *accountDataRepository) ScheduleAccountDeletion(accountId uuid.UUID, scheduledAt time.Time) error {
+	res := r.db.Exec(`UPDATE hydroponic_system.accounts
+					  SET deletion_scheduled_at = ?, updated_at = ?
+					  WHERE id = ? AND deleted_at IS NULL AND deletion_scheduled_at IS NULL`,
+		scheduledAt, time.Now(), accountId)
+
+	if res.Error != nil {
+		logger.Error("accountDataRepository", "Failed to schedule account deletion", map[string]string{
+			"account_id": accountId.String(),
+			"error":      res.Error.Error(),
+		})
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errs.AccountDeletionAlreadyScheduled
+	}
+	return nil
+}
+
+func (r *accountDataRepository) CancelAccountDeletion(accountId uuid.UUID) error {
+	res := r.db.Exec(`UPDATE hydroponic_system.accounts
+					  SET deletion_scheduled_at = NULL, updated_at = ?
+					  WHERE id = ? AND deleted_at IS NULL AND deletion_scheduled_at IS NOT NULL`,
+		time.Now(), accountId)
+
+	if res.Error != nil {
+		logger.Error("accountDataRepository", "Failed to cancel account deletion", map[string]string{
+			"account_id": accountId.String(),
+			"error":      res.Error.Error(),
+		})
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errs.AccountDeletionNotScheduled
+	}
+	return nil
+}
+
+func (r *accountDataRepository) GetAccountsDueForDeletion(now time.Time, limit int) ([]uuid.UUID, error) {
+	var accountIds []uuid.UUID
+
+	res := r.db.Raw(`SELECT id FROM hydroponic_system.accounts
+					 WHERE deletion_scheduled_at <= ? AND deleted_at IS NULL
+					 ORDER BY deletion_scheduled_at
+					 LIMIT ?`,
+		now, limit).Scan(&accountIds)
+
+	if res.Error != nil {
+		logger.Error("accountDataRepository", "Failed to fetch accounts due for deletion", map[string]string{
+			"error": res.Error.Error(),
+		})
+		return nil, res.Error
+	}
+	return accountIds, nil
+}
+
+// PurgeAccount closes an account whose grace period is over, in one
+// transaction:
+//   - farms the account owns alone are deleted with their system units,
+//     readings, tank transactions, aggregations and growth plans;
+//   - API tokens scoped to a deleted farm are revoked, whoever owns them;
+//   - farms shared with other owners are kept and the account leaves them;
+//   - credentials, sessions and tokens of the account are deleted;
+//   - the account and its profiles are anonymized and marked deleted, so rows
+//     kept for other users still point to a valid account;
+//   - the organization keeps an admin, or is closed when nobody is left.
+func (r *accountDataRepository) PurgeAccount(accountId uuid.UUID, now time.Time) error {
+	logger.Info("accountDataRepository", "Purging account", map[string]string{
+		"account_id": accountId.String(),
+	})
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		account := &model.User{}
+		res := tx.Raw(`SELECT id, organization_id, organization_role
+					   FROM hydroponic_system.accounts
+					   WHERE id = ? AND deleted_at IS NULL AND deletion_scheduled_at <= ?
+					   FOR UPDATE`,
+			accountId, now).Scan(account)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errs.AccountDeletionNotScheduled
+		}
+
+		var farmIds []uuid.UUID
+		err := tx.Raw(`SELECT m.farm_id FROM hydroponic_system.farm_members m
+					   WHERE m.account_id = ? AND m.role = ?
+					   AND NOT EXISTS (
+					   	SELECT 1 FROM hydroponic_system.farm_members o
+					   	WHERE o.farm_id = m.farm_id AND o.role = ? AND o.account_id <> m.account_id
+					   )
+					   FOR UPDATE`,
+			accountId, constant.RoleOwner, constant.RoleOwner).Scan(&farmIds).Error
+		if err != nil {
+			return err
+		}
+
+		if len(farmIds) > 0 {
+			// tokens of other members scoped to these farms lose their scope
+			// rows with the farms, revoke them instead of leaving them empty
+			err = tx.Exec(`UPDATE hydroponic_system.api_tokens
+						   SET revoked_at = ?
+						   WHERE revoked_at IS NULL AND id IN (
+						   	SELECT api_token_id FROM hydroponic_system.api_token_farms WHERE farm_id IN ?
+						   )`,
+				now, farmIds).Error
+			if err != nil {
+				return err
+			}
+
+			// children first, their farm and system columns are NOT NULL
+			farmScripts := []string{
+				`DELETE FROM hydroponic_system.ingest_receipts WHERE system_id IN (
+					SELECT id FROM hydroponic_system.system_units WHERE farm_id IN ?)`,
+				`DELETE FROM hydroponic_system.readings WHERE farm_id IN ?`,
+				`DELETE FROM hydroponic_system.growth_hist WHERE farm_id IN ?`,
+				`DELETE FROM hydroponic_system.tank_trans WHERE farm_id IN ?`,
+				`DELETE FROM hydroponic_system.aggregations WHERE farm_id IN ?`,
+				`DELETE FROM hydroponic_system.growth_plans WHERE farm_id IN ?`,
+				`DELETE FROM hydroponic_system.device_nonces WHERE key_id IN (
+					SELECT c.key_id FROM hydroponic_system.device_credentials c
+					JOIN hydroponic_system.system_units su ON su.id = c.system_unit_id
+					WHERE su.farm_id IN ?)`,
+				`DELETE FROM hydroponic_system.system_units WHERE farm_id IN ?`,
+				`DELETE FROM hydroponic_system.farms WHERE id IN ?`,
+			}
+			for _, sqlScript := range farmScripts {
+				err := tx.Exec(sqlScript, farmIds).Error
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		accountScripts := []string{
+			`DELETE FROM hydroponic_system.farm_members WHERE account_id = ?`,
+			`DELETE FROM hydroponic_system.farm_invitations WHERE invited_by = ? AND accepted_at IS NULL`,
+			`DELETE FROM hydroponic_system.sessions WHERE account_id = ?`,
+			`DELETE FROM hydroponic_system.refresh_tokens WHERE account_id = ?`,
+			`DELETE FROM hydroponic_system.password_histories WHERE account_id = ?`,
+			`DELETE FROM hydroponic_system.password_reset_tokens WHERE account_id = ?`,
+			`DELETE FROM hydroponic_system.email_verification_tokens WHERE account_id = ?`,
+			`DELETE FROM hydroponic_system.mfa_recovery_codes WHERE account_id = ?`,
+			`DELETE FROM hydroponic_system.api_tokens WHERE account_id = ?`,
+		}
+		for _, sqlScript := range accountScripts {
+			err := tx.Exec(sqlScript, accountId).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		err = tx.Exec(`UPDATE hydroponic_system.profiles
+					   SET "name" = 'deleted-' || id, address = '', updated_at = ?, deleted_at = COALESCE(deleted_at, ?)
+					   WHERE account_id = ?`,
+			now, now, accountId).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Exec(`UPDATE hydroponic_system.accounts
+					   SET username = 'deleted-' || id, email = 'deleted-' || id || '@deleted.invalid', "password" = '',
+					   mfa_secret = NULL, mfa_enabled_at = NULL, mfa_last_step = NULL, email_verified_at = NULL,
+					   organization_role = ?, updated_at = ?, deleted_at = ?
+					   WHERE id = ?`,
+			constant.OrgRoleMember, now, now, accountId).Error
+		if err != nil {
+			return err
+		}
+
+		return handOverOrganization(tx, account.OrganizationId, now)
+	})
+
+	if err != nil {
+		logger.Error("accountDataRepository", "Failed to purge account", map[string]string{
+			"account_id": accountId.String(),
+			"error":      err.Error(),
+		})
+		return err
+	}
+
+	logger.Info("accountDataRepository", "Account purged", map[string]string{
+		"account_id": accountId.String(),
+	})
+	return nil
+}
+
+// handOverOrganization makes sure an organization that lost an account still
+// has an admin. The longest standing member is promoted; an organization
+// without members left is closed.
+func handOverOrganization(tx *gorm.DB, organizationId uuid.UUID, now time.Time) error {
+	var admins int64
+	err := tx.Raw(`SELECT count(*) FROM hydroponic_system.accounts
+				   WHERE organization_id = ? AND organization_role = ? AND deleted_at IS NULL`,
+		organizationId, constant.OrgRoleAdmin).Scan(&admins).Error
+	if err != nil || admins > 0 {
+		return err
+	}
+
+	res := tx.Exec(`UPDATE hydroponic_system.accounts
+					SET organization_role = ?, updated_at = ?
+					WHERE id = (
+						SELECT id FROM hydroponic_system.accounts
+						WHERE organization_id = ? AND deleted_at IS NULL
+						ORDER BY created_at
+						LIMIT 1
+					)`,
+		constant.OrgRoleAdmin, now, organizationId)
+	if res.Error != nil || res.RowsAffected > 0 {
+		return res.Error
+	}
+
+	return tx.Exec(`UPDATE hydroponic_system.organizations
+					SET updated_at = ?, deleted_at = ?
+					WHERE id = ? AND deleted_at IS NULL`,
+		now, now, organizationId).Error
+}
@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/constant"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "repository-test")
+	if err != nil {
+		panic(err)
+	}
+	err = logger.Init(filepath.Join(dir, "app.log"))
+	if err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// recordedStatement is a statement sent to a recordingDB, with its white
+// space collapsed.
+type recordedStatement struct {
+	sql  string
+	args []driver.Value
+}
+
+// recordingDB is a database/sql driver that records every statement and
+// answers queries from a callback, so the statements of a repository method
+// can be checked without a postgres server.
+type recordingDB struct {
+	query func(sql string) ([]string, [][]driver.Value)
+
+	mu         sync.Mutex
+	statements []recordedStatement
+	committed  bool
+}
+
+func newRecordingGorm(t *testing.T, db *recordingDB) *gorm.DB {
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: sql.OpenDB(db)}), &gorm.Config{
+		Logger: gormlogger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gormDB
+}
+
+// index returns the position of the first statement starting with prefix, or
+// -1.
+func (db *recordingDB) index(prefix string) int {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for i, statement := range db.statements {
+		if strings.HasPrefix(statement.sql, prefix) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (db *recordingDB) record(query string, args []driver.NamedValue) string {
+	statement := recordedStatement{sql: strings.Join(strings.Fields(query), " ")}
+	for _, arg := range args {
+		statement.args = append(statement.args, arg.Value)
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.statements = append(db.statements, statement)
+	return statement.sql
+}
+
+func (db *recordingDB) Connect(context.Context) (driver.Conn, error) {
+	return &recordingConn{db: db}, nil
+}
+
+func (db *recordingDB) Driver() driver.Driver {
+	return recordingDriver{}
+}
+
+type recordingDriver struct{}
+
+func (recordingDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("open a recordingDB with sql.OpenDB")
+}
+
+type recordingConn struct {
+	db *recordingDB
+}
+
+func (c *recordingConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements are not supported")
+}
+
+func (c *recordingConn) Close() error {
+	return nil
+}
+
+func (c *recordingConn) Begin() (driver.Tx, error) {
+	return c, nil
+}
+
+func (c *recordingConn) Commit() error {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.committed = true
+	return nil
+}
+
+func (c *recordingConn) Rollback() error {
+	return nil
+}
+
+func (c *recordingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.db.record(query, args)
+	return driver.RowsAffected(1), nil
+}
+
+func (c *recordingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	columns, rows := c.db.query(c.db.record(query, args))
+	return &recordingRows{columns: columns, rows: rows}, nil
+}
+
+type recordingRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *recordingRows) Columns() []string {
+	return r.columns
+}
+
+func (r *recordingRows) Close() error {
+	return nil
+}
+
+func (r *recordingRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func TestPurgeAccountRevokesTokensScopedToDeletedFarms(t *testing.T) {
+	accountId := uuid.New()
+	organizationId := uuid.New()
+	farmId := uuid.New()
+
+	db := &recordingDB{
+		query: func(sql string) ([]string, [][]driver.Value) {
+			switch {
+			case strings.HasPrefix(sql, "SELECT id, organization_id, organization_role FROM hydroponic_system.accounts"):
+				return []string{"id", "organization_id", "organization_role"},
+					[][]driver.Value{{accountId.String(), organizationId.String(), constant.OrgRoleAdmin}}
+			case strings.HasPrefix(sql, "SELECT m.farm_id"):
+				return []string{"farm_id"}, [][]driver.Value{{farmId.String()}}
+			case strings.HasPrefix(sql, "SELECT count(*)"):
+				return []string{"count"}, [][]driver.Value{{int64(1)}}
+			}
+			return nil, nil
+		},
+	}
+
+	err := NewAccountDataRepository(newRecordingGorm(t, db)).PurgeAccount(accountId, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !db.committed {
+		t.Fatal("purge was not committed")
+	}
+
+	revoke := db.index("UPDATE hydroponic_system.api_tokens SET revoked_at")
+	deleteFarms := db.index("DELETE FROM hydroponic_system.farms WHERE id IN")
+	if revoke < 0 || deleteFarms < 0 {
+		t.Fatalf("statements missing, revoke at %d, farm delete at %d", revoke, deleteFarms)
+	}
+	if revoke > deleteFarms {
+		t.Errorf("tokens revoked after the farms were deleted, their scope rows are gone by then")
+	}
+
+	statement := db.statements[revoke]
+	if !strings.Contains(statement.sql, "SELECT api_token_id FROM hydroponic_system.api_token_farms WHERE farm_id IN") {
+		t.Errorf("revoke does not select tokens by their farm scope: %s", statement.sql)
+	}
+	if len(statement.args) != 2 || statement.args[1] != farmId.String() {
+		t.Errorf("revoke args = %v, want the purge time and farm %s", statement.args, farmId)
+	}
+}
@@ -1,10 +1,10 @@
 package repository
 
 import (
-	"fmt"
 	"time"
 
-	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/dto"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/constant"
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
 	"github.com/google/uuid"
@@ -13,9 +13,14 @@ import (
 
 type AccountRepository interface {
 	Begin() *gorm.DB
-	CreateUser(input *dto.RegisterBody) (*model.User, error)
+	CreateUser(inputModel *model.User, organizationId *uuid.UUID) (*model.User, error)
 	GetUserById(accountID uuid.UUID) (*model.User, error)
 	GetUserByName(name *string) (*model.User, error)
+	GetUserCredentialById(accountID uuid.UUID) (*model.User, error)
+	GetUserByEmail(email *string) (*model.User, error)
+	GetPasswordHistories(accountID uuid.UUID, limit int) ([]*model.PasswordHistory, error)
+	UpdatePassword(inputModel *model.User, previousHash string) error
+	UpdatePasswordHash(accountID uuid.UUID, previousHash string, newHash string) error
 }
 
 type accountRepository struct {
@@ -33,30 +38,42 @@ func (r *accountRepository) Begin() *gorm.DB {
 	return r.db.Begin()
 }
 
-func (r *accountRepository) CreateUser(input *dto.RegisterBody) (*model.User, error) {
+// CreateUser creates an account as a member of the given organization. When
+// organizationId is nil a personal organization named after the user is
+// created in the same transaction, with the user as its admin.
+func (r *accountRepository) CreateUser(inputModel *model.User, organizationId *uuid.UUID) (*model.User, error) {
 	logger.Info("accountRepository", "Creating a new user", map[string]string{
-		"username": input.UserName,
-		"email":    input.Email,
+		"username": inputModel.Username,
+		"email":    inputModel.Email,
 	})
 
-	var inputModel = &model.User{
-		Username: input.UserName,
-		Email:    input.Email,
-		Password: input.Password,
-		Role:     input.Role,
-	}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		organizationRole := constant.OrgRoleMember
+		if organizationId == nil {
+			organization := &model.Organization{}
+			res := tx.Raw(`INSERT INTO hydroponic_system.organizations (name, created_at) 
+						   VALUES (?, ?) 
+						   RETURNING id, name`,
+				inputModel.Username, time.Now()).Scan(organization)
+			if res.Error != nil {
+				return res.Error
+			}
+			organizationId = &organization.ID
+			organizationRole = constant.OrgRoleAdmin
+		}
 
-	sqlScript := `INSERT INTO hydroponic_system.accounts (username, email, password, role, created_at) 
-				VALUES (?,?,?,?,?) 
-				RETURNING id, username, email, password, role;`
+		sqlScript := `INSERT INTO hydroponic_system.accounts (username, email, password, role, organization_id, organization_role, created_at) 
+				VALUES (?,?,?,?,?,?,?) 
+				RETURNING id, username, email, password, role, organization_id, organization_role, email_verified_at;`
 
-	res := r.db.Raw(sqlScript, input.UserName, input.Email, input.Password, input.Role, time.Now()).Scan(inputModel)
+		return tx.Raw(sqlScript, inputModel.Username, inputModel.Email, inputModel.Password, inputModel.Role, *organizationId, organizationRole, time.Now()).Scan(inputModel).Error
+	})
 
-	if res.Error != nil {
+	if err != nil {
 		logger.Error("accountRepository", "Failed to create user", map[string]string{
-			"error": res.Error.Error(),
+			"error": err.Error(),
 		})
-		return nil, res.Error
+		return nil, err
 	}
 
 	logger.Info("accountRepository", "User created successfully", map[string]string{
@@ -72,7 +89,7 @@ func (r *accountRepository) GetUserById(accountID uuid.UUID) (*model.User, error
 	})
 
 	var inputModel *model.User
-	sqlScript := `SELECT id, username, email, role FROM hydroponic_system.accounts WHERE id = ?`
+	sqlScript := `SELECT id, username, email, role, organization_id, organization_role, email_verified_at FROM hydroponic_system.accounts WHERE id = ? AND deleted_at IS NULL`
 
 	res := r.db.Raw(sqlScript, accountID).Scan(&inputModel)
 
@@ -84,6 +101,10 @@ func (r *accountRepository) GetUserById(accountID uuid.UUID) (*model.User, error
 		return nil, res.Error
 	}
 
+	if inputModel == nil {
+		return nil, nil
+	}
+
 	logger.Info("accountRepository", "User fetched successfully", map[string]string{
 		"userID":   inputModel.ID.String(),
 		"username": inputModel.Username,
@@ -96,15 +117,13 @@ func (r *accountRepository) GetUserByName(name *string) (*model.User, error) {
 
 	var user *model.User
 
-	sqlScript := `SELECT id, username, password 
+	sqlScript := `SELECT id, username, email, password, role, organization_id, organization_role, email_verified_at, mfa_secret, mfa_enabled_at
 				  FROM hydroponic_system.accounts 
 				  WHERE 
-				  	username = '%s' AND
+				  	username = ? AND
 				 	deleted_at IS NULL`
 
-	sqlScript = fmt.Sprintf(sqlScript, *name)
-
-	res := r.db.Raw(sqlScript).Scan(&user)
+	res := r.db.Raw(sqlScript, *name).Scan(&user)
 
 	if res.Error != nil {
 		logger.Error("accountRepository", "Failed to fetch account", map[string]string{
@@ -122,3 +141,123 @@ func (r *accountRepository) GetUserByName(name *string) (*model.User, error) {
 	})
 	return user, nil
 }
+
+// GetUserCredentialById is GetUserById including the password hash. Keep its
+// use limited to credential checks.
+func (r *accountRepository) GetUserCredentialById(accountID uuid.UUID) (*model.User, error) {
+	var user *model.User
+	sqlScript := `SELECT id, username, email, password, role, organization_id, organization_role, email_verified_at, mfa_secret, mfa_enabled_at
+				  FROM hydroponic_system.accounts
+				  WHERE id = ? AND deleted_at IS NULL`
+
+	res := r.db.Raw(sqlScript, accountID).Scan(&user)
+
+	if res.Error != nil {
+		logger.Error("accountRepository", "Failed to fetch user credential", map[string]string{
+			"error":  res.Error.Error(),
+			"userID": accountID.String(),
+		})
+		return nil, res.Error
+	}
+
+	return user, nil
+}
+
+func (r *accountRepository) GetUserByEmail(email *string) (*model.User, error) {
+	var user *model.User
+
+	sqlScript := `SELECT id, username, email, role, organization_id, organization_role, email_verified_at
+				  FROM hydroponic_system.accounts
+				  WHERE lower(email) = lower(?) AND deleted_at IS NULL
+				  LIMIT 1`
+
+	res := r.db.Raw(sqlScript, *email).Scan(&user)
+
+	if res.Error != nil {
+		logger.Error("accountRepository", "Failed to fetch account by email", map[string]string{
+			"error": res.Error.Error(),
+		})
+		return nil, res.Error
+	}
+
+	return user, nil
+}
+
+func (r *accountRepository) GetPasswordHistories(accountID uuid.UUID, limit int) ([]*model.PasswordHistory, error) {
+	var histories []*model.PasswordHistory
+
+	sqlScript := `SELECT id, account_id, password_hash, created_at
+				  FROM hydroponic_system.password_histories
+				  WHERE account_id = ?
+				  ORDER BY created_at DESC
+				  LIMIT ?`
+
+	res := r.db.Raw(sqlScript, accountID, limit).Scan(&histories)
+
+	if res.Error != nil {
+		logger.Error("accountRepository", "Failed to fetch password history", map[string]string{
+			"error":  res.Error.Error(),
+			"userID": accountID.String(),
+		})
+		return nil, res.Error
+	}
+
+	return histories, nil
+}
+
+// UpdatePassword stores the new password hash and moves the previous hash
+// into the password history in a single transaction.
+func (r *accountRepository) UpdatePassword(inputModel *model.User, previousHash string) error {
+	logger.Info("accountRepository", "Updating password", map[string]string{
+		"userID": inputModel.ID.String(),
+	})
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		res := tx.Exec(`UPDATE hydroponic_system.accounts
+						SET password = ?, updated_at = ?
+						WHERE id = ? AND deleted_at IS NULL`,
+			inputModel.Password, now, inputModel.ID)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errs.InvalidAccountId
+		}
+
+		return tx.Exec(`INSERT INTO hydroponic_system.password_histories (account_id, password_hash, created_at)
+						VALUES (?, ?, ?)`,
+			inputModel.ID, previousHash, now).Error
+	})
+
+	if err != nil {
+		logger.Error("accountRepository", "Failed to update password", map[string]string{
+			"userID": inputModel.ID.String(),
+			"error":  err.Error(),
+		})
+		return err
+	}
+
+	return nil
+}
+
+// UpdatePasswordHash replaces the hash of an unchanged password, for example
+// after a hasher upgrade. It does nothing when the password was changed in the
+// meantime and does not touch the password history.
+func (r *accountRepository) UpdatePasswordHash(accountID uuid.UUID, previousHash string, newHash string) error {
+	res := r.db.Exec(`UPDATE hydroponic_system.accounts
+					  SET password = ?
+					  WHERE id = ? AND password = ? AND deleted_at IS NULL`,
+		newHash, accountID, previousHash)
+
+	if res.Error != nil {
+		logger.Error("accountRepository", "Failed to update password hash", map[string]string{
+			"userID": accountID.String(),
+			"error":  res.Error.Error(),
+		})
+		return res.Error
+	}
+
+	return nil
+}
@@ -54,24 +54,42 @@ func (r *aggregationRepository) GetAggregatedDataByFilter(inputModel *model.Aggr
 
 	var outputModel []*model.AggregatedDataByFilter
 
-	sqlScript := `SELECT  
-					activity,
+	// averages of the monthly rows are weighted by their sample counts,
+	// avg_<metric> is SUM(total_<metric>) / SUM(total_data_<metric>) over the
+	// months that carry a count. Rows written before the counts existed fall
+	// back to the plain average.
+	sqlScript := `WITH a AS (
+					SELECT 
+						"time",
+						activity,
+						value
+					FROM hydroponic_system.aggregations
+						WHERE "name" = 'growth-hist'
+							AND time_range = 'monthly'
+							AND ("time"::date BETWEEN ? AND ?)
+							AND farm_id = ?
+							AND system_id = ?
+				), averages AS (
+					SELECT 
+						'avg_' || substr(d.activity, 12) AS activity,
+						SUM(t.value) / NULLIF(SUM(d.value), 0) AS value
+					FROM a d
+					JOIN a t ON t."time" = d."time" 
+						AND t.activity = 'total_' || substr(d.activity, 12)
+					WHERE d.activity LIKE 'total\_data\_%'
+					GROUP BY substr(d.activity, 12)
+				)
+				SELECT  
+					a.activity,
 					CASE 
-						WHEN activity = 'max_ph' THEN MAX(value)
-						WHEN activity = 'max_ppm' THEN MAX(value)
-						WHEN activity = 'min_ppm' THEN MIN(value)
-						WHEN activity = 'min_ph' THEN MIN(value)
-						WHEN activity = 'total_ph' THEN SUM(value)
-						WHEN activity = 'total_ppm' THEN SUM(value)
-						WHEN activity = 'total_data' THEN SUM(value)
+						WHEN a.activity LIKE 'max\_%' THEN MAX(a.value)
+						WHEN a.activity LIKE 'min\_%' THEN MIN(a.value)
+						WHEN a.activity LIKE 'avg\_%' THEN COALESCE(MAX(v.value), AVG(a.value))
+						ELSE SUM(a.value)
 					END AS value
-				FROM hydroponic_system.aggregations
-					WHERE "name" = 'growth-hist'
-						AND time_range = 'monthly'
-						AND ("time"::date BETWEEN ? AND ?)
-						AND farm_id = ?
-						AND system_id = ?
-				GROUP BY activity`
+				FROM a
+				LEFT JOIN averages v ON v.activity = a.activity
+				GROUP BY a.activity`
 
 	res := r.db.Raw(sqlScript, *startDate, *endDate, inputModel.FarmId, inputModel.SystemId).Scan(&outputModel)
 
@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"time"
+
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ApiTokenRepository interface {
+	CreateApiToken(inputModel *model.ApiToken) (*model.ApiToken, error)
+	GetActiveApiTokenByHash(tokenHash string) (*model.ApiToken, error)
+	GetApiTokensByAccountId(accountId *uuid.UUID) ([]*model.ApiToken, error)
+	RevokeApiToken(inputModel *model.ApiToken) error
+	TouchApiToken(inputModel *model.ApiToken) error
+}
+
+type apiTokenRepository struct {
+	db *gorm.DB
+}
+
+type apiTokenFarm struct {
+	ApiTokenId uuid.UUID
+	FarmId     uuid.UUID
+}
+
+func NewApiTokenRepository(db *gorm.DB) ApiTokenRepository {
+	return &apiTokenRepository{
+		db: db,
+	}
+}
+
+func (r *apiTokenRepository) CreateApiToken(inputModel *model.ApiToken) (*model.ApiToken, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		sqlScript := `INSERT INTO hydroponic_system.api_tokens (account_id, name, token_prefix, token_hash, permission, scoped, expires_at, created_at)
+					  VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+					  RETURNING id, account_id, name, token_prefix, permission, scoped, expires_at, created_at;`
+
+		res := tx.Raw(sqlScript,
+			inputModel.AccountId,
+			inputModel.Name,
+			inputModel.TokenPrefix,
+			inputModel.TokenHash,
+			inputModel.Permission,
+			inputModel.Scoped,
+			inputModel.ExpiresAt,
+			time.Now()).Scan(inputModel)
+		if res.Error != nil {
+			return res.Error
+		}
+
+		for _, farmId := range inputModel.FarmIds {
+			err := tx.Exec(`INSERT INTO hydroponic_system.api_token_farms (api_token_id, farm_id) VALUES (?, ?)`,
+				inputModel.ID, farmId).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		logger.Error("apiTokenRepository", "Failed to create API token", map[string]string{
+			"account_id": inputModel.AccountId.String(),
+			"error":      err.Error(),
+		})
+		return nil, err
+	}
+
+	logger.Info("apiTokenRepository", "API token created", map[string]string{
+		"id":         inputModel.ID.String(),
+		"account_id": inputModel.AccountId.String(),
+	})
+	return inputModel, nil
+}
+
+func (r *apiTokenRepository) GetActiveApiTokenByHash(tokenHash string) (*model.ApiToken, error) {
+	var token model.ApiToken
+
+	sqlScript := `SELECT id, account_id, name, token_prefix, permission, scoped, last_used_at, last_used_ip, expires_at, revoked_at, created_at
+				  FROM hydroponic_system.api_tokens
+				  WHERE token_hash = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)`
+
+	res := r.db.Raw(sqlScript, tokenHash, time.Now()).Scan(&token)
+
+	if res.Error != nil {
+		logger.Error("apiTokenRepository", "Failed to fetch API token", map[string]string{
+			"error": res.Error.Error(),
+		})
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, errs.InvalidApiToken
+	}
+
+	tokens := []*model.ApiToken{&token}
+	err := r.loadFarmIds(tokens)
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *apiTokenRepository) GetApiTokensByAccountId(accountId *uuid.UUID) ([]*model.ApiToken, error) {
+	var tokens []*model.ApiToken
+
+	sqlScript := `SELECT id, account_id, name, token_prefix, permission, scoped, last_used_at, last_used_ip, expires_at, revoked_at, created_at
+				  FROM hydroponic_system.api_tokens
+				  WHERE account_id = ? AND revoked_at IS NULL
+				  ORDER BY created_at DESC`
+
+	res := r.db.Raw(sqlScript, accountId).Scan(&tokens)
+
+	if res.Error != nil {
+		logger.Error("apiTokenRepository", "Failed to fetch API tokens", map[string]string{
+			"account_id": accountId.String(),
+			"error":      res.Error.Error(),
+		})
+		return nil, res.Error
+	}
+
+	err := r.loadFarmIds(tokens)
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (r *apiTokenRepository) RevokeApiToken(inputModel *model.ApiToken) error {
+	sqlScript := `UPDATE hydroponic_system.api_tokens
+				  SET revoked_at = ?
+				  WHERE id = ? AND account_id = ? AND revoked_at IS NULL`
+
+	res := r.db.Exec(sqlScript, time.Now(), inputModel.ID, inputModel.AccountId)
+
+	if res.Error != nil {
+		logger.Error("apiTokenRepository", "Failed to revoke API token", map[string]string{
+			"id":    inputModel.ID.String(),
+			"error": res.Error.Error(),
+		})
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errs.InvalidApiTokenID
+	}
+	return nil
+}
+
+func (r *apiTokenRepository) TouchApiToken(inputModel *model.ApiToken) error {
+	sqlScript := `UPDATE hydroponic_system.api_tokens
+				  SET last_used_at = ?, last_used_ip = ?
+				  WHERE id = ?`
+
+	return r.db.Exec(sqlScript, time.Now(), inputModel.LastUsedIp, inputModel.ID).Error
+}
+
+func (r *apiTokenRepository) loadFarmIds(tokens []*model.ApiToken) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	byId := map[uuid.UUID]*model.ApiToken{}
+	tokenIds := make([]uuid.UUID, 0, len(tokens))
+	for _, token := range tokens {
+		byId[token.ID] = token
+		tokenIds = append(tokenIds, token.ID)
+	}
+
+	var rows []apiTokenFarm
+	sqlScript := `SELECT api_token_id, farm_id
+				  FROM hydroponic_system.api_token_farms
+				  WHERE api_token_id IN ?`
+
+	res := r.db.Raw(sqlScript, tokenIds).Scan(&rows)
+	if res.Error != nil {
+		logger.Error("apiTokenRepository", "Failed to fetch API token farms", map[string]string{
+			"error": res.Error.Error(),
+		})
+		return res.Error
+	}
+
+	for _, row := range rows {
+		token := byId[row.ApiTokenId]
+		token.FarmIds = append(token.FarmIds, row.FarmId)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type DeviceCredentialRepository interface {
+	CreateDeviceCredential(inputModel *model.DeviceCredential) (*model.DeviceCredential, error)
+	GetActiveDeviceCredential(keyId string) (*model.DeviceCredentialJoined, error)
+	GetActiveDeviceCredentialsByUnitKey(unitKey uuid.UUID) ([]*model.DeviceCredentialJoined, error)
+	TouchDeviceCredential(inputModel *model.DeviceCredential) error
+	UseDeviceNonce(keyId string, nonce string) (bool, error)
+	DeleteDeviceNoncesBefore(before time.Time) (int64, error)
+}
+
+type deviceCredentialRepository struct {
+	db *gorm.DB
+}
+
+func NewDeviceCredentialRepository(db *gorm.DB) DeviceCredentialRepository {
+	return &deviceCredentialRepository{
+		db: db,
+	}
+}
+
+// CreateDeviceCredential stores a new credential for a system unit and revokes
+// the credentials it replaces, so a unit only ever has one valid secret.
+func (r *deviceCredentialRepository) CreateDeviceCredential(inputModel *model.DeviceCredential) (*model.DeviceCredential, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		err := tx.Exec(`UPDATE hydroponic_system.device_credentials
+						SET revoked_at = ?
+						WHERE system_unit_id = ? AND revoked_at IS NULL`,
+			now, inputModel.SystemUnitId).Error
+		if err != nil {
+			return err
+		}
+
+		sqlScript := `INSERT INTO hydroponic_system.device_credentials (system_unit_id, key_id, secret_ciphertext, created_at)
+					  VALUES (?, ?, ?, ?)
+					  RETURNING id, system_unit_id, key_id, created_at;`
+
+		return tx.Raw(sqlScript,
+			inputModel.SystemUnitId,
+			inputModel.KeyId,
+			inputModel.SecretCiphertext,
+			now).Scan(inputModel).Error
+	})
+
+	if err != nil {
+		logger.Error("deviceCredentialRepository", "Failed to create device credential", map[string]string{
+			"system_unit_id": inputModel.SystemUnitId.String(),
+			"error":          err.Error(),
+		})
+		return nil, err
+	}
+
+	logger.Info("deviceCredentialRepository", "Device credential created", map[string]string{
+		"system_unit_id": inputModel.SystemUnitId.String(),
+		"key_id":         inputModel.KeyId,
+	})
+	return inputModel, nil
+}
+
+func (r *deviceCredentialRepository) GetActiveDeviceCredential(keyId string) (*model.DeviceCredentialJoined, error) {
+	var credential *model.DeviceCredentialJoined
+
+	sqlScript := `SELECT dc.id, dc.system_unit_id, su.farm_id, su.unit_key, dc.key_id, dc.secret_ciphertext, dc.last_used_at
+				  FROM hydroponic_system.device_credentials dc
+				  JOIN hydroponic_system.system_units su ON su.id = dc.system_unit_id
+				  JOIN hydroponic_system.farms f ON f.id = su.farm_id
+				  WHERE dc.key_id = ?
+				  AND dc.revoked_at IS NULL
+				  AND su.deleted_at IS NULL
+				  AND f.deleted_at IS NULL`
+
+	res := r.db.Raw(sqlScript, keyId).Scan(&credential)
+
+	if res.Error != nil {
+		logger.Error("deviceCredentialRepository", "Failed to fetch device credential", map[string]string{
+			"key_id": keyId,
+			"error":  res.Error.Error(),
+		})
+		return nil, res.Error
+	}
+	return credential, nil
+}
+
+// GetActiveDeviceCredentialsByUnitKey returns the active credentials of the
+// system units built on a unit key. A key is not unique across farms, so the
+// caller tells them apart by their secret.
+func (r *deviceCredentialRepository) GetActiveDeviceCredentialsByUnitKey(unitKey uuid.UUID) ([]*model.DeviceCredentialJoined, error) {
+	var credentials []*model.DeviceCredentialJoined
+
+	sqlScript := `SELECT dc.id, dc.system_unit_id, su.farm_id, su.unit_key, dc.key_id, dc.secret_ciphertext, dc.last_used_at
+				  FROM hydroponic_system.device_credentials dc
+				  JOIN hydroponic_system.system_units su ON su.id = dc.system_unit_id
+				  JOIN hydroponic_system.farms f ON f.id = su.farm_id
+				  WHERE su.unit_key = ?
+				  AND dc.revoked_at IS NULL
+				  AND su.deleted_at IS NULL
+				  AND f.deleted_at IS NULL`
+
+	res := r.db.Raw(sqlScript, unitKey).Scan(&credentials)
+
+	if res.Error != nil {
+		logger.Error("deviceCredentialRepository", "Failed to fetch device credentials", map[string]string{
+			"unit_key": unitKey.String(),
+			"error":    res.Error.Error(),
+		})
+		return nil, res.Error
+	}
+	return credentials, nil
+}
+
+func (r *deviceCredentialRepository) TouchDeviceCredential(inputModel *model.DeviceCredential) error {
+	sqlScript := `UPDATE hydroponic_system.device_credentials
+				  SET last_used_at = ?
+				  WHERE id = ?`
+
+	return r.db.Exec(sqlScript, time.Now(), inputModel.ID).Error
+}
+
+// UseDeviceNonce records a nonce for a key. It reports false when the nonce
+// has been seen before, which means the request is a replay.
+func (r *deviceCredentialRepository) UseDeviceNonce(keyId string, nonce string) (bool, error) {
+	sqlScript := `INSERT INTO hydroponic_system.device_nonces (key_id, nonce, created_at)
+				  VALUES (?, ?, ?)
+				  ON CONFLICT (key_id, nonce) DO NOTHING`
+
+	res := r.db.Exec(sqlScript, keyId, nonce, time.Now())
+
+	if res.Error != nil {
+		logger.Error("deviceCredentialRepository", "Failed to store device nonce", map[string]string{
+			"key_id": keyId,
+			"error":  res.Error.Error(),
+		})
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+func (r *deviceCredentialRepository) DeleteDeviceNoncesBefore(before time.Time) (int64, error) {
+	res := r.db.Exec(`DELETE FROM hydroponic_system.device_nonces WHERE created_at < ?`, before)
+
+	if res.Error != nil {
+		logger.Error("deviceCredentialRepository", "Failed to delete expired device nonces", map[string]string{
+			"error": res.Error.Error(),
+		})
+		return 0, res.Error
+	}
+	return res.RowsAffected, nil
+}
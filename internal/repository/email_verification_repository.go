@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"time"
+
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+	"gorm.io/gorm"
+)
+
+type EmailVerificationRepository interface {
+	CreateEmailVerificationToken(inputModel *model.EmailVerificationToken) (*model.EmailVerificationToken, error)
+	VerifyEmail(inputModel *model.EmailVerificationToken) (*model.EmailVerificationToken, error)
+}
+
+type emailVerificationRepository struct {
+	db *gorm.DB
+}
+
+func NewEmailVerificationRepository(db *gorm.DB) EmailVerificationRepository {
+	return &emailVerificationRepository{
+		db: db,
+	}
+}
+
+func (r *emailVerificationRepository) CreateEmailVerificationToken(inputModel *model.EmailVerificationToken) (*model.EmailVerificationToken, error) {
+	logger.Info("emailVerificationRepository", "Creating email verification token", map[string]string{
+		"account_id": inputModel.AccountId.String(),
+	})
+
+	sqlScript := `INSERT INTO hydroponic_system.email_verification_tokens (account_id, token_hash, expires_at, created_at)
+				  VALUES (?, ?, ?, ?)
+				  RETURNING id, account_id, token_hash, expires_at, created_at;`
+
+	res := r.db.Raw(sqlScript,
+		inputModel.AccountId,
+		inputModel.TokenHash,
+		inputModel.ExpiresAt,
+		time.Now()).Scan(inputModel)
+
+	if res.Error != nil {
+		logger.Error("emailVerificationRepository", "Failed to create email verification token", map[string]string{
+			"error": res.Error.Error(),
+		})
+		return nil, res.Error
+	}
+
+	return inputModel, nil
+}
+
+// VerifyEmail consumes the token matching inputModel.TokenHash and marks the
+// account email as verified in one transaction.
+func (r *emailVerificationRepository) VerifyEmail(inputModel *model.EmailVerificationToken) (*model.EmailVerificationToken, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		res := tx.Raw(`UPDATE hydroponic_system.email_verification_tokens
+					   SET used_at = ?
+					   WHERE token_hash = ? AND used_at IS NULL AND expires_at > ?
+					   RETURNING id, account_id, token_hash, expires_at, used_at, created_at`,
+			now, inputModel.TokenHash, now).Scan(inputModel)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errs.InvalidEmailVerifyToken
+		}
+
+		err := tx.Exec(`UPDATE hydroponic_system.email_verification_tokens
+						SET used_at = ?
+						WHERE account_id = ? AND used_at IS NULL`,
+			now, inputModel.AccountId).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Exec(`UPDATE hydroponic_system.accounts
+						SET email_verified_at = COALESCE(email_verified_at, ?), updated_at = ?
+						WHERE id = ?`,
+			now, now, inputModel.AccountId).Error
+	})
+
+	if err != nil {
+		logger.Warn("emailVerificationRepository", "Failed to verify email", map[string]string{
+			"error": err.Error(),
+		})
+		return nil, err
+	}
+
+	logger.Info("emailVerificationRepository", "Email verified", map[string]string{
+		"account_id": inputModel.AccountId.String(),
+	})
+	return inputModel, nil
+}
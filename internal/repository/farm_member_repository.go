@@ -0,0 +1,335 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/constant"
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type FarmMemberRepository interface {
+	GetFarmMember(farmId uuid.UUID, scope *Scope) (*model.FarmMember, error)
+	GetFarmMembers(farmId uuid.UUID) ([]*model.FarmMemberJoined, error)
+	UpdateFarmMemberRole(inputModel *model.FarmMember) (*model.FarmMember, error)
+	DeleteFarmMember(inputModel *model.FarmMember) (*model.FarmMember, error)
+	CreateFarmInvitation(inputModel *model.FarmInvitation) (*model.FarmInvitation, error)
+	GetPendingFarmInvitations(farmId uuid.UUID) ([]*model.FarmInvitation, error)
+	GetActiveFarmInvitation(tokenHash string) (*model.FarmInvitation, error)
+	RevokeFarmInvitation(inputModel *model.FarmInvitation) error
+	AcceptFarmInvitation(tokenHash string, scope *Scope) (*model.FarmMember, error)
+}
+
+type farmMemberRepository struct {
+	db *gorm.DB
+}
+
+func NewFarmMemberRepository(db *gorm.DB) FarmMemberRepository {
+	return &farmMemberRepository{
+		db: db,
+	}
+}
+
+// GetFarmMember returns the membership of an account on a live farm of its
+// organization, or nil when the account is not a member.
+func (r *farmMemberRepository) GetFarmMember(farmId uuid.UUID, scope *Scope) (*model.FarmMember, error) {
+	var member *model.FarmMember
+
+	sqlScript := `SELECT m.id, m.farm_id, m.account_id, m.role, m.created_at, m.updated_at
+				  FROM hydroponic_system.farm_members m
+				  JOIN hydroponic_system.farms f ON f.id = m.farm_id
+				  WHERE m.farm_id = ? AND m.account_id = ?
+				  AND f.organization_id = ?
+				  AND f.deleted_at IS NULL`
+
+	res := r.db.Raw(sqlScript, farmId, scope.AccountID, scope.OrganizationID).Scan(&member)
+
+	if res.Error != nil {
+		logger.Error("farmMemberRepository", "Failed to fetch farm member", map[string]string{
+			"farm_id":    farmId.String(),
+			"account_id": scope.AccountID.String(),
+			"error":      res.Error.Error(),
+		})
+		return nil, res.Error
+	}
+	return member, nil
+}
+
+func (r *farmMemberRepository) GetFarmMembers(farmId uuid.UUID) ([]*model.FarmMemberJoined, error) {
+	var members []*model.FarmMemberJoined
+
+	sqlScript := `SELECT m.id, m.farm_id, m.account_id, m.role, m.created_at, m.updated_at, a.username, a.email
+				  FROM hydroponic_system.farm_members m
+				  JOIN hydroponic_system.accounts a ON a.id = m.account_id
+				  WHERE m.farm_id = ?
+				  AND a.deleted_at IS NULL
+				  ORDER BY m.created_at`
+
+	res := r.db.Raw(sqlScript, farmId).Scan(&members)
+
+	if res.Error != nil {
+		logger.Error("farmMemberRepository", "Failed to fetch farm members", map[string]string{
+			"farm_id": farmId.String(),
+			"error":   res.Error.Error(),
+		})
+		return nil, res.Error
+	}
+	return members, nil
+}
+
+// UpdateFarmMemberRole changes the role of a member. A farm always keeps at
+// least one owner, so demoting the last owner fails with LastFarmOwner.
+func (r *farmMemberRepository) UpdateFarmMemberRole(inputModel *model.FarmMember) (*model.FarmMember, error) {
+	logger.Info("farmMemberRepository", "Updating farm member role", map[string]string{
+		"farm_id":    inputModel.FarmId.String(),
+		"account_id": inputModel.AccountId.String(),
+		"role":       inputModel.Role,
+	})
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if inputModel.Role != constant.RoleOwner {
+			err := checkRemainingOwner(tx, inputModel)
+			if err != nil {
+				return err
+			}
+		}
+
+		res := tx.Raw(`UPDATE hydroponic_system.farm_members
+					   SET role = ?, updated_at = ?
+					   WHERE farm_id = ? AND account_id = ?
+					   RETURNING id, farm_id, account_id, role, created_at, updated_at`,
+			inputModel.Role, time.Now(), inputModel.FarmId, inputModel.AccountId).Scan(inputModel)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errs.InvalidFarmMember
+		}
+		return nil
+	})
+
+	if err != nil {
+		logger.Error("farmMemberRepository", "Failed to update farm member role", map[string]string{
+			"farm_id":    inputModel.FarmId.String(),
+			"account_id": inputModel.AccountId.String(),
+			"error":      err.Error(),
+		})
+		return nil, err
+	}
+
+	return inputModel, nil
+}
+
+// DeleteFarmMember removes a member from a farm unless it is the last owner.
+func (r *farmMemberRepository) DeleteFarmMember(inputModel *model.FarmMember) (*model.FarmMember, error) {
+	logger.Info("farmMemberRepository", "Removing farm member", map[string]string{
+		"farm_id":    inputModel.FarmId.String(),
+		"account_id": inputModel.AccountId.String(),
+	})
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := checkRemainingOwner(tx, inputModel)
+		if err != nil {
+			return err
+		}
+
+		res := tx.Raw(`DELETE FROM hydroponic_system.farm_members
+					   WHERE farm_id = ? AND account_id = ?
+					   RETURNING id, farm_id, account_id, role, created_at, updated_at`,
+			inputModel.FarmId, inputModel.AccountId).Scan(inputModel)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errs.InvalidFarmMember
+		}
+		return nil
+	})
+
+	if err != nil {
+		logger.Error("farmMemberRepository", "Failed to remove farm member", map[string]string{
+			"farm_id":    inputModel.FarmId.String(),
+			"account_id": inputModel.AccountId.String(),
+			"error":      err.Error(),
+		})
+		return nil, err
+	}
+
+	return inputModel, nil
+}
+
+// checkRemainingOwner fails when the member is the only owner of its farm. The
+// owner rows are locked so two concurrent demotions cannot both pass.
+func checkRemainingOwner(tx *gorm.DB, member *model.FarmMember) error {
+	var owners []uuid.UUID
+
+	res := tx.Raw(`SELECT account_id FROM hydroponic_system.farm_members
+				   WHERE farm_id = ? AND role = ?
+				   FOR UPDATE`,
+		member.FarmId, constant.RoleOwner).Scan(&owners)
+	if res.Error != nil {
+		return res.Error
+	}
+
+	if len(owners) == 1 && owners[0] == member.AccountId {
+		return errs.LastFarmOwner
+	}
+	return nil
+}
+
+func (r *farmMemberRepository) CreateFarmInvitation(inputModel *model.FarmInvitation) (*model.FarmInvitation, error) {
+	logger.Info("farmMemberRepository", "Creating farm invitation", map[string]string{
+		"farm_id": inputModel.FarmId.String(),
+		"role":    inputModel.Role,
+	})
+
+	sqlScript := `INSERT INTO hydroponic_system.farm_invitations (farm_id, email, role, token_hash, invited_by, expires_at, created_at)
+				  VALUES (?, ?, ?, ?, ?, ?, ?)
+				  RETURNING id, farm_id, email, role, invited_by, expires_at, created_at;`
+
+	res := r.db.Raw(sqlScript,
+		inputModel.FarmId,
+		inputModel.Email,
+		inputModel.Role,
+		inputModel.TokenHash,
+		inputModel.InvitedBy,
+		inputModel.ExpiresAt,
+		time.Now()).Scan(inputModel)
+
+	if res.Error != nil {
+		logger.Error("farmMemberRepository", "Failed to create farm invitation", map[string]string{
+			"error": res.Error.Error(),
+		})
+		return nil, res.Error
+	}
+
+	return inputModel, nil
+}
+
+func (r *farmMemberRepository) GetPendingFarmInvitations(farmId uuid.UUID) ([]*model.FarmInvitation, error) {
+	var invitations []*model.FarmInvitation
+
+	sqlScript := `SELECT id, farm_id, email, role, invited_by, expires_at, created_at
+				  FROM hydroponic_system.farm_invitations
+				  WHERE farm_id = ?
+				  AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?
+				  ORDER BY created_at DESC`
+
+	res := r.db.Raw(sqlScript, farmId, time.Now()).Scan(&invitations)
+
+	if res.Error != nil {
+		logger.Error("farmMemberRepository", "Failed to fetch farm invitations", map[string]string{
+			"farm_id": farmId.String(),
+			"error":   res.Error.Error(),
+		})
+		return nil, res.Error
+	}
+	return invitations, nil
+}
+
+func (r *farmMemberRepository) GetActiveFarmInvitation(tokenHash string) (*model.FarmInvitation, error) {
+	var invitation *model.FarmInvitation
+
+	sqlScript := `SELECT i.id, i.farm_id, i.email, i.role, i.invited_by, i.expires_at, i.created_at
+				  FROM hydroponic_system.farm_invitations i
+				  JOIN hydroponic_system.farms f ON f.id = i.farm_id
+				  WHERE i.token_hash = ?
+				  AND i.accepted_at IS NULL AND i.revoked_at IS NULL AND i.expires_at > ?
+				  AND f.deleted_at IS NULL`
+
+	res := r.db.Raw(sqlScript, tokenHash, time.Now()).Scan(&invitation)
+
+	if res.Error != nil {
+		logger.Error("farmMemberRepository", "Failed to fetch farm invitation", map[string]string{
+			"error": res.Error.Error(),
+		})
+		return nil, res.Error
+	}
+	if invitation == nil {
+		return nil, errs.InvalidFarmInvitation
+	}
+	return invitation, nil
+}
+
+func (r *farmMemberRepository) RevokeFarmInvitation(inputModel *model.FarmInvitation) error {
+	sqlScript := `UPDATE hydroponic_system.farm_invitations
+				  SET revoked_at = ?
+				  WHERE id = ? AND farm_id = ?
+				  AND accepted_at IS NULL AND revoked_at IS NULL`
+
+	res := r.db.Exec(sqlScript, time.Now(), inputModel.ID, inputModel.FarmId)
+
+	if res.Error != nil {
+		logger.Error("farmMemberRepository", "Failed to revoke farm invitation", map[string]string{
+			"id":    inputModel.ID.String(),
+			"error": res.Error.Error(),
+		})
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errs.InvalidFarmInvitationID
+	}
+	return nil
+}
+
+// AcceptFarmInvitation consumes an invitation and adds the account to the
+// farm with the invited role. An account that is already a member keeps its
+// current role. Invitations to farms of another organization or sent to
+// another email address than the account's are rejected.
+func (r *farmMemberRepository) AcceptFarmInvitation(tokenHash string, scope *Scope) (*model.FarmMember, error) {
+	logger.Info("farmMemberRepository", "Accepting farm invitation", map[string]string{
+		"account_id": scope.AccountID.String(),
+	})
+
+	accountId := scope.AccountID
+
+	member := &model.FarmMember{}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		invitation := &model.FarmInvitation{}
+		res := tx.Raw(`UPDATE hydroponic_system.farm_invitations i
+					   SET accepted_at = ?, accepted_by = ?
+					   FROM hydroponic_system.farms f, hydroponic_system.accounts a
+					   WHERE i.token_hash = ?
+					   AND f.id = i.farm_id AND f.deleted_at IS NULL
+					   AND f.organization_id = ?
+					   AND a.id = ? AND a.deleted_at IS NULL
+					   AND lower(i.email) = lower(a.email)
+					   AND i.accepted_at IS NULL AND i.revoked_at IS NULL AND i.expires_at > ?
+					   RETURNING i.id, i.farm_id, i.role`,
+			now, accountId, tokenHash, scope.OrganizationID, accountId, now).Scan(invitation)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errs.InvalidFarmInvitation
+		}
+
+		err := tx.Exec(`INSERT INTO hydroponic_system.farm_members (farm_id, account_id, role, created_at, updated_at)
+						VALUES (?, ?, ?, ?, ?)
+						ON CONFLICT (farm_id, account_id) DO NOTHING`,
+			invitation.FarmId, accountId, invitation.Role, now, now).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Raw(`SELECT id, farm_id, account_id, role, created_at, updated_at
+					   FROM hydroponic_system.farm_members
+					   WHERE farm_id = ? AND account_id = ?`,
+			invitation.FarmId, accountId).Scan(member).Error
+	})
+
+	if err != nil {
+		logger.Error("farmMemberRepository", "Failed to accept farm invitation", map[string]string{
+			"account_id": accountId.String(),
+			"error":      err.Error(),
+		})
+		return nil, err
+	}
+
+	return member, nil
+}
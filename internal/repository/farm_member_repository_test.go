@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/constant"
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/google/uuid"
+)
+
+func TestAcceptFarmInvitationMatchesAccountEmail(t *testing.T) {
+	scope := &Scope{AccountID: uuid.New(), OrganizationID: uuid.New()}
+	invitationId := uuid.New()
+	farmId := uuid.New()
+
+	tests := []struct {
+		name string
+		// emailMatches stands in for the database finding the invitation
+		// with the email of the account
+		emailMatches bool
+		wantErr      error
+	}{
+		{"invited email", true, nil},
+		{"other email", false, errs.InvalidFarmInvitation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &recordingDB{
+				query: func(sql string) ([]string, [][]driver.Value) {
+					switch {
+					case strings.HasPrefix(sql, "UPDATE hydroponic_system.farm_invitations"):
+						if !tt.emailMatches {
+							return []string{"id", "farm_id", "role"}, nil
+						}
+						return []string{"id", "farm_id", "role"},
+							[][]driver.Value{{invitationId.String(), farmId.String(), constant.RoleOperator}}
+					case strings.HasPrefix(sql, "SELECT id, farm_id, account_id, role"):
+						return []string{"id", "farm_id", "account_id", "role", "created_at", "updated_at"},
+							[][]driver.Value{{uuid.NewString(), farmId.String(), scope.AccountID.String(), constant.RoleOperator, time.Now(), time.Now()}}
+					}
+					return nil, nil
+				},
+			}
+
+			member, err := NewFarmMemberRepository(newRecordingGorm(t, db)).AcceptFarmInvitation("token-hash", scope)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("AcceptFarmInvitation error = %v, want %v", err, tt.wantErr)
+			}
+
+			update := db.index("UPDATE hydroponic_system.farm_invitations")
+			if update < 0 {
+				t.Fatal("invitation was not updated")
+			}
+			statement := db.statements[update]
+			if !strings.Contains(statement.sql, "AND a.deleted_at IS NULL AND lower(i.email) = lower(a.email)") {
+				t.Errorf("update does not match the invitation email with the account: %s", statement.sql)
+			}
+			if len(statement.args) != 6 || statement.args[4] != scope.AccountID.String() {
+				t.Errorf("update args = %v, want the account %s for the email check", statement.args, scope.AccountID)
+			}
+
+			joined := db.index("INSERT INTO hydroponic_system.farm_members") >= 0
+			if tt.wantErr != nil {
+				if joined {
+					t.Error("account joined the farm of an invitation sent to another email")
+				}
+				return
+			}
+			if !joined {
+				t.Error("account did not join the farm")
+			}
+			if member.FarmId != farmId || member.AccountId != scope.AccountID {
+				t.Errorf("member = %+v, want account %s on farm %s", member, scope.AccountID, farmId)
+			}
+		})
+	}
+}
@@ -4,18 +4,22 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/constant"
 	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 type FarmRepository interface {
 	CreateFarm(inputModel *model.Farm) (*model.Farm, error)
-	GetFarms() ([]*model.Farm, error)
+	GetFarmsByScope(scope *Scope) ([]*model.Farm, error)
+	GetFarmsByOrganizationId(organizationId *uuid.UUID) ([]*model.Farm, error)
 	GetFarmById(inputModel *model.Farm) (*model.Farm, error)
-	UpdateFarm(inputModel *model.Farm) (*model.Farm, error)
-	DeleteFarm(inputModel *model.Farm) (*model.Farm, error)
+	GetFarmByIdAndScope(inputModel *model.Farm, scope *Scope) (*model.Farm, error)
+	UpdateFarm(inputModel *model.Farm, scope *Scope) (*model.Farm, error)
+	DeleteFarm(inputModel *model.Farm, scope *Scope) (*model.Farm, error)
 }
 
 type farmRepository struct {
@@ -34,17 +38,33 @@ func (r *farmRepository) CreateFarm(inputModel *model.Farm) (*model.Farm, error)
 		"name":      inputModel.Name,
 	})
 
-	sqlScript := `INSERT INTO hydroponic_system.farms (profile_id , name , address, created_at) 
-				VALUES (?,?,?,?) 
-				RETURNING profile_id, name, address;`
+	// The farm belongs to the organization of its profile and the account of
+	// the profile becomes its first owner.
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		sqlScript := `INSERT INTO hydroponic_system.farms (profile_id, organization_id, name, address, created_at) 
+				SELECT id, organization_id, ?, ?, ? 
+				FROM hydroponic_system.profiles 
+				WHERE id = ? AND deleted_at IS NULL 
+				RETURNING id, profile_id, organization_id, name, address;`
 
-	res := r.db.Raw(sqlScript, inputModel.ProfileId, inputModel.Name, inputModel.Address, time.Now()).Scan(&inputModel)
+		res := tx.Raw(sqlScript, inputModel.Name, inputModel.Address, time.Now(), inputModel.ProfileId).Scan(&inputModel)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errs.InvalidProfileID
+		}
 
-	if res.Error != nil {
+		return tx.Exec(`INSERT INTO hydroponic_system.farm_members (farm_id, account_id, role, created_at, updated_at)
+						SELECT ?, account_id, ?, ?, ? FROM hydroponic_system.profiles WHERE id = ?`,
+			inputModel.ID, constant.RoleOwner, time.Now(), time.Now(), inputModel.ProfileId).Error
+	})
+
+	if err != nil {
 		logger.Error("farmRepository", "Failed to create farm", map[string]string{
-			"error": res.Error.Error(),
+			"error": err.Error(),
 		})
-		return nil, res.Error
+		return nil, err
 	}
 
 	logger.Info("farmRepository", "Farm created successfully", map[string]string{
@@ -54,15 +74,18 @@ func (r *farmRepository) CreateFarm(inputModel *model.Farm) (*model.Farm, error)
 	return inputModel, nil
 }
 
-func (r *farmRepository) GetFarms() ([]*model.Farm, error) {
-	logger.Info("farmRepository", "Fetching all farms", nil)
+func (r *farmRepository) GetFarmsByScope(scope *Scope) ([]*model.Farm, error) {
+	logger.Info("farmRepository", "Fetching farms of account", map[string]string{
+		"accountID":      scope.AccountID.String(),
+		"organizationID": scope.OrganizationID.String(),
+	})
 
 	var farms []*model.Farm
 
 	sqlScript := `SELECT * FROM hydroponic_system.farms 
-				WHERE deleted_at IS NULL`
+				WHERE id IN (` + accessibleFarmIdsSQL + `)`
 
-	res := r.db.Raw(sqlScript).Scan(&farms)
+	res := r.db.Raw(sqlScript, scope.AccountID, scope.OrganizationID).Scan(&farms)
 
 	if res.Error != nil {
 		logger.Error("farmRepository", "Failed to fetch farms", map[string]string{
@@ -77,6 +100,33 @@ func (r *farmRepository) GetFarms() ([]*model.Farm, error) {
 	return farms, nil
 }
 
+func (r *farmRepository) GetFarmsByOrganizationId(organizationId *uuid.UUID) ([]*model.Farm, error) {
+	logger.Info("farmRepository", "Fetching farms of organization", map[string]string{
+		"organizationID": organizationId.String(),
+	})
+
+	var farms []*model.Farm
+
+	sqlScript := `SELECT * FROM hydroponic_system.farms 
+				WHERE organization_id = ? 
+				AND deleted_at IS NULL 
+				ORDER BY created_at`
+
+	res := r.db.Raw(sqlScript, *organizationId).Scan(&farms)
+
+	if res.Error != nil {
+		logger.Error("farmRepository", "Failed to fetch farms of organization", map[string]string{
+			"error": res.Error.Error(),
+		})
+		return nil, res.Error
+	}
+
+	logger.Info("farmRepository", "Fetched farms of organization successfully", map[string]string{
+		"count": strconv.Itoa(len(farms)),
+	})
+	return farms, nil
+}
+
 func (r *farmRepository) GetFarmById(inputModel *model.Farm) (*model.Farm, error) {
 	logger.Info("farmRepository", "Fetching farm by ID", map[string]string{
 		"farmID": inputModel.ID.String(),
@@ -107,7 +157,37 @@ func (r *farmRepository) GetFarmById(inputModel *model.Farm) (*model.Farm, error
 	return inputModel, nil
 }
 
-func (r *farmRepository) UpdateFarm(inputModel *model.Farm) (*model.Farm, error) {
+func (r *farmRepository) GetFarmByIdAndScope(inputModel *model.Farm, scope *Scope) (*model.Farm, error) {
+	logger.Info("farmRepository", "Fetching farm by ID for account", map[string]string{
+		"farmID":    inputModel.ID.String(),
+		"accountID": scope.AccountID.String(),
+	})
+
+	sqlScript := `SELECT * FROM hydroponic_system.farms 
+				WHERE id = ? 
+				AND id IN (` + accessibleFarmIdsSQL + `)`
+
+	res := r.db.Raw(sqlScript, inputModel.ID, scope.AccountID, scope.OrganizationID).Scan(&inputModel)
+
+	if res.Error != nil {
+		logger.Error("farmRepository", "Failed to fetch farm", map[string]string{
+			"farmID": inputModel.ID.String(),
+			"error":  res.Error.Error(),
+		})
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		logger.Warn("farmRepository", "Farm ID not found for account", map[string]string{
+			"farmID":    inputModel.ID.String(),
+			"accountID": scope.AccountID.String(),
+		})
+		return nil, errs.InvalidFarmID
+	}
+
+	return inputModel, nil
+}
+
+func (r *farmRepository) UpdateFarm(inputModel *model.Farm, scope *Scope) (*model.Farm, error) {
 	logger.Info("farmRepository", "Updating farm", map[string]string{
 		"farmID": inputModel.ID.String(),
 		"name":   inputModel.Name,
@@ -116,9 +196,10 @@ func (r *farmRepository) UpdateFarm(inputModel *model.Farm) (*model.Farm, error)
 	sqlScript := `UPDATE hydroponic_system.farms 
 				SET updated_at = ?, name = ?, address = ?  
 				WHERE id = ? 
+				AND id IN (` + accessibleFarmIdsSQL + `)
 				RETURNING *`
 
-	res := r.db.Raw(sqlScript, time.Now(), inputModel.Name, inputModel.Address, inputModel.ID).Scan(&inputModel)
+	res := r.db.Raw(sqlScript, time.Now(), inputModel.Name, inputModel.Address, inputModel.ID, scope.AccountID, scope.OrganizationID).Scan(&inputModel)
 
 	if res.Error != nil {
 		logger.Error("farmRepository", "Failed to update farm", map[string]string{
@@ -141,7 +222,7 @@ func (r *farmRepository) UpdateFarm(inputModel *model.Farm) (*model.Farm, error)
 	return inputModel, nil
 }
 
-func (r *farmRepository) DeleteFarm(inputModel *model.Farm) (*model.Farm, error) {
+func (r *farmRepository) DeleteFarm(inputModel *model.Farm, scope *Scope) (*model.Farm, error) {
 	logger.Info("farmRepository", "Deleting farm", map[string]string{
 		"farmID": inputModel.ID.String(),
 	})
@@ -149,9 +230,10 @@ func (r *farmRepository) DeleteFarm(inputModel *model.Farm) (*model.Farm, error)
 	sqlScript := `UPDATE hydroponic_system.farms 
 				SET deleted_at = ? 
 				WHERE id = ? 
+				AND id IN (` + accessibleFarmIdsSQL + `)
 				RETURNING *`
 
-	res := r.db.Raw(sqlScript, time.Now(), inputModel.ID).Scan(&inputModel)
+	res := r.db.Raw(sqlScript, time.Now(), inputModel.ID, scope.AccountID, scope.OrganizationID).Scan(&inputModel)
 
 	if res.Error != nil {
 		logger.Error("farmRepository", "Failed to delete farm", map[string]string{
@@ -2,23 +2,30 @@ package repository
 
 import (
 	"strconv"
-	"time"
+	"strings"
 
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/dto"
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 type GrowthHistRepository interface {
-	CreateGrowthHistory(inputModel *model.GrowthHist) (*model.GrowthHist, error)
-	CreateGrowthHistoryBatch(values *string) (int, error)
-	GetAggregateByFilter(inputModel *dto.GetGrowthFilter, startDate *string, endDate *string) (*model.GrowthHistAggregate, error)
-	GetDataByFilter(inputModel *dto.GetGrowthFilter, startDate *string, endDate *string) ([]*model.GrowthHistFilter, error)
+	CreateReadings(readings []*model.Reading, receipt *model.IngestReceipt) ([]*model.Reading, error)
+	CreateReadingsBulk(readings []*model.Reading) (int64, error)
+	CreateReadingSamples(samples []*model.ReadingSample) (int64, error)
+	CreateReadingsBatch(values *string) (int, error)
+	GetAggregateByFilter(inputModel *dto.GetGrowthFilter, startDate *string, endDate *string) ([]*model.MetricAggregate, error)
+	GetDataByFilter(inputModel *dto.GetGrowthFilter, startDate *string, endDate *string) ([]*model.ReadingFilter, error)
 	GetMonthlyAggregation() ([]*model.GrowthHistMonthlyAggregation, error)
 	GetPrevMonthAggregation() ([]*model.GrowthHistMonthlyAggregation, error)
 }
 
+// readingsPerStatement keeps bulk inserts well below the 65535 bind parameter
+// limit of postgres.
+const readingsPerStatement = 1000
+
 type growthHistRepository struct {
 	db *gorm.DB
 }
@@ -27,75 +34,219 @@ func NewGrowthHistRepository(db *gorm.DB) GrowthHistRepository {
 	return &growthHistRepository{db: db}
 }
 
-func (r *growthHistRepository) CreateGrowthHistory(inputModel *model.GrowthHist) (*model.GrowthHist, error) {
-	logger.Info("growthHistRepository", "Creating new growth history record", nil)
+// CreateReadings stores the readings of one or more samples in a single
+// statement. A non nil receipt is stored in the same transaction.
+func (r *growthHistRepository) CreateReadings(readings []*model.Reading, receipt *model.IngestReceipt) ([]*model.Reading, error) {
+	logger.Info("growthHistRepository", "Creating readings", map[string]string{
+		"count": strconv.Itoa(len(readings)),
+	})
 
-	sqlScript := `INSERT INTO hydroponic_system.growth_hist(farm_id, system_id, ppm, ph, created_at) 
-				  VALUES (?, ?, ?, ?, ?) 
-				  RETURNING farm_id, system_id, ppm, ph;`
+	var outputModel []*model.Reading
 
-	res := r.db.Raw(sqlScript, inputModel.FarmId, inputModel.SystemId, inputModel.Ppm, inputModel.Ph, time.Now()).Scan(inputModel)
+	sqlScript, args := insertReadingsSQL(readings)
+	sqlScript += ` RETURNING id, sample_id, farm_id, system_id, metric, value, measured_at, received_at, skew_flagged, created_at;`
 
-	if res.Error != nil {
-		logger.Error("growthHistRepository", "Failed to create growth history", map[string]string{
-			"error": res.Error.Error(),
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if receipt != nil {
+			err := insertIngestReceipt(tx, receipt)
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Raw(sqlScript, args...).Scan(&outputModel).Error
+	})
+
+	if err != nil {
+		logger.Error("growthHistRepository", "Failed to create readings", map[string]string{
+			"error": err.Error(),
 		})
-		return nil, res.Error
+		return nil, err
 	}
 
-	logger.Info("growthHistRepository", "Growth history created successfully", map[string]string{
-		"farm_id":   inputModel.FarmId.String(),
-		"system_id": inputModel.SystemId.String(),
+	logger.Info("growthHistRepository", "Readings created successfully", map[string]string{
+		"count": strconv.Itoa(len(outputModel)),
 	})
-	return inputModel, nil
+	return outputModel, nil
 }
 
-func (r *growthHistRepository) CreateGrowthHistoryBatch(values *string) (int, error) {
-	logger.Info("growthHistRepository", "Creating batch growth history records", nil)
+// CreateReadingsBulk inserts readings in chunks inside one transaction, so
+// either all of them are stored or none.
+func (r *growthHistRepository) CreateReadingsBulk(readings []*model.Reading) (int64, error) {
+	logger.Info("growthHistRepository", "Creating bulk readings", map[string]string{
+		"count": strconv.Itoa(len(readings)),
+	})
+
+	var inserted int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		inserted, err = insertReadingsChunked(tx, readings)
+		return err
+	})
 
-	var inputModel *model.GrowthHist
+	if err != nil {
+		logger.Error("growthHistRepository", "Failed to create bulk readings", map[string]string{
+			"error": err.Error(),
+		})
+		return 0, err
+	}
 
-	sqlScript := `INSERT INTO hydroponic_system.growth_hist(farm_id, system_id, ppm, ph, created_at) 
+	logger.Info("growthHistRepository", "Bulk readings created successfully", map[string]string{
+		"count": strconv.FormatInt(inserted, 10),
+	})
+	return inserted, nil
+}
+
+// CreateReadingSamples stores a batch of samples from the write queue in one
+// transaction. Receipts already stored by another instance are skipped along
+// with their readings instead of failing the batch.
+func (r *growthHistRepository) CreateReadingSamples(samples []*model.ReadingSample) (int64, error) {
+	logger.Info("growthHistRepository", "Creating queued samples", map[string]string{
+		"count": strconv.Itoa(len(samples)),
+	})
+
+	var receipts []*model.IngestReceipt
+	for _, sample := range samples {
+		if sample.Receipt != nil {
+			sample.Receipt.ID = uuid.New()
+			receipts = append(receipts, sample.Receipt)
+		}
+	}
+
+	var inserted int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		stored := make(map[uuid.UUID]bool, len(receipts))
+		for start := 0; start < len(receipts); start += readingsPerStatement {
+			end := min(start+readingsPerStatement, len(receipts))
+
+			var ids []uuid.UUID
+			sqlScript, args := insertIngestReceiptsSQL(receipts[start:end])
+			err := tx.Raw(sqlScript+` ON CONFLICT DO NOTHING RETURNING id;`, args...).Scan(&ids).Error
+			if err != nil {
+				return err
+			}
+			for _, id := range ids {
+				stored[id] = true
+			}
+		}
+
+		var readings []*model.Reading
+		for _, sample := range samples {
+			if sample.Receipt != nil && !stored[sample.Receipt.ID] {
+				logger.Warn("growthHistRepository", "Skipping sample with a stored receipt", map[string]string{
+					"system_id": sample.Receipt.SystemId.String(),
+				})
+				continue
+			}
+			readings = append(readings, sample.Readings...)
+		}
+
+		var err error
+		inserted, err = insertReadingsChunked(tx, readings)
+		return err
+	})
+
+	if err != nil {
+		logger.Error("growthHistRepository", "Failed to create queued samples", map[string]string{
+			"error": err.Error(),
+		})
+		return 0, err
+	}
+
+	logger.Info("growthHistRepository", "Queued samples created successfully", map[string]string{
+		"count": strconv.FormatInt(inserted, 10),
+	})
+	return inserted, nil
+}
+
+// insertReadingsChunked inserts readings with one statement per
+// readingsPerStatement rows.
+func insertReadingsChunked(tx *gorm.DB, readings []*model.Reading) (int64, error) {
+	var inserted int64
+	for start := 0; start < len(readings); start += readingsPerStatement {
+		end := min(start+readingsPerStatement, len(readings))
+
+		sqlScript, args := insertReadingsSQL(readings[start:end])
+		res := tx.Exec(sqlScript, args...)
+		if res.Error != nil {
+			return 0, res.Error
+		}
+		inserted += res.RowsAffected
+	}
+	return inserted, nil
+}
+
+func insertReadingsSQL(readings []*model.Reading) (string, []interface{}) {
+	placeholders := make([]string, 0, len(readings))
+	args := make([]interface{}, 0, len(readings)*9)
+	for _, reading := range readings {
+		placeholders = append(placeholders, "(?, ?, ?, ?, ?, ?, ?, ?, ?)")
+		args = append(args, reading.SampleId, reading.FarmId, reading.SystemId, reading.Metric, reading.Value,
+			reading.MeasuredAt, reading.ReceivedAt, reading.SkewFlagged, reading.ReceivedAt)
+	}
+
+	sqlScript := `INSERT INTO hydroponic_system.readings(sample_id, farm_id, system_id, metric, value, measured_at, received_at, skew_flagged, created_at) 
+				  VALUES ` + strings.Join(placeholders, ", ")
+	return sqlScript, args
+}
+
+func (r *growthHistRepository) CreateReadingsBatch(values *string) (int, error) {
+	logger.Info("growthHistRepository", "Creating batch reading records", nil)
+
+	var inputModel *model.Reading
+
+	sqlScript := `INSERT INTO hydroponic_system.readings(sample_id, farm_id, system_id, metric, value, measured_at, received_at, created_at) 
 				  VALUES ` + *values + ` 
-				  RETURNING farm_id, system_id, ppm, ph;`
+				  RETURNING sample_id, farm_id, system_id, metric, value;`
 
 	res := r.db.Raw(sqlScript).Scan(&inputModel)
 
 	if res.Error != nil {
-		logger.Error("growthHistRepository", "Failed to create batch growth history", map[string]string{
+		logger.Error("growthHistRepository", "Failed to create batch readings", map[string]string{
 			"error": res.Error.Error(),
 		})
 		return 0, res.Error
 	}
 
-	logger.Info("growthHistRepository", "Batch growth history created successfully", nil)
+	logger.Info("growthHistRepository", "Batch readings created successfully", nil)
 	return 1, nil
 }
 
-func (r *growthHistRepository) GetAggregateByFilter(inputModel *dto.GetGrowthFilter, startDate *string, endDate *string) (*model.GrowthHistAggregate, error) {
-	logger.Info("growthHistRepository", "Fetching aggregate growth history", map[string]string{
+// GetAggregateByFilter returns one aggregate per metric in the filter, or per
+// metric with readings when the filter names none.
+func (r *growthHistRepository) GetAggregateByFilter(inputModel *dto.GetGrowthFilter, startDate *string, endDate *string) ([]*model.MetricAggregate, error) {
+	logger.Info("growthHistRepository", "Fetching aggregate readings", map[string]string{
 		"farm_id":   inputModel.FarmId,
 		"system_id": inputModel.SystemId,
+		"metrics":   strings.Join(inputModel.Metrics, ","),
 	})
 
-	outputModel := &model.GrowthHistAggregate{}
+	var outputModel []*model.MetricAggregate
 
 	sqlScript := `SELECT
-					COALESCE(SUM(ppm),0) as totalPpm,
-					COALESCE(SUM(ph),0) as totalPh,
-					COALESCE(COUNT(id),0) as totalData,
-					COALESCE(MIN(ppm),0) as minPpm,
-					COALESCE(MAX(ppm),0) as maxPpm,
-					COALESCE(MIN(ph),0) as minPh,
-					COALESCE(MAX(ph),0) as maxPh,
-					COALESCE(NULLIF(SUM(ppm), 0) / NULLIF(COUNT(id), 0), 0) as avgPpm,
-					COALESCE(NULLIF(SUM(ph), 0) / NULLIF(COUNT(id), 0), 0) as avgPh
-				  FROM hydroponic_system.growth_hist gh
-				  WHERE created_at::date BETWEEN ? AND ?
-				  AND farm_id = ?
-				  AND system_id = ?;`
+					r.metric,
+					m.unit,
+					COALESCE(SUM(r.value),0) as total,
+					COUNT(r.id) as total_data,
+					COALESCE(MIN(r.value),0) as min,
+					COALESCE(MAX(r.value),0) as max,
+					COALESCE(AVG(r.value),0) as avg
+				  FROM hydroponic_system.readings r
+				  JOIN hydroponic_system.metrics m ON m."name" = r.metric
+				  WHERE r.measured_at::date BETWEEN ? AND ?
+				  AND r.farm_id = ?
+				  AND r.system_id = ?
+				  AND r.deleted_at IS NULL`
+	args := []interface{}{*startDate, *endDate, inputModel.FarmId, inputModel.SystemId}
+	if len(inputModel.Metrics) > 0 {
+		sqlScript += `
+				  AND r.metric IN ?`
+		args = append(args, inputModel.Metrics)
+	}
+	sqlScript += `
+				  GROUP BY r.metric, m.unit
+				  ORDER BY r.metric;`
 
-	res := r.db.Raw(sqlScript, *startDate, *endDate, inputModel.FarmId, inputModel.SystemId).Scan(outputModel)
+	res := r.db.Raw(sqlScript, args...).Scan(&outputModel)
 
 	if res.Error != nil {
 		logger.Error("growthHistRepository", "Failed to fetch aggregate data", map[string]string{
@@ -107,71 +258,50 @@ func (r *growthHistRepository) GetAggregateByFilter(inputModel *dto.GetGrowthFil
 	return outputModel, nil
 }
 
-func (r *growthHistRepository) GetDataByFilter(inputModel *dto.GetGrowthFilter, startDate *string, endDate *string) ([]*model.GrowthHistFilter, error) {
-	logger.Info("growthHistRepository", "Fetching filtered growth history data", nil)
+func (r *growthHistRepository) GetDataByFilter(inputModel *dto.GetGrowthFilter, startDate *string, endDate *string) ([]*model.ReadingFilter, error) {
+	logger.Info("growthHistRepository", "Fetching filtered readings", nil)
 
-	var outputModel []*model.GrowthHistFilter
+	var outputModel []*model.ReadingFilter
 
-	sqlScript := `SELECT ppm, ph, created_at
-				  FROM hydroponic_system.growth_hist gh
-				  WHERE created_at::date BETWEEN ? AND ?
+	sqlScript := `SELECT sample_id, metric, value, measured_at, received_at, skew_flagged
+				  FROM hydroponic_system.readings r
+				  WHERE measured_at::date BETWEEN ? AND ?
 				  AND farm_id = ?
-				  AND system_id = ?;`
+				  AND system_id = ?
+				  AND deleted_at IS NULL`
+	args := []interface{}{*startDate, *endDate, inputModel.FarmId, inputModel.SystemId}
+	if len(inputModel.Metrics) > 0 {
+		sqlScript += `
+				  AND metric IN ?`
+		args = append(args, inputModel.Metrics)
+	}
+	sqlScript += `
+				  ORDER BY measured_at, sample_id, metric;`
 
-	res := r.db.Raw(sqlScript, *startDate, *endDate, inputModel.FarmId, inputModel.SystemId).Scan(&outputModel)
+	res := r.db.Raw(sqlScript, args...).Scan(&outputModel)
 
 	if res.Error != nil {
-		logger.Error("growthHistRepository", "Failed to fetch filtered growth history data", map[string]string{
+		logger.Error("growthHistRepository", "Failed to fetch filtered readings", map[string]string{
 			"error": res.Error.Error(),
 		})
 		return nil, res.Error
 	}
 
-	logger.Info("growthHistRepository", "Filtered growth history data fetched successfully", map[string]string{
+	logger.Info("growthHistRepository", "Filtered readings fetched successfully", map[string]string{
 		"count": strconv.Itoa(len(outputModel)),
 	})
 	return outputModel, nil
 }
 
 func (r *growthHistRepository) GetMonthlyAggregation() ([]*model.GrowthHistMonthlyAggregation, error) {
-	logger.Info("growthHistRepository", "Fetching monthly growth history aggregation", nil)
-
-	var outputModel []*model.GrowthHistMonthlyAggregation
+	logger.Info("growthHistRepository", "Fetching monthly reading aggregation", nil)
 
-	sqlScript := `SELECT 
-					farm_id,
-					system_id,
-					EXTRACT(YEAR FROM created_at) AS year,
-					EXTRACT(MONTH FROM created_at) AS month,
-					jsonb_build_object(
-						'total_data', COUNT(*),
-						'total_ph', ROUND(SUM(ph)::numeric, 2),
-						'total_ppm', ROUND(SUM(ppm)::numeric, 2),
-						'max_ph', ROUND(MAX(ph)::numeric, 2),
-						'min_ph', ROUND(MIN(ph)::numeric, 2),
-						'max_ppm', ROUND(MAX(ppm)::numeric, 2),
-						'min_ppm', ROUND(MIN(ppm)::numeric, 2)
-					) AS aggregated_values
-				FROM hydroponic_system.growth_hist gh
-				WHERE created_at < DATE_TRUNC('month', CURRENT_DATE)
-				GROUP BY 
-					farm_id, 
-					system_id, 
-					EXTRACT(YEAR FROM created_at),
-					EXTRACT(MONTH FROM created_at)
-				ORDER BY 
-					year, 
-					month, 
-					farm_id, 
-					system_id;`
-
-	res := r.db.Raw(sqlScript).Scan(&outputModel)
-
-	if res.Error != nil {
+	outputModel, err := r.getMonthlyAggregation(`measured_at < DATE_TRUNC('month', CURRENT_DATE)`)
+	if err != nil {
 		logger.Error("growthHistRepository", "Failed to fetch monthly aggregation", map[string]string{
-			"error": res.Error.Error(),
+			"error": err.Error(),
 		})
-		return nil, res.Error
+		return nil, err
 	}
 
 	logger.Info("growthHistRepository", "Monthly aggregation fetched successfully", map[string]string{
@@ -181,50 +311,104 @@ func (r *growthHistRepository) GetMonthlyAggregation() ([]*model.GrowthHistMonth
 }
 
 func (r *growthHistRepository) GetPrevMonthAggregation() ([]*model.GrowthHistMonthlyAggregation, error) {
-	logger.Info("growthHistRepository", "Fetching previous month's growth history aggregation", nil)
+	logger.Info("growthHistRepository", "Fetching previous month's reading aggregation", nil)
+
+	outputModel, err := r.getMonthlyAggregation(`measured_at >= DATE_TRUNC('month', CURRENT_DATE - INTERVAL '1 month')
+						AND measured_at < DATE_TRUNC('month', CURRENT_DATE)`)
+	if err != nil {
+		logger.Error("growthHistRepository", "Failed to fetch previous month aggregation", map[string]string{
+			"error": err.Error(),
+		})
+		return nil, err
+	}
+
+	logger.Info("growthHistRepository", "Previous month aggregation fetched successfully", map[string]string{
+		"count": strconv.Itoa(len(outputModel)),
+	})
+	return outputModel, nil
+}
 
+// getMonthlyAggregation rolls the readings matching condition up per farm,
+// system unit and month of measurement. Every metric adds total_, min_, max_, avg_ and
+// total_data_ keys suffixed with its name, so ppm keeps the total_ppm style
+// activity names of the old growth_hist rollups. total_data is still written
+// for readers of those rollups and counts the samples of the month, as the
+// growth_hist rows did.
+func (r *growthHistRepository) getMonthlyAggregation(condition string) ([]*model.GrowthHistMonthlyAggregation, error) {
 	var outputModel []*model.GrowthHistMonthlyAggregation
 
-	sqlScript := `SELECT 
-					farm_id,
-					system_id,
-					EXTRACT(YEAR FROM created_at) AS year,
-					EXTRACT(MONTH FROM created_at) AS month,
-					jsonb_build_object(
-						'avg_ppm', ROUND(AVG(ppm)::numeric, 2),
-						'total_data', COUNT(*),
-						'total_ph', ROUND(SUM(ph)::numeric, 2),
-						'total_ppm', ROUND(SUM(ppm)::numeric, 2),
-						'max_ph', ROUND(MAX(ph)::numeric, 2),
-						'min_ph', ROUND(MIN(ph)::numeric, 2),
-						'max_ppm', ROUND(MAX(ppm)::numeric, 2),
-						'min_ppm', ROUND(MIN(ppm)::numeric, 2)
-					) AS aggregated_values
-				FROM hydroponic_system.growth_hist gh
-				WHERE created_at >= DATE_TRUNC('month', CURRENT_DATE - INTERVAL '1 month')
-				AND created_at < DATE_TRUNC('month', CURRENT_DATE)
+	sqlScript := `WITH r AS (
+					SELECT 
+						sample_id,
+						farm_id,
+						system_id,
+						metric,
+						value,
+						EXTRACT(YEAR FROM measured_at) AS year,
+						EXTRACT(MONTH FROM measured_at) AS month
+					FROM hydroponic_system.readings
+					WHERE ` + condition + `
+					AND deleted_at IS NULL
+				)
+				SELECT 
+					m.farm_id,
+					m.system_id,
+					m.year,
+					m.month,
+					jsonb_object_agg(s.key, s.value) || jsonb_build_object('total_data', MAX(c.total_data)) AS aggregated_values
+				FROM (
+					SELECT 
+						farm_id,
+						system_id,
+						year,
+						month,
+						jsonb_build_object(
+							'total_data_' || metric, COUNT(*),
+							'avg_' || metric, ROUND(AVG(value)::numeric, 2),
+							'total_' || metric, ROUND(SUM(value)::numeric, 2),
+							'max_' || metric, ROUND(MAX(value)::numeric, 2),
+							'min_' || metric, ROUND(MIN(value)::numeric, 2)
+						) AS metric_values
+					FROM r
+					GROUP BY 
+						farm_id, 
+						system_id, 
+						metric,
+						year,
+						month
+				) m
+				JOIN (
+					SELECT 
+						farm_id,
+						system_id,
+						year,
+						month,
+						COUNT(DISTINCT sample_id) AS total_data
+					FROM r
+					GROUP BY 
+						farm_id, 
+						system_id, 
+						year,
+						month
+				) c ON c.farm_id = m.farm_id
+					AND c.system_id = m.system_id
+					AND c.year = m.year
+					AND c.month = m.month
+				CROSS JOIN LATERAL jsonb_each(m.metric_values) s
 				GROUP BY 
-					farm_id, 
-					system_id, 
-					EXTRACT(YEAR FROM created_at),
-					EXTRACT(MONTH FROM created_at)
+					m.farm_id, 
+					m.system_id, 
+					m.year, 
+					m.month
 				ORDER BY 
-					year, 
-					month, 
-					farm_id, 
-					system_id;`
+					m.year, 
+					m.month, 
+					m.farm_id, 
+					m.system_id;`
 
 	res := r.db.Raw(sqlScript).Scan(&outputModel)
-
 	if res.Error != nil {
-		logger.Error("growthHistRepository", "Failed to fetch previous month aggregation", map[string]string{
-			"error": res.Error.Error(),
-		})
 		return nil, res.Error
 	}
-
-	logger.Info("growthHistRepository", "Previous month aggregation fetched successfully", map[string]string{
-		"count": strconv.Itoa(len(outputModel)),
-	})
 	return outputModel, nil
 }
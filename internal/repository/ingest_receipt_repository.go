@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type IngestReceiptRepository interface {
+	GetReceipts(systemId uuid.UUID, messageId *string, sequence *int64) ([]*model.IngestReceipt, error)
+	GetSequenceStats(systemId uuid.UUID) (*model.SequenceStats, error)
+	GetSequenceGaps(systemId uuid.UUID) ([]*model.SequenceGap, error)
+}
+
+type ingestReceiptRepository struct {
+	db *gorm.DB
+}
+
+func NewIngestReceiptRepository(db *gorm.DB) IngestReceiptRepository {
+	return &ingestReceiptRepository{db: db}
+}
+
+// GetReceipts returns the receipts of a system unit that match the message id
+// or the sequence number. Two receipts come back when each key matches a
+// different request.
+func (r *ingestReceiptRepository) GetReceipts(systemId uuid.UUID, messageId *string, sequence *int64) ([]*model.IngestReceipt, error) {
+	var receipts []*model.IngestReceipt
+
+	sqlScript := `SELECT id, system_id, kind, message_id, "sequence", request_hash, response, created_at
+				  FROM hydroponic_system.ingest_receipts
+				  WHERE system_id = ? AND (message_id = ? OR "sequence" = ?);`
+
+	res := r.db.Raw(sqlScript, systemId, messageId, sequence).Scan(&receipts)
+
+	if res.Error != nil {
+		logger.Error("ingestReceiptRepository", "Failed to fetch ingest receipts", map[string]string{
+			"error": res.Error.Error(),
+		})
+		return nil, res.Error
+	}
+	return receipts, nil
+}
+
+func (r *ingestReceiptRepository) GetSequenceStats(systemId uuid.UUID) (*model.SequenceStats, error) {
+	stats := &model.SequenceStats{}
+
+	sqlScript := `SELECT MIN("sequence") AS first, MAX("sequence") AS last, COUNT("sequence") AS received
+				  FROM hydroponic_system.ingest_receipts
+				  WHERE system_id = ?;`
+
+	res := r.db.Raw(sqlScript, systemId).Scan(stats)
+
+	if res.Error != nil {
+		logger.Error("ingestReceiptRepository", "Failed to fetch sequence stats", map[string]string{
+			"error": res.Error.Error(),
+		})
+		return nil, res.Error
+	}
+	return stats, nil
+}
+
+// GetSequenceGaps lists the missing ranges between the lowest and highest
+// sequence number received from a system unit.
+func (r *ingestReceiptRepository) GetSequenceGaps(systemId uuid.UUID) ([]*model.SequenceGap, error) {
+	var gaps []*model.SequenceGap
+
+	sqlScript := `SELECT prev_sequence + 1 AS "from", "sequence" - 1 AS "to"
+				  FROM (
+				  	SELECT "sequence", LAG("sequence") OVER (ORDER BY "sequence") AS prev_sequence
+				  	FROM hydroponic_system.ingest_receipts
+				  	WHERE system_id = ? AND "sequence" IS NOT NULL
+				  ) s
+				  WHERE "sequence" > prev_sequence + 1
+				  ORDER BY "from";`
+
+	res := r.db.Raw(sqlScript, systemId).Scan(&gaps)
+
+	if res.Error != nil {
+		logger.Error("ingestReceiptRepository", "Failed to fetch sequence gaps", map[string]string{
+			"error": res.Error.Error(),
+		})
+		return nil, res.Error
+	}
+
+	logger.Info("ingestReceiptRepository", "Sequence gaps fetched", map[string]string{
+		"system_id": systemId.String(),
+		"count":     strconv.Itoa(len(gaps)),
+	})
+	return gaps, nil
+}
+
+// insertIngestReceiptsSQL builds a multi-row insert of receipts with the id
+// set by the caller.
+func insertIngestReceiptsSQL(receipts []*model.IngestReceipt) (string, []interface{}) {
+	now := time.Now()
+	placeholders := make([]string, 0, len(receipts))
+	args := make([]interface{}, 0, len(receipts)*8)
+	for _, receipt := range receipts {
+		placeholders = append(placeholders, "(?, ?, ?, ?, ?, ?, ?, ?)")
+		args = append(args, receipt.ID, receipt.SystemId, receipt.Kind, receipt.MessageId, receipt.Sequence,
+			receipt.RequestHash, string(receipt.Response), now)
+	}
+
+	sqlScript := `INSERT INTO hydroponic_system.ingest_receipts(id, system_id, kind, message_id, "sequence", request_hash, response, created_at)
+				  VALUES ` + strings.Join(placeholders, ", ")
+	return sqlScript, args
+}
+
+// insertIngestReceipt stores a receipt in the transaction that stores its
+// readings or tank transaction. A retry racing the original request fails
+// here on the unique keys and rolls the whole transaction back.
+func insertIngestReceipt(tx *gorm.DB, receipt *model.IngestReceipt) error {
+	sqlScript := `INSERT INTO hydroponic_system.ingest_receipts(system_id, kind, message_id, "sequence", request_hash, response, created_at)
+				  VALUES (?, ?, ?, ?, ?, ?, ?);`
+
+	return tx.Exec(sqlScript,
+		receipt.SystemId,
+		receipt.Kind,
+		receipt.MessageId,
+		receipt.Sequence,
+		receipt.RequestHash,
+		string(receipt.Response),
+		time.Now()).Error
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+	"gorm.io/gorm"
+)
+
+type LoginThrottleRepository interface {
+	GetLoginThrottles(keys []string) ([]*model.LoginThrottle, error)
+	IncrementFailedLogin(key string, window time.Duration) (*model.LoginThrottle, error)
+	LockLoginThrottle(key string, until time.Time) error
+	DeleteLoginThrottles(keys []string) (int64, error)
+}
+
+type loginThrottleRepository struct {
+	db *gorm.DB
+}
+
+func NewLoginThrottleRepository(db *gorm.DB) LoginThrottleRepository {
+	return &loginThrottleRepository{
+		db: db,
+	}
+}
+
+func (r *loginThrottleRepository) GetLoginThrottles(keys []string) ([]*model.LoginThrottle, error) {
+	var throttles []*model.LoginThrottle
+
+	sqlScript := `SELECT throttle_key, failed_count, last_failed_at, locked_until
+				  FROM hydroponic_system.login_throttles
+				  WHERE throttle_key IN ?`
+
+	res := r.db.Raw(sqlScript, keys).Scan(&throttles)
+
+	if res.Error != nil {
+		logger.Error("loginThrottleRepository", "Failed to fetch login throttles", map[string]string{
+			"error": res.Error.Error(),
+		})
+		return nil, res.Error
+	}
+
+	return throttles, nil
+}
+
+// IncrementFailedLogin counts one more failed attempt for key. Failures older
+// than window are forgotten and the counter starts again at one.
+func (r *loginThrottleRepository) IncrementFailedLogin(key string, window time.Duration) (*model.LoginThrottle, error) {
+	var throttle *model.LoginThrottle
+
+	now := time.Now()
+	sqlScript := `INSERT INTO hydroponic_system.login_throttles (throttle_key, failed_count, last_failed_at)
+				  VALUES (?, 1, ?)
+				  ON CONFLICT (throttle_key) DO UPDATE
+				  SET failed_count = CASE
+				  		WHEN login_throttles.last_failed_at < ? THEN 1
+				  		ELSE login_throttles.failed_count + 1
+				  	END,
+				  	last_failed_at = EXCLUDED.last_failed_at
+				  RETURNING throttle_key, failed_count, last_failed_at, locked_until`
+
+	res := r.db.Raw(sqlScript, key, now, now.Add(-window)).Scan(&throttle)
+
+	if res.Error != nil {
+		logger.Error("loginThrottleRepository", "Failed to record failed login", map[string]string{
+			"error": res.Error.Error(),
+		})
+		return nil, res.Error
+	}
+
+	return throttle, nil
+}
+
+func (r *loginThrottleRepository) LockLoginThrottle(key string, until time.Time) error {
+	sqlScript := `UPDATE hydroponic_system.login_throttles
+				  SET locked_until = ?
+				  WHERE throttle_key = ?`
+
+	res := r.db.Exec(sqlScript, until, key)
+
+	if res.Error != nil {
+		logger.Error("loginThrottleRepository", "Failed to lock login throttle", map[string]string{
+			"error": res.Error.Error(),
+		})
+		return res.Error
+	}
+	return nil
+}
+
+func (r *loginThrottleRepository) DeleteLoginThrottles(keys []string) (int64, error) {
+	sqlScript := `DELETE FROM hydroponic_system.login_throttles
+				  WHERE throttle_key IN ?`
+
+	res := r.db.Exec(sqlScript, keys)
+
+	if res.Error != nil {
+		logger.Error("loginThrottleRepository", "Failed to delete login throttles", map[string]string{
+			"error": res.Error.Error(),
+		})
+		return 0, res.Error
+	}
+
+	logger.Info("loginThrottleRepository", "Login throttles cleared", map[string]string{
+		"count": strconv.FormatInt(res.RowsAffected, 10),
+	})
+	return res.RowsAffected, nil
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"strconv"
+
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+	"gorm.io/gorm"
+)
+
+type MetricRepository interface {
+	GetMetrics() ([]*model.Metric, error)
+}
+
+type metricRepository struct {
+	db *gorm.DB
+}
+
+func NewMetricRepository(db *gorm.DB) MetricRepository {
+	return &metricRepository{db: db}
+}
+
+func (r *metricRepository) GetMetrics() ([]*model.Metric, error) {
+	var metrics []*model.Metric
+
+	sqlScript := `SELECT "name", unit, min_value, max_value, COALESCE(description, '') AS description, created_at, updated_at
+				  FROM hydroponic_system.metrics
+				  ORDER BY "name";`
+
+	res := r.db.Raw(sqlScript).Scan(&metrics)
+
+	if res.Error != nil {
+		logger.Error("metricRepository", "Failed to fetch metrics", map[string]string{
+			"error": res.Error.Error(),
+		})
+		return nil, res.Error
+	}
+
+	logger.Info("metricRepository", "Metrics fetched successfully", map[string]string{
+		"count": strconv.Itoa(len(metrics)),
+	})
+	return metrics, nil
+}
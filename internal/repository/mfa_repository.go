@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"time"
+
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type MfaRepository interface {
+	SetPendingMfaSecret(accountId uuid.UUID, encryptedSecret string) error
+	EnableMfa(accountId uuid.UUID, recoveryCodeHashes []string) error
+	DisableMfa(accountId uuid.UUID) error
+	UseRecoveryCode(accountId uuid.UUID, codeHash string) (bool, error)
+	UseTotpStep(accountId uuid.UUID, step int64) (bool, error)
+}
+
+type mfaRepository struct {
+	db *gorm.DB
+}
+
+func NewMfaRepository(db *gorm.DB) MfaRepository {
+	return &mfaRepository{
+		db: db,
+	}
+}
+
+// SetPendingMfaSecret stores a secret that is not enforced until EnableMfa
+// confirms the user can produce codes for it.
+func (r *mfaRepository) SetPendingMfaSecret(accountId uuid.UUID, encryptedSecret string) error {
+	sqlScript := `UPDATE hydroponic_system.accounts
+				  SET mfa_secret = ?, mfa_last_step = NULL, updated_at = ?
+				  WHERE id = ? AND mfa_enabled_at IS NULL AND deleted_at IS NULL`
+
+	res := r.db.Exec(sqlScript, encryptedSecret, time.Now(), accountId)
+
+	if res.Error != nil {
+		logger.Error("mfaRepository", "Failed to store pending MFA secret", map[string]string{
+			"account_id": accountId.String(),
+			"error":      res.Error.Error(),
+		})
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errs.MfaAlreadyEnabled
+	}
+	return nil
+}
+
+// EnableMfa turns on MFA and replaces the recovery codes of the account.
+func (r *mfaRepository) EnableMfa(accountId uuid.UUID, recoveryCodeHashes []string) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		res := tx.Exec(`UPDATE hydroponic_system.accounts
+						SET mfa_enabled_at = ?, updated_at = ?
+						WHERE id = ? AND mfa_secret IS NOT NULL AND mfa_enabled_at IS NULL`,
+			now, now, accountId)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errs.MfaNotEnrolled
+		}
+
+		err := tx.Exec(`DELETE FROM hydroponic_system.mfa_recovery_codes WHERE account_id = ?`, accountId).Error
+		if err != nil {
+			return err
+		}
+
+		for _, codeHash := range recoveryCodeHashes {
+			err = tx.Exec(`INSERT INTO hydroponic_system.mfa_recovery_codes (account_id, code_hash, created_at)
+						   VALUES (?, ?, ?)`,
+				accountId, codeHash, now).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		logger.Error("mfaRepository", "Failed to enable MFA", map[string]string{
+			"account_id": accountId.String(),
+			"error":      err.Error(),
+		})
+		return err
+	}
+	return nil
+}
+
+func (r *mfaRepository) DisableMfa(accountId uuid.UUID) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`UPDATE hydroponic_system.accounts
+						SET mfa_secret = NULL, mfa_enabled_at = NULL, mfa_last_step = NULL, updated_at = ?
+						WHERE id = ?`,
+			time.Now(), accountId).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Exec(`DELETE FROM hydroponic_system.mfa_recovery_codes WHERE account_id = ?`, accountId).Error
+	})
+
+	if err != nil {
+		logger.Error("mfaRepository", "Failed to disable MFA", map[string]string{
+			"account_id": accountId.String(),
+			"error":      err.Error(),
+		})
+		return err
+	}
+	return nil
+}
+
+// UseRecoveryCode marks a matching unused recovery code as used. It reports
+// false when no such code exists.
+func (r *mfaRepository) UseRecoveryCode(accountId uuid.UUID, codeHash string) (bool, error) {
+	sqlScript := `UPDATE hydroponic_system.mfa_recovery_codes
+				  SET used_at = ?
+				  WHERE account_id = ? AND code_hash = ? AND used_at IS NULL`
+
+	res := r.db.Exec(sqlScript, time.Now(), accountId, codeHash)
+
+	if res.Error != nil {
+		logger.Error("mfaRepository", "Failed to use recovery code", map[string]string{
+			"account_id": accountId.String(),
+			"error":      res.Error.Error(),
+		})
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// UseTotpStep records the time step of an accepted TOTP code. It reports false
+// when a code of the same or a later step was already accepted.
+func (r *mfaRepository) UseTotpStep(accountId uuid.UUID, step int64) (bool, error) {
+	sqlScript := `UPDATE hydroponic_system.accounts
+				  SET mfa_last_step = ?
+				  WHERE id = ? AND (mfa_last_step IS NULL OR mfa_last_step < ?)`
+
+	res := r.db.Exec(sqlScript, step, accountId, step)
+
+	if res.Error != nil {
+		logger.Error("mfaRepository", "Failed to record TOTP step", map[string]string{
+			"account_id": accountId.String(),
+			"error":      res.Error.Error(),
+		})
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
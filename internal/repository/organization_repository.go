@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/constant"
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type OrganizationRepository interface {
+	GetOrganizationById(organizationId uuid.UUID) (*model.Organization, error)
+	UpdateOrganization(inputModel *model.Organization) (*model.Organization, error)
+	GetOrganizationMembers(organizationId uuid.UUID) ([]*model.OrganizationMember, error)
+	UpdateOrganizationMemberRole(organizationId uuid.UUID, accountId uuid.UUID, role string) (*model.OrganizationMember, error)
+}
+
+type organizationRepository struct {
+	db *gorm.DB
+}
+
+func NewOrganizationRepository(db *gorm.DB) OrganizationRepository {
+	return &organizationRepository{
+		db: db,
+	}
+}
+
+func (r *organizationRepository) GetOrganizationById(organizationId uuid.UUID) (*model.Organization, error) {
+	organization := &model.Organization{}
+
+	sqlScript := `SELECT id, name, created_at, updated_at
+				  FROM hydroponic_system.organizations
+				  WHERE id = ? AND deleted_at IS NULL`
+
+	res := r.db.Raw(sqlScript, organizationId).Scan(organization)
+
+	if res.Error != nil {
+		logger.Error("organizationRepository", "Failed to fetch organization", map[string]string{
+			"organization_id": organizationId.String(),
+			"error":           res.Error.Error(),
+		})
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, errs.InvalidOrganizationID
+	}
+	return organization, nil
+}
+
+func (r *organizationRepository) UpdateOrganization(inputModel *model.Organization) (*model.Organization, error) {
+	logger.Info("organizationRepository", "Updating organization", map[string]string{
+		"organization_id": inputModel.ID.String(),
+	})
+
+	sqlScript := `UPDATE hydroponic_system.organizations
+				  SET name = ?, updated_at = ?
+				  WHERE id = ? AND deleted_at IS NULL
+				  RETURNING id, name, created_at, updated_at`
+
+	res := r.db.Raw(sqlScript, inputModel.Name, time.Now(), inputModel.ID).Scan(inputModel)
+
+	if res.Error != nil {
+		logger.Error("organizationRepository", "Failed to update organization", map[string]string{
+			"organization_id": inputModel.ID.String(),
+			"error":           res.Error.Error(),
+		})
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, errs.InvalidOrganizationID
+	}
+	return inputModel, nil
+}
+
+func (r *organizationRepository) GetOrganizationMembers(organizationId uuid.UUID) ([]*model.OrganizationMember, error) {
+	var members []*model.OrganizationMember
+
+	sqlScript := `SELECT id AS account_id, username, email, organization_role, created_at
+				  FROM hydroponic_system.accounts
+				  WHERE organization_id = ? AND deleted_at IS NULL
+				  ORDER BY created_at`
+
+	res := r.db.Raw(sqlScript, organizationId).Scan(&members)
+
+	if res.Error != nil {
+		logger.Error("organizationRepository", "Failed to fetch organization members", map[string]string{
+			"organization_id": organizationId.String(),
+			"error":           res.Error.Error(),
+		})
+		return nil, res.Error
+	}
+	return members, nil
+}
+
+// UpdateOrganizationMemberRole changes the organization role of an account.
+// An organization always keeps at least one admin, so demoting the last one
+// fails with LastOrganizationAdmin.
+func (r *organizationRepository) UpdateOrganizationMemberRole(organizationId uuid.UUID, accountId uuid.UUID, role string) (*model.OrganizationMember, error) {
+	logger.Info("organizationRepository", "Updating organization member role", map[string]string{
+		"organization_id": organizationId.String(),
+		"account_id":      accountId.String(),
+		"role":            role,
+	})
+
+	member := &model.OrganizationMember{}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if role != constant.OrgRoleAdmin {
+			var admins []uuid.UUID
+
+			res := tx.Raw(`SELECT id FROM hydroponic_system.accounts
+						   WHERE organization_id = ? AND organization_role = ? AND deleted_at IS NULL
+						   FOR UPDATE`,
+				organizationId, constant.OrgRoleAdmin).Scan(&admins)
+			if res.Error != nil {
+				return res.Error
+			}
+			if len(admins) == 1 && admins[0] == accountId {
+				return errs.LastOrganizationAdmin
+			}
+		}
+
+		res := tx.Raw(`UPDATE hydroponic_system.accounts
+					   SET organization_role = ?, updated_at = ?
+					   WHERE id = ? AND organization_id = ? AND deleted_at IS NULL
+					   RETURNING id AS account_id, username, email, organization_role, created_at`,
+			role, time.Now(), accountId, organizationId).Scan(member)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errs.InvalidOrganizationMember
+		}
+		return nil
+	})
+
+	if err != nil {
+		logger.Error("organizationRepository", "Failed to update organization member role", map[string]string{
+			"organization_id": organizationId.String(),
+			"account_id":      accountId.String(),
+			"error":           err.Error(),
+		})
+		return nil, err
+	}
+
+	return member, nil
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"time"
+
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+	"gorm.io/gorm"
+)
+
+type PasswordResetRepository interface {
+	CreatePasswordResetToken(inputModel *model.PasswordResetToken) (*model.PasswordResetToken, error)
+	GetActivePasswordResetToken(inputModel *model.PasswordResetToken) (*model.PasswordResetToken, error)
+	ResetPassword(token *model.PasswordResetToken, account *model.User, previousHash string) error
+}
+
+type passwordResetRepository struct {
+	db *gorm.DB
+}
+
+func NewPasswordResetRepository(db *gorm.DB) PasswordResetRepository {
+	return &passwordResetRepository{
+		db: db,
+	}
+}
+
+func (r *passwordResetRepository) CreatePasswordResetToken(inputModel *model.PasswordResetToken) (*model.PasswordResetToken, error) {
+	logger.Info("passwordResetRepository", "Creating password reset token", map[string]string{
+		"account_id": inputModel.AccountId.String(),
+	})
+
+	sqlScript := `INSERT INTO hydroponic_system.password_reset_tokens (account_id, token_hash, expires_at, created_at)
+				  VALUES (?, ?, ?, ?)
+				  RETURNING id, account_id, token_hash, expires_at, created_at;`
+
+	res := r.db.Raw(sqlScript,
+		inputModel.AccountId,
+		inputModel.TokenHash,
+		inputModel.ExpiresAt,
+		time.Now()).Scan(inputModel)
+
+	if res.Error != nil {
+		logger.Error("passwordResetRepository", "Failed to create password reset token", map[string]string{
+			"error": res.Error.Error(),
+		})
+		return nil, res.Error
+	}
+
+	return inputModel, nil
+}
+
+func (r *passwordResetRepository) GetActivePasswordResetToken(inputModel *model.PasswordResetToken) (*model.PasswordResetToken, error) {
+	sqlScript := `SELECT id, account_id, token_hash, expires_at, used_at, created_at
+				  FROM hydroponic_system.password_reset_tokens
+				  WHERE token_hash = ? AND used_at IS NULL AND expires_at > ?`
+
+	res := r.db.Raw(sqlScript, inputModel.TokenHash, time.Now()).Scan(inputModel)
+
+	if res.Error != nil {
+		logger.Error("passwordResetRepository", "Failed to fetch password reset token", map[string]string{
+			"error": res.Error.Error(),
+		})
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, errs.InvalidPasswordResetToken
+	}
+
+	return inputModel, nil
+}
+
+// ResetPassword consumes the reset token and stores the new password. Every
+// other outstanding reset token, session and refresh token of the account is
+// revoked in the same transaction.
+func (r *passwordResetRepository) ResetPassword(token *model.PasswordResetToken, account *model.User, previousHash string) error {
+	logger.Info("passwordResetRepository", "Resetting password", map[string]string{
+		"account_id": account.ID.String(),
+	})
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		res := tx.Exec(`UPDATE hydroponic_system.password_reset_tokens
+						SET used_at = ?
+						WHERE id = ? AND used_at IS NULL AND expires_at > ?`,
+			now, token.ID, now)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errs.InvalidPasswordResetToken
+		}
+
+		err := tx.Exec(`UPDATE hydroponic_system.password_reset_tokens
+						SET used_at = ?
+						WHERE account_id = ? AND used_at IS NULL`,
+			now, account.ID).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Exec(`UPDATE hydroponic_system.accounts
+					   SET password = ?, updated_at = ?
+					   WHERE id = ?`,
+			account.Password, now, account.ID).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Exec(`INSERT INTO hydroponic_system.password_histories (account_id, password_hash, created_at)
+					   VALUES (?, ?, ?)`,
+			account.ID, previousHash, now).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Exec(`UPDATE hydroponic_system.sessions
+					   SET revoked_at = ?
+					   WHERE account_id = ? AND revoked_at IS NULL`,
+			now, account.ID).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Exec(`UPDATE hydroponic_system.refresh_tokens
+						SET revoked_at = ?
+						WHERE account_id = ? AND revoked_at IS NULL`,
+			now, account.ID).Error
+	})
+
+	if err != nil {
+		logger.Error("passwordResetRepository", "Failed to reset password", map[string]string{
+			"account_id": account.ID.String(),
+			"error":      err.Error(),
+		})
+		return err
+	}
+
+	return nil
+}
@@ -13,7 +13,7 @@ import (
 type ProfileRepository interface {
 	CreateProfile(inputModel *model.Profile) (*model.Profile, error)
 	GetProfileById(inputModel *model.Profile) (*model.Profile, error)
-	GetProfiles() ([]*model.Profile, error)
+	GetProfilesByAccountId(inputModel *model.Profile) ([]*model.Profile, error)
 	UpdateProfile(inputModel *model.Profile) (*model.Profile, error)
 	DeleteProfile(inputModel *model.Profile) (*model.Profile, error)
 	CheckCreatedProfileByAccountId(inputModel *model.Profile) (*model.Profile, error)
@@ -33,11 +33,11 @@ func (r *profileRepository) CreateProfile(inputModel *model.Profile) (*model.Pro
 		"account_id": inputModel.AccountId.String(),
 	})
 
-	sqlScript := `INSERT INTO hydroponic_system.profiles (account_id, name, address, created_at) 
-				  VALUES (?, ?, ?, ?) 
-				  RETURNING id, account_id, name, address;`
+	sqlScript := `INSERT INTO hydroponic_system.profiles (account_id, organization_id, name, address, created_at) 
+				  VALUES (?, ?, ?, ?, ?) 
+				  RETURNING id, account_id, organization_id, name, address;`
 
-	res := r.db.Raw(sqlScript, inputModel.AccountId, inputModel.Name, inputModel.Address, time.Now()).Scan(&inputModel)
+	res := r.db.Raw(sqlScript, inputModel.AccountId, inputModel.OrganizationId, inputModel.Name, inputModel.Address, time.Now()).Scan(&inputModel)
 
 	if res.Error != nil {
 		logger.Error("profileRepository", "Failed to create profile", map[string]string{
@@ -52,16 +52,18 @@ func (r *profileRepository) CreateProfile(inputModel *model.Profile) (*model.Pro
 	return inputModel, nil
 }
 
-func (r *profileRepository) GetProfiles() ([]*model.Profile, error) {
-	logger.Info("profileRepository", "Fetching all profiles", nil)
+func (r *profileRepository) GetProfilesByAccountId(inputModel *model.Profile) ([]*model.Profile, error) {
+	logger.Info("profileRepository", "Fetching profiles of account", map[string]string{
+		"account_id": inputModel.AccountId.String(),
+	})
 
 	var profiles []*model.Profile
 
-	sqlScript := `SELECT id, account_id, name, address 
+	sqlScript := `SELECT id, account_id, organization_id, name, address 
 				  FROM hydroponic_system.profiles 
-				  WHERE deleted_at IS NULL`
+				  WHERE account_id = ? AND organization_id = ? AND deleted_at IS NULL`
 
-	res := r.db.Raw(sqlScript).Scan(&profiles)
+	res := r.db.Raw(sqlScript, inputModel.AccountId, inputModel.OrganizationId).Scan(&profiles)
 
 	if res.Error != nil {
 		logger.Error("profileRepository", "Failed to fetch profiles", map[string]string{
@@ -81,11 +83,11 @@ func (r *profileRepository) CheckCreatedProfileByAccountId(inputModel *model.Pro
 		"account_id": inputModel.AccountId.String(),
 	})
 
-	sqlScript := `SELECT id, account_id, name, address 
+	sqlScript := `SELECT id, account_id, organization_id, name, address 
 				  FROM hydroponic_system.profiles 
-				  WHERE account_id = ? AND deleted_at IS NULL`
+				  WHERE account_id = ? AND organization_id = ? AND deleted_at IS NULL`
 
-	res := r.db.Raw(sqlScript, inputModel.AccountId).Scan(&inputModel)
+	res := r.db.Raw(sqlScript, inputModel.AccountId, inputModel.OrganizationId).Scan(&inputModel)
 
 	if res.Error != nil {
 		logger.Error("profileRepository", "Error checking profile by account ID", map[string]string{
@@ -111,11 +113,11 @@ func (r *profileRepository) GetProfileById(inputModel *model.Profile) (*model.Pr
 		"profile_id": inputModel.ID.String(),
 	})
 
-	sqlScript := `SELECT id, account_id, name, address 
+	sqlScript := `SELECT id, account_id, organization_id, name, address 
 				  FROM hydroponic_system.profiles 
-				  WHERE id = ? AND deleted_at IS NULL`
+				  WHERE id = ? AND account_id = ? AND organization_id = ? AND deleted_at IS NULL`
 
-	res := r.db.Raw(sqlScript, inputModel.ID).Scan(&inputModel)
+	res := r.db.Raw(sqlScript, inputModel.ID, inputModel.AccountId, inputModel.OrganizationId).Scan(&inputModel)
 
 	if res.Error != nil {
 		logger.Error("profileRepository", "Error fetching profile by ID", map[string]string{
@@ -143,10 +145,10 @@ func (r *profileRepository) UpdateProfile(inputModel *model.Profile) (*model.Pro
 
 	sqlScript := `UPDATE hydroponic_system.profiles 
 				  SET updated_at = ?, name = ?, address = ?  
-				  WHERE id = ? AND deleted_at IS NULL
-				  RETURNING id, account_id, name, address`
+				  WHERE id = ? AND account_id = ? AND organization_id = ? AND deleted_at IS NULL
+				  RETURNING id, account_id, organization_id, name, address`
 
-	res := r.db.Raw(sqlScript, time.Now(), inputModel.Name, inputModel.Address, inputModel.ID).Scan(&inputModel)
+	res := r.db.Raw(sqlScript, time.Now(), inputModel.Name, inputModel.Address, inputModel.ID, inputModel.AccountId, inputModel.OrganizationId).Scan(&inputModel)
 
 	if res.Error != nil {
 		logger.Error("profileRepository", "Failed to update profile", map[string]string{
@@ -175,10 +177,10 @@ func (r *profileRepository) DeleteProfile(inputModel *model.Profile) (*model.Pro
 
 	sqlScript := `UPDATE hydroponic_system.profiles 
 				  SET deleted_at = ? 
-				  WHERE id = ? AND deleted_at IS NULL
-				  RETURNING id, account_id, name, address`
+				  WHERE id = ? AND account_id = ? AND organization_id = ? AND deleted_at IS NULL
+				  RETURNING id, account_id, organization_id, name, address`
 
-	res := r.db.Raw(sqlScript, time.Now(), inputModel.ID).Scan(&inputModel)
+	res := r.db.Raw(sqlScript, time.Now(), inputModel.ID, inputModel.AccountId, inputModel.OrganizationId).Scan(&inputModel)
 
 	if res.Error != nil {
 		logger.Error("profileRepository", "Failed to delete profile", map[string]string{
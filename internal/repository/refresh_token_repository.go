@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"strconv"
+	"time"
+
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+	"gorm.io/gorm"
+)
+
+type RefreshTokenRepository interface {
+	CreateRefreshToken(inputModel *model.RefreshToken) (*model.RefreshToken, error)
+	GetRefreshTokenById(inputModel *model.RefreshToken) (*model.RefreshToken, error)
+	RotateRefreshToken(oldToken *model.RefreshToken, newToken *model.RefreshToken) (bool, error)
+	RevokeRefreshTokenFamily(inputModel *model.RefreshToken) error
+}
+
+type refreshTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) RefreshTokenRepository {
+	return &refreshTokenRepository{
+		db: db,
+	}
+}
+
+func (r *refreshTokenRepository) CreateRefreshToken(inputModel *model.RefreshToken) (*model.RefreshToken, error) {
+	logger.Info("refreshTokenRepository", "Creating refresh token", map[string]string{
+		"account_id": inputModel.AccountId.String(),
+		"family_id":  inputModel.FamilyId.String(),
+	})
+
+	res := createRefreshToken(r.db, inputModel)
+	if res.Error != nil {
+		logger.Error("refreshTokenRepository", "Failed to create refresh token", map[string]string{
+			"error": res.Error.Error(),
+		})
+		return nil, res.Error
+	}
+
+	logger.Info("refreshTokenRepository", "Refresh token created successfully", map[string]string{
+		"id": inputModel.ID.String(),
+	})
+	return inputModel, nil
+}
+
+func (r *refreshTokenRepository) GetRefreshTokenById(inputModel *model.RefreshToken) (*model.RefreshToken, error) {
+	logger.Info("refreshTokenRepository", "Fetching refresh token by ID", map[string]string{
+		"id": inputModel.ID.String(),
+	})
+
+	sqlScript := `SELECT id, account_id, family_id, token_hash, replaced_by, expires_at, revoked_at, created_at
+				  FROM hydroponic_system.refresh_tokens
+				  WHERE id = ?`
+
+	res := r.db.Raw(sqlScript, inputModel.ID).Scan(inputModel)
+
+	if res.Error != nil {
+		logger.Error("refreshTokenRepository", "Failed to fetch refresh token", map[string]string{
+			"id":    inputModel.ID.String(),
+			"error": res.Error.Error(),
+		})
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		logger.Warn("refreshTokenRepository", "Refresh token not found", map[string]string{
+			"id": inputModel.ID.String(),
+		})
+		return nil, errs.InvalidToken
+	}
+
+	return inputModel, nil
+}
+
+// RotateRefreshToken marks oldToken as replaced by newToken and stores newToken
+// in a single transaction. It reports false when oldToken had already been
+// rotated or revoked, which callers must treat as token reuse.
+func (r *refreshTokenRepository) RotateRefreshToken(oldToken *model.RefreshToken, newToken *model.RefreshToken) (bool, error) {
+	logger.Info("refreshTokenRepository", "Rotating refresh token", map[string]string{
+		"old_id":    oldToken.ID.String(),
+		"new_id":    newToken.ID.String(),
+		"family_id": oldToken.FamilyId.String(),
+	})
+
+	rotated := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		sqlScript := `UPDATE hydroponic_system.refresh_tokens
+					  SET revoked_at = ?, replaced_by = ?
+					  WHERE id = ? AND revoked_at IS NULL
+					  RETURNING id`
+
+		var revokedId *string
+		res := tx.Raw(sqlScript, time.Now(), newToken.ID, oldToken.ID).Scan(&revokedId)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return nil
+		}
+
+		res = createRefreshToken(tx, newToken)
+		if res.Error != nil {
+			return res.Error
+		}
+
+		rotated = true
+		return nil
+	})
+
+	if err != nil {
+		logger.Error("refreshTokenRepository", "Failed to rotate refresh token", map[string]string{
+			"old_id": oldToken.ID.String(),
+			"error":  err.Error(),
+		})
+		return false, err
+	}
+
+	return rotated, nil
+}
+
+func (r *refreshTokenRepository) RevokeRefreshTokenFamily(inputModel *model.RefreshToken) error {
+	logger.Warn("refreshTokenRepository", "Revoking refresh token family", map[string]string{
+		"family_id": inputModel.FamilyId.String(),
+	})
+
+	sqlScript := `UPDATE hydroponic_system.refresh_tokens
+				  SET revoked_at = ?
+				  WHERE family_id = ? AND revoked_at IS NULL`
+
+	res := r.db.Exec(sqlScript, time.Now(), inputModel.FamilyId)
+
+	if res.Error != nil {
+		logger.Error("refreshTokenRepository", "Failed to revoke refresh token family", map[string]string{
+			"family_id": inputModel.FamilyId.String(),
+			"error":     res.Error.Error(),
+		})
+		return res.Error
+	}
+
+	logger.Info("refreshTokenRepository", "Refresh token family revoked", map[string]string{
+		"family_id": inputModel.FamilyId.String(),
+		"count":     strconv.FormatInt(res.RowsAffected, 10),
+	})
+	return nil
+}
+
+func createRefreshToken(db *gorm.DB, inputModel *model.RefreshToken) *gorm.DB {
+	sqlScript := `INSERT INTO hydroponic_system.refresh_tokens (id, account_id, family_id, token_hash, expires_at, created_at)
+				  VALUES (?, ?, ?, ?, ?, ?)
+				  RETURNING id, account_id, family_id, token_hash, expires_at, created_at;`
+
+	return db.Raw(sqlScript,
+		inputModel.ID,
+		inputModel.AccountId,
+		inputModel.FamilyId,
+		inputModel.TokenHash,
+		inputModel.ExpiresAt,
+		time.Now()).Scan(inputModel)
+}
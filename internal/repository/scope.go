@@ -0,0 +1,22 @@
+package repository
+
+import "github.com/google/uuid"
+
+// Scope limits a query to what an account may see inside its organization.
+// Every tenant owned row is matched on the organization id as well, so a
+// membership can never reach into another tenant.
+type Scope struct {
+	AccountID      uuid.UUID
+	OrganizationID uuid.UUID
+}
+
+// accessibleFarmIdsSQL selects the ids of the live farms of the organization
+// that the account is a member of, whatever its role there. It takes the
+// account id and the organization id of a Scope as parameters and is meant
+// to be used as "farm_id IN (...)". Role checks are done by the services.
+const accessibleFarmIdsSQL = `SELECT scoped_m.farm_id
+	FROM hydroponic_system.farm_members scoped_m
+	JOIN hydroponic_system.farms scoped_f ON scoped_f.id = scoped_m.farm_id
+	WHERE scoped_m.account_id = ?
+	AND scoped_f.organization_id = ?
+	AND scoped_f.deleted_at IS NULL`
@@ -0,0 +1,226 @@
+package repository
+
+import (
+	"strconv"
+	"time"
+
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+	"gorm.io/gorm"
+)
+
+type SessionRepository interface {
+	CreateSession(inputModel *model.Session) (*model.Session, error)
+	GetActiveSessionById(inputModel *model.Session) (*model.Session, error)
+	GetActiveSessionsByAccountId(inputModel *model.Session) ([]*model.Session, error)
+	TouchSession(inputModel *model.Session) error
+	ExtendSession(inputModel *model.Session) error
+	RevokeSession(inputModel *model.Session) (int64, error)
+	RevokeSessionsByAccountId(inputModel *model.Session) (int64, error)
+}
+
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &sessionRepository{
+		db: db,
+	}
+}
+
+func (r *sessionRepository) CreateSession(inputModel *model.Session) (*model.Session, error) {
+	logger.Info("sessionRepository", "Creating session", map[string]string{
+		"account_id": inputModel.AccountId.String(),
+	})
+
+	sqlScript := `INSERT INTO hydroponic_system.sessions (account_id, user_agent, ip_address, last_seen_at, expires_at, created_at)
+				  VALUES (?, ?, ?, ?, ?, ?)
+				  RETURNING id, account_id, user_agent, ip_address, last_seen_at, expires_at, created_at;`
+
+	now := time.Now()
+	res := r.db.Raw(sqlScript,
+		inputModel.AccountId,
+		inputModel.UserAgent,
+		inputModel.IpAddress,
+		now,
+		inputModel.ExpiresAt,
+		now).Scan(inputModel)
+
+	if res.Error != nil {
+		logger.Error("sessionRepository", "Failed to create session", map[string]string{
+			"error": res.Error.Error(),
+		})
+		return nil, res.Error
+	}
+
+	logger.Info("sessionRepository", "Session created successfully", map[string]string{
+		"id": inputModel.ID.String(),
+	})
+	return inputModel, nil
+}
+
+func (r *sessionRepository) GetActiveSessionById(inputModel *model.Session) (*model.Session, error) {
+	sqlScript := `SELECT id, account_id, user_agent, ip_address, last_seen_at, expires_at, revoked_at, created_at
+				  FROM hydroponic_system.sessions
+				  WHERE id = ? AND account_id = ? AND revoked_at IS NULL AND expires_at > ?`
+
+	res := r.db.Raw(sqlScript, inputModel.ID, inputModel.AccountId, time.Now()).Scan(inputModel)
+
+	if res.Error != nil {
+		logger.Error("sessionRepository", "Failed to fetch session", map[string]string{
+			"id":    inputModel.ID.String(),
+			"error": res.Error.Error(),
+		})
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		logger.Warn("sessionRepository", "Active session not found", map[string]string{
+			"id": inputModel.ID.String(),
+		})
+		return nil, errs.InvalidSession
+	}
+
+	return inputModel, nil
+}
+
+func (r *sessionRepository) GetActiveSessionsByAccountId(inputModel *model.Session) ([]*model.Session, error) {
+	logger.Info("sessionRepository", "Fetching active sessions", map[string]string{
+		"account_id": inputModel.AccountId.String(),
+	})
+
+	var sessions []*model.Session
+
+	sqlScript := `SELECT id, account_id, user_agent, ip_address, last_seen_at, expires_at, created_at
+				  FROM hydroponic_system.sessions
+				  WHERE account_id = ? AND revoked_at IS NULL AND expires_at > ?
+				  ORDER BY last_seen_at DESC`
+
+	res := r.db.Raw(sqlScript, inputModel.AccountId, time.Now()).Scan(&sessions)
+
+	if res.Error != nil {
+		logger.Error("sessionRepository", "Failed to fetch active sessions", map[string]string{
+			"account_id": inputModel.AccountId.String(),
+			"error":      res.Error.Error(),
+		})
+		return nil, res.Error
+	}
+
+	logger.Info("sessionRepository", "Active sessions fetched successfully", map[string]string{
+		"count": strconv.Itoa(len(sessions)),
+	})
+	return sessions, nil
+}
+
+func (r *sessionRepository) TouchSession(inputModel *model.Session) error {
+	sqlScript := `UPDATE hydroponic_system.sessions
+				  SET last_seen_at = ?, ip_address = COALESCE(NULLIF(?, ''), ip_address)
+				  WHERE id = ?`
+
+	res := r.db.Exec(sqlScript, time.Now(), inputModel.IpAddress, inputModel.ID)
+
+	if res.Error != nil {
+		logger.Error("sessionRepository", "Failed to update session last seen", map[string]string{
+			"id":    inputModel.ID.String(),
+			"error": res.Error.Error(),
+		})
+		return res.Error
+	}
+	return nil
+}
+
+func (r *sessionRepository) ExtendSession(inputModel *model.Session) error {
+	sqlScript := `UPDATE hydroponic_system.sessions
+				  SET last_seen_at = ?, expires_at = ?
+				  WHERE id = ? AND revoked_at IS NULL`
+
+	res := r.db.Exec(sqlScript, time.Now(), inputModel.ExpiresAt, inputModel.ID)
+
+	if res.Error != nil {
+		logger.Error("sessionRepository", "Failed to extend session", map[string]string{
+			"id":    inputModel.ID.String(),
+			"error": res.Error.Error(),
+		})
+		return res.Error
+	}
+	return nil
+}
+
+// RevokeSession revokes a single session of the account together with the
+// refresh token family that belongs to it.
+func (r *sessionRepository) RevokeSession(inputModel *model.Session) (int64, error) {
+	logger.Info("sessionRepository", "Revoking session", map[string]string{
+		"id":         inputModel.ID.String(),
+		"account_id": inputModel.AccountId.String(),
+	})
+
+	var revoked int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		res := tx.Exec(`UPDATE hydroponic_system.sessions
+						SET revoked_at = ?
+						WHERE id = ? AND account_id = ? AND revoked_at IS NULL`,
+			now, inputModel.ID, inputModel.AccountId)
+		if res.Error != nil {
+			return res.Error
+		}
+		revoked = res.RowsAffected
+
+		return tx.Exec(`UPDATE hydroponic_system.refresh_tokens
+						SET revoked_at = ?
+						WHERE family_id = ? AND account_id = ? AND revoked_at IS NULL`,
+			now, inputModel.ID, inputModel.AccountId).Error
+	})
+
+	if err != nil {
+		logger.Error("sessionRepository", "Failed to revoke session", map[string]string{
+			"id":    inputModel.ID.String(),
+			"error": err.Error(),
+		})
+		return 0, err
+	}
+
+	return revoked, nil
+}
+
+// RevokeSessionsByAccountId revokes every session and refresh token of the account.
+func (r *sessionRepository) RevokeSessionsByAccountId(inputModel *model.Session) (int64, error) {
+	logger.Info("sessionRepository", "Revoking all sessions", map[string]string{
+		"account_id": inputModel.AccountId.String(),
+	})
+
+	var revoked int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		res := tx.Exec(`UPDATE hydroponic_system.sessions
+						SET revoked_at = ?
+						WHERE account_id = ? AND revoked_at IS NULL`,
+			now, inputModel.AccountId)
+		if res.Error != nil {
+			return res.Error
+		}
+		revoked = res.RowsAffected
+
+		return tx.Exec(`UPDATE hydroponic_system.refresh_tokens
+						SET revoked_at = ?
+						WHERE account_id = ? AND revoked_at IS NULL`,
+			now, inputModel.AccountId).Error
+	})
+
+	if err != nil {
+		logger.Error("sessionRepository", "Failed to revoke all sessions", map[string]string{
+			"account_id": inputModel.AccountId.String(),
+			"error":      err.Error(),
+		})
+		return 0, err
+	}
+
+	logger.Info("sessionRepository", "All sessions revoked", map[string]string{
+		"account_id": inputModel.AccountId.String(),
+		"count":      strconv.FormatInt(revoked, 10),
+	})
+	return revoked, nil
+}
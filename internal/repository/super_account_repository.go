@@ -5,11 +5,15 @@ import (
 
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 type SuperAccountRepository interface {
 	CreateSuperUser(input *model.SuperUser) (*model.SuperUser, error)
+	GetSuperUserByName(username *string) (*model.SuperUser, error)
+	GetSuperUserById(id uuid.UUID) (*model.SuperUser, error)
+	UpdateSuperUserPasswordHash(id uuid.UUID, previousHash string, newHash string) error
 }
 
 type superAccountRepository struct {
@@ -47,3 +51,66 @@ func (r *superAccountRepository) CreateSuperUser(input *model.SuperUser) (*model
 	})
 	return input, nil
 }
+
+func (r *superAccountRepository) GetSuperUserByName(username *string) (*model.SuperUser, error) {
+	logger.Info("superAccountRepository", "Fetching super user by username", map[string]string{
+		"username": *username,
+	})
+
+	var superUser *model.SuperUser
+
+	sqlScript := `SELECT id, username, password
+				  FROM super_admin.accounts
+				  WHERE username = ? AND deleted_at IS NULL`
+
+	res := r.db.Raw(sqlScript, *username).Scan(&superUser)
+
+	if res.Error != nil {
+		logger.Error("superAccountRepository", "Failed to fetch super user", map[string]string{
+			"username": *username,
+			"error":    res.Error.Error(),
+		})
+		return nil, res.Error
+	}
+
+	return superUser, nil
+}
+
+func (r *superAccountRepository) GetSuperUserById(id uuid.UUID) (*model.SuperUser, error) {
+	var superUser *model.SuperUser
+
+	sqlScript := `SELECT id, username, password
+				  FROM super_admin.accounts
+				  WHERE id = ? AND deleted_at IS NULL`
+
+	res := r.db.Raw(sqlScript, id).Scan(&superUser)
+
+	if res.Error != nil {
+		logger.Error("superAccountRepository", "Failed to fetch super user", map[string]string{
+			"id":    id.String(),
+			"error": res.Error.Error(),
+		})
+		return nil, res.Error
+	}
+
+	return superUser, nil
+}
+
+// UpdateSuperUserPasswordHash replaces the hash of an unchanged password, for
+// example after a hasher upgrade.
+func (r *superAccountRepository) UpdateSuperUserPasswordHash(id uuid.UUID, previousHash string, newHash string) error {
+	res := r.db.Exec(`UPDATE super_admin.accounts
+					  SET password = ?
+					  WHERE id = ? AND password = ?`,
+		newHash, id, previousHash)
+
+	if res.Error != nil {
+		logger.Error("superAccountRepository", "Failed to update password hash", map[string]string{
+			"id":    id.String(),
+			"error": res.Error.Error(),
+		})
+		return res.Error
+	}
+
+	return nil
+}
@@ -7,15 +7,19 @@ import (
 	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 type SystemUnitRepository interface {
 	CreateSystemUnit(inputModel *model.SystemUnit) (*model.SystemUnit, error)
-	UpdateSystemUnit(inputModel *model.SystemUnit) (*model.SystemUnit, error)
-	GetSystemUnits(farmId *string) ([]*model.SystemUnitJoined, error)
+	UpdateSystemUnit(inputModel *model.SystemUnit, scope *Scope) (*model.SystemUnit, error)
+	GetSystemUnitsByScope(scope *Scope, farmIds []uuid.UUID) ([]*model.SystemUnitJoined, error)
+	GetSystemUnitsByOrganizationId(organizationId *uuid.UUID, farmIds []uuid.UUID) ([]*model.SystemUnitJoined, error)
 	GetSystemUnitById(inputModel *model.SystemUnit) (*model.SystemUnit, error)
-	DeleteSystemUnitById(inputModel *model.SystemUnit) (*model.SystemUnit, error)
+	GetSystemUnitByIdAndScope(inputModel *model.SystemUnit, scope *Scope) (*model.SystemUnit, error)
+	GetSystemUnitsByUnitKeyAndScope(unitKey uuid.UUID, scope *Scope) ([]*model.SystemUnit, error)
+	DeleteSystemUnitById(inputModel *model.SystemUnit, scope *Scope) (*model.SystemUnit, error)
 }
 
 type systemUnitRepository struct {
@@ -58,23 +62,31 @@ func (r *systemUnitRepository) CreateSystemUnit(inputModel *model.SystemUnit) (*
 	return inputModel, nil
 }
 
-func (r *systemUnitRepository) GetSystemUnits(farmId *string) ([]*model.SystemUnitJoined, error) {
+func (r *systemUnitRepository) GetSystemUnitsByScope(scope *Scope, farmIds []uuid.UUID) ([]*model.SystemUnitJoined, error) {
 	logger.Info("systemUnitRepository", "Fetching system units", map[string]string{
-		"farmId": *farmId,
+		"accountId": scope.AccountID.String(),
+		"farmIds":   strconv.Itoa(len(farmIds)),
 	})
 
 	var units []*model.SystemUnitJoined
 	sqlScript := `SELECT su.id, su.unit_key, su.farm_id, f.name as farm_name, su.tank_volume, su.tank_a_volume, su.tank_b_volume
 				  FROM hydroponic_system.system_units su
 				  LEFT JOIN hydroponic_system.farms f ON f.id = su.farm_id
-				  WHERE su.deleted_at IS NULL AND su.farm_id = ?`
+				  WHERE su.deleted_at IS NULL 
+				  AND su.farm_id IN (` + accessibleFarmIdsSQL + `)`
+	params := []interface{}{scope.AccountID, scope.OrganizationID}
 
-	res := r.db.Raw(sqlScript, *farmId).Scan(&units)
+	if len(farmIds) > 0 {
+		sqlScript += ` AND su.farm_id IN ?`
+		params = append(params, farmIds)
+	}
+
+	res := r.db.Raw(sqlScript, params...).Scan(&units)
 
 	if res.Error != nil {
 		logger.Error("systemUnitRepository", "Failed to fetch system units", map[string]string{
-			"farmId": *farmId,
-			"error":  res.Error.Error(),
+			"accountId": scope.AccountID.String(),
+			"error":     res.Error.Error(),
 		})
 		return nil, res.Error
 	}
@@ -85,6 +97,42 @@ func (r *systemUnitRepository) GetSystemUnits(farmId *string) ([]*model.SystemUn
 	return units, nil
 }
 
+func (r *systemUnitRepository) GetSystemUnitsByOrganizationId(organizationId *uuid.UUID, farmIds []uuid.UUID) ([]*model.SystemUnitJoined, error) {
+	logger.Info("systemUnitRepository", "Fetching system units of organization", map[string]string{
+		"organizationId": organizationId.String(),
+		"farmIds":        strconv.Itoa(len(farmIds)),
+	})
+
+	var units []*model.SystemUnitJoined
+	sqlScript := `SELECT su.id, su.unit_key, su.farm_id, f.name as farm_name, su.tank_volume, su.tank_a_volume, su.tank_b_volume
+				  FROM hydroponic_system.system_units su
+				  JOIN hydroponic_system.farms f ON f.id = su.farm_id
+				  WHERE su.deleted_at IS NULL 
+				  AND f.deleted_at IS NULL 
+				  AND f.organization_id = ?`
+	params := []interface{}{*organizationId}
+
+	if len(farmIds) > 0 {
+		sqlScript += ` AND su.farm_id IN ?`
+		params = append(params, farmIds)
+	}
+
+	res := r.db.Raw(sqlScript, params...).Scan(&units)
+
+	if res.Error != nil {
+		logger.Error("systemUnitRepository", "Failed to fetch system units of organization", map[string]string{
+			"organizationId": organizationId.String(),
+			"error":          res.Error.Error(),
+		})
+		return nil, res.Error
+	}
+
+	logger.Info("systemUnitRepository", "Successfully fetched system units of organization", map[string]string{
+		"count": strconv.Itoa(len(units)),
+	})
+	return units, nil
+}
+
 func (r *systemUnitRepository) GetSystemUnitById(inputModel *model.SystemUnit) (*model.SystemUnit, error) {
 	logger.Info("systemUnitRepository", "Fetching system unit by ID", map[string]string{
 		"id": inputModel.ID.String(),
@@ -116,7 +164,63 @@ func (r *systemUnitRepository) GetSystemUnitById(inputModel *model.SystemUnit) (
 	return inputModel, nil
 }
 
-func (r *systemUnitRepository) UpdateSystemUnit(inputModel *model.SystemUnit) (*model.SystemUnit, error) {
+func (r *systemUnitRepository) GetSystemUnitByIdAndScope(inputModel *model.SystemUnit, scope *Scope) (*model.SystemUnit, error) {
+	logger.Info("systemUnitRepository", "Fetching system unit by ID for account", map[string]string{
+		"id":        inputModel.ID.String(),
+		"accountId": scope.AccountID.String(),
+	})
+
+	sqlScript := `SELECT id, farm_id, unit_key, tank_volume, tank_a_volume, tank_b_volume
+				  FROM hydroponic_system.system_units
+				  WHERE id = ? 
+				  AND deleted_at IS NULL
+				  AND farm_id IN (` + accessibleFarmIdsSQL + `)`
+
+	res := r.db.Raw(sqlScript, inputModel.ID, scope.AccountID, scope.OrganizationID).Scan(inputModel)
+
+	if res.Error != nil {
+		logger.Error("systemUnitRepository", "Failed to fetch system unit", map[string]string{
+			"id":    inputModel.ID.String(),
+			"error": res.Error.Error(),
+		})
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		logger.Warn("systemUnitRepository", "No system unit found for account", map[string]string{
+			"id":        inputModel.ID.String(),
+			"accountId": scope.AccountID.String(),
+		})
+		return nil, errs.InvalidSystemUnitID
+	}
+
+	return inputModel, nil
+}
+
+// GetSystemUnitsByUnitKeyAndScope returns the system units built on a unit
+// key that the account can see. A key may be used by units of several farms.
+func (r *systemUnitRepository) GetSystemUnitsByUnitKeyAndScope(unitKey uuid.UUID, scope *Scope) ([]*model.SystemUnit, error) {
+	var systemUnits []*model.SystemUnit
+
+	sqlScript := `SELECT id, farm_id, unit_key, tank_volume, tank_a_volume, tank_b_volume
+				  FROM hydroponic_system.system_units
+				  WHERE unit_key = ?
+				  AND deleted_at IS NULL
+				  AND farm_id IN (` + accessibleFarmIdsSQL + `)`
+
+	res := r.db.Raw(sqlScript, unitKey, scope.AccountID, scope.OrganizationID).Scan(&systemUnits)
+
+	if res.Error != nil {
+		logger.Error("systemUnitRepository", "Failed to fetch system units by unit key", map[string]string{
+			"unitKey": unitKey.String(),
+			"error":   res.Error.Error(),
+		})
+		return nil, res.Error
+	}
+
+	return systemUnits, nil
+}
+
+func (r *systemUnitRepository) UpdateSystemUnit(inputModel *model.SystemUnit, scope *Scope) (*model.SystemUnit, error) {
 	logger.Info("systemUnitRepository", "Updating system unit", map[string]string{
 		"id": inputModel.ID.String(),
 	})
@@ -124,6 +228,8 @@ func (r *systemUnitRepository) UpdateSystemUnit(inputModel *model.SystemUnit) (*
 	sqlScript := `UPDATE hydroponic_system.system_units 
 				  SET updated_at = ?, unit_key = ?, farm_id = ?, tank_volume = ?, tank_a_volume = ?, tank_b_volume = ? 
 				  WHERE id = ? 
+				  AND deleted_at IS NULL
+				  AND farm_id IN (` + accessibleFarmIdsSQL + `)
 				  RETURNING id, farm_id, unit_key, tank_volume, tank_a_volume, tank_b_volume`
 
 	res := r.db.Raw(sqlScript,
@@ -133,7 +239,9 @@ func (r *systemUnitRepository) UpdateSystemUnit(inputModel *model.SystemUnit) (*
 		inputModel.TankVolume,
 		inputModel.TankAVolume,
 		inputModel.TankBVolume,
-		inputModel.ID).Scan(inputModel)
+		inputModel.ID,
+		scope.AccountID,
+		scope.OrganizationID).Scan(inputModel)
 
 	if res.Error != nil {
 		logger.Error("systemUnitRepository", "Failed to update system unit", map[string]string{
@@ -155,7 +263,7 @@ func (r *systemUnitRepository) UpdateSystemUnit(inputModel *model.SystemUnit) (*
 	return inputModel, nil
 }
 
-func (r *systemUnitRepository) DeleteSystemUnitById(inputModel *model.SystemUnit) (*model.SystemUnit, error) {
+func (r *systemUnitRepository) DeleteSystemUnitById(inputModel *model.SystemUnit, scope *Scope) (*model.SystemUnit, error) {
 	logger.Info("systemUnitRepository", "Deleting system unit", map[string]string{
 		"id": inputModel.ID.String(),
 	})
@@ -163,9 +271,11 @@ func (r *systemUnitRepository) DeleteSystemUnitById(inputModel *model.SystemUnit
 	sqlScript := `UPDATE hydroponic_system.system_units 
 				  SET deleted_at = ? 
 				  WHERE id = ? 
+				  AND deleted_at IS NULL
+				  AND farm_id IN (` + accessibleFarmIdsSQL + `)
 				  RETURNING id`
 
-	res := r.db.Raw(sqlScript, time.Now(), inputModel.ID).Scan(inputModel)
+	res := r.db.Raw(sqlScript, time.Now(), inputModel.ID, scope.AccountID, scope.OrganizationID).Scan(inputModel)
 
 	if res.Error != nil {
 		logger.Error("systemUnitRepository", "Failed to delete system unit", map[string]string{
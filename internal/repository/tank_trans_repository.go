@@ -10,7 +10,7 @@ import (
 )
 
 type TankTransRepository interface {
-	CreateTankTransaction(inputModel *model.TankTran) (*model.TankTran, error)
+	CreateTankTransaction(inputModel *model.TankTran, receipt *model.IngestReceipt) (*model.TankTran, error)
 }
 
 type tankTransRepository struct {
@@ -21,7 +21,9 @@ func NewTankTransRepository(db *gorm.DB) TankTransRepository {
 	return &tankTransRepository{db: db}
 }
 
-func (r *tankTransRepository) CreateTankTransaction(inputModel *model.TankTran) (*model.TankTran, error) {
+// CreateTankTransaction stores a tank transaction under the id set by the
+// caller. A non nil receipt is stored in the same transaction.
+func (r *tankTransRepository) CreateTankTransaction(inputModel *model.TankTran, receipt *model.IngestReceipt) (*model.TankTran, error) {
 	logger.Info("tankTransRepository", "Creating a new tank transaction", map[string]string{
 		"farmId":      inputModel.FarmId.String(),
 		"systemId":    inputModel.SystemId.String(),
@@ -30,23 +32,32 @@ func (r *tankTransRepository) CreateTankTransaction(inputModel *model.TankTran)
 		"bVolume":     strconv.Itoa(inputModel.BVolume),
 	})
 
-	sqlScript := `INSERT INTO hydroponic_system.tank_trans(farm_id, system_id, water_volume, a_volume, b_volume, created_at) 
-				  VALUES (?, ?, ?, ?, ?, ?) 
+	sqlScript := `INSERT INTO hydroponic_system.tank_trans(id, farm_id, system_id, water_volume, a_volume, b_volume, created_at) 
+				  VALUES (?, ?, ?, ?, ?, ?, ?) 
 				  RETURNING id, farm_id, system_id, water_volume, a_volume, b_volume, created_at;`
 
-	res := r.db.Raw(sqlScript,
-		inputModel.FarmId,
-		inputModel.SystemId,
-		inputModel.WaterVolume,
-		inputModel.AVolume,
-		inputModel.BVolume,
-		time.Now()).Scan(inputModel)
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if receipt != nil {
+			err := insertIngestReceipt(tx, receipt)
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Raw(sqlScript,
+			inputModel.ID,
+			inputModel.FarmId,
+			inputModel.SystemId,
+			inputModel.WaterVolume,
+			inputModel.AVolume,
+			inputModel.BVolume,
+			time.Now()).Scan(inputModel).Error
+	})
 
-	if res.Error != nil {
+	if err != nil {
 		logger.Error("tankTransRepository", "Failed to create tank transaction", map[string]string{
-			"error": res.Error.Error(),
+			"error": err.Error(),
 		})
-		return nil, res.Error
+		return nil, err
 	}
 
 	logger.Info("tankTransRepository", "Successfully created tank transaction", map[string]string{
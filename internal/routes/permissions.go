@@ -0,0 +1,69 @@
+package routes
+
+import (
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/constant"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/middleware"
+)
+
+var (
+	allRoles   = constant.Roles
+	ownerRoles = []string{constant.RoleOwner}
+)
+
+// routePermissions checks the account role. Routes on a single farm are open
+// to every account role here because the services check the role the caller
+// has on that farm through its membership, and organization routes are
+// checked against the organization role by the service.
+var routePermissions = middleware.RoutePermissions{
+	"GET /organization/":                   allRoles,
+	"PUT /organization/":                   allRoles,
+	"GET /organization/members":            allRoles,
+	"PUT /organization/members/:accountId": allRoles,
+	"GET /organization/farms":              allRoles,
+	"GET /organization/system-units":       allRoles,
+
+	"POST /profile/create":       allRoles,
+	"GET /profile/:profileId":    allRoles,
+	"GET /profile/":              allRoles,
+	"PUT /profile/:profileId":    allRoles,
+	"DELETE /profile/:profileId": allRoles,
+
+	"POST /farm/create":    ownerRoles,
+	"GET /farm/":           allRoles,
+	"GET /farm/:farmId":    allRoles,
+	"PUT /farm/:farmId":    allRoles,
+	"DELETE /farm/:farmId": allRoles,
+
+	"GET /farm/:farmId/members":                      allRoles,
+	"PUT /farm/:farmId/members/:accountId":           allRoles,
+	"DELETE /farm/:farmId/members/:accountId":        allRoles,
+	"POST /farm/:farmId/invitations":                 allRoles,
+	"GET /farm/:farmId/invitations":                  allRoles,
+	"DELETE /farm/:farmId/invitations/:invitationId": allRoles,
+
+	"POST /system/create":      allRoles,
+	"GET /system/":             allRoles,
+	"PUT /system/:systemId":    allRoles,
+	"DELETE /system/:systemId": allRoles,
+
+	"POST /system/:systemId/credential":   allRoles,
+	"GET /system/:systemId/sequence-gaps": allRoles,
+
+	"POST /growth-hist/create":            allRoles,
+	"POST /growth-hist/random-data":       allRoles,
+	"GET /growth-hist/aggregation/filter": allRoles,
+	"GET /growth-hist/filter":             allRoles,
+
+	"GET /readings/metrics":            allRoles,
+	"POST /readings/create":            allRoles,
+	"POST /readings/bulk":              allRoles,
+	"GET /readings/aggregation/filter": allRoles,
+	"GET /readings/filter":             allRoles,
+
+	"POST /write": allRoles,
+
+	"POST /tank-trans/create": allRoles,
+
+	"GET /aggregation/growth-hist":         ownerRoles,
+	"GET /aggregation/growth-hist/monthly": ownerRoles,
+}
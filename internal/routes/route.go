@@ -2,70 +2,146 @@ package routes
 
 import (
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/handler"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/middleware"
 	"github.com/gin-gonic/gin"
 )
 
 type Handlers struct {
-	Account      *handler.AccountHandler
-	Profile      *handler.ProfileHandler
-	Farm         *handler.FarmHandler
-	SystemUnit   *handler.SystemUnitHandler
-	GrowthHist   *handler.GrowthHistHandler
-	SuperAccount *handler.SuperAccountHandler
-	UnitId       *handler.UnitIdHandler
-	TankTrans    *handler.TankTransHandler
-	Aggregation  *handler.AggregationHandler
+	Account       *handler.AccountHandler
+	Profile       *handler.ProfileHandler
+	Farm          *handler.FarmHandler
+	SystemUnit    *handler.SystemUnitHandler
+	GrowthHist    *handler.GrowthHistHandler
+	SuperAccount  *handler.SuperAccountHandler
+	UnitId        *handler.UnitIdHandler
+	TankTrans     *handler.TankTransHandler
+	Aggregation   *handler.AggregationHandler
+	Key           *handler.KeyHandler
+	ApiToken      *handler.ApiTokenHandler
+	FarmMember    *handler.FarmMemberHandler
+	Organization  *handler.OrganizationHandler
+	AccountData   *handler.AccountDataHandler
+	ReadingIngest *handler.ReadingIngestHandler
 }
 
 type Middlewares struct {
-	Auth gin.HandlerFunc
+	// Auth accepts session JWTs and API tokens, SessionAuth only JWTs.
+	Auth        gin.HandlerFunc
+	SessionAuth gin.HandlerFunc
+	SuperAuth   gin.HandlerFunc
+	Stepup      gin.HandlerFunc
+	SuperStepup gin.HandlerFunc
+	DeviceAuth  gin.HandlerFunc
 }
 
 func Build(srv *gin.Engine, h Handlers, middlewares Middlewares) {
+	authorize := middleware.CreateAuthorization(routePermissions)
+
+	srv.GET("/.well-known/jwks.json", h.Key.GetJWKS)
+
 	auth := srv.Group("/auth")
 	auth.POST("/register", h.Account.CreateUser)
 	auth.POST("/login", h.Account.Login)
-	// auth.GET("/refresh", middlewares.Auth, h.Account.Refresh)
+	auth.POST("/login/mfa", h.Account.LoginMfa)
+	auth.POST("/refresh", h.Account.Refresh)
+	auth.POST("/logout", middlewares.SessionAuth, h.Account.Logout)
+	auth.POST("/logout-all", middlewares.SessionAuth, h.Account.LogoutAll)
+	auth.GET("/sessions", middlewares.SessionAuth, h.Account.GetSessions)
+	auth.POST("/change-password", middlewares.SessionAuth, h.Account.ChangePassword)
+	auth.POST("/forgot-password", h.Account.ForgotPassword)
+	auth.POST("/reset-password", h.Account.ResetPassword)
+	auth.POST("/verify-email", h.Account.VerifyEmail)
+	auth.POST("/verify-email/resend", middlewares.SessionAuth, h.Account.ResendVerification)
+	auth.POST("/mfa/enroll", middlewares.SessionAuth, h.Account.EnrollMfa)
+	auth.POST("/mfa/activate", middlewares.SessionAuth, h.Account.ActivateMfa)
+	auth.POST("/mfa/disable", middlewares.SessionAuth, h.Account.DisableMfa)
+	auth.POST("/step-up", middlewares.SessionAuth, h.Account.StepUp)
+	auth.POST("/api-tokens", middlewares.SessionAuth, h.ApiToken.CreateApiToken)
+	auth.GET("/api-tokens", middlewares.SessionAuth, h.ApiToken.GetApiTokens)
+	auth.DELETE("/api-tokens/:tokenId", middlewares.SessionAuth, h.ApiToken.RevokeApiToken)
+	auth.POST("/invitations/accept", middlewares.SessionAuth, h.FarmMember.AcceptInvitation)
+	auth.GET("/account/export", middlewares.SessionAuth, h.AccountData.ExportAccount)
+	auth.POST("/account/deletion", middlewares.SessionAuth, middlewares.Stepup, h.AccountData.RequestDeletion)
+	auth.DELETE("/account/deletion", middlewares.SessionAuth, h.AccountData.CancelDeletion)
 
-	profile := srv.Group("/profile")
+	organization := srv.Group("/organization", middlewares.Auth, authorize)
+	organization.GET("/", h.Organization.GetOrganization)
+	organization.PUT("/", h.Organization.UpdateOrganization)
+	organization.GET("/members", h.Organization.GetMembers)
+	organization.PUT("/members/:accountId", h.Organization.UpdateMemberRole)
+	organization.GET("/farms", h.Organization.GetFarms)
+	organization.GET("/system-units", h.Organization.GetSystemUnits)
+
+	profile := srv.Group("/profile", middlewares.Auth, authorize)
 	profile.POST("/create", h.Profile.CreateProfile)
 	profile.GET("/:profileId", h.Profile.GetProfileDetails)
 	profile.GET("/", h.Profile.GetProfiles)
 	profile.PUT("/:profileId", h.Profile.UpdateProfile)
 	profile.DELETE("/:profileId", h.Profile.DeleteProfile)
 
-	farm := srv.Group("/farm")
+	farm := srv.Group("/farm", middlewares.Auth, authorize)
 	farm.POST("/create", h.Farm.CreateFarm)
 	farm.GET("/", h.Farm.GetFarms)
 	farm.GET("/:farmId", h.Farm.GetFarmDetails)
 	farm.PUT("/:farmId", h.Farm.UpdateFarm)
-	farm.DELETE("/:farmId", h.Farm.DeleteFarm)
+	farm.DELETE("/:farmId", middlewares.Stepup, h.Farm.DeleteFarm)
+	farm.GET("/:farmId/members", h.FarmMember.GetMembers)
+	farm.PUT("/:farmId/members/:accountId", h.FarmMember.UpdateMemberRole)
+	farm.DELETE("/:farmId/members/:accountId", h.FarmMember.RemoveMember)
+	farm.POST("/:farmId/invitations", h.FarmMember.InviteMember)
+	farm.GET("/:farmId/invitations", h.FarmMember.GetInvitations)
+	farm.DELETE("/:farmId/invitations/:invitationId", h.FarmMember.RevokeInvitation)
 
-	systemUnit := srv.Group("/system")
+	systemUnit := srv.Group("/system", middlewares.Auth, authorize)
 	systemUnit.POST("/create", h.SystemUnit.CreateSystemUnit)
 	systemUnit.GET("/", h.SystemUnit.GetSystemUnits)
 	systemUnit.PUT("/:systemId", h.SystemUnit.UpdateSystemUnit)
-	systemUnit.DELETE("/:systemId", h.SystemUnit.DeleteSystemIdById)
+	systemUnit.DELETE("/:systemId", middlewares.Stepup, h.SystemUnit.DeleteSystemIdById)
+	systemUnit.POST("/:systemId/credential", h.SystemUnit.RotateDeviceCredential)
+	systemUnit.GET("/:systemId/sequence-gaps", h.SystemUnit.GetSequenceGaps)
 
-	growthHistory := srv.Group("/growth-hist")
+	growthHistory := srv.Group("/growth-hist", middlewares.Auth, authorize)
 	growthHistory.POST("/create", h.GrowthHist.CreateGrowthHist)
 	growthHistory.POST("/random-data", h.GrowthHist.GenerateDummyData)
 	growthHistory.GET("/aggregation/filter", h.GrowthHist.GetGrowthHistAggregationByFilter)
 	growthHistory.GET("/filter", h.GrowthHist.GetGrowthHistByFilter)
 
-	tankTrans := srv.Group("/tank-trans")
+	readings := srv.Group("/readings", middlewares.Auth, authorize)
+	readings.GET("/metrics", h.GrowthHist.GetMetrics)
+	readings.POST("/create", h.GrowthHist.CreateReadings)
+	readings.POST("/bulk", h.ReadingIngest.IngestReadings)
+	readings.GET("/aggregation/filter", h.GrowthHist.GetReadingAggregationByFilter)
+	readings.GET("/filter", h.GrowthHist.GetReadingsByFilter)
+
+	// InfluxDB clients write to /write at the root
+	srv.POST("/write", middlewares.Auth, authorize, h.ReadingIngest.WriteLineProtocol)
+
+	tankTrans := srv.Group("/tank-trans", middlewares.Auth, authorize)
 	tankTrans.POST("/create", h.TankTrans.CreateTankTransaction)
 
-	aggregation := srv.Group("/aggregation")
+	// devices sign each request with their own credential instead of a token
+	device := srv.Group("/device", middlewares.DeviceAuth)
+	device.POST("/growth-hist", h.GrowthHist.CreateDeviceGrowthHist)
+	device.POST("/readings", h.GrowthHist.CreateDeviceReadings)
+	device.POST("/tank-trans", h.TankTrans.CreateDeviceTankTransaction)
+
+	ingest := srv.Group("/ingest", middlewares.SuperAuth)
+	ingest.GET("/queue", h.ReadingIngest.GetQueueStats)
+
+	aggregation := srv.Group("/aggregation", middlewares.Auth, authorize)
 	aggregation.GET("/growth-hist", h.Aggregation.CreateBatchAggregationGrowthHist)
 	aggregation.GET("/growth-hist/monthly", h.Aggregation.CreateCurrentMonthAggregationGrowthHist)
 
 	// super admin
 	authSuper := srv.Group("/auth-super")
-	authSuper.POST("/register", h.SuperAccount.CreateSuperUser)
+	authSuper.POST("/login", h.SuperAccount.Login)
+	authSuper.POST("/register", middlewares.SuperAuth, h.SuperAccount.CreateSuperUser)
+	authSuper.POST("/unlock-login", middlewares.SuperAuth, h.SuperAccount.UnlockLogin)
+	authSuper.POST("/step-up", middlewares.SuperAuth, h.SuperAccount.StepUp)
+	authSuper.POST("/impersonate", middlewares.SuperAuth, h.SuperAccount.Impersonate)
 
-	unitId := srv.Group("/unit-id")
+	unitId := srv.Group("/unit-id", middlewares.SuperAuth)
 	unitId.POST("/", h.UnitId.CreateUnitId)
 	unitId.GET("/", h.UnitId.GetUnitIds)
-	unitId.DELETE("/:unitId", h.UnitId.DeleteUnitIdById)
+	unitId.DELETE("/:unitId", middlewares.SuperStepup, h.UnitId.DeleteUnitIdById)
 }
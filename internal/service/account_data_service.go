@@ -0,0 +1,258 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/dto"
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/repository"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/mailer"
+)
+
+// Account export formats.
+const (
+	ExportFormatJSON = "json"
+	ExportFormatCSV  = "csv"
+)
+
+// purgeBatchSize bounds how many accounts one purge run closes.
+const purgeBatchSize = 100
+
+type AccountDataService interface {
+	ExportAccount(caller *dto.Caller, format string) (*dto.AccountExportFile, error)
+	RequestDeletion(caller *dto.Caller) (*dto.AccountDeletionResponse, error)
+	CancelDeletion(caller *dto.Caller) error
+	PurgeDueAccounts()
+}
+
+type accountDataService struct {
+	accountDataRepo repository.AccountDataRepository
+	accountRepo     repository.AccountRepository
+	sessionRepo     repository.SessionRepository
+	mailer          mailer.Mailer
+	gracePeriod     time.Duration
+}
+
+type AccountDataServiceConfig struct {
+	AccountDataRepo repository.AccountDataRepository
+	AccountRepo     repository.AccountRepository
+	SessionRepo     repository.SessionRepository
+	Mailer          mailer.Mailer
+	GracePeriod     time.Duration
+}
+
+func NewAccountDataService(config AccountDataServiceConfig) AccountDataService {
+	return &accountDataService{
+		accountDataRepo: config.AccountDataRepo,
+		accountRepo:     config.AccountRepo,
+		sessionRepo:     config.SessionRepo,
+		mailer:          config.Mailer,
+		gracePeriod:     config.GracePeriod,
+	}
+}
+
+// ExportAccount renders everything stored about the caller either as one JSON
+// document or as a zip archive with one CSV file per table.
+func (s *accountDataService) ExportAccount(caller *dto.Caller, format string) (*dto.AccountExportFile, error) {
+	if format == "" {
+		format = ExportFormatJSON
+	}
+	if format != ExportFormatJSON && format != ExportFormatCSV {
+		return nil, errs.InvalidExportFormat
+	}
+
+	export, err := s.accountDataRepo.GetAccountExport(caller.AccountID)
+	if err != nil {
+		return nil, errs.ErrorExportingAccount
+	}
+
+	baseName := "account-export-" + time.Now().UTC().Format("20060102")
+
+	var file *dto.AccountExportFile
+	if format == ExportFormatJSON {
+		data, err := json.MarshalIndent(export, "", "  ")
+		if err != nil {
+			return nil, errs.ErrorExportingAccount
+		}
+		file = &dto.AccountExportFile{FileName: baseName + ".json", ContentType: "application/json", Data: data}
+	} else {
+		data, err := exportCSVArchive(export)
+		if err != nil {
+			return nil, errs.ErrorExportingAccount
+		}
+		file = &dto.AccountExportFile{FileName: baseName + ".zip", ContentType: "application/zip", Data: data}
+	}
+
+	logger.Info("accountDataService", "Account data exported", map[string]string{
+		"account_id": caller.AccountID.String(),
+		"format":     format,
+		"bytes":      strconv.Itoa(len(file.Data)),
+	})
+	return file, nil
+}
+
+// RequestDeletion schedules the caller's account for deletion after the grace
+// period and signs it out everywhere. Signing in again during the grace period
+// is still possible, so the deletion can be cancelled.
+func (s *accountDataService) RequestDeletion(caller *dto.Caller) (*dto.AccountDeletionResponse, error) {
+	account, err := s.accountRepo.GetUserById(caller.AccountID)
+	if err != nil || account == nil {
+		return nil, errs.InvalidAccountId
+	}
+
+	scheduledAt := time.Now().Add(s.gracePeriod)
+	err = s.accountDataRepo.ScheduleAccountDeletion(caller.AccountID, scheduledAt)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.sessionRepo.RevokeSessionsByAccountId(&model.Session{AccountId: caller.AccountID})
+	if err != nil {
+		logger.Error("accountDataService", "Failed to revoke sessions of closing account", map[string]string{
+			"account_id": caller.AccountID.String(),
+			"error":      err.Error(),
+		})
+	}
+
+	err = s.mailer.Send(&mailer.Message{
+		To:      account.Email,
+		Subject: "Your account will be deleted",
+		Body: fmt.Sprintf("Hi %s,\n\nYour account and the farms you own alone will be deleted on %s. Sign in and cancel the deletion before then to keep them.\n\nIf you did not ask for this, sign in and change your password.\n",
+			account.Username, scheduledAt.UTC().Format(time.RFC1123)),
+	})
+	if err != nil {
+		logger.Error("accountDataService", "Failed to send account deletion email", map[string]string{
+			"account_id": caller.AccountID.String(),
+			"error":      err.Error(),
+		})
+	}
+
+	logger.Info("accountDataService", "Account deletion scheduled", map[string]string{
+		"account_id":   caller.AccountID.String(),
+		"scheduled_at": scheduledAt.Format(time.RFC3339),
+	})
+	return &dto.AccountDeletionResponse{DeletionScheduledAt: scheduledAt}, nil
+}
+
+func (s *accountDataService) CancelDeletion(caller *dto.Caller) error {
+	err := s.accountDataRepo.CancelAccountDeletion(caller.AccountID)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("accountDataService", "Account deletion cancelled", map[string]string{
+		"account_id": caller.AccountID.String(),
+	})
+	return nil
+}
+
+// PurgeDueAccounts closes the accounts whose grace period is over. Each
+// account is purged in its own transaction, so one failure does not hold back
+// the others.
+func (s *accountDataService) PurgeDueAccounts() {
+	now := time.Now()
+
+	accountIds, err := s.accountDataRepo.GetAccountsDueForDeletion(now, purgeBatchSize)
+	if err != nil {
+		return
+	}
+
+	purged := 0
+	for _, accountId := range accountIds {
+		if s.accountDataRepo.PurgeAccount(accountId, now) == nil {
+			purged++
+		}
+	}
+
+	if len(accountIds) > 0 {
+		logger.Info("accountDataService", "Purged accounts due for deletion", map[string]string{
+			"due":    strconv.Itoa(len(accountIds)),
+			"purged": strconv.Itoa(purged),
+		})
+	}
+}
+
+func exportCSVArchive(export *model.AccountExport) ([]byte, error) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	account := export.Account
+	files := []struct {
+		name   string
+		header []string
+		rows   [][]string
+	}{
+		{"account.csv", []string{"id", "username", "email", "role", "organization_id", "organization_role", "email_verified_at", "mfa_enabled_at", "deletion_scheduled_at", "created_at"}, [][]string{{
+			account.ID.String(), account.Username, account.Email, account.Role, account.OrganizationId.String(), account.OrganizationRole,
+			formatExportTime(account.EmailVerifiedAt), formatExportTime(account.MfaEnabledAt), formatExportTime(account.DeletionScheduledAt), formatExportTime(&account.CreatedAt),
+		}}},
+		{"profiles.csv", []string{"id", "organization_id", "name", "address", "created_at"}, nil},
+		{"farms.csv", []string{"id", "profile_id", "organization_id", "name", "address", "created_at"}, nil},
+		{"system_units.csv", []string{"id", "farm_id", "unit_key", "tank_volume", "tank_a_volume", "tank_b_volume", "created_at"}, nil},
+		{"readings.csv", []string{"id", "sample_id", "farm_id", "system_id", "metric", "value", "measured_at", "received_at", "skew_flagged"}, nil},
+		{"tank_trans.csv", []string{"id", "farm_id", "system_id", "water_volume", "a_volume", "b_volume", "created_at"}, nil},
+		{"aggregations.csv", []string{"id", "farm_id", "system_id", "name", "value", "time_range", "activity", "time"}, nil},
+	}
+
+	for _, p := range export.Profiles {
+		files[1].rows = append(files[1].rows, []string{p.ID.String(), p.OrganizationId.String(), p.Name, p.Address, formatExportTime(&p.CreatedAt)})
+	}
+	for _, f := range export.Farms {
+		files[2].rows = append(files[2].rows, []string{f.ID.String(), f.ProfileId.String(), f.OrganizationId.String(), f.Name, f.Address, formatExportTime(&f.CreatedAt)})
+	}
+	for _, u := range export.SystemUnits {
+		files[3].rows = append(files[3].rows, []string{u.ID.String(), u.FarmId.String(), u.UnitKey.String(),
+			strconv.Itoa(u.TankVolume), strconv.Itoa(u.TankAVolume), strconv.Itoa(u.TankBVolume), formatExportTime(&u.CreatedAt)})
+	}
+	for _, r := range export.Readings {
+		files[4].rows = append(files[4].rows, []string{r.ID.String(), r.SampleId.String(), r.FarmId.String(), r.SystemId.String(),
+			r.Metric, strconv.FormatFloat(r.Value, 'f', -1, 64), formatExportTime(&r.MeasuredAt), formatExportTime(&r.ReceivedAt),
+			strconv.FormatBool(r.SkewFlagged)})
+	}
+	for _, t := range export.TankTrans {
+		files[5].rows = append(files[5].rows, []string{t.ID.String(), t.FarmId.String(), t.SystemId.String(),
+			strconv.Itoa(t.WaterVolume), strconv.Itoa(t.AVolume), strconv.Itoa(t.BVolume), formatExportTime(&t.CreatedAt)})
+	}
+	for _, a := range export.Aggregations {
+		files[6].rows = append(files[6].rows, []string{a.ID.String(), a.FarmId.String(), a.SystemId.String(),
+			a.Name, strconv.FormatFloat(a.Value, 'f', -1, 64), a.TimeRange, a.Activity, formatExportTime(&a.Time)})
+	}
+
+	for _, file := range files {
+		w, err := archive.Create(file.name)
+		if err != nil {
+			return nil, err
+		}
+
+		writer := csv.NewWriter(w)
+		err = writer.Write(file.header)
+		if err != nil {
+			return nil, err
+		}
+		err = writer.WriteAll(file.rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := archive.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func formatExportTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -1,42 +1,102 @@
 package service
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/constant"
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/dto"
 	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/repository"
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/hasher"
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/mailer"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/passwordpolicy"
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/tokenprovider"
+	"github.com/google/uuid"
 )
 
 type AccountService interface {
 	SignUp(input *dto.RegisterBody) (*dto.RegisterResponse, error)
 	Login(input *dto.LoginBody) (*dto.LoginResponse, error)
+	LoginMfa(input *dto.LoginMfaBody) (*dto.LoginResponse, error)
+	Refresh(refreshToken string) (*dto.LoginResponse, error)
+	ChangePassword(accountId *uuid.UUID, input *dto.ChangePasswordBody) error
+	ForgotPassword(input *dto.ForgotPasswordBody) error
+	ResetPassword(input *dto.ResetPasswordBody) error
+	VerifyEmail(input *dto.VerifyEmailBody) error
+	ResendVerification(accountId *uuid.UUID) error
 }
 
 type accountService struct {
-	accountRepo repository.AccountRepository
-	profileRepo repository.ProfileRepository
-	hasher      hasher.Hasher
-	jwtProvider tokenprovider.JWTTokenProvider
+	accountRepo       repository.AccountRepository
+	profileRepo       repository.ProfileRepository
+	refreshTokenRepo  repository.RefreshTokenRepository
+	sessionRepo       repository.SessionRepository
+	hasher            hasher.Hasher
+	jwtProvider       tokenprovider.JWTTokenProvider
+	passwordPolicy    *passwordpolicy.Policy
+	passwordResetRepo repository.PasswordResetRepository
+	mailer            mailer.Mailer
+	passwordResetTTL  time.Duration
+	frontEndBase      string
+	emailVerifyRepo   repository.EmailVerificationRepository
+	emailVerifyTTL    time.Duration
+	loginThrottle     LoginThrottleService
+	mfaService        MfaService
+	farmMemberService FarmMemberService
+	dummyHash         *dummyHash
+}
+
+type dummyHash struct {
+	once sync.Once
+	hash string
 }
 
 type AccountServiceConfig struct {
-	AccountRepo repository.AccountRepository
-	ProfileRepo repository.ProfileRepository
-	Hasher      hasher.Hasher
-	JwtProvider tokenprovider.JWTTokenProvider
+	AccountRepo       repository.AccountRepository
+	ProfileRepo       repository.ProfileRepository
+	RefreshTokenRepo  repository.RefreshTokenRepository
+	SessionRepo       repository.SessionRepository
+	Hasher            hasher.Hasher
+	JwtProvider       tokenprovider.JWTTokenProvider
+	PasswordPolicy    *passwordpolicy.Policy
+	PasswordResetRepo repository.PasswordResetRepository
+	Mailer            mailer.Mailer
+	PasswordResetTTL  time.Duration
+	FrontEndBase      string
+	EmailVerifyRepo   repository.EmailVerificationRepository
+	EmailVerifyTTL    time.Duration
+	LoginThrottle     LoginThrottleService
+	MfaService        MfaService
+	FarmMemberService FarmMemberService
 }
 
 func NewAccountService(config AccountServiceConfig) AccountService {
 	return &accountService{
-		accountRepo: config.AccountRepo,
-		profileRepo: config.ProfileRepo,
-		hasher:      config.Hasher,
-		jwtProvider: config.JwtProvider,
+		accountRepo:       config.AccountRepo,
+		profileRepo:       config.ProfileRepo,
+		refreshTokenRepo:  config.RefreshTokenRepo,
+		sessionRepo:       config.SessionRepo,
+		hasher:            config.Hasher,
+		jwtProvider:       config.JwtProvider,
+		passwordPolicy:    config.PasswordPolicy,
+		passwordResetRepo: config.PasswordResetRepo,
+		mailer:            config.Mailer,
+		passwordResetTTL:  config.PasswordResetTTL,
+		frontEndBase:      config.FrontEndBase,
+		emailVerifyRepo:   config.EmailVerifyRepo,
+		emailVerifyTTL:    config.EmailVerifyTTL,
+		loginThrottle:     config.LoginThrottle,
+		mfaService:        config.MfaService,
+		farmMemberService: config.FarmMemberService,
+		dummyHash:         &dummyHash{},
 	}
 }
 
@@ -57,6 +117,37 @@ func (s *accountService) SignUp(input *dto.RegisterBody) (*dto.RegisterResponse,
 		return nil, errs.UsernameAlreadyUsed
 	}
 
+	email := strings.ToLower(strings.TrimSpace(input.Email))
+	account, err = s.accountRepo.GetUserByEmail(&email)
+	if err != nil {
+		logger.Error("accountService", "GetUserByEmail", map[string]string{
+			"error": err.Error(),
+		})
+		return nil, err
+	}
+	if account != nil {
+		return nil, errs.EmailAlreadyUsed
+	}
+
+	err = s.passwordPolicy.Validate(input.UserName, input.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	// Invited users join the organization of the farm, everyone else gets a
+	// personal organization they own. The role is never taken from the
+	// request: invited users reach farms through their membership and may not
+	// create farms in the organization they joined.
+	var organizationId *uuid.UUID
+	role := constant.RoleOwner
+	if input.InvitationToken != "" {
+		organizationId, err = s.farmMemberService.CheckInvitation(input.InvitationToken, email)
+		if err != nil {
+			return nil, err
+		}
+		role = constant.RoleOperator
+	}
+
 	// Hashing password
 	hashed, err := s.hasher.Hash(input.Password)
 	if err != nil {
@@ -70,12 +161,12 @@ func (s *accountService) SignUp(input *dto.RegisterBody) (*dto.RegisterResponse,
 	})
 
 	// Creating user account
-	res, err := s.accountRepo.CreateUser(&dto.RegisterBody{
-		UserName: input.UserName,
+	res, err := s.accountRepo.CreateUser(&model.User{
+		Username: input.UserName,
 		Password: hashed,
-		Email:    input.Email,
-		Role:     input.Role,
-	})
+		Email:    email,
+		Role:     role,
+	}, organizationId)
 	if err != nil {
 		logger.Error("accountService", "Failed to create user account", map[string]string{
 			"username": input.UserName,
@@ -89,7 +180,7 @@ func (s *accountService) SignUp(input *dto.RegisterBody) (*dto.RegisterResponse,
 	})
 
 	// Creating profile
-	resProfile, err := s.profileRepo.CreateProfile(&model.Profile{AccountId: res.ID, Name: res.Username})
+	resProfile, err := s.profileRepo.CreateProfile(&model.Profile{AccountId: res.ID, OrganizationId: res.OrganizationId, Name: res.Username})
 	if err != nil {
 		logger.Error("accountService", "Failed to create user profile", map[string]string{
 			"user_id": res.ID.String(),
@@ -102,16 +193,42 @@ func (s *accountService) SignUp(input *dto.RegisterBody) (*dto.RegisterResponse,
 		"user_id":    res.ID.String(),
 	})
 
+	// The invitation was checked above; losing a race for it leaves a normal
+	// account that can be invited again.
+	var farmMember *dto.FarmMemberResponse
+	if input.InvitationToken != "" {
+		caller := &dto.Caller{AccountID: res.ID, Role: res.Role, OrganizationID: res.OrganizationId, OrganizationRole: res.OrganizationRole}
+		farmMember, err = s.farmMemberService.AcceptInvitation(caller, &dto.AcceptFarmInvitationBody{Token: input.InvitationToken})
+		if err != nil {
+			logger.Error("accountService", "Failed to accept farm invitation", map[string]string{
+				"user_id": res.ID.String(),
+				"error":   err.Error(),
+			})
+		}
+	}
+
+	// Unverified accounts stay read-only, so a failed email only needs a resend.
+	err = s.sendVerificationEmail(res)
+	if err != nil {
+		logger.Error("accountService", "Failed to send verification email", map[string]string{
+			"user_id": res.ID.String(),
+			"error":   err.Error(),
+		})
+	}
+
 	// Preparing response
 	respBody := &dto.RegisterResponse{
-		UserID:   res.ID,
-		Username: res.Username,
-		Role:     res.Role,
+		UserID:           res.ID,
+		Username:         res.Username,
+		Role:             res.Role,
+		OrganizationID:   res.OrganizationId,
+		OrganizationRole: res.OrganizationRole,
 		ProfileResponse: &dto.ProfileResponse{
 			ID:      resProfile.ID,
 			Name:    resProfile.Name,
 			Address: resProfile.Address,
 		},
+		FarmMember: farmMember,
 	}
 
 	logger.Info("accountService", "SignUp process completed successfully", map[string]string{
@@ -122,6 +239,10 @@ func (s *accountService) SignUp(input *dto.RegisterBody) (*dto.RegisterResponse,
 }
 
 func (s accountService) Login(input *dto.LoginBody) (*dto.LoginResponse, error) {
+	err := s.loginThrottle.CheckLogin(input.Username, input.IpAddress)
+	if err != nil {
+		return nil, err
+	}
 
 	account, err := s.accountRepo.GetUserByName(&input.Username)
 	if err != nil {
@@ -130,11 +251,15 @@ func (s accountService) Login(input *dto.LoginBody) (*dto.LoginResponse, error)
 		})
 		return nil, err
 	}
-	if account == nil {
-		return nil, errs.UsernamePasswordIncorrect
+
+	// Unknown usernames still pay for a hash comparison so response time does
+	// not reveal which usernames exist.
+	hashedPassword := s.dummyPasswordHash()
+	if account != nil {
+		hashedPassword = account.Password
 	}
 
-	passwordOk, err := s.hasher.IsEqual(account.Password, input.Password)
+	passwordOk, err := s.hasher.IsEqual(hashedPassword, input.Password)
 	if err != nil {
 		logger.Error("accountService", "hasher isEqual", map[string]string{
 			"error": err.Error(),
@@ -142,25 +267,219 @@ func (s accountService) Login(input *dto.LoginBody) (*dto.LoginResponse, error)
 		return nil, err
 	}
 
-	if !passwordOk {
-		return nil, errs.PasswordDoesntMatch
+	if account == nil || !passwordOk {
+		s.loginThrottle.RecordFailedLogin(input.Username, input.IpAddress)
+		return nil, errs.UsernamePasswordIncorrect
+	}
+
+	s.rehashPassword(account, input.Password)
+
+	// The throttle is only reset once every factor has passed, otherwise a
+	// known password would allow unlimited guesses at the TOTP code.
+	if account.MfaEnabledAt != nil {
+		mfaToken, err := s.jwtProvider.GenerateMfaToken(account, uuid.New())
+		if err != nil {
+			return nil, errs.ErrorGeneratingToken
+		}
+		return &dto.LoginResponse{MfaRequired: true, MfaToken: mfaToken}, nil
+	}
+
+	s.loginThrottle.ResetLogin(input.Username)
+
+	return s.startSession(account, input.UserAgent, input.IpAddress)
+}
+
+// LoginMfa completes a login that Login answered with MfaRequired.
+func (s accountService) LoginMfa(input *dto.LoginMfaBody) (*dto.LoginResponse, error) {
+	claims, err := s.jwtProvider.ValidateMfaToken(input.MfaToken)
+	if err != nil {
+		return nil, errs.InvalidToken
+	}
+
+	userId, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return nil, errs.InvalidToken
+	}
+
+	account, err := s.accountRepo.GetUserCredentialById(userId)
+	if err != nil || account == nil {
+		return nil, errs.InvalidToken
 	}
 
+	err = s.loginThrottle.CheckLogin(account.Username, input.IpAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.mfaService.VerifySecondFactor(account, &dto.MfaCodeBody{
+		Code:         input.Code,
+		RecoveryCode: input.RecoveryCode,
+	})
+	if errors.Is(err, errs.InvalidMfaCode) {
+		s.loginThrottle.RecordFailedLogin(account.Username, input.IpAddress)
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.loginThrottle.ResetLogin(account.Username)
+
+	return s.startSession(account, input.UserAgent, input.IpAddress)
+}
+
+func (s accountService) startSession(account *model.User, userAgent string, ipAddress string) (*dto.LoginResponse, error) {
 	userClaims := model.User{}
 
 	userClaims.ID = account.ID
 	userClaims.Username = account.Username
 	userClaims.Role = account.Role
+	userClaims.OrganizationId = account.OrganizationId
+	userClaims.OrganizationRole = account.OrganizationRole
+	userClaims.EmailVerifiedAt = account.EmailVerifiedAt
 
-	return s.generateLoginResponse(&userClaims)
+	session, err := s.sessionRepo.CreateSession(&model.Session{
+		AccountId: account.ID,
+		UserAgent: userAgent,
+		IpAddress: ipAddress,
+		ExpiresAt: time.Now().Add(s.jwtProvider.RefreshTokenDuration()),
+	})
+	if err != nil {
+		logger.Error("accountService", "Failed to create session", map[string]string{
+			"error": err.Error(),
+		})
+		return nil, errs.ErrorGeneratingToken
+	}
 
+	return s.generateLoginResponse(&userClaims, session)
 }
 
-func (s accountService) generateLoginResponse(user *model.User) (*dto.LoginResponse, error) {
+func (s accountService) Refresh(refreshToken string) (*dto.LoginResponse, error) {
+	claims, err := s.jwtProvider.ValidateRefreshToken(refreshToken)
+	if err != nil {
+		logger.Warn("accountService", "Invalid refresh token", map[string]string{
+			"error": err.Error(),
+		})
+		return nil, errs.InvalidToken
+	}
+
+	tokenId, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return nil, errs.InvalidToken
+	}
+
+	userId, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return nil, errs.InvalidToken
+	}
 
-	fmt.Println("Generating login response for user:", *user)
+	storedToken, err := s.refreshTokenRepo.GetRefreshTokenById(&model.RefreshToken{ID: tokenId})
+	if err != nil {
+		return nil, errs.InvalidToken
+	}
+
+	if storedToken.AccountId != userId || storedToken.TokenHash != hasher.TokenDigest(refreshToken) {
+		return nil, errs.InvalidToken
+	}
+
+	if storedToken.RevokedAt != nil {
+		return nil, s.handleRefreshTokenReuse(storedToken)
+	}
+
+	// The refresh token family id is the id of the session it was issued for.
+	session, err := s.sessionRepo.GetActiveSessionById(&model.Session{
+		ID:        storedToken.FamilyId,
+		AccountId: storedToken.AccountId,
+	})
+	if err != nil {
+		return nil, errs.InvalidToken
+	}
+
+	// Reload the account so role and email verification changes reach the
+	// new access token.
+	user, err := s.accountRepo.GetUserById(userId)
+	if err != nil || user == nil {
+		return nil, errs.InvalidToken
+	}
+
+	newTokenId := uuid.New()
+	newRefreshToken, err := s.jwtProvider.GenerateRefreshToken(user, newTokenId)
+	if err != nil {
+		logger.Error("accountService", "Failed to generate refresh token", map[string]string{
+			"error": err.Error(),
+		})
+		return nil, errs.ErrorGeneratingToken
+	}
 
-	accesToken, err := s.jwtProvider.GenerateAccessToken(user)
+	rotated, err := s.refreshTokenRepo.RotateRefreshToken(storedToken, &model.RefreshToken{
+		ID:        newTokenId,
+		AccountId: storedToken.AccountId,
+		FamilyId:  storedToken.FamilyId,
+		TokenHash: hasher.TokenDigest(newRefreshToken),
+		ExpiresAt: time.Now().Add(s.jwtProvider.RefreshTokenDuration()),
+	})
+	if err != nil {
+		return nil, errs.ErrorGeneratingToken
+	}
+	if !rotated {
+		// Another request rotated this token between our read and the update.
+		return nil, s.handleRefreshTokenReuse(storedToken)
+	}
+
+	accessToken, err := s.jwtProvider.GenerateAccessToken(user, session.ID)
+	if err != nil {
+		logger.Error("accountService", "Failed to generate access token", map[string]string{
+			"error": err.Error(),
+		})
+		return nil, errs.ErrorGeneratingToken
+	}
+
+	err = s.sessionRepo.ExtendSession(&model.Session{
+		ID:        session.ID,
+		ExpiresAt: time.Now().Add(s.jwtProvider.RefreshTokenDuration()),
+	})
+	if err != nil {
+		return nil, errs.ErrorGeneratingToken
+	}
+
+	logger.Info("accountService", "Refresh token rotated", map[string]string{
+		"user_id":   user.ID.String(),
+		"family_id": storedToken.FamilyId.String(),
+	})
+	return &dto.LoginResponse{
+		AccesToken:   accessToken,
+		RefreshToken: newRefreshToken,
+	}, nil
+}
+
+func (s accountService) handleRefreshTokenReuse(storedToken *model.RefreshToken) error {
+	logger.Warn("accountService", "Refresh token reuse detected, revoking family", map[string]string{
+		"account_id": storedToken.AccountId.String(),
+		"family_id":  storedToken.FamilyId.String(),
+	})
+
+	_, err := s.sessionRepo.RevokeSession(&model.Session{
+		ID:        storedToken.FamilyId,
+		AccountId: storedToken.AccountId,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.refreshTokenRepo.RevokeRefreshTokenFamily(storedToken)
+	if err != nil {
+		return err
+	}
+	return errs.RefreshTokenReused
+}
+
+func (s accountService) generateLoginResponse(user *model.User, session *model.Session) (*dto.LoginResponse, error) {
+
+	logger.Info("accountService", "Generating login response", map[string]string{
+		"user_id": user.ID.String(),
+	})
+
+	accesToken, err := s.jwtProvider.GenerateAccessToken(user, session.ID)
 
 	if err != nil {
 		logger.Error("accountService", "Failed to generate access token", map[string]string{
@@ -169,7 +488,8 @@ func (s accountService) generateLoginResponse(user *model.User) (*dto.LoginRespo
 		return nil, err
 	}
 
-	refreshToken, err := s.jwtProvider.GenerateRefreshToken(user)
+	refreshTokenId := uuid.New()
+	refreshToken, err := s.jwtProvider.GenerateRefreshToken(user, refreshTokenId)
 
 	if err != nil {
 		logger.Error("accountService", "Failed to generate refresh token", map[string]string{
@@ -178,8 +498,288 @@ func (s accountService) generateLoginResponse(user *model.User) (*dto.LoginRespo
 		return nil, err
 	}
 
+	// Every login starts a new refresh token family, keyed by the session id;
+	// rotations stay inside it.
+	_, err = s.refreshTokenRepo.CreateRefreshToken(&model.RefreshToken{
+		ID:        refreshTokenId,
+		AccountId: user.ID,
+		FamilyId:  session.ID,
+		TokenHash: hasher.TokenDigest(refreshToken),
+		ExpiresAt: time.Now().Add(s.jwtProvider.RefreshTokenDuration()),
+	})
+	if err != nil {
+		return nil, errs.ErrorGeneratingToken
+	}
+
 	return &dto.LoginResponse{
 		AccesToken:   accesToken,
 		RefreshToken: refreshToken,
 	}, nil
 }
+
+// ChangePassword replaces the password of the account after checking the
+// current one. The new password must satisfy the policy and differ from the
+// current password and the last HistorySize passwords.
+func (s accountService) ChangePassword(accountId *uuid.UUID, input *dto.ChangePasswordBody) error {
+	logger.Info("accountService", "Changing password", map[string]string{
+		"user_id": accountId.String(),
+	})
+
+	account, err := s.accountRepo.GetUserCredentialById(*accountId)
+	if err != nil {
+		return errs.ErrorChangingPassword
+	}
+	if account == nil {
+		return errs.InvalidAccountId
+	}
+
+	passwordOk, err := s.hasher.IsEqual(account.Password, input.CurrentPassword)
+	if err != nil {
+		return err
+	}
+	if !passwordOk {
+		return errs.PasswordDoesntMatch
+	}
+
+	err = s.checkNewPassword(account, input.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	hashed, err := s.hasher.Hash(input.NewPassword)
+	if err != nil {
+		return errs.ErrorGeneratingHashedPassword
+	}
+
+	err = s.accountRepo.UpdatePassword(&model.User{ID: account.ID, Password: hashed}, account.Password)
+	if err != nil {
+		return errs.ErrorChangingPassword
+	}
+
+	logger.Info("accountService", "Password changed successfully", map[string]string{
+		"user_id": accountId.String(),
+	})
+	return nil
+}
+
+// checkNewPassword applies the password policy and rejects the current
+// password and the last HistorySize passwords of the account.
+func (s accountService) checkNewPassword(account *model.User, newPassword string) error {
+	err := s.passwordPolicy.Validate(account.Username, newPassword)
+	if err != nil {
+		return err
+	}
+
+	previousHashes := []string{account.Password}
+	if s.passwordPolicy.HistorySize > 0 {
+		histories, err := s.accountRepo.GetPasswordHistories(account.ID, s.passwordPolicy.HistorySize)
+		if err != nil {
+			return errs.ErrorChangingPassword
+		}
+		for _, history := range histories {
+			previousHashes = append(previousHashes, history.PasswordHash)
+		}
+	}
+
+	for _, previousHash := range previousHashes {
+		same, err := s.hasher.IsEqual(previousHash, newPassword)
+		if err != nil {
+			return err
+		}
+		if same {
+			return errs.PasswordSameAsBefore
+		}
+	}
+
+	return nil
+}
+
+// ForgotPassword mails a single use reset link when the email belongs to an
+// account. It reports success either way so the endpoint cannot be used to
+// discover registered emails.
+func (s accountService) ForgotPassword(input *dto.ForgotPasswordBody) error {
+	account, err := s.accountRepo.GetUserByEmail(&input.Email)
+	if err != nil {
+		return errs.ErrorRequestingPasswordReset
+	}
+	if account == nil {
+		logger.Warn("accountService", "Password reset requested for unknown email", nil)
+		return nil
+	}
+
+	rawToken, err := generateOpaqueToken()
+	if err != nil {
+		return errs.ErrorRequestingPasswordReset
+	}
+
+	_, err = s.passwordResetRepo.CreatePasswordResetToken(&model.PasswordResetToken{
+		AccountId: account.ID,
+		TokenHash: hasher.TokenDigest(rawToken),
+		ExpiresAt: time.Now().Add(s.passwordResetTTL),
+	})
+	if err != nil {
+		return errs.ErrorRequestingPasswordReset
+	}
+
+	err = s.mailer.Send(&mailer.Message{
+		To:      account.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to reset your password. It expires in %s and can only be used once.\n\n%s/reset-password?token=%s\n\nIf you did not request this, you can ignore this email.\n",
+			account.Username, s.passwordResetTTL.String(), s.frontEndBase, rawToken),
+	})
+	if err != nil {
+		logger.Error("accountService", "Failed to send password reset email", map[string]string{
+			"user_id": account.ID.String(),
+			"error":   err.Error(),
+		})
+		return errs.ErrorRequestingPasswordReset
+	}
+
+	logger.Info("accountService", "Password reset email sent", map[string]string{
+		"user_id": account.ID.String(),
+	})
+	return nil
+}
+
+// ResetPassword sets a new password using a token from ForgotPassword. All
+// sessions of the account are signed out afterwards.
+func (s accountService) ResetPassword(input *dto.ResetPasswordBody) error {
+	token, err := s.passwordResetRepo.GetActivePasswordResetToken(&model.PasswordResetToken{
+		TokenHash: hasher.TokenDigest(input.Token),
+	})
+	if err != nil {
+		return errs.InvalidPasswordResetToken
+	}
+
+	account, err := s.accountRepo.GetUserCredentialById(token.AccountId)
+	if err != nil || account == nil {
+		return errs.InvalidPasswordResetToken
+	}
+
+	err = s.checkNewPassword(account, input.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	hashed, err := s.hasher.Hash(input.NewPassword)
+	if err != nil {
+		return errs.ErrorGeneratingHashedPassword
+	}
+
+	previousHash := account.Password
+	account.Password = hashed
+	err = s.passwordResetRepo.ResetPassword(token, account, previousHash)
+	if errors.Is(err, errs.InvalidPasswordResetToken) {
+		return err
+	}
+	if err != nil {
+		return errs.ErrorChangingPassword
+	}
+
+	logger.Info("accountService", "Password reset successfully", map[string]string{
+		"user_id": account.ID.String(),
+	})
+	return nil
+}
+
+func (s accountService) VerifyEmail(input *dto.VerifyEmailBody) error {
+	token, err := s.emailVerifyRepo.VerifyEmail(&model.EmailVerificationToken{
+		TokenHash: hasher.TokenDigest(input.Token),
+	})
+	if err != nil {
+		return errs.InvalidEmailVerifyToken
+	}
+
+	logger.Info("accountService", "Email verified", map[string]string{
+		"user_id": token.AccountId.String(),
+	})
+	return nil
+}
+
+func (s accountService) ResendVerification(accountId *uuid.UUID) error {
+	account, err := s.accountRepo.GetUserById(*accountId)
+	if err != nil || account == nil {
+		return errs.InvalidAccountId
+	}
+	if account.EmailVerifiedAt != nil {
+		return errs.EmailAlreadyVerified
+	}
+
+	err = s.sendVerificationEmail(account)
+	if err != nil {
+		logger.Error("accountService", "Failed to send verification email", map[string]string{
+			"user_id": account.ID.String(),
+			"error":   err.Error(),
+		})
+		return errs.ErrorSendingVerification
+	}
+	return nil
+}
+
+func (s accountService) sendVerificationEmail(account *model.User) error {
+	rawToken, err := generateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.emailVerifyRepo.CreateEmailVerificationToken(&model.EmailVerificationToken{
+		AccountId: account.ID,
+		TokenHash: hasher.TokenDigest(rawToken),
+		ExpiresAt: time.Now().Add(s.emailVerifyTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(&mailer.Message{
+		To:      account.Email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf("Hi %s,\n\nConfirm your email address with the link below. It expires in %s.\n\n%s/verify-email?token=%s\n\nUntil then your account is read-only.\n",
+			account.Username, s.emailVerifyTTL.String(), s.frontEndBase, rawToken),
+	})
+}
+
+// rehashPassword upgrades a hash made by an older algorithm or with weaker
+// parameters while the plain password is at hand. Failures only cost the
+// upgrade, the login goes on.
+func (s accountService) rehashPassword(account *model.User, password string) {
+	if !s.hasher.NeedsRehash(account.Password) {
+		return
+	}
+
+	hashed, err := s.hasher.Hash(password)
+	if err == nil {
+		err = s.accountRepo.UpdatePasswordHash(account.ID, account.Password, hashed)
+	}
+	if err != nil {
+		logger.Error("accountService", "Failed to rehash password", map[string]string{
+			"user_id": account.ID.String(),
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	logger.Info("accountService", "Password rehashed", map[string]string{
+		"user_id": account.ID.String(),
+	})
+	account.Password = hashed
+}
+
+func (s accountService) dummyPasswordHash() string {
+	s.dummyHash.once.Do(func() {
+		hash, err := s.hasher.Hash(uuid.NewString())
+		if err == nil {
+			s.dummyHash.hash = hash
+		}
+	})
+	return s.dummyHash.hash
+}
+
+func generateOpaqueToken() (string, error) {
+	buf := make([]byte, 32)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/constant"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/dto"
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/repository"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/hasher"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+	"github.com/google/uuid"
+)
+
+// apiTokenPrefixLength is how much of a raw token is kept in clear text so
+// users can tell their tokens apart.
+const apiTokenPrefixLength = 12
+
+type ApiTokenService interface {
+	CreateApiToken(caller *dto.Caller, input *dto.CreateApiTokenBody) (*dto.CreateApiTokenResponse, error)
+	GetApiTokens(caller *dto.Caller) ([]*dto.ApiTokenResponse, error)
+	RevokeApiToken(caller *dto.Caller, tokenId *uuid.UUID) error
+	Authenticate(rawToken string, ipAddress string) (*dto.ApiTokenAuth, error)
+}
+
+type apiTokenService struct {
+	apiTokenRepo repository.ApiTokenRepository
+	accountRepo  repository.AccountRepository
+	farmRepo     repository.FarmRepository
+}
+
+type ApiTokenServiceConfig struct {
+	ApiTokenRepo repository.ApiTokenRepository
+	AccountRepo  repository.AccountRepository
+	FarmRepo     repository.FarmRepository
+}
+
+func NewApiTokenService(config ApiTokenServiceConfig) ApiTokenService {
+	return &apiTokenService{
+		apiTokenRepo: config.ApiTokenRepo,
+		accountRepo:  config.AccountRepo,
+		farmRepo:     config.FarmRepo,
+	}
+}
+
+// CreateApiToken issues a token for the caller. The raw token is returned
+// once; only its digest is stored.
+func (s *apiTokenService) CreateApiToken(caller *dto.Caller, input *dto.CreateApiTokenBody) (*dto.CreateApiTokenResponse, error) {
+	for _, farmId := range input.FarmIds {
+		farm, err := s.farmRepo.GetFarmByIdAndScope(&model.Farm{ID: farmId}, scopeOf(caller))
+		if err != nil || farm == nil || !caller.CanAccessFarm(farmId) {
+			return nil, errs.InvalidFarmID
+		}
+	}
+
+	rawToken, err := generateApiToken()
+	if err != nil {
+		return nil, errs.ErrorCreatingApiToken
+	}
+
+	var expiresAt *time.Time
+	if input.ExpiresInDays > 0 {
+		expiry := time.Now().AddDate(0, 0, input.ExpiresInDays)
+		expiresAt = &expiry
+	}
+
+	token, err := s.apiTokenRepo.CreateApiToken(&model.ApiToken{
+		AccountId:   caller.AccountID,
+		Name:        input.Name,
+		TokenPrefix: rawToken[:apiTokenPrefixLength],
+		TokenHash:   hasher.TokenDigest(rawToken),
+		Permission:  input.Permission,
+		Scoped:      len(input.FarmIds) > 0,
+		ExpiresAt:   expiresAt,
+		FarmIds:     input.FarmIds,
+	})
+	if err != nil {
+		return nil, errs.ErrorCreatingApiToken
+	}
+
+	return &dto.CreateApiTokenResponse{
+		ApiTokenResponse: toApiTokenResponse(token),
+		Token:            rawToken,
+	}, nil
+}
+
+func (s *apiTokenService) GetApiTokens(caller *dto.Caller) ([]*dto.ApiTokenResponse, error) {
+	tokens, err := s.apiTokenRepo.GetApiTokensByAccountId(&caller.AccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]*dto.ApiTokenResponse, 0, len(tokens))
+	for _, token := range tokens {
+		resp = append(resp, toApiTokenResponse(token))
+	}
+	return resp, nil
+}
+
+func (s *apiTokenService) RevokeApiToken(caller *dto.Caller, tokenId *uuid.UUID) error {
+	err := s.apiTokenRepo.RevokeApiToken(&model.ApiToken{ID: *tokenId, AccountId: caller.AccountID})
+	if errors.Is(err, errs.InvalidApiTokenID) {
+		return err
+	}
+	if err != nil {
+		return errs.InvalidApiTokenID
+	}
+
+	logger.Info("apiTokenService", "API token revoked", map[string]string{
+		"id":         tokenId.String(),
+		"account_id": caller.AccountID.String(),
+	})
+	return nil
+}
+
+// Authenticate resolves a raw API token to the identity it acts as. Read
+// tokens act as viewers, on the account and on every farm; write tokens keep
+// the roles of the account.
+func (s *apiTokenService) Authenticate(rawToken string, ipAddress string) (*dto.ApiTokenAuth, error) {
+	token, err := s.apiTokenRepo.GetActiveApiTokenByHash(hasher.TokenDigest(rawToken))
+	if err != nil {
+		return nil, errs.InvalidApiToken
+	}
+
+	account, err := s.accountRepo.GetUserById(token.AccountId)
+	if err != nil || account == nil {
+		return nil, errs.InvalidApiToken
+	}
+
+	role := account.Role
+	if token.Permission != constant.ApiTokenPermissionWrite {
+		role = constant.RoleViewer
+	}
+
+	if token.LastUsedAt == nil || time.Since(*token.LastUsedAt) > lastSeenResolution || token.LastUsedIp != ipAddress {
+		err = s.apiTokenRepo.TouchApiToken(&model.ApiToken{ID: token.ID, LastUsedIp: ipAddress})
+		if err != nil {
+			logger.Warn("apiTokenService", "Failed to update API token last used", map[string]string{
+				"id":    token.ID.String(),
+				"error": err.Error(),
+			})
+		}
+	}
+
+	// a scoped token whose farms were all deleted reaches no farm at all,
+	// never every farm of the account
+	var farmIds []uuid.UUID
+	if token.Scoped {
+		farmIds = append(make([]uuid.UUID, 0, len(token.FarmIds)), token.FarmIds...)
+	}
+
+	return &dto.ApiTokenAuth{
+		TokenID:          token.ID,
+		AccountID:        account.ID,
+		Username:         account.Username,
+		Role:             role,
+		OrganizationID:   account.OrganizationId,
+		OrganizationRole: account.OrganizationRole,
+		EmailVerified:    account.EmailVerifiedAt != nil,
+		ReadOnly:         token.Permission != constant.ApiTokenPermissionWrite,
+		FarmIds:          farmIds,
+	}, nil
+}
+
+func toApiTokenResponse(token *model.ApiToken) *dto.ApiTokenResponse {
+	return &dto.ApiTokenResponse{
+		ID:          token.ID,
+		Name:        token.Name,
+		TokenPrefix: token.TokenPrefix,
+		Permission:  token.Permission,
+		Scoped:      token.Scoped,
+		FarmIds:     token.FarmIds,
+		LastUsedAt:  token.LastUsedAt,
+		LastUsedIp:  token.LastUsedIp,
+		ExpiresAt:   token.ExpiresAt,
+		CreatedAt:   token.CreatedAt,
+	}
+}
+
+func generateApiToken() (string, error) {
+	buf := make([]byte, 32)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+	return constant.ApiTokenPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/constant"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/dto"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/repository"
+	"github.com/google/uuid"
+)
+
+type fakeApiTokenRepo struct {
+	repository.ApiTokenRepository
+	token *model.ApiToken
+}
+
+func (f *fakeApiTokenRepo) GetActiveApiTokenByHash(tokenHash string) (*model.ApiToken, error) {
+	return f.token, nil
+}
+
+func (f *fakeApiTokenRepo) TouchApiToken(inputModel *model.ApiToken) error {
+	return nil
+}
+
+type fakeAccountRepo struct {
+	repository.AccountRepository
+	user *model.User
+}
+
+func (f *fakeAccountRepo) GetUserById(accountID uuid.UUID) (*model.User, error) {
+	return f.user, nil
+}
+
+func TestApiTokenAuthenticateScope(t *testing.T) {
+	farmA, farmB := uuid.New(), uuid.New()
+	user := &model.User{ID: uuid.New(), Role: constant.RoleOwner}
+
+	tests := []struct {
+		name    string
+		scoped  bool
+		farmIds []uuid.UUID
+		want    map[uuid.UUID]bool
+	}{
+		{"unscoped", false, nil, map[uuid.UUID]bool{farmA: true, farmB: true}},
+		{"scoped", true, []uuid.UUID{farmA}, map[uuid.UUID]bool{farmA: true, farmB: false}},
+		{"scoped farms all deleted", true, nil, map[uuid.UUID]bool{farmA: false, farmB: false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lastUsed := time.Now()
+			s := NewApiTokenService(ApiTokenServiceConfig{
+				ApiTokenRepo: &fakeApiTokenRepo{token: &model.ApiToken{
+					ID:         uuid.New(),
+					AccountId:  user.ID,
+					Permission: constant.ApiTokenPermissionWrite,
+					Scoped:     tt.scoped,
+					FarmIds:    tt.farmIds,
+					LastUsedAt: &lastUsed,
+				}},
+				AccountRepo: &fakeAccountRepo{user: user},
+			})
+
+			auth, err := s.Authenticate("token", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			caller := &dto.Caller{FarmIds: auth.FarmIds}
+			for farmId, want := range tt.want {
+				if got := caller.CanAccessFarm(farmId); got != want {
+					t.Errorf("CanAccessFarm(%s) = %v, want %v", farmId, got, want)
+				}
+			}
+		})
+	}
+}
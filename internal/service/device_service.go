@@ -0,0 +1,249 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/dto"
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/repository"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/secretcipher"
+	"github.com/google/uuid"
+)
+
+const (
+	// deviceSignatureTolerance is how far a device clock may drift from ours.
+	deviceSignatureTolerance = 5 * time.Minute
+	// deviceNonceRetention keeps nonces long enough to cover every timestamp
+	// that is still accepted.
+	deviceNonceRetention = 2 * deviceSignatureTolerance
+	deviceKeyIdPrefix    = "dev_"
+	maxDeviceNonceLength = 64
+)
+
+type DeviceService interface {
+	ProvisionCredential(systemUnitId uuid.UUID) (*dto.DeviceCredentialResponse, error)
+	Authenticate(input *dto.DeviceSignedRequest) (*dto.DeviceAuth, error)
+	AuthenticateUnit(unitKey uuid.UUID, secret string) (*dto.DeviceAuth, error)
+	CheckCredential(device *dto.DeviceAuth) error
+	DeleteExpiredNonces() error
+}
+
+type deviceService struct {
+	deviceCredentialRepo repository.DeviceCredentialRepository
+	secretCipher         secretcipher.Cipher
+}
+
+type DeviceServiceConfig struct {
+	DeviceCredentialRepo repository.DeviceCredentialRepository
+	SecretCipher         secretcipher.Cipher
+}
+
+func NewDeviceService(config DeviceServiceConfig) DeviceService {
+	return &deviceService{
+		deviceCredentialRepo: config.DeviceCredentialRepo,
+		secretCipher:         config.SecretCipher,
+	}
+}
+
+// ProvisionCredential creates a new key for a system unit and revokes its
+// previous one. The secret is only returned here.
+func (s *deviceService) ProvisionCredential(systemUnitId uuid.UUID) (*dto.DeviceCredentialResponse, error) {
+	keyBytes := make([]byte, 8)
+	secretBytes := make([]byte, 32)
+	_, err := rand.Read(keyBytes)
+	if err != nil {
+		return nil, errs.ErrorProvisioningDevice
+	}
+	_, err = rand.Read(secretBytes)
+	if err != nil {
+		return nil, errs.ErrorProvisioningDevice
+	}
+
+	keyId := deviceKeyIdPrefix + hex.EncodeToString(keyBytes)
+	secret := base64.RawURLEncoding.EncodeToString(secretBytes)
+
+	ciphertext, err := s.secretCipher.Encrypt(secret)
+	if err != nil {
+		return nil, errs.ErrorProvisioningDevice
+	}
+
+	_, err = s.deviceCredentialRepo.CreateDeviceCredential(&model.DeviceCredential{
+		SystemUnitId:     systemUnitId,
+		KeyId:            keyId,
+		SecretCiphertext: ciphertext,
+	})
+	if err != nil {
+		return nil, errs.ErrorProvisioningDevice
+	}
+
+	return &dto.DeviceCredentialResponse{KeyId: keyId, Secret: secret}, nil
+}
+
+// Authenticate verifies a device request. The signature is the hex encoded
+// HMAC-SHA256, keyed with the device secret, of
+//
+//	timestamp + "\n" + nonce + "\n" + METHOD + "\n" + path + "\n" + hex(sha256(body))
+//
+// where timestamp is in unix seconds. Each nonce is accepted once per key.
+func (s *deviceService) Authenticate(input *dto.DeviceSignedRequest) (*dto.DeviceAuth, error) {
+	if input.KeyId == "" || input.Nonce == "" || len(input.Nonce) > maxDeviceNonceLength || input.Signature == "" {
+		return nil, errs.InvalidDeviceSignature
+	}
+
+	timestamp, err := strconv.ParseInt(input.Timestamp, 10, 64)
+	if err != nil {
+		return nil, errs.InvalidDeviceSignature
+	}
+	drift := time.Since(time.Unix(timestamp, 0))
+	if drift > deviceSignatureTolerance || drift < -deviceSignatureTolerance {
+		return nil, errs.DeviceRequestExpired
+	}
+
+	credential, err := s.deviceCredentialRepo.GetActiveDeviceCredential(input.KeyId)
+	if err != nil || credential == nil {
+		return nil, errs.InvalidDeviceSignature
+	}
+
+	secret, err := s.secretCipher.Decrypt(credential.SecretCiphertext)
+	if err != nil {
+		logger.Error("deviceService", "Failed to decrypt device secret", map[string]string{
+			"key_id": input.KeyId,
+			"error":  err.Error(),
+		})
+		return nil, errs.InvalidDeviceSignature
+	}
+
+	signature, err := hex.DecodeString(input.Signature)
+	if err != nil || !hmac.Equal(signature, signDeviceRequest(secret, input)) {
+		logger.Warn("deviceService", "Device signature mismatch", map[string]string{
+			"key_id": input.KeyId,
+		})
+		return nil, errs.InvalidDeviceSignature
+	}
+
+	// The nonce is only stored for valid signatures so that unauthenticated
+	// requests cannot burn nonces of a real device.
+	fresh, err := s.deviceCredentialRepo.UseDeviceNonce(input.KeyId, input.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	if !fresh {
+		logger.Warn("deviceService", "Device request replayed", map[string]string{
+			"key_id": input.KeyId,
+		})
+		return nil, errs.DeviceNonceReused
+	}
+
+	if credential.LastUsedAt == nil || time.Since(*credential.LastUsedAt) > lastSeenResolution {
+		err = s.deviceCredentialRepo.TouchDeviceCredential(&model.DeviceCredential{ID: credential.ID})
+		if err != nil {
+			logger.Warn("deviceService", "Failed to update device last used", map[string]string{
+				"key_id": input.KeyId,
+				"error":  err.Error(),
+			})
+		}
+	}
+
+	return &dto.DeviceAuth{
+		CredentialID: credential.ID,
+		KeyId:        credential.KeyId,
+		SystemUnitID: credential.SystemUnitId,
+		FarmID:       credential.FarmId,
+		UnitKey:      credential.UnitKey,
+	}, nil
+}
+
+// AuthenticateUnit checks the secret a device presents with its unit key on
+// transports that cannot sign each message, such as an MQTT connection.
+func (s *deviceService) AuthenticateUnit(unitKey uuid.UUID, secret string) (*dto.DeviceAuth, error) {
+	if secret == "" {
+		return nil, errs.InvalidDeviceSecret
+	}
+
+	credentials, err := s.deviceCredentialRepo.GetActiveDeviceCredentialsByUnitKey(unitKey)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, credential := range credentials {
+		expected, err := s.secretCipher.Decrypt(credential.SecretCiphertext)
+		if err != nil {
+			logger.Error("deviceService", "Failed to decrypt device secret", map[string]string{
+				"key_id": credential.KeyId,
+				"error":  err.Error(),
+			})
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(secret)) != 1 {
+			continue
+		}
+
+		if credential.LastUsedAt == nil || time.Since(*credential.LastUsedAt) > lastSeenResolution {
+			err = s.deviceCredentialRepo.TouchDeviceCredential(&model.DeviceCredential{ID: credential.ID})
+			if err != nil {
+				logger.Warn("deviceService", "Failed to update device last used", map[string]string{
+					"key_id": credential.KeyId,
+					"error":  err.Error(),
+				})
+			}
+		}
+
+		return &dto.DeviceAuth{
+			CredentialID: credential.ID,
+			KeyId:        credential.KeyId,
+			SystemUnitID: credential.SystemUnitId,
+			FarmID:       credential.FarmId,
+			UnitKey:      credential.UnitKey,
+		}, nil
+	}
+
+	logger.Warn("deviceService", "Device secret mismatch", map[string]string{
+		"unit_key": unitKey.String(),
+	})
+	return nil, errs.InvalidDeviceSecret
+}
+
+// CheckCredential reports whether the credential a device authenticated with
+// is still active, for connections that outlive a rotation or revocation.
+func (s *deviceService) CheckCredential(device *dto.DeviceAuth) error {
+	credential, err := s.deviceCredentialRepo.GetActiveDeviceCredential(device.KeyId)
+	if err != nil {
+		return errs.ErrorCheckingDeviceCredential
+	}
+	if credential == nil || credential.ID != device.CredentialID {
+		logger.Warn("deviceService", "Device credential no longer active", map[string]string{
+			"key_id": device.KeyId,
+		})
+		return errs.DeviceCredentialRevoked
+	}
+	return nil
+}
+
+func (s *deviceService) DeleteExpiredNonces() error {
+	deleted, err := s.deviceCredentialRepo.DeleteDeviceNoncesBefore(time.Now().Add(-deviceNonceRetention))
+	if err != nil {
+		return err
+	}
+
+	logger.Info("deviceService", "Expired device nonces deleted", map[string]string{
+		"count": strconv.FormatInt(deleted, 10),
+	})
+	return nil
+}
+
+func signDeviceRequest(secret string, input *dto.DeviceSignedRequest) []byte {
+	bodyHash := sha256.Sum256(input.Body)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(input.Timestamp + "\n" + input.Nonce + "\n" + input.Method + "\n" + input.Path + "\n" + hex.EncodeToString(bodyHash[:])))
+	return mac.Sum(nil)
+}
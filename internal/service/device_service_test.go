@@ -0,0 +1,230 @@
+package service
+
+import (
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/dto"
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/repository"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/secretcipher"
+	"github.com/google/uuid"
+)
+
+const (
+	testDeviceKeyId  = "dev_0123456789abcdef"
+	testDeviceSecret = "device-secret"
+)
+
+type fakeDeviceCredentialRepo struct {
+	repository.DeviceCredentialRepository
+	credential *model.DeviceCredentialJoined
+	nonces     map[string]bool
+}
+
+func (f *fakeDeviceCredentialRepo) GetActiveDeviceCredential(keyId string) (*model.DeviceCredentialJoined, error) {
+	if f.credential == nil || f.credential.KeyId != keyId {
+		return nil, errs.InvalidDeviceSignature
+	}
+	return f.credential, nil
+}
+
+func (f *fakeDeviceCredentialRepo) UseDeviceNonce(keyId string, nonce string) (bool, error) {
+	if f.nonces[keyId+"/"+nonce] {
+		return false, nil
+	}
+	f.nonces[keyId+"/"+nonce] = true
+	return true, nil
+}
+
+func (f *fakeDeviceCredentialRepo) TouchDeviceCredential(inputModel *model.DeviceCredential) error {
+	return nil
+}
+
+func newTestDeviceService(t *testing.T) (DeviceService, *fakeDeviceCredentialRepo) {
+	t.Helper()
+	cipher, err := secretcipher.NewAESGCM("device-service-test-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := cipher.Encrypt(testDeviceSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := &fakeDeviceCredentialRepo{
+		credential: &model.DeviceCredentialJoined{
+			ID:               uuid.New(),
+			SystemUnitId:     uuid.New(),
+			FarmId:           uuid.New(),
+			UnitKey:          uuid.New(),
+			KeyId:            testDeviceKeyId,
+			SecretCiphertext: ciphertext,
+		},
+		nonces: map[string]bool{},
+	}
+	return NewDeviceService(DeviceServiceConfig{DeviceCredentialRepo: repo, SecretCipher: cipher}), repo
+}
+
+// signedDeviceRequest is a growth-hist request signed with the test secret at
+// the given time.
+func signedDeviceRequest(at time.Time, nonce string) *dto.DeviceSignedRequest {
+	input := &dto.DeviceSignedRequest{
+		KeyId:     testDeviceKeyId,
+		Timestamp: strconv.FormatInt(at.Unix(), 10),
+		Nonce:     nonce,
+		Method:    "POST",
+		Path:      "/device/growth-hist",
+		Body:      []byte(`{"ppm":812.5,"ph":6.2}`),
+	}
+	input.Signature = hex.EncodeToString(signDeviceRequest(testDeviceSecret, input))
+	return input
+}
+
+func TestSignDeviceRequest(t *testing.T) {
+	// computed independently from the string to sign documented on
+	// Authenticate
+	input := &dto.DeviceSignedRequest{
+		Timestamp: "1700000000",
+		Nonce:     "nonce-0001",
+		Method:    "POST",
+		Path:      "/device/growth-hist",
+		Body:      []byte(`{"ppm":812.5,"ph":6.2}`),
+	}
+	want := "edef9cfe43060d9db10c829a2be46b4d414e7bb77ec62a9ae2927b22ac988c8a"
+
+	if got := hex.EncodeToString(signDeviceRequest(testDeviceSecret, input)); got != want {
+		t.Errorf("signDeviceRequest = %s, want %s", got, want)
+	}
+}
+
+func TestDeviceAuthenticate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(input *dto.DeviceSignedRequest)
+		wantErr error
+	}{
+		{
+			name:   "valid",
+			modify: func(input *dto.DeviceSignedRequest) {},
+		},
+		{
+			name: "expired timestamp",
+			modify: func(input *dto.DeviceSignedRequest) {
+				*input = *signedDeviceRequest(time.Now().Add(-deviceSignatureTolerance-time.Minute), input.Nonce)
+			},
+			wantErr: errs.DeviceRequestExpired,
+		},
+		{
+			name: "future timestamp",
+			modify: func(input *dto.DeviceSignedRequest) {
+				*input = *signedDeviceRequest(time.Now().Add(deviceSignatureTolerance+time.Minute), input.Nonce)
+			},
+			wantErr: errs.DeviceRequestExpired,
+		},
+		{
+			name:    "timestamp not in unix seconds",
+			modify:  func(input *dto.DeviceSignedRequest) { input.Timestamp = time.Now().Format(time.RFC3339) },
+			wantErr: errs.InvalidDeviceSignature,
+		},
+		{
+			name:    "timestamp changed after signing",
+			modify:  func(input *dto.DeviceSignedRequest) { input.Timestamp = strconv.FormatInt(time.Now().Unix()+1, 10) },
+			wantErr: errs.InvalidDeviceSignature,
+		},
+		{
+			name:    "tampered body",
+			modify:  func(input *dto.DeviceSignedRequest) { input.Body = []byte(`{"ppm":812.5,"ph":7.2}`) },
+			wantErr: errs.InvalidDeviceSignature,
+		},
+		{
+			name:    "tampered path",
+			modify:  func(input *dto.DeviceSignedRequest) { input.Path = "/device/tank-trans" },
+			wantErr: errs.InvalidDeviceSignature,
+		},
+		{
+			name:    "tampered method",
+			modify:  func(input *dto.DeviceSignedRequest) { input.Method = "PUT" },
+			wantErr: errs.InvalidDeviceSignature,
+		},
+		{
+			name:    "signature not hex",
+			modify:  func(input *dto.DeviceSignedRequest) { input.Signature = "zz" + input.Signature[2:] },
+			wantErr: errs.InvalidDeviceSignature,
+		},
+		{
+			name:    "truncated signature",
+			modify:  func(input *dto.DeviceSignedRequest) { input.Signature = input.Signature[:32] },
+			wantErr: errs.InvalidDeviceSignature,
+		},
+		{
+			name:    "upper case key id",
+			modify:  func(input *dto.DeviceSignedRequest) { input.KeyId = strings.ToUpper(input.KeyId) },
+			wantErr: errs.InvalidDeviceSignature,
+		},
+		{
+			name:    "missing nonce",
+			modify:  func(input *dto.DeviceSignedRequest) { *input = *signedDeviceRequest(time.Now(), "") },
+			wantErr: errs.InvalidDeviceSignature,
+		},
+		{
+			name: "nonce too long",
+			modify: func(input *dto.DeviceSignedRequest) {
+				*input = *signedDeviceRequest(time.Now(), strings.Repeat("n", maxDeviceNonceLength+1))
+			},
+			wantErr: errs.InvalidDeviceSignature,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, repo := newTestDeviceService(t)
+
+			input := signedDeviceRequest(time.Now(), "nonce-0001")
+			tt.modify(input)
+
+			device, err := service.Authenticate(input)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Authenticate error = %v, want %v", err, tt.wantErr)
+				}
+				if len(repo.nonces) != 0 {
+					t.Errorf("rejected request stored its nonce")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			credential := repo.credential
+			if device.CredentialID != credential.ID || device.SystemUnitID != credential.SystemUnitId ||
+				device.FarmID != credential.FarmId || device.UnitKey != credential.UnitKey {
+				t.Errorf("device = %+v, want the credential %+v", device, credential)
+			}
+		})
+	}
+}
+
+func TestDeviceAuthenticateReusedNonce(t *testing.T) {
+	service, _ := newTestDeviceService(t)
+
+	input := signedDeviceRequest(time.Now(), "nonce-0001")
+	_, err := service.Authenticate(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = service.Authenticate(input)
+	if !errors.Is(err, errs.DeviceNonceReused) {
+		t.Errorf("replayed request error = %v, want %v", err, errs.DeviceNonceReused)
+	}
+
+	_, err = service.Authenticate(signedDeviceRequest(time.Now(), "nonce-0002"))
+	if err != nil {
+		t.Errorf("request with a new nonce: %v", err)
+	}
+}
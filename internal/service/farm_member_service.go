@@ -0,0 +1,285 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/dto"
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/repository"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/hasher"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/mailer"
+	"github.com/google/uuid"
+)
+
+type FarmMemberService interface {
+	GetMembers(caller *dto.Caller, farmId *uuid.UUID) ([]*dto.FarmMemberResponse, error)
+	UpdateMemberRole(caller *dto.Caller, farmId *uuid.UUID, accountId *uuid.UUID, input *dto.UpdateFarmMemberBody) (*dto.FarmMemberResponse, error)
+	RemoveMember(caller *dto.Caller, farmId *uuid.UUID, accountId *uuid.UUID) (*dto.FarmMemberResponse, error)
+	InviteMember(caller *dto.Caller, farmId *uuid.UUID, input *dto.InviteFarmMemberBody) (*dto.FarmInvitationResponse, error)
+	GetInvitations(caller *dto.Caller, farmId *uuid.UUID) ([]*dto.FarmInvitationResponse, error)
+	RevokeInvitation(caller *dto.Caller, farmId *uuid.UUID, invitationId *uuid.UUID) error
+	CheckInvitation(token string, email string) (*uuid.UUID, error)
+	AcceptInvitation(caller *dto.Caller, input *dto.AcceptFarmInvitationBody) (*dto.FarmMemberResponse, error)
+}
+
+type farmMemberService struct {
+	farmMemberRepo repository.FarmMemberRepository
+	farmRepo       repository.FarmRepository
+	mailer         mailer.Mailer
+	frontEndBase   string
+	invitationTTL  time.Duration
+}
+
+type FarmMemberServiceConfig struct {
+	FarmMemberRepo repository.FarmMemberRepository
+	FarmRepo       repository.FarmRepository
+	Mailer         mailer.Mailer
+	FrontEndBase   string
+	InvitationTTL  time.Duration
+}
+
+func NewFarmMemberService(config FarmMemberServiceConfig) FarmMemberService {
+	return &farmMemberService{
+		farmMemberRepo: config.FarmMemberRepo,
+		farmRepo:       config.FarmRepo,
+		mailer:         config.Mailer,
+		frontEndBase:   config.FrontEndBase,
+		invitationTTL:  config.InvitationTTL,
+	}
+}
+
+func (s *farmMemberService) GetMembers(caller *dto.Caller, farmId *uuid.UUID) ([]*dto.FarmMemberResponse, error) {
+	_, err := authorizeFarm(s.farmMemberRepo, caller, *farmId, farmReaders)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.farmMemberRepo.GetFarmMembers(*farmId)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]*dto.FarmMemberResponse, 0, len(members))
+	for _, member := range members {
+		resp = append(resp, &dto.FarmMemberResponse{
+			FarmID:    member.FarmId,
+			AccountID: member.AccountId,
+			Username:  member.Username,
+			Email:     member.Email,
+			Role:      member.Role,
+			CreatedAt: member.CreatedAt,
+		})
+	}
+
+	logger.Info("farmMemberService", "Fetched farm members", map[string]string{
+		"farm_id": farmId.String(),
+		"count":   strconv.Itoa(len(resp)),
+	})
+	return resp, nil
+}
+
+func (s *farmMemberService) UpdateMemberRole(caller *dto.Caller, farmId *uuid.UUID, accountId *uuid.UUID, input *dto.UpdateFarmMemberBody) (*dto.FarmMemberResponse, error) {
+	_, err := authorizeFarm(s.farmMemberRepo, caller, *farmId, farmOwners)
+	if err != nil {
+		return nil, err
+	}
+
+	member, err := s.farmMemberRepo.UpdateFarmMemberRole(&model.FarmMember{
+		FarmId:    *farmId,
+		AccountId: *accountId,
+		Role:      input.Role,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("farmMemberService", "Farm member role changed", map[string]string{
+		"farm_id":    farmId.String(),
+		"account_id": accountId.String(),
+		"role":       member.Role,
+		"changed_by": caller.AccountID.String(),
+	})
+	return toFarmMemberResponse(member), nil
+}
+
+// RemoveMember lets owners remove any member and every member leave a farm
+// on its own.
+func (s *farmMemberService) RemoveMember(caller *dto.Caller, farmId *uuid.UUID, accountId *uuid.UUID) (*dto.FarmMemberResponse, error) {
+	roles := farmOwners
+	if *accountId == caller.AccountID {
+		if caller.ReadOnly {
+			return nil, errs.ForbiddenAccess
+		}
+		roles = farmReaders
+	}
+
+	_, err := authorizeFarm(s.farmMemberRepo, caller, *farmId, roles)
+	if err != nil {
+		return nil, err
+	}
+
+	member, err := s.farmMemberRepo.DeleteFarmMember(&model.FarmMember{
+		FarmId:    *farmId,
+		AccountId: *accountId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("farmMemberService", "Farm member removed", map[string]string{
+		"farm_id":    farmId.String(),
+		"account_id": accountId.String(),
+		"removed_by": caller.AccountID.String(),
+	})
+	return toFarmMemberResponse(member), nil
+}
+
+// InviteMember mails a single use invitation link to the given address. The
+// invitation can be accepted by an existing account or while registering.
+func (s *farmMemberService) InviteMember(caller *dto.Caller, farmId *uuid.UUID, input *dto.InviteFarmMemberBody) (*dto.FarmInvitationResponse, error) {
+	_, err := authorizeFarm(s.farmMemberRepo, caller, *farmId, farmOwners)
+	if err != nil {
+		return nil, err
+	}
+
+	farm, err := s.farmRepo.GetFarmById(&model.Farm{ID: *farmId})
+	if err != nil {
+		return nil, errs.InvalidFarmID
+	}
+
+	rawToken, err := generateOpaqueToken()
+	if err != nil {
+		return nil, errs.ErrorInvitingFarmMember
+	}
+
+	invitation, err := s.farmMemberRepo.CreateFarmInvitation(&model.FarmInvitation{
+		FarmId:    *farmId,
+		Email:     strings.ToLower(strings.TrimSpace(input.Email)),
+		Role:      input.Role,
+		TokenHash: hasher.TokenDigest(rawToken),
+		InvitedBy: caller.AccountID,
+		ExpiresAt: time.Now().Add(s.invitationTTL),
+	})
+	if err != nil {
+		return nil, errs.ErrorInvitingFarmMember
+	}
+
+	err = s.mailer.Send(&mailer.Message{
+		To:      invitation.Email,
+		Subject: "You have been invited to " + farm.Name,
+		Body: fmt.Sprintf("Hi,\n\nYou have been invited to join the farm %s as %s. Use the link below to accept with your account or to create one. It expires in %s and can only be used once.\n\n%s/farm-invitation?token=%s\n\nIf you were not expecting this, you can ignore this email.\n",
+			farm.Name, invitation.Role, s.invitationTTL.String(), s.frontEndBase, rawToken),
+	})
+	if err != nil {
+		logger.Error("farmMemberService", "Failed to send farm invitation email", map[string]string{
+			"invitation_id": invitation.ID.String(),
+			"error":         err.Error(),
+		})
+		return nil, errs.ErrorInvitingFarmMember
+	}
+
+	logger.Info("farmMemberService", "Farm invitation sent", map[string]string{
+		"farm_id":       farmId.String(),
+		"invitation_id": invitation.ID.String(),
+		"role":          invitation.Role,
+	})
+	return toFarmInvitationResponse(invitation), nil
+}
+
+func (s *farmMemberService) GetInvitations(caller *dto.Caller, farmId *uuid.UUID) ([]*dto.FarmInvitationResponse, error) {
+	_, err := authorizeFarm(s.farmMemberRepo, caller, *farmId, farmOwners)
+	if err != nil {
+		return nil, err
+	}
+
+	invitations, err := s.farmMemberRepo.GetPendingFarmInvitations(*farmId)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]*dto.FarmInvitationResponse, 0, len(invitations))
+	for _, invitation := range invitations {
+		resp = append(resp, toFarmInvitationResponse(invitation))
+	}
+	return resp, nil
+}
+
+func (s *farmMemberService) RevokeInvitation(caller *dto.Caller, farmId *uuid.UUID, invitationId *uuid.UUID) error {
+	_, err := authorizeFarm(s.farmMemberRepo, caller, *farmId, farmOwners)
+	if err != nil {
+		return err
+	}
+
+	err = s.farmMemberRepo.RevokeFarmInvitation(&model.FarmInvitation{ID: *invitationId, FarmId: *farmId})
+	if err != nil {
+		return err
+	}
+
+	logger.Info("farmMemberService", "Farm invitation revoked", map[string]string{
+		"farm_id":       farmId.String(),
+		"invitation_id": invitationId.String(),
+	})
+	return nil
+}
+
+// CheckInvitation reports whether a token belongs to an invitation that can
+// still be accepted by an account with email, so registration can fail before
+// creating the account. It returns the organization of the invited farm, which
+// the new account joins.
+func (s *farmMemberService) CheckInvitation(token string, email string) (*uuid.UUID, error) {
+	invitation, err := s.farmMemberRepo.GetActiveFarmInvitation(hasher.TokenDigest(token))
+	if err != nil || invitation == nil {
+		return nil, errs.InvalidFarmInvitation
+	}
+	if !strings.EqualFold(invitation.Email, email) {
+		return nil, errs.InvalidFarmInvitation
+	}
+
+	farm, err := s.farmRepo.GetFarmById(&model.Farm{ID: invitation.FarmId})
+	if err != nil {
+		return nil, errs.InvalidFarmInvitation
+	}
+
+	return &farm.OrganizationId, nil
+}
+
+// AcceptInvitation joins a farm of the caller's organization. Farms of other
+// organizations are out of reach, their invitations are reported as invalid.
+func (s *farmMemberService) AcceptInvitation(caller *dto.Caller, input *dto.AcceptFarmInvitationBody) (*dto.FarmMemberResponse, error) {
+	member, err := s.farmMemberRepo.AcceptFarmInvitation(hasher.TokenDigest(input.Token), scopeOf(caller))
+	if err != nil {
+		return nil, errs.InvalidFarmInvitation
+	}
+
+	logger.Info("farmMemberService", "Farm invitation accepted", map[string]string{
+		"farm_id":    member.FarmId.String(),
+		"account_id": caller.AccountID.String(),
+		"role":       member.Role,
+	})
+	return toFarmMemberResponse(member), nil
+}
+
+func toFarmMemberResponse(member *model.FarmMember) *dto.FarmMemberResponse {
+	return &dto.FarmMemberResponse{
+		FarmID:    member.FarmId,
+		AccountID: member.AccountId,
+		Role:      member.Role,
+		CreatedAt: member.CreatedAt,
+	}
+}
+
+func toFarmInvitationResponse(invitation *model.FarmInvitation) *dto.FarmInvitationResponse {
+	return &dto.FarmInvitationResponse{
+		ID:        invitation.ID,
+		FarmID:    invitation.FarmId,
+		Email:     invitation.Email,
+		Role:      invitation.Role,
+		ExpiresAt: invitation.ExpiresAt,
+		CreatedAt: invitation.CreatedAt,
+	}
+}
@@ -12,41 +12,48 @@ import (
 )
 
 type FarmService interface {
-	CreateFarm(input *dto.CreateFarm) (*dto.FarmResponse, error)
-	GetFarms() ([]*dto.FarmResponse, error)
-	GetFarmDetails(farmId *uuid.UUID) (*dto.FarmResponse, error)
-	UpdateFarm(farmId *uuid.UUID, farmData *dto.UpdateFarm) (*dto.FarmResponse, error)
-	DeleteFarm(farmId *uuid.UUID) (*dto.FarmResponse, error)
+	CreateFarm(caller *dto.Caller, input *dto.CreateFarm) (*dto.FarmResponse, error)
+	GetFarms(caller *dto.Caller) ([]*dto.FarmResponse, error)
+	GetFarmDetails(caller *dto.Caller, farmId *uuid.UUID) (*dto.FarmResponse, error)
+	UpdateFarm(caller *dto.Caller, farmId *uuid.UUID, farmData *dto.UpdateFarm) (*dto.FarmResponse, error)
+	DeleteFarm(caller *dto.Caller, farmId *uuid.UUID) (*dto.FarmResponse, error)
 }
 
 type farmService struct {
-	farmRepo    repository.FarmRepository
-	profileRepo repository.ProfileRepository
+	farmRepo       repository.FarmRepository
+	farmMemberRepo repository.FarmMemberRepository
+	profileRepo    repository.ProfileRepository
 }
 
 type FarmServiceConfig struct {
-	FarmRepo    repository.FarmRepository
-	ProfileRepo repository.ProfileRepository
+	FarmRepo       repository.FarmRepository
+	FarmMemberRepo repository.FarmMemberRepository
+	ProfileRepo    repository.ProfileRepository
 }
 
 func NewFarmService(config FarmServiceConfig) FarmService {
 	return &farmService{
-		farmRepo:    config.FarmRepo,
-		profileRepo: config.ProfileRepo,
+		farmRepo:       config.FarmRepo,
+		farmMemberRepo: config.FarmMemberRepo,
+		profileRepo:    config.ProfileRepo,
 	}
 }
 
-func (s *farmService) CreateFarm(input *dto.CreateFarm) (*dto.FarmResponse, error) {
+func (s *farmService) CreateFarm(caller *dto.Caller, input *dto.CreateFarm) (*dto.FarmResponse, error) {
 	logger.Info("farmService", "Creating new farm", map[string]string{
 		"profile_id": input.ProfileID.String(),
 		"name":       input.Name,
 	})
 
-	profile, err := s.profileRepo.GetProfileById(&model.Profile{ID: input.ProfileID})
+	// A farm scoped token cannot widen its own scope by creating farms.
+	if caller.FarmIds != nil {
+		return nil, errs.ForbiddenAccess
+	}
+
+	profile, err := s.profileRepo.GetProfileById(&model.Profile{ID: input.ProfileID, AccountId: caller.AccountID, OrganizationId: caller.OrganizationID})
 	if err != nil || profile == nil {
 		logger.Error("farmService", "Invalid profile ID", map[string]string{
 			"profile_id": input.ProfileID.String(),
-			"error":      err.Error(),
 		})
 		return nil, errs.InvalidProfileID
 	}
@@ -74,10 +81,12 @@ func (s *farmService) CreateFarm(input *dto.CreateFarm) (*dto.FarmResponse, erro
 	}, nil
 }
 
-func (s *farmService) GetFarms() ([]*dto.FarmResponse, error) {
-	logger.Info("farmService", "Fetching all farms", nil)
+func (s *farmService) GetFarms(caller *dto.Caller) ([]*dto.FarmResponse, error) {
+	logger.Info("farmService", "Fetching farms of caller", map[string]string{
+		"account_id": caller.AccountID.String(),
+	})
 
-	res, err := s.farmRepo.GetFarms()
+	res, err := s.farmRepo.GetFarmsByScope(scopeOf(caller))
 	if err != nil {
 		logger.Error("farmService", "Failed to fetch farms", map[string]string{
 			"error": err.Error(),
@@ -87,6 +96,9 @@ func (s *farmService) GetFarms() ([]*dto.FarmResponse, error) {
 
 	var farmResponse []*dto.FarmResponse
 	for _, farm := range res {
+		if !caller.CanAccessFarm(farm.ID) {
+			continue
+		}
 		farmResponse = append(farmResponse, &dto.FarmResponse{
 			ID:      farm.ID,
 			Name:    farm.Name,
@@ -100,12 +112,17 @@ func (s *farmService) GetFarms() ([]*dto.FarmResponse, error) {
 	return farmResponse, nil
 }
 
-func (s *farmService) GetFarmDetails(farmId *uuid.UUID) (*dto.FarmResponse, error) {
+func (s *farmService) GetFarmDetails(caller *dto.Caller, farmId *uuid.UUID) (*dto.FarmResponse, error) {
 	logger.Info("farmService", "Fetching farm details", map[string]string{
 		"farm_id": farmId.String(),
 	})
 
-	res, err := s.farmRepo.GetFarmById(&model.Farm{ID: *farmId})
+	_, err := authorizeFarm(s.farmMemberRepo, caller, *farmId, farmReaders)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.farmRepo.GetFarmByIdAndScope(&model.Farm{ID: *farmId}, scopeOf(caller))
 	if err != nil {
 		logger.Error("farmService", "Failed to fetch farm details", map[string]string{
 			"farm_id": farmId.String(),
@@ -125,13 +142,18 @@ func (s *farmService) GetFarmDetails(farmId *uuid.UUID) (*dto.FarmResponse, erro
 	}, nil
 }
 
-func (s *farmService) UpdateFarm(farmId *uuid.UUID, farmData *dto.UpdateFarm) (*dto.FarmResponse, error) {
+func (s *farmService) UpdateFarm(caller *dto.Caller, farmId *uuid.UUID, farmData *dto.UpdateFarm) (*dto.FarmResponse, error) {
 	logger.Info("farmService", "Updating farm", map[string]string{
 		"farm_id":  farmId.String(),
 		"new_name": farmData.Name,
 	})
 
-	res, err := s.farmRepo.UpdateFarm(&model.Farm{ID: *farmId, Name: farmData.Name, Address: farmData.Address})
+	_, err := authorizeFarm(s.farmMemberRepo, caller, *farmId, farmOwners)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.farmRepo.UpdateFarm(&model.Farm{ID: *farmId, Name: farmData.Name, Address: farmData.Address}, scopeOf(caller))
 	if err != nil {
 		logger.Error("farmService", "Failed to update farm", map[string]string{
 			"farm_id": farmId.String(),
@@ -151,12 +173,17 @@ func (s *farmService) UpdateFarm(farmId *uuid.UUID, farmData *dto.UpdateFarm) (*
 	}, nil
 }
 
-func (s *farmService) DeleteFarm(farmId *uuid.UUID) (*dto.FarmResponse, error) {
+func (s *farmService) DeleteFarm(caller *dto.Caller, farmId *uuid.UUID) (*dto.FarmResponse, error) {
 	logger.Info("farmService", "Deleting farm", map[string]string{
 		"farm_id": farmId.String(),
 	})
 
-	res, err := s.farmRepo.DeleteFarm(&model.Farm{ID: *farmId})
+	_, err := authorizeFarm(s.farmMemberRepo, caller, *farmId, farmOwners)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.farmRepo.DeleteFarm(&model.Farm{ID: *farmId}, scopeOf(caller))
 	if err != nil {
 		logger.Error("farmService", "Failed to delete farm", map[string]string{
 			"farm_id": farmId.String(),
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"encoding/json"
 	"fmt"
 	"math"
 	"math/rand"
@@ -10,6 +11,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/constant"
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/dto"
 	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
@@ -18,122 +20,465 @@ import (
 	"github.com/google/uuid"
 )
 
+// Clock skew policy modes, applied to device timestamps outside the allowed
+// window: reject the sample, clamp the timestamp to the window edge, or keep
+// it and flag the readings.
+const (
+	SkewPolicyReject = "reject"
+	SkewPolicyClamp  = "clamp"
+	SkewPolicyFlag   = "flag"
+)
+
+// ClockSkewPolicy bounds how far a device measured_at may lie ahead of or
+// behind the server clock. Gateways that buffered readings during an outage
+// need a generous MaxPast.
+type ClockSkewPolicy struct {
+	Mode      string
+	MaxFuture time.Duration
+	MaxPast   time.Duration
+}
+
+func DefaultClockSkewPolicy() ClockSkewPolicy {
+	return ClockSkewPolicy{
+		Mode:      SkewPolicyFlag,
+		MaxFuture: 5 * time.Minute,
+		MaxPast:   7 * 24 * time.Hour,
+	}
+}
+
+// apply returns the measured time to store for a sample received at
+// receivedAt, and whether it was out of bounds. Samples without a device
+// timestamp are measured when received.
+func (p ClockSkewPolicy) apply(measuredAt *time.Time, receivedAt time.Time) (time.Time, bool, error) {
+	if measuredAt == nil {
+		return receivedAt, false, nil
+	}
+
+	earliest := receivedAt.Add(-p.MaxPast)
+	latest := receivedAt.Add(p.MaxFuture)
+	if !measuredAt.Before(earliest) && !measuredAt.After(latest) {
+		return *measuredAt, false, nil
+	}
+
+	switch p.Mode {
+	case SkewPolicyClamp:
+		if measuredAt.Before(earliest) {
+			return earliest, true, nil
+		}
+		return latest, true, nil
+	case SkewPolicyFlag:
+		return *measuredAt, true, nil
+	default:
+		return time.Time{}, false, errs.MeasuredAtOutOfRange
+	}
+}
+
+// GrowthHistService stores and queries readings of any metric in the catalog.
+// The GrowthHist methods are the original ppm and pH API, kept on top of the
+// generic readings.
 type GrowthHistService interface {
-	CreateGrowthHist(input *dto.GrowthHist) (*dto.GrowthHistResponse, error)
-	GenerateDummyData(input *dto.GrowthHistDummyDataBody) (*dto.GrowthHistResponse, error)
-	GetGrowthHistAggregationByFilter(getGrowthFilterBody *dto.GetGrowthFilter) (*dto.GetGrowthAggregationResp, error)
-	GetGrowthHistByFilter(getGrowthFilterBody *dto.GetGrowthFilter) (*dto.GetGrowthDataResp, error)
+	GetMetrics() ([]*model.Metric, error)
+	CreateReadings(caller *dto.Caller, input *dto.CreateReadingsBody) (*dto.ReadingsResponse, error)
+	CreateDeviceReadings(device *dto.DeviceAuth, input *dto.DeviceReadingsBody) (*dto.ReadingsResponse, error)
+	GetReadingAggregationByFilter(caller *dto.Caller, getGrowthFilterBody *dto.GetGrowthFilter) (*dto.GetReadingAggregationResp, error)
+	GetReadingsByFilter(caller *dto.Caller, getGrowthFilterBody *dto.GetGrowthFilter) (*dto.GetReadingDataResp, error)
+	CreateGrowthHist(caller *dto.Caller, input *dto.GrowthHist) (*dto.GrowthHistResponse, error)
+	CreateDeviceGrowthHist(device *dto.DeviceAuth, input *dto.DeviceGrowthHist) (*dto.GrowthHistResponse, error)
+	GenerateDummyData(caller *dto.Caller, input *dto.GrowthHistDummyDataBody) (*dto.GrowthHistResponse, error)
+	GetGrowthHistAggregationByFilter(caller *dto.Caller, getGrowthFilterBody *dto.GetGrowthFilter) (*dto.GetGrowthAggregationResp, error)
+	GetGrowthHistByFilter(caller *dto.Caller, getGrowthFilterBody *dto.GetGrowthFilter) (*dto.GetGrowthDataResp, error)
 }
 
 type growthHistService struct {
-	growthHistRepo  repository.GrowthHistRepository
-	farmRepo        repository.FarmRepository
-	systemUnitRepo  repository.SystemUnitRepository
-	aggregationRepo repository.AggregationRepository
+	growthHistRepo    repository.GrowthHistRepository
+	ingestReceiptRepo repository.IngestReceiptRepository
+	metricRepo        repository.MetricRepository
+	farmMemberRepo    repository.FarmMemberRepository
+	systemUnitRepo    repository.SystemUnitRepository
+	aggregationRepo   repository.AggregationRepository
+	readingQueue      ReadingQueue
+	skewPolicy        ClockSkewPolicy
 }
 
 type GrowthHistServiceConfig struct {
-	GrowthHistRepo  repository.GrowthHistRepository
-	FarmRepo        repository.FarmRepository
-	SystemUnitRepo  repository.SystemUnitRepository
-	AggregationRepo repository.AggregationRepository
+	GrowthHistRepo    repository.GrowthHistRepository
+	IngestReceiptRepo repository.IngestReceiptRepository
+	MetricRepo        repository.MetricRepository
+	FarmMemberRepo    repository.FarmMemberRepository
+	SystemUnitRepo    repository.SystemUnitRepository
+	AggregationRepo   repository.AggregationRepository
+	// ReadingQueue batches sample writes; without it samples are stored
+	// before the response.
+	ReadingQueue ReadingQueue
+	SkewPolicy   ClockSkewPolicy
 }
 
 func NewGrowthHistService(config GrowthHistServiceConfig) GrowthHistService {
 	return &growthHistService{
-		growthHistRepo:  config.GrowthHistRepo,
-		farmRepo:        config.FarmRepo,
-		systemUnitRepo:  config.SystemUnitRepo,
-		aggregationRepo: config.AggregationRepo,
+		growthHistRepo:    config.GrowthHistRepo,
+		ingestReceiptRepo: config.IngestReceiptRepo,
+		metricRepo:        config.MetricRepo,
+		farmMemberRepo:    config.FarmMemberRepo,
+		systemUnitRepo:    config.SystemUnitRepo,
+		aggregationRepo:   config.AggregationRepo,
+		readingQueue:      config.ReadingQueue,
+		skewPolicy:        config.SkewPolicy,
 	}
 }
 
-func (s *growthHistService) CreateGrowthHist(input *dto.GrowthHist) (*dto.GrowthHistResponse, error) {
-	logger.Info("growthHistService", "Creating Growth History", map[string]string{
+// legacyMetrics are the metrics the growth history endpoints expose.
+var legacyMetrics = []string{constant.MetricPpm, constant.MetricPh}
+
+func (s *growthHistService) GetMetrics() ([]*model.Metric, error) {
+	metrics, err := s.metricRepo.GetMetrics()
+	if err != nil {
+		return nil, errs.ErrorOnGettingMetrics
+	}
+	return metrics, nil
+}
+
+func (s *growthHistService) CreateReadings(caller *dto.Caller, input *dto.CreateReadingsBody) (*dto.ReadingsResponse, error) {
+	logger.Info("growthHistService", "Creating readings", map[string]string{
 		"farmId":   input.FarmId.String(),
 		"systemId": input.SystemId.String(),
 	})
 
-	farm, err := s.farmRepo.GetFarmById(&model.Farm{
-		ID: input.FarmId,
-	})
-	if err != nil || farm == nil {
-		logger.Error("growthHistService", "Invalid Farm ID", map[string]string{
+	_, _, err := resolveSystemUnit(s.farmMemberRepo, s.systemUnitRepo, caller, input.FarmId, input.SystemId, farmWriters)
+	if err != nil {
+		logger.Error("growthHistService", "Farm or system unit not accessible", map[string]string{
 			"error": err.Error(),
 		})
-		return nil, errs.InvalidFarmID
+		return nil, err
 	}
 
-	systemUnit, err := s.systemUnitRepo.GetSystemUnitById(&model.SystemUnit{
-		ID: input.SystemId,
+	receipt := newIngestReceipt(input.SystemId, receiptKindReadings, input.IngestKey, input)
+	return s.createReadings(input.FarmId, input.SystemId, input.MeasuredAt, input.Readings, receipt)
+}
+
+// CreateDeviceReadings stores a sample sent by a signed device request. The
+// farm and system unit come from the device credential, not the body.
+func (s *growthHistService) CreateDeviceReadings(device *dto.DeviceAuth, input *dto.DeviceReadingsBody) (*dto.ReadingsResponse, error) {
+	logger.Info("growthHistService", "Creating readings from device", map[string]string{
+		"keyId":    device.KeyId,
+		"systemId": device.SystemUnitID.String(),
 	})
-	if err != nil || systemUnit == nil {
-		logger.Error("growthHistService", "Invalid System Unit ID", map[string]string{
+
+	receipt := newIngestReceipt(device.SystemUnitID, receiptKindReadings, input.IngestKey, input)
+	return s.createReadings(device.FarmID, device.SystemUnitID, input.MeasuredAt, input.Readings, receipt)
+}
+
+func (s *growthHistService) CreateGrowthHist(caller *dto.Caller, input *dto.GrowthHist) (*dto.GrowthHistResponse, error) {
+	logger.Info("growthHistService", "Creating Growth History", map[string]string{
+		"farmId":   input.FarmId.String(),
+		"systemId": input.SystemId.String(),
+	})
+
+	_, _, err := resolveSystemUnit(s.farmMemberRepo, s.systemUnitRepo, caller, input.FarmId, input.SystemId, farmWriters)
+	if err != nil {
+		logger.Error("growthHistService", "Farm or system unit not accessible", map[string]string{
 			"error": err.Error(),
 		})
-		return nil, errs.InvalidSystemUnitID
+		return nil, err
 	}
 
-	growthHist, err := s.growthHistRepo.CreateGrowthHistory(&model.GrowthHist{
-		FarmId:   input.FarmId,
-		SystemId: input.SystemId,
-		Ppm:      input.Ppm,
-		Ph:       input.Ph,
+	receipt := newIngestReceipt(input.SystemId, receiptKindReadings, input.IngestKey, input)
+	resp, err := s.createReadings(input.FarmId, input.SystemId, input.MeasuredAt, map[string]float64{
+		constant.MetricPpm: input.Ppm,
+		constant.MetricPh:  input.Ph,
+	}, receipt)
+	if err != nil {
+		return nil, err
+	}
+	return growthHistResponse(resp), nil
+}
+
+// CreateDeviceGrowthHist stores a reading sent by a signed device request. The
+// farm and system unit come from the device credential, not the body.
+func (s *growthHistService) CreateDeviceGrowthHist(device *dto.DeviceAuth, input *dto.DeviceGrowthHist) (*dto.GrowthHistResponse, error) {
+	logger.Info("growthHistService", "Creating Growth History from device", map[string]string{
+		"keyId":    device.KeyId,
+		"systemId": device.SystemUnitID.String(),
 	})
+
+	receipt := newIngestReceipt(device.SystemUnitID, receiptKindReadings, input.IngestKey, input)
+	resp, err := s.createReadings(device.FarmID, device.SystemUnitID, input.MeasuredAt, map[string]float64{
+		constant.MetricPpm: input.Ppm,
+		constant.MetricPh:  input.Ph,
+	}, receipt)
 	if err != nil {
-		logger.Error("growthHistService", "Error creating new Growth History", map[string]string{
+		return nil, err
+	}
+	return growthHistResponse(resp), nil
+}
+
+// createReadings stores values as one sample after checking them against the
+// metric catalog and the clock skew policy. A retry of a sample stored with a
+// receipt gets the original response, before any check that may have changed
+// since.
+func (s *growthHistService) createReadings(farmId uuid.UUID, systemId uuid.UUID, measuredAt *time.Time, values map[string]float64, receipt *model.IngestReceipt) (*dto.ReadingsResponse, error) {
+	if receipt != nil {
+		replay, err := s.replayReadings(receipt)
+		if err != nil || replay != nil {
+			return replay, err
+		}
+	}
+
+	err := s.validateReadings(values)
+	if err != nil {
+		return nil, err
+	}
+
+	receivedAt := time.Now()
+	sampleTime, skewFlagged, err := s.skewPolicy.apply(measuredAt, receivedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	sampleId := uuid.New()
+	readings := make([]*model.Reading, 0, len(values))
+	for metric, value := range values {
+		readings = append(readings, &model.Reading{
+			SampleId:    sampleId,
+			FarmId:      farmId,
+			SystemId:    systemId,
+			Metric:      metric,
+			Value:       value,
+			MeasuredAt:  sampleTime,
+			ReceivedAt:  receivedAt,
+			SkewFlagged: skewFlagged,
+		})
+	}
+
+	respBody := &dto.ReadingsResponse{
+		SampleId:    sampleId,
+		FarmId:      farmId,
+		SystemId:    systemId,
+		Readings:    values,
+		MeasuredAt:  sampleTime,
+		ReceivedAt:  receivedAt,
+		SkewFlagged: skewFlagged,
+	}
+	if receipt != nil {
+		receipt.Response, err = json.Marshal(respBody)
+		if err != nil {
+			return nil, errs.ErrorOnCreatingNewGrowthHist
+		}
+	}
+
+	if s.readingQueue != nil {
+		return s.queueReadings(respBody, readings, receipt)
+	}
+
+	_, err = s.growthHistRepo.CreateReadings(readings, receipt)
+	if err != nil {
+		logger.Error("growthHistService", "Error creating readings", map[string]string{
 			"error": err.Error(),
 		})
+		// a concurrent retry may have stored the same sample first
+		if receipt != nil {
+			replay, replayErr := s.replayReadings(receipt)
+			if replayErr != nil || replay != nil {
+				return replay, replayErr
+			}
+		}
 		return nil, errs.ErrorOnCreatingNewGrowthHist
 	}
 
-	respBody := &dto.GrowthHistResponse{
-		ID:       growthHist.ID,
-		FarmId:   growthHist.FarmId,
-		SystemId: growthHist.SystemId,
-		Ppm:      growthHist.Ppm,
-		Ph:       growthHist.Ph,
+	logger.Info("growthHistService", "Readings created successfully", map[string]string{
+		"sampleId": sampleId.String(),
+	})
+	return respBody, nil
+}
+
+// queueReadings hands a sample to the write queue. Its response is complete
+// before the readings are stored, so the request does not wait for the flush.
+func (s *growthHistService) queueReadings(respBody *dto.ReadingsResponse, readings []*model.Reading, receipt *model.IngestReceipt) (*dto.ReadingsResponse, error) {
+	pending, err := s.readingQueue.Enqueue(&model.ReadingSample{Readings: readings, Receipt: receipt})
+	if err != nil {
+		logger.Warn("growthHistService", "Readings not queued", map[string]string{
+			"sampleId": respBody.SampleId.String(),
+			"error":    err.Error(),
+		})
+		return nil, err
+	}
+
+	// a concurrent retry queued the same keys first
+	if len(pending) > 0 {
+		replay := &dto.ReadingsResponse{}
+		_, err := replayReceipt(pending, receipt, replay)
+		if err != nil {
+			return nil, err
+		}
+		return replay, nil
 	}
 
-	logger.Info("growthHistService", "Growth History created successfully", map[string]string{
-		"growthHistId": respBody.ID.String(),
+	logger.Info("growthHistService", "Readings queued successfully", map[string]string{
+		"sampleId": respBody.SampleId.String(),
 	})
 	return respBody, nil
 }
 
-func (s *growthHistService) GetGrowthHistAggregationByFilter(getGrowthFilterBody *dto.GetGrowthFilter) (*dto.GetGrowthAggregationResp, error) {
-	logger.Info("growthHistService", "Fetching Growth History Aggregation", map[string]string{
+// replayReadings returns the response of an earlier sample, queued or stored
+// with the same receipt keys, or nil for a new sample.
+func (s *growthHistService) replayReadings(receipt *model.IngestReceipt) (*dto.ReadingsResponse, error) {
+	replay := &dto.ReadingsResponse{}
+	var found bool
+	var err error
+	if s.readingQueue != nil {
+		found, err = replayReceipt(s.readingQueue.Pending(receipt), receipt, replay)
+	}
+	if err == nil && !found {
+		found, err = findReplay(s.ingestReceiptRepo, receipt, replay)
+	}
+	if err != nil || !found {
+		return nil, err
+	}
+	return replay, nil
+}
+
+func (s *growthHistService) validateReadings(values map[string]float64) error {
+	catalog, err := loadMetricCatalog(s.metricRepo)
+	if err != nil {
+		return err
+	}
+
+	return checkReadings(catalog, values)
+}
+
+func (s *growthHistService) validateMetricNames(names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	catalog, err := loadMetricCatalog(s.metricRepo)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if _, ok := catalog[name]; !ok {
+			return errs.InvalidMetric
+		}
+	}
+	return nil
+}
+
+// loadMetricCatalog returns the metric catalog keyed by name.
+func loadMetricCatalog(metricRepo repository.MetricRepository) (map[string]*model.Metric, error) {
+	metrics, err := metricRepo.GetMetrics()
+	if err != nil {
+		return nil, errs.ErrorOnGettingMetrics
+	}
+
+	catalog := make(map[string]*model.Metric, len(metrics))
+	for _, metric := range metrics {
+		catalog[metric.Name] = metric
+	}
+	return catalog, nil
+}
+
+// checkReadings rejects empty samples, unknown metrics and values outside the
+// metric's valid range. NaN and infinities are refused before the range check,
+// which NaN would pass as every comparison with it is false.
+func checkReadings(catalog map[string]*model.Metric, values map[string]float64) error {
+	if len(values) == 0 {
+		return errs.EmptyReadings
+	}
+
+	for name, value := range values {
+		metric, ok := catalog[name]
+		if !ok {
+			return errs.InvalidMetric
+		}
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return errs.MetricValueNotFinite
+		}
+		if !metric.InRange(value) {
+			return errs.MetricValueOutOfRange
+		}
+	}
+	return nil
+}
+
+func growthHistResponse(resp *dto.ReadingsResponse) *dto.GrowthHistResponse {
+	return &dto.GrowthHistResponse{
+		ID:       resp.SampleId,
+		FarmId:   resp.FarmId,
+		SystemId: resp.SystemId,
+		Ppm:      resp.Readings[constant.MetricPpm],
+		Ph:       resp.Readings[constant.MetricPh],
+	}
+}
+
+func (s *growthHistService) GetReadingAggregationByFilter(caller *dto.Caller, getGrowthFilterBody *dto.GetGrowthFilter) (*dto.GetReadingAggregationResp, error) {
+	logger.Info("growthHistService", "Fetching Reading Aggregation", map[string]string{
 		"farmId":   getGrowthFilterBody.FarmId,
 		"systemId": getGrowthFilterBody.SystemId,
 		"period":   getGrowthFilterBody.Period,
 	})
 
-	var aggregateResult *model.GrowthHistAggregate
+	err := s.checkGrowthFilterAccess(caller, getGrowthFilterBody)
+	if err != nil {
+		return nil, err
+	}
 
-	farm, err := s.farmRepo.GetFarmById(&model.Farm{
-		ID: uuid.MustParse(getGrowthFilterBody.FarmId),
-	})
-	if err != nil || farm == nil {
-		logger.Error("growthHistService", "Invalid Farm ID", map[string]string{
-			"error": err.Error(),
-		})
-		return nil, errs.InvalidFarmID
+	err = s.validateMetricNames(getGrowthFilterBody.Metrics)
+	if err != nil {
+		return nil, err
 	}
 
-	systemUnit, err := s.systemUnitRepo.GetSystemUnitById(&model.SystemUnit{
-		ID: uuid.MustParse(getGrowthFilterBody.SystemId),
-	})
-	if err != nil || systemUnit == nil {
-		logger.Error("growthHistService", "Invalid System Unit ID", map[string]string{
+	startDate, endDate := periodDates(getGrowthFilterBody)
+	aggregateResult, err := s.growthHistRepo.GetAggregateByFilter(getGrowthFilterBody, &startDate, &endDate)
+	if err != nil {
+		logger.Error("growthHistService", "Error fetching aggregated data", map[string]string{
 			"error": err.Error(),
 		})
-		return nil, errs.InvalidSystemUnitID
+		return nil, errs.ErrorOnGettingAggregatedData
 	}
 
+	logger.Info("growthHistService", "Successfully fetched Reading Aggregation", nil)
+	return &dto.GetReadingAggregationResp{
+		Period:        getGrowthFilterBody.Period,
+		AggregateData: aggregateResult,
+	}, nil
+}
+
+func (s *growthHistService) GetGrowthHistAggregationByFilter(caller *dto.Caller, getGrowthFilterBody *dto.GetGrowthFilter) (*dto.GetGrowthAggregationResp, error) {
+	getGrowthFilterBody.Metrics = legacyMetrics
+	resp, err := s.GetReadingAggregationByFilter(caller, getGrowthFilterBody)
+	if err != nil {
+		return nil, err
+	}
+
+	aggregateResult := &model.GrowthHistAggregate{}
+	for _, aggregate := range resp.AggregateData {
+		switch aggregate.Metric {
+		case constant.MetricPpm:
+			aggregateResult.TotalPpm = aggregate.Total
+			aggregateResult.MinPpm = aggregate.Min
+			aggregateResult.MaxPpm = aggregate.Max
+			aggregateResult.AvgPpm = aggregate.Avg
+			aggregateResult.TotalData = aggregate.TotalData
+		case constant.MetricPh:
+			aggregateResult.TotalPh = aggregate.Total
+			aggregateResult.MinPh = aggregate.Min
+			aggregateResult.MaxPh = aggregate.Max
+			aggregateResult.AvgPh = aggregate.Avg
+		}
+	}
+
+	return &dto.GetGrowthAggregationResp{
+		Period:        resp.Period,
+		AggregateData: aggregateResult,
+	}, nil
+}
+
+// periodDates turns the filter period into the inclusive date range to query.
+func periodDates(filter *dto.GetGrowthFilter) (string, string) {
 	currentDateTime := time.Now()
 	var startDate, endDate string
 
-	switch getGrowthFilterBody.Period {
+	switch filter.Period {
 	case "today":
 		startDate = currentDateTime.Format("2006-01-02")
 		endDate = startDate
@@ -144,26 +489,14 @@ func (s *growthHistService) GetGrowthHistAggregationByFilter(getGrowthFilterBody
 		startDate = currentDateTime.AddDate(0, -1, 0).Format("2006-01-02")
 		endDate = currentDateTime.Format("2006-01-02")
 	case "custom":
-		startDate = getGrowthFilterBody.StartDate.Format("2006-01-02")
-		endDate = getGrowthFilterBody.EndDate.Format("2006-01-02")
-	}
-
-	aggregateResult, err = s.growthHistRepo.GetAggregateByFilter(getGrowthFilterBody, &startDate, &endDate)
-	if err != nil {
-		logger.Error("growthHistService", "Error fetching aggregated data", map[string]string{
-			"error": err.Error(),
-		})
-		return nil, errs.ErrorOnGettingAggregatedData
+		startDate = filter.StartDate.Format("2006-01-02")
+		endDate = filter.EndDate.Format("2006-01-02")
 	}
 
-	logger.Info("growthHistService", "Successfully fetched Growth History Aggregation", nil)
-	return &dto.GetGrowthAggregationResp{
-		Period:        getGrowthFilterBody.Period,
-		AggregateData: aggregateResult,
-	}, nil
+	return startDate, endDate
 }
 
-func (s *growthHistService) GenerateDummyData(input *dto.GrowthHistDummyDataBody) (*dto.GrowthHistResponse, error) {
+func (s *growthHistService) GenerateDummyData(caller *dto.Caller, input *dto.GrowthHistDummyDataBody) (*dto.GrowthHistResponse, error) {
 	start := time.Now()
 	logger.Info("growthHistService", "Generating dummy data", map[string]string{
 		"farmId":   input.FarmId.String(),
@@ -173,20 +506,12 @@ func (s *growthHistService) GenerateDummyData(input *dto.GrowthHistDummyDataBody
 	var memStart runtime.MemStats
 	runtime.ReadMemStats(&memStart)
 
-	farm, err := s.farmRepo.GetFarmById(&model.Farm{ID: input.FarmId})
-	if err != nil || farm == nil {
-		logger.Error("growthHistService", "Invalid Farm ID", map[string]string{
-			"error": err.Error(),
-		})
-		return nil, errs.InvalidFarmID
-	}
-
-	systemUnit, err := s.systemUnitRepo.GetSystemUnitById(&model.SystemUnit{ID: input.SystemId})
-	if err != nil || systemUnit == nil {
-		logger.Error("growthHistService", "Invalid System Unit ID", map[string]string{
+	_, _, err := resolveSystemUnit(s.farmMemberRepo, s.systemUnitRepo, caller, input.FarmId, input.SystemId, farmOwners)
+	if err != nil {
+		logger.Error("growthHistService", "Farm or system unit not accessible", map[string]string{
 			"error": err.Error(),
 		})
-		return nil, errs.InvalidSystemUnitID
+		return nil, err
 	}
 
 	startTime := time.Now().AddDate(-4, 0, 0)
@@ -208,12 +533,11 @@ func (s *growthHistService) GenerateDummyData(input *dto.GrowthHistDummyDataBody
 			defer wg.Done()
 			for t := range jobs {
 				farmData := generateRandomFarmData(t)
-				record := fmt.Sprintf("('%s','%s',%s,%s,'%s')",
-					input.FarmId.String(),
-					input.SystemId.String(),
-					floatToString(farmData.Ppm),
-					floatToString(farmData.Ph),
-					farmData.CreatedAt.Format("2006-01-02 15:04:05"),
+				sampleId := uuid.New().String()
+				createdAt := farmData.CreatedAt.Format("2006-01-02 15:04:05")
+				record := fmt.Sprintf("('%s','%s','%s','%s',%s,'%s','%s','%s'),('%s','%s','%s','%s',%s,'%s','%s','%s')",
+					sampleId, input.FarmId.String(), input.SystemId.String(), constant.MetricPpm, floatToString(farmData.Ppm), createdAt, createdAt, createdAt,
+					sampleId, input.FarmId.String(), input.SystemId.String(), constant.MetricPh, floatToString(farmData.Ph), createdAt, createdAt, createdAt,
 				)
 				results <- record
 			}
@@ -231,7 +555,7 @@ func (s *growthHistService) GenerateDummyData(input *dto.GrowthHistDummyDataBody
 	close(results)
 
 	finalBatchValues := strings.Join([]string{}, ",")
-	s.growthHistRepo.CreateGrowthHistoryBatch(&finalBatchValues)
+	s.growthHistRepo.CreateReadingsBatch(&finalBatchValues)
 
 	elapsed := time.Since(start)
 	logger.Info("growthHistService", "Dummy data generation completed", map[string]string{
@@ -244,54 +568,103 @@ func (s *growthHistService) GenerateDummyData(input *dto.GrowthHistDummyDataBody
 	}, nil
 }
 
-func (s *growthHistService) GetGrowthHistByFilter(getGrowthFilterBody *dto.GetGrowthFilter) (*dto.GetGrowthDataResp, error) {
-	logger.Info("growthHistService", "Fetching Growth History by filter", map[string]string{
+func (s *growthHistService) GetReadingsByFilter(caller *dto.Caller, getGrowthFilterBody *dto.GetGrowthFilter) (*dto.GetReadingDataResp, error) {
+	logger.Info("growthHistService", "Fetching Readings by filter", map[string]string{
 		"farmId":   getGrowthFilterBody.FarmId,
 		"systemId": getGrowthFilterBody.SystemId,
 	})
 
-	farm, err := s.farmRepo.GetFarmById(&model.Farm{
-		ID: uuid.MustParse(getGrowthFilterBody.FarmId),
-	})
-	if err != nil || farm == nil {
-		logger.Error("growthHistService", "Invalid Farm ID", map[string]string{
-			"error": err.Error(),
-		})
-		return nil, errs.InvalidFarmID
+	err := s.checkGrowthFilterAccess(caller, getGrowthFilterBody)
+	if err != nil {
+		return nil, err
 	}
 
-	systemUnit, err := s.systemUnitRepo.GetSystemUnitById(&model.SystemUnit{
-		ID: uuid.MustParse(getGrowthFilterBody.SystemId),
-	})
-	if err != nil || systemUnit == nil {
-		logger.Error("growthHistService", "Invalid System Unit ID", map[string]string{
-			"error": err.Error(),
-		})
-		return nil, errs.InvalidSystemUnitID
+	err = s.validateMetricNames(getGrowthFilterBody.Metrics)
+	if err != nil {
+		return nil, err
 	}
 
 	startDate := getGrowthFilterBody.StartDate.Format("2006-01-02")
 	endDate := getGrowthFilterBody.EndDate.Format("2006-01-02")
-	aggregateResult, err := s.growthHistRepo.GetDataByFilter(&dto.GetGrowthFilter{
+	readings, err := s.growthHistRepo.GetDataByFilter(&dto.GetGrowthFilter{
 		FarmId:   getGrowthFilterBody.FarmId,
 		SystemId: getGrowthFilterBody.SystemId,
+		Metrics:  getGrowthFilterBody.Metrics,
 	}, &startDate, &endDate)
 
 	if err != nil {
-		logger.Error("growthHistService", "Error fetching Growth History data", map[string]string{
+		logger.Error("growthHistService", "Error fetching Readings data", map[string]string{
 			"error": err.Error(),
 		})
 		return nil, err
 	}
 
-	logger.Info("growthHistService", "Successfully fetched Growth History data", nil)
-	return &dto.GetGrowthDataResp{
+	logger.Info("growthHistService", "Successfully fetched Readings data", nil)
+	return &dto.GetReadingDataResp{
 		StartDate: getGrowthFilterBody.StartDate,
 		EndDate:   getGrowthFilterBody.EndDate,
-		Data:      aggregateResult,
+		Data:      readings,
 	}, nil
 }
 
+// GetGrowthHistByFilter returns the ppm and pH readings paired up by sample.
+func (s *growthHistService) GetGrowthHistByFilter(caller *dto.Caller, getGrowthFilterBody *dto.GetGrowthFilter) (*dto.GetGrowthDataResp, error) {
+	getGrowthFilterBody.Metrics = legacyMetrics
+	resp, err := s.GetReadingsByFilter(caller, getGrowthFilterBody)
+	if err != nil {
+		return nil, err
+	}
+
+	data := []*model.GrowthHistFilter{}
+	samples := make(map[uuid.UUID]*model.GrowthHistFilter)
+	for _, reading := range resp.Data {
+		sample, ok := samples[reading.SampleId]
+		if !ok {
+			sample = &model.GrowthHistFilter{CreatedAt: reading.MeasuredAt}
+			samples[reading.SampleId] = sample
+			data = append(data, sample)
+		}
+
+		switch reading.Metric {
+		case constant.MetricPpm:
+			sample.Ppm = reading.Value
+		case constant.MetricPh:
+			sample.Ph = reading.Value
+		}
+	}
+
+	return &dto.GetGrowthDataResp{
+		StartDate: resp.StartDate,
+		EndDate:   resp.EndDate,
+		Data:      data,
+	}, nil
+}
+
+// checkGrowthFilterAccess verifies that the farm and system unit named in the
+// filter are visible to the caller.
+func (s *growthHistService) checkGrowthFilterAccess(caller *dto.Caller, filter *dto.GetGrowthFilter) error {
+	farmId, err := uuid.Parse(filter.FarmId)
+	if err != nil {
+		return errs.InvalidFarmID
+	}
+
+	systemId, err := uuid.Parse(filter.SystemId)
+	if err != nil {
+		return errs.InvalidSystemUnitID
+	}
+
+	_, _, err = resolveSystemUnit(s.farmMemberRepo, s.systemUnitRepo, caller, farmId, systemId, farmReaders)
+	if err != nil {
+		logger.Error("growthHistService", "Farm or system unit not accessible", map[string]string{
+			"farmId":   filter.FarmId,
+			"systemId": filter.SystemId,
+		})
+		return err
+	}
+
+	return nil
+}
+
 func generateRandomFarmData(t time.Time) *model.GrowthHist {
 	logger.Info("growthHistService", "Generating random farm data", map[string]string{
 		"timestamp": t.String(),
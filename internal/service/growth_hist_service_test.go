@@ -0,0 +1,44 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
+)
+
+func TestCheckReadings(t *testing.T) {
+	minPh, maxPh := 0.0, 14.0
+	catalog := map[string]*model.Metric{
+		"ph":  {Name: "ph", MinValue: &minPh, MaxValue: &maxPh},
+		"ppm": {Name: "ppm"},
+	}
+
+	tests := []struct {
+		name    string
+		values  map[string]float64
+		wantErr error
+	}{
+		{"in range", map[string]float64{"ph": 6.2, "ppm": 800}, nil},
+		{"at bounds", map[string]float64{"ph": 14}, nil},
+		{"open bounds", map[string]float64{"ppm": -1e300}, nil},
+		{"empty", map[string]float64{}, errs.EmptyReadings},
+		{"unknown metric", map[string]float64{"co2": 400}, errs.InvalidMetric},
+		{"below range", map[string]float64{"ph": -0.1}, errs.MetricValueOutOfRange},
+		{"above range", map[string]float64{"ph": 14.1}, errs.MetricValueOutOfRange},
+		{"NaN within bounds", map[string]float64{"ph": math.NaN()}, errs.MetricValueNotFinite},
+		{"NaN without bounds", map[string]float64{"ppm": math.NaN()}, errs.MetricValueNotFinite},
+		{"positive infinity", map[string]float64{"ppm": math.Inf(1)}, errs.MetricValueNotFinite},
+		{"negative infinity", map[string]float64{"ppm": math.Inf(-1)}, errs.MetricValueNotFinite},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkReadings(catalog, tt.values)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("checkReadings() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
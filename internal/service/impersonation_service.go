@@ -0,0 +1,124 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/dto"
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/repository"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/tokenprovider"
+	"github.com/google/uuid"
+)
+
+// ImpersonationService lets a super admin act as a farmer account for
+// support. Tokens are short lived, read only unless asked otherwise, and every
+// request made with one is written to the system log.
+type ImpersonationService interface {
+	Impersonate(actorId uuid.UUID, input *dto.ImpersonateBody) (*dto.ImpersonateResponse, error)
+	Authenticate(token string) (*tokenprovider.JwtClaims, *dto.ImpersonationAuth, error)
+	RecordRequest(auth *dto.ImpersonationAuth, method string, path string, ipAddress string) error
+}
+
+type impersonationService struct {
+	accountRepo      repository.AccountRepository
+	jwtProvider      tokenprovider.JWTTokenProvider
+	systemLogService SystemLogService
+	tokenTTL         time.Duration
+}
+
+type ImpersonationServiceConfig struct {
+	AccountRepo repository.AccountRepository
+	// JwtProvider must be the farmer provider so the regular auth middleware
+	// accepts the tokens.
+	JwtProvider      tokenprovider.JWTTokenProvider
+	SystemLogService SystemLogService
+	TokenTTL         time.Duration
+}
+
+func NewImpersonationService(config ImpersonationServiceConfig) ImpersonationService {
+	return &impersonationService{
+		accountRepo:      config.AccountRepo,
+		jwtProvider:      config.JwtProvider,
+		systemLogService: config.SystemLogService,
+		tokenTTL:         config.TokenTTL,
+	}
+}
+
+func (s *impersonationService) Impersonate(actorId uuid.UUID, input *dto.ImpersonateBody) (*dto.ImpersonateResponse, error) {
+	logger.Info("impersonationService", "Starting impersonation", map[string]string{
+		"actorId":   actorId.String(),
+		"accountId": input.AccountID.String(),
+	})
+
+	user, err := s.accountRepo.GetUserById(input.AccountID)
+	if err != nil || user == nil {
+		return nil, errs.InvalidAccountId
+	}
+
+	impersonationId := uuid.New()
+	readOnly := !input.AllowWrite
+	expiresAt := time.Now().Add(s.tokenTTL)
+
+	// log the grant before handing out the token so no impersonation goes
+	// unrecorded
+	err = s.systemLogService.CreateSystemLog(fmt.Sprintf(
+		"Start Impersonation: {ID:%s, Actor:%s, Account:%s, ReadOnly:%t, ExpiresAt:%s, Reason:%q}",
+		impersonationId, actorId, user.ID, readOnly, expiresAt.Format(time.RFC3339), input.Reason))
+	if err != nil {
+		return nil, errs.ErrorImpersonating
+	}
+
+	accessToken, err := s.jwtProvider.GenerateImpersonationToken(user, actorId, impersonationId, readOnly, s.tokenTTL)
+	if err != nil {
+		logger.Error("impersonationService", "Error generating impersonation token", map[string]string{
+			"error": err.Error(),
+		})
+		return nil, errs.ErrorGeneratingToken
+	}
+
+	return &dto.ImpersonateResponse{
+		ImpersonationID: impersonationId,
+		AccountID:       user.ID,
+		Username:        user.Username,
+		AccessToken:     accessToken,
+		ReadOnly:        readOnly,
+		ExpiresAt:       expiresAt,
+	}, nil
+}
+
+// Authenticate validates an impersonation token and returns its claims along
+// with the actor and subject it was issued for.
+func (s *impersonationService) Authenticate(token string) (*tokenprovider.JwtClaims, *dto.ImpersonationAuth, error) {
+	claims, err := s.jwtProvider.ValidateImpersonationToken(token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	impersonationId, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return nil, nil, errs.InvalidToken
+	}
+	actorId, err := uuid.Parse(claims.ActorID)
+	if err != nil {
+		return nil, nil, errs.InvalidToken
+	}
+	accountId, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return nil, nil, errs.InvalidToken
+	}
+
+	return claims, &dto.ImpersonationAuth{
+		ImpersonationID: impersonationId,
+		ActorID:         actorId,
+		AccountID:       accountId,
+		ReadOnly:        claims.ReadOnly,
+	}, nil
+}
+
+func (s *impersonationService) RecordRequest(auth *dto.ImpersonationAuth, method string, path string, ipAddress string) error {
+	return s.systemLogService.CreateSystemLog(fmt.Sprintf(
+		"Impersonated Request: {ID:%s, Actor:%s, Account:%s, Method:%s, Path:%s, IP:%s}",
+		auth.ImpersonationID, auth.ActorID, auth.AccountID, method, path, ipAddress))
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/dto"
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/repository"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+	"github.com/google/uuid"
+)
+
+// Records an ingest receipt can stand for.
+const (
+	receiptKindReadings  = "readings"
+	receiptKindTankTrans = "tank_trans"
+)
+
+// newIngestReceipt returns the receipt to store with a write, or nil when the
+// client sent neither a message id nor a sequence number. The request hash
+// tells a retry apart from a different request reusing the same key.
+func newIngestReceipt(systemId uuid.UUID, kind string, key dto.IngestKey, request interface{}) *model.IngestReceipt {
+	if key.MessageId == "" && key.Sequence == nil {
+		return nil
+	}
+
+	// request bodies are decoded from JSON, so they always encode again
+	body, _ := json.Marshal(request)
+	hash := sha256.Sum256(body)
+
+	receipt := &model.IngestReceipt{
+		SystemId:    systemId,
+		Kind:        kind,
+		Sequence:    key.Sequence,
+		RequestHash: hex.EncodeToString(hash[:]),
+	}
+	if key.MessageId != "" {
+		receipt.MessageId = &key.MessageId
+	}
+	return receipt
+}
+
+// findReplay looks up an earlier write with the same message id or sequence
+// number and decodes its response into resp. It reports false when the write
+// is new, and fails when the keys belong to a different request.
+func findReplay(ingestReceiptRepo repository.IngestReceiptRepository, receipt *model.IngestReceipt, resp interface{}) (bool, error) {
+	earlier, err := ingestReceiptRepo.GetReceipts(receipt.SystemId, receipt.MessageId, receipt.Sequence)
+	if err != nil {
+		return false, errs.ErrorCheckingIngestKey
+	}
+	return replayReceipt(earlier, receipt, resp)
+}
+
+// replayReceipt decodes into resp the response of the earlier receipts that
+// share a key with receipt.
+func replayReceipt(earlier []*model.IngestReceipt, receipt *model.IngestReceipt, resp interface{}) (bool, error) {
+	if len(earlier) == 0 {
+		return false, nil
+	}
+
+	original := earlier[0]
+	if len(earlier) > 1 || original.Kind != receipt.Kind || original.RequestHash != receipt.RequestHash {
+		return false, errs.IngestKeyReused
+	}
+
+	err := json.Unmarshal(original.Response, resp)
+	if err != nil {
+		return false, errs.ErrorCheckingIngestKey
+	}
+
+	logger.Info("ingestReceipt", "Replaying earlier response", map[string]string{
+		"systemId":  receipt.SystemId.String(),
+		"receiptId": original.ID.String(),
+	})
+	return true, nil
+}
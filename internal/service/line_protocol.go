@@ -0,0 +1,217 @@
+package service
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
+	"github.com/google/uuid"
+)
+
+// Line protocol naming. A line of the readings measurement carries one field
+// per metric; a line named after a metric carries its reading in a single
+// value field. Other tags, such as the host tag of Telegraf, are ignored.
+const (
+	lineMeasurementReadings = "readings"
+	lineFieldValue          = "value"
+	lineTagUnitKey          = "unit_key"
+	lineTagSystemId         = "system_id"
+)
+
+// lineTarget is the system unit named by the tags of a line.
+type lineTarget struct {
+	unitKey  uuid.UUID
+	systemId uuid.UUID
+}
+
+// linePrecisions maps the precision query parameter of a write to the unit of
+// its timestamps. Both the 1.x and the 2.x spellings are accepted.
+var linePrecisions = map[string]time.Duration{
+	"":   time.Nanosecond,
+	"n":  time.Nanosecond,
+	"ns": time.Nanosecond,
+	"u":  time.Microsecond,
+	"us": time.Microsecond,
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+}
+
+func parseLineProtocolRows(body io.Reader, precision time.Duration, catalog map[string]*model.Metric) ([]*bulkRow, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), bulkMaxLineSize)
+
+	var rows []*bulkRow
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if len(rows) == bulkMaxRows {
+			return nil, errs.TooManyBulkRows
+		}
+
+		row := &bulkRow{line: line}
+		row.err = parseLineProtocolRow(row, text, precision, catalog)
+		rows = append(rows, row)
+	}
+
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, errs.InvalidLineProtocol
+		}
+		return nil, errs.InvalidRequestBody
+	}
+	return rows, nil
+}
+
+// parseLineProtocolRow reads
+//
+//	measurement[,tag=value...] field=value[,field=value...] [timestamp]
+//
+// into row. Commas, spaces and equal signs are escaped with a backslash, and
+// string field values are quoted.
+func parseLineProtocolRow(row *bulkRow, text string, precision time.Duration, catalog map[string]*model.Metric) error {
+	sections := splitLineProtocol(text, ' ', true)
+	if len(sections) < 2 || len(sections) > 3 {
+		return errs.InvalidLineProtocol
+	}
+
+	seriesKey := splitLineProtocol(sections[0], ',', false)
+	measurement := unescapeLineProtocol(seriesKey[0])
+	if measurement == "" {
+		return errs.InvalidLineProtocol
+	}
+	if _, ok := catalog[measurement]; !ok && measurement != lineMeasurementReadings {
+		return errs.UnknownMeasurement
+	}
+
+	for _, tag := range seriesKey[1:] {
+		pair := splitLineProtocol(tag, '=', false)
+		if len(pair) != 2 || pair[0] == "" || pair[1] == "" {
+			return errs.InvalidLineProtocol
+		}
+
+		var err error
+		switch unescapeLineProtocol(pair[0]) {
+		case lineTagUnitKey:
+			row.unitKey, err = uuid.Parse(unescapeLineProtocol(pair[1]))
+		case lineTagSystemId:
+			row.systemId, err = uuid.Parse(unescapeLineProtocol(pair[1]))
+		}
+		if err != nil {
+			return errs.InvalidSystemUnitID
+		}
+	}
+	if row.unitKey == uuid.Nil && row.systemId == uuid.Nil {
+		return errs.MissingSystemUnitTag
+	}
+
+	row.readings = make(map[string]float64)
+	for _, field := range splitLineProtocol(sections[1], ',', true) {
+		pair := splitLineProtocol(field, '=', true)
+		if len(pair) != 2 || pair[0] == "" || pair[1] == "" {
+			return errs.InvalidLineProtocol
+		}
+		value, err := parseLineProtocolValue(pair[1])
+		if err != nil {
+			return err
+		}
+
+		name := unescapeLineProtocol(pair[0])
+		if measurement != lineMeasurementReadings {
+			if name != lineFieldValue {
+				return errs.InvalidMetricField
+			}
+			name = measurement
+		}
+		row.readings[name] = value
+	}
+
+	if len(sections) == 3 {
+		timestamp, err := strconv.ParseInt(sections[2], 10, 64)
+		if err != nil || timestamp > math.MaxInt64/int64(precision) || timestamp < math.MinInt64/int64(precision) {
+			return errs.InvalidLineTimestamp
+		}
+		measuredAt := time.Unix(0, timestamp*int64(precision))
+		row.measuredAt = &measuredAt
+	}
+	return nil
+}
+
+// parseLineProtocolValue reads a float, integer (1i) or unsigned (1u) field.
+// Readings are numbers, so strings and booleans are refused.
+func parseLineProtocolValue(raw string) (float64, error) {
+	if strings.HasPrefix(raw, `"`) {
+		return 0, errs.NonNumericField
+	}
+	switch raw {
+	case "t", "T", "true", "True", "TRUE", "f", "F", "false", "False", "FALSE":
+		return 0, errs.NonNumericField
+	}
+
+	switch raw[len(raw)-1] {
+	case 'i':
+		value, err := strconv.ParseInt(raw[:len(raw)-1], 10, 64)
+		if err != nil {
+			return 0, errs.InvalidLineProtocol
+		}
+		return float64(value), nil
+	case 'u':
+		value, err := strconv.ParseUint(raw[:len(raw)-1], 10, 64)
+		if err != nil {
+			return 0, errs.InvalidLineProtocol
+		}
+		return float64(value), nil
+	}
+
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, errs.InvalidLineProtocol
+	}
+	return value, nil
+}
+
+// splitLineProtocol splits s at every sep that is neither escaped nor, when
+// quoted is set, inside a quoted field value. Escapes are kept.
+func splitLineProtocol(s string, sep byte, quoted bool) []string {
+	var parts []string
+	inQuotes := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			i++
+		case s[i] == '"' && quoted:
+			inQuotes = !inQuotes
+		case s[i] == sep && !inQuotes:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func unescapeLineProtocol(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && strings.IndexByte(`,= "\`, s[i+1]) >= 0 {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
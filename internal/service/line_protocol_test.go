@@ -0,0 +1,313 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
+	"github.com/google/uuid"
+)
+
+var testCatalog = map[string]*model.Metric{
+	"ppm": {Name: "ppm"},
+	"ph":  {Name: "ph"},
+}
+
+const (
+	testUnitKey  = "6f1c2a4e-8b7d-4c3e-9a1f-2d5e7b9c0a13"
+	testSystemId = "0b9e4d2c-1a3f-4e5d-8c7b-6a9f8e7d5c41"
+)
+
+func TestParseLineProtocolRow(t *testing.T) {
+	unitKey := uuid.MustParse(testUnitKey)
+	systemId := uuid.MustParse(testSystemId)
+
+	tests := []struct {
+		name         string
+		line         string
+		precision    time.Duration
+		wantUnitKey  uuid.UUID
+		wantSystemId uuid.UUID
+		wantReadings map[string]float64
+		wantAt       *time.Time
+		wantErr      error
+	}{
+		{
+			name:         "readings measurement",
+			line:         "readings,unit_key=" + testUnitKey + " ppm=812.5,ph=6.2",
+			wantUnitKey:  unitKey,
+			wantReadings: map[string]float64{"ppm": 812.5, "ph": 6.2},
+		},
+		{
+			name:         "metric measurement",
+			line:         "ph,system_id=" + testSystemId + " value=6.2",
+			wantSystemId: systemId,
+			wantReadings: map[string]float64{"ph": 6.2},
+		},
+		{
+			name:         "integer and unsigned fields",
+			line:         "readings,unit_key=" + testUnitKey + " ppm=800i,ph=6u",
+			wantUnitKey:  unitKey,
+			wantReadings: map[string]float64{"ppm": 800, "ph": 6},
+		},
+		{
+			name:         "both tags and an ignored tag",
+			line:         "readings,host=pi,unit_key=" + testUnitKey + ",system_id=" + testSystemId + " ppm=1",
+			wantUnitKey:  unitKey,
+			wantSystemId: systemId,
+			wantReadings: map[string]float64{"ppm": 1},
+		},
+		{
+			name:         "escaped comma, space and equal sign in a tag value",
+			line:         `readings,host=green\,house\ 1\=a,unit_key=` + testUnitKey + " ppm=1",
+			wantUnitKey:  unitKey,
+			wantReadings: map[string]float64{"ppm": 1},
+		},
+		{
+			name:         "escaped space and comma in a field key",
+			line:         `readings,unit_key=` + testUnitKey + ` water\ temp\,c=21.5`,
+			wantUnitKey:  unitKey,
+			wantReadings: map[string]float64{"water temp,c": 21.5},
+		},
+		{
+			name:    "escaped comma and space in the measurement",
+			line:    `p\,p\ m,unit_key=` + testUnitKey + " value=1",
+			wantErr: errs.UnknownMeasurement,
+		},
+		{
+			name:      "seconds timestamp",
+			line:      "readings,unit_key=" + testUnitKey + " ppm=1 1700000000",
+			precision: time.Second,
+			wantAt:    timePtr(time.Unix(1700000000, 0)),
+		},
+		{
+			name:   "nanosecond timestamp",
+			line:   "readings,unit_key=" + testUnitKey + " ppm=1 1700000000123456789",
+			wantAt: timePtr(time.Unix(1700000000, 123456789)),
+		},
+		{
+			name:      "negative timestamp",
+			line:      "readings,unit_key=" + testUnitKey + " ppm=1 -60",
+			precision: time.Minute,
+			wantAt:    timePtr(time.Unix(-3600, 0)),
+		},
+		{
+			name:    "quoted string with spaces and commas",
+			line:    `readings,unit_key=` + testUnitKey + ` note="a b,c=d",ppm=1`,
+			wantErr: errs.NonNumericField,
+		},
+		{
+			name:    "quoted string with an escaped quote",
+			line:    `readings,unit_key=` + testUnitKey + ` note="say \"hi there\""`,
+			wantErr: errs.NonNumericField,
+		},
+		{
+			name:    "boolean field",
+			line:    "readings,unit_key=" + testUnitKey + " ppm=true",
+			wantErr: errs.NonNumericField,
+		},
+		{
+			name:    "NaN field",
+			line:    "readings,unit_key=" + testUnitKey + " ppm=NaN",
+			wantErr: errs.InvalidLineProtocol,
+		},
+		{
+			name:    "infinite field",
+			line:    "readings,unit_key=" + testUnitKey + " ppm=+Inf",
+			wantErr: errs.InvalidLineProtocol,
+		},
+		{
+			name:    "integer field overflow",
+			line:    "readings,unit_key=" + testUnitKey + " ppm=9223372036854775808i",
+			wantErr: errs.InvalidLineProtocol,
+		},
+		{
+			name:    "nanosecond timestamp overflow",
+			line:    "readings,unit_key=" + testUnitKey + " ppm=1 9223372036854775808",
+			wantErr: errs.InvalidLineTimestamp,
+		},
+		{
+			name:      "seconds timestamp overflow",
+			line:      "readings,unit_key=" + testUnitKey + " ppm=1 9223372037",
+			precision: time.Second,
+			wantErr:   errs.InvalidLineTimestamp,
+		},
+		{
+			name:      "negative seconds timestamp overflow",
+			line:      "readings,unit_key=" + testUnitKey + " ppm=1 -9223372037",
+			precision: time.Second,
+			wantErr:   errs.InvalidLineTimestamp,
+		},
+		{
+			name:    "float timestamp",
+			line:    "readings,unit_key=" + testUnitKey + " ppm=1 1700000000.5",
+			wantErr: errs.InvalidLineTimestamp,
+		},
+		{
+			name:    "unknown measurement",
+			line:    "co2,unit_key=" + testUnitKey + " value=1",
+			wantErr: errs.UnknownMeasurement,
+		},
+		{
+			name:    "metric measurement with another field",
+			line:    "ph,unit_key=" + testUnitKey + " ppm=1",
+			wantErr: errs.InvalidMetricField,
+		},
+		{
+			name:    "no unit tag",
+			line:    "readings,host=pi ppm=1",
+			wantErr: errs.MissingSystemUnitTag,
+		},
+		{
+			name:    "invalid unit key",
+			line:    "readings,unit_key=abc ppm=1",
+			wantErr: errs.InvalidSystemUnitID,
+		},
+		{
+			name:    "tag without value",
+			line:    "readings,unit_key= ppm=1",
+			wantErr: errs.InvalidLineProtocol,
+		},
+		{
+			name:    "no fields",
+			line:    "readings,unit_key=" + testUnitKey,
+			wantErr: errs.InvalidLineProtocol,
+		},
+		{
+			name:    "field without value",
+			line:    "readings,unit_key=" + testUnitKey + " ppm=",
+			wantErr: errs.InvalidLineProtocol,
+		},
+		{
+			name:    "too many sections",
+			line:    "readings,unit_key=" + testUnitKey + " ppm=1 1700000000 extra",
+			wantErr: errs.InvalidLineProtocol,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			precision := tt.precision
+			if precision == 0 {
+				precision = time.Nanosecond
+			}
+
+			row := &bulkRow{}
+			err := parseLineProtocolRow(row, tt.line, precision, testCatalog)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("parseLineProtocolRow() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if tt.wantReadings != nil {
+				if row.unitKey != tt.wantUnitKey || row.systemId != tt.wantSystemId {
+					t.Errorf("tags = (%s, %s), want (%s, %s)", row.unitKey, row.systemId, tt.wantUnitKey, tt.wantSystemId)
+				}
+				if !equalReadings(row.readings, tt.wantReadings) {
+					t.Errorf("readings = %v, want %v", row.readings, tt.wantReadings)
+				}
+			}
+			switch {
+			case tt.wantAt == nil && row.measuredAt != nil:
+				t.Errorf("measuredAt = %v, want none", *row.measuredAt)
+			case tt.wantAt != nil && (row.measuredAt == nil || !row.measuredAt.Equal(*tt.wantAt)):
+				t.Errorf("measuredAt = %v, want %v", row.measuredAt, *tt.wantAt)
+			}
+		})
+	}
+}
+
+func TestParseLineProtocolRows(t *testing.T) {
+	body := strings.Join([]string{
+		"# written by telegraf",
+		"readings,unit_key=" + testUnitKey + " ppm=800,ph=6.1 1700000000",
+		"",
+		"co2,unit_key=" + testUnitKey + " value=1 1700000000",
+		"  ph,system_id=" + testSystemId + " value=6.3 1700000060  ",
+	}, "\n")
+
+	rows, err := parseLineProtocolRows(strings.NewReader(body), time.Second, testCatalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		line int
+		err  error
+	}{
+		{2, nil},
+		{4, errs.UnknownMeasurement},
+		{5, nil},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(want))
+	}
+	for i, w := range want {
+		if rows[i].line != w.line || !errors.Is(rows[i].err, w.err) {
+			t.Errorf("row %d = line %d error %v, want line %d error %v", i, rows[i].line, rows[i].err, w.line, w.err)
+		}
+	}
+}
+
+func TestParseLineProtocolRowsLimits(t *testing.T) {
+	line := "readings,unit_key=" + testUnitKey + " ppm=1\n"
+
+	tests := []struct {
+		name    string
+		body    string
+		wantErr error
+	}{
+		{"rows at limit", strings.Repeat(line, bulkMaxRows), nil},
+		{"too many rows", strings.Repeat(line, bulkMaxRows+1), errs.TooManyBulkRows},
+		{"line too long", "readings,unit_key=" + testUnitKey + " ppm=" + strings.Repeat("1", bulkMaxLineSize), errs.InvalidLineProtocol},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseLineProtocolRows(strings.NewReader(tt.body), time.Nanosecond, testCatalog)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("parseLineProtocolRows() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUnescapeLineProtocol(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"plain", "plain"},
+		{`a\,b`, "a,b"},
+		{`a\ b`, "a b"},
+		{`a\=b`, "a=b"},
+		{`a\"b`, `a"b`},
+		{`a\\b`, `a\b`},
+		{`a\nb`, `a\nb`},
+		{`trailing\`, `trailing\`},
+	}
+	for _, tt := range tests {
+		if got := unescapeLineProtocol(tt.in); got != tt.want {
+			t.Errorf("unescapeLineProtocol(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func equalReadings(got map[string]float64, want map[string]float64) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for name, value := range want {
+		if v, ok := got[name]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/dto"
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/repository"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+)
+
+const (
+	throttleKeyUserPrefix = "user:"
+	throttleKeyIpPrefix   = "ip:"
+)
+
+// LoginThrottlePolicy controls when failed logins start locking a username or
+// an IP address. Every failure past the limit doubles the lockout, starting at
+// BaseLockout and capped at MaxLockout.
+type LoginThrottlePolicy struct {
+	MaxAccountAttempts int
+	MaxIpAttempts      int
+	Window             time.Duration
+	BaseLockout        time.Duration
+	MaxLockout         time.Duration
+}
+
+func DefaultLoginThrottlePolicy() LoginThrottlePolicy {
+	return LoginThrottlePolicy{
+		MaxAccountAttempts: 5,
+		MaxIpAttempts:      20,
+		Window:             15 * time.Minute,
+		BaseLockout:        time.Minute,
+		MaxLockout:         time.Hour,
+	}
+}
+
+type LoginThrottleService interface {
+	CheckLogin(username string, ipAddress string) error
+	RecordFailedLogin(username string, ipAddress string)
+	ResetLogin(username string)
+	Unlock(input *dto.UnlockLoginBody) (*dto.UnlockLoginResponse, error)
+}
+
+type loginThrottleService struct {
+	loginThrottleRepo repository.LoginThrottleRepository
+	systemLogRepo     repository.SystemLogRepository
+	policy            LoginThrottlePolicy
+}
+
+type LoginThrottleServiceConfig struct {
+	LoginThrottleRepo repository.LoginThrottleRepository
+	SystemLogRepo     repository.SystemLogRepository
+	Policy            LoginThrottlePolicy
+}
+
+func NewLoginThrottleService(config LoginThrottleServiceConfig) LoginThrottleService {
+	return &loginThrottleService{
+		loginThrottleRepo: config.LoginThrottleRepo,
+		systemLogRepo:     config.SystemLogRepo,
+		policy:            config.Policy,
+	}
+}
+
+// CheckLogin rejects the attempt while either the username or the IP address
+// is locked. Unknown usernames are throttled the same way as existing ones.
+func (s *loginThrottleService) CheckLogin(username string, ipAddress string) error {
+	throttles, err := s.loginThrottleRepo.GetLoginThrottles(throttleKeys(username, ipAddress))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, throttle := range throttles {
+		if throttle.LockedUntil != nil && throttle.LockedUntil.After(now) {
+			logger.Warn("loginThrottleService", "Login attempt while locked", map[string]string{
+				"key":          throttle.ThrottleKey,
+				"locked_until": throttle.LockedUntil.Format(time.RFC3339),
+			})
+			return errs.TooManyLoginAttempts
+		}
+	}
+
+	return nil
+}
+
+func (s *loginThrottleService) RecordFailedLogin(username string, ipAddress string) {
+	s.recordFailure(throttleKeyUserPrefix+normalizeUsername(username), s.policy.MaxAccountAttempts)
+	if ipAddress != "" {
+		s.recordFailure(throttleKeyIpPrefix+ipAddress, s.policy.MaxIpAttempts)
+	}
+}
+
+// ResetLogin clears the username counter after a successful login. The IP
+// counter is left to expire so that successes on one account cannot hide
+// guessing against others from the same address.
+func (s *loginThrottleService) ResetLogin(username string) {
+	_, err := s.loginThrottleRepo.DeleteLoginThrottles([]string{throttleKeyUserPrefix + normalizeUsername(username)})
+	if err != nil {
+		logger.Warn("loginThrottleService", "Failed to reset login throttle", map[string]string{
+			"error": err.Error(),
+		})
+	}
+}
+
+func (s *loginThrottleService) Unlock(input *dto.UnlockLoginBody) (*dto.UnlockLoginResponse, error) {
+	keys := []string{}
+	if input.Username != "" {
+		keys = append(keys, throttleKeyUserPrefix+normalizeUsername(input.Username))
+	}
+	if input.IpAddress != "" {
+		keys = append(keys, throttleKeyIpPrefix+input.IpAddress)
+	}
+	if len(keys) == 0 {
+		return nil, errs.InvalidRequestBody
+	}
+
+	cleared, err := s.loginThrottleRepo.DeleteLoginThrottles(keys)
+	if err != nil {
+		return nil, errs.ErrorUnlockingLogin
+	}
+
+	s.writeSystemLog("Login unlocked: {keys:" + strings.Join(keys, ",") + "}")
+
+	return &dto.UnlockLoginResponse{Cleared: cleared}, nil
+}
+
+func (s *loginThrottleService) recordFailure(key string, maxAttempts int) {
+	throttle, err := s.loginThrottleRepo.IncrementFailedLogin(key, s.policy.Window)
+	if err != nil || throttle == nil {
+		return
+	}
+
+	if throttle.FailedCount < maxAttempts {
+		return
+	}
+
+	lockout := s.lockoutDuration(throttle.FailedCount - maxAttempts)
+	until := time.Now().Add(lockout)
+	err = s.loginThrottleRepo.LockLoginThrottle(key, until)
+	if err != nil {
+		return
+	}
+
+	logger.Warn("loginThrottleService", "Login locked", map[string]string{
+		"key":      key,
+		"failures": strconv.Itoa(throttle.FailedCount),
+		"until":    until.Format(time.RFC3339),
+	})
+	s.writeSystemLog("Login locked: {key:" + key + ", failures:" + strconv.Itoa(throttle.FailedCount) + ", until:" + until.Format(time.RFC3339) + "}")
+}
+
+func (s *loginThrottleService) lockoutDuration(excess int) time.Duration {
+	lockout := float64(s.policy.BaseLockout) * math.Pow(2, float64(excess))
+	if lockout > float64(s.policy.MaxLockout) {
+		return s.policy.MaxLockout
+	}
+	return time.Duration(lockout)
+}
+
+func (s *loginThrottleService) writeSystemLog(message string) {
+	err := s.systemLogRepo.CreateSystemLog(&model.SystemLog{Message: message})
+	if err != nil {
+		logger.Error("loginThrottleService", "Failed to write system log", map[string]string{
+			"error": err.Error(),
+		})
+	}
+}
+
+func throttleKeys(username string, ipAddress string) []string {
+	keys := []string{throttleKeyUserPrefix + normalizeUsername(username)}
+	if ipAddress != "" {
+		keys = append(keys, throttleKeyIpPrefix+ipAddress)
+	}
+	return keys
+}
+
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
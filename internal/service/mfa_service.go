@@ -0,0 +1,301 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/dto"
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/repository"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/hasher"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/secretcipher"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/tokenprovider"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/totp"
+	"github.com/google/uuid"
+)
+
+// recoveryCodeCount is the number of single use recovery codes handed out
+// when MFA is activated.
+const recoveryCodeCount = 10
+
+type MfaService interface {
+	Enroll(accountId *uuid.UUID) (*dto.MfaEnrollResponse, error)
+	Activate(accountId *uuid.UUID, input *dto.MfaCodeBody) (*dto.MfaRecoveryCodesResponse, error)
+	Disable(accountId *uuid.UUID, input *dto.MfaCodeBody) error
+	VerifySecondFactor(account *model.User, input *dto.MfaCodeBody) error
+	StepUp(accountId *uuid.UUID, sessionId *uuid.UUID, input *dto.StepupBody) (*dto.StepupResponse, error)
+}
+
+type mfaService struct {
+	accountRepo   repository.AccountRepository
+	mfaRepo       repository.MfaRepository
+	secretCipher  secretcipher.Cipher
+	hasher        hasher.Hasher
+	jwtProvider   tokenprovider.JWTTokenProvider
+	loginThrottle LoginThrottleService
+	issuer        string
+}
+
+type MfaServiceConfig struct {
+	AccountRepo   repository.AccountRepository
+	MfaRepo       repository.MfaRepository
+	SecretCipher  secretcipher.Cipher
+	Hasher        hasher.Hasher
+	JwtProvider   tokenprovider.JWTTokenProvider
+	LoginThrottle LoginThrottleService
+	Issuer        string
+}
+
+func NewMfaService(config MfaServiceConfig) MfaService {
+	return &mfaService{
+		accountRepo:   config.AccountRepo,
+		mfaRepo:       config.MfaRepo,
+		secretCipher:  config.SecretCipher,
+		hasher:        config.Hasher,
+		jwtProvider:   config.JwtProvider,
+		loginThrottle: config.LoginThrottle,
+		issuer:        config.Issuer,
+	}
+}
+
+// Enroll generates a new TOTP secret for the account. The secret is only
+// enforced once Activate confirms a code generated from it.
+func (s *mfaService) Enroll(accountId *uuid.UUID) (*dto.MfaEnrollResponse, error) {
+	account, err := s.accountRepo.GetUserCredentialById(*accountId)
+	if err != nil || account == nil {
+		return nil, errs.InvalidAccountId
+	}
+	if account.MfaEnabledAt != nil {
+		return nil, errs.MfaAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, errs.ErrorEnrollingMfa
+	}
+
+	encrypted, err := s.secretCipher.Encrypt(secret)
+	if err != nil {
+		return nil, errs.ErrorEnrollingMfa
+	}
+
+	err = s.mfaRepo.SetPendingMfaSecret(account.ID, encrypted)
+	if errors.Is(err, errs.MfaAlreadyEnabled) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, errs.ErrorEnrollingMfa
+	}
+
+	return &dto.MfaEnrollResponse{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(s.issuer, account.Username, secret),
+	}, nil
+}
+
+// Activate turns MFA on after checking a code from the enrolled secret and
+// returns the recovery codes. They are only shown this once.
+func (s *mfaService) Activate(accountId *uuid.UUID, input *dto.MfaCodeBody) (*dto.MfaRecoveryCodesResponse, error) {
+	account, err := s.accountRepo.GetUserCredentialById(*accountId)
+	if err != nil || account == nil {
+		return nil, errs.InvalidAccountId
+	}
+	if account.MfaEnabledAt != nil {
+		return nil, errs.MfaAlreadyEnabled
+	}
+	if account.MfaSecret == "" {
+		return nil, errs.MfaNotEnrolled
+	}
+
+	err = s.throttled(account, input.IpAddress, func() error {
+		if !s.validateCode(account, input.Code) {
+			return errs.InvalidMfaCode
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	recoveryCodes := make([]string, 0, recoveryCodeCount)
+	codeHashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, errs.ErrorEnrollingMfa
+		}
+		recoveryCodes = append(recoveryCodes, code)
+		codeHashes = append(codeHashes, hasher.TokenDigest(normalizeRecoveryCode(code)))
+	}
+
+	err = s.mfaRepo.EnableMfa(account.ID, codeHashes)
+	if errors.Is(err, errs.MfaNotEnrolled) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, errs.ErrorEnrollingMfa
+	}
+
+	logger.Info("mfaService", "MFA enabled", map[string]string{
+		"user_id": account.ID.String(),
+	})
+	return &dto.MfaRecoveryCodesResponse{RecoveryCodes: recoveryCodes}, nil
+}
+
+func (s *mfaService) Disable(accountId *uuid.UUID, input *dto.MfaCodeBody) error {
+	account, err := s.accountRepo.GetUserCredentialById(*accountId)
+	if err != nil || account == nil {
+		return errs.InvalidAccountId
+	}
+
+	err = s.throttled(account, input.IpAddress, func() error {
+		return s.VerifySecondFactor(account, input)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.mfaRepo.DisableMfa(account.ID)
+	if err != nil {
+		return errs.ErrorDisablingMfa
+	}
+
+	logger.Info("mfaService", "MFA disabled", map[string]string{
+		"user_id": account.ID.String(),
+	})
+	return nil
+}
+
+// VerifySecondFactor accepts either a TOTP code or an unused recovery code.
+// A recovery code is consumed on success.
+func (s *mfaService) VerifySecondFactor(account *model.User, input *dto.MfaCodeBody) error {
+	if account.MfaEnabledAt == nil {
+		return errs.MfaNotEnabled
+	}
+
+	if input.Code != "" {
+		if s.validateCode(account, input.Code) {
+			return nil
+		}
+		return errs.InvalidMfaCode
+	}
+
+	if input.RecoveryCode != "" {
+		used, err := s.mfaRepo.UseRecoveryCode(account.ID, hasher.TokenDigest(normalizeRecoveryCode(input.RecoveryCode)))
+		if err != nil {
+			return err
+		}
+		if used {
+			logger.Warn("mfaService", "Recovery code used", map[string]string{
+				"user_id": account.ID.String(),
+			})
+			return nil
+		}
+	}
+
+	return errs.InvalidMfaCode
+}
+
+// StepUp re-authenticates the caller and issues a step-up token bound to the
+// current session. Accounts with MFA must use a TOTP code, others their
+// password.
+func (s *mfaService) StepUp(accountId *uuid.UUID, sessionId *uuid.UUID, input *dto.StepupBody) (*dto.StepupResponse, error) {
+	account, err := s.accountRepo.GetUserCredentialById(*accountId)
+	if err != nil || account == nil {
+		return nil, errs.InvalidAccountId
+	}
+
+	err = s.throttled(account, input.IpAddress, func() error {
+		if account.MfaEnabledAt != nil {
+			if !s.validateCode(account, input.Code) {
+				return errs.InvalidMfaCode
+			}
+			return nil
+		}
+
+		passwordOk, err := s.hasher.IsEqual(account.Password, input.Password)
+		if err != nil || !passwordOk {
+			return errs.PasswordDoesntMatch
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stepupToken, err := s.jwtProvider.GenerateStepupToken(account, *sessionId)
+	if err != nil {
+		return nil, errs.ErrorGeneratingStepup
+	}
+
+	return &dto.StepupResponse{StepupToken: stepupToken}, nil
+}
+
+// throttled runs a code or password check under the login throttle, so that
+// a session cannot be used to keep guessing past the login lockout.
+func (s *mfaService) throttled(account *model.User, ipAddress string, verify func() error) error {
+	err := s.loginThrottle.CheckLogin(account.Username, ipAddress)
+	if err != nil {
+		return err
+	}
+
+	err = verify()
+	if errors.Is(err, errs.InvalidMfaCode) || errors.Is(err, errs.PasswordDoesntMatch) {
+		s.loginThrottle.RecordFailedLogin(account.Username, ipAddress)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	s.loginThrottle.ResetLogin(account.Username)
+	return nil
+}
+
+func (s *mfaService) validateCode(account *model.User, code string) bool {
+	secret, err := s.secretCipher.Decrypt(account.MfaSecret)
+	if err != nil {
+		logger.Error("mfaService", "Failed to decrypt MFA secret", map[string]string{
+			"user_id": account.ID.String(),
+			"error":   err.Error(),
+		})
+		return false
+	}
+
+	step, ok := totp.Match(secret, code, time.Now())
+	if !ok {
+		return false
+	}
+
+	fresh, err := s.mfaRepo.UseTotpStep(account.ID, step)
+	if err != nil {
+		return false
+	}
+	if !fresh {
+		logger.Warn("mfaService", "Replayed MFA code", map[string]string{
+			"user_id": account.ID.String(),
+		})
+	}
+	return fresh
+}
+
+// generateRecoveryCode returns a code such as "3f9a1-c02be".
+func generateRecoveryCode() (string, error) {
+	buf := make([]byte, 5)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+	code := hex.EncodeToString(buf)
+	return code[:5] + "-" + code[5:], nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.ReplaceAll(code, "-", "")
+}
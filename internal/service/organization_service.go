@@ -0,0 +1,200 @@
+package service
+
+import (
+	"strconv"
+
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/constant"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/dto"
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/repository"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+	"github.com/google/uuid"
+)
+
+type OrganizationService interface {
+	GetOrganization(caller *dto.Caller) (*dto.OrganizationResponse, error)
+	UpdateOrganization(caller *dto.Caller, input *dto.UpdateOrganizationBody) (*dto.OrganizationResponse, error)
+	GetMembers(caller *dto.Caller) ([]*dto.OrganizationMemberResponse, error)
+	UpdateMemberRole(caller *dto.Caller, accountId *uuid.UUID, input *dto.UpdateOrganizationMemberBody) (*dto.OrganizationMemberResponse, error)
+	GetFarms(caller *dto.Caller) ([]*dto.FarmResponse, error)
+	GetSystemUnits(caller *dto.Caller, filter *dto.SystemUnitFilter) ([]*dto.SystemUnitResponse, error)
+}
+
+type organizationService struct {
+	organizationRepo repository.OrganizationRepository
+	farmRepo         repository.FarmRepository
+	systemUnitRepo   repository.SystemUnitRepository
+}
+
+type OrganizationServiceConfig struct {
+	OrganizationRepo repository.OrganizationRepository
+	FarmRepo         repository.FarmRepository
+	SystemUnitRepo   repository.SystemUnitRepository
+}
+
+func NewOrganizationService(config OrganizationServiceConfig) OrganizationService {
+	return &organizationService{
+		organizationRepo: config.OrganizationRepo,
+		farmRepo:         config.FarmRepo,
+		systemUnitRepo:   config.SystemUnitRepo,
+	}
+}
+
+func (s *organizationService) GetOrganization(caller *dto.Caller) (*dto.OrganizationResponse, error) {
+	organization, err := s.organizationRepo.GetOrganizationById(caller.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	return toOrganizationResponse(organization, caller.OrganizationRole), nil
+}
+
+func (s *organizationService) UpdateOrganization(caller *dto.Caller, input *dto.UpdateOrganizationBody) (*dto.OrganizationResponse, error) {
+	if !caller.IsOrganizationAdmin() {
+		return nil, errs.ForbiddenAccess
+	}
+
+	organization, err := s.organizationRepo.UpdateOrganization(&model.Organization{ID: caller.OrganizationID, Name: input.Name})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("organizationService", "Organization updated", map[string]string{
+		"organization_id": organization.ID.String(),
+	})
+	return toOrganizationResponse(organization, caller.OrganizationRole), nil
+}
+
+func (s *organizationService) GetMembers(caller *dto.Caller) ([]*dto.OrganizationMemberResponse, error) {
+	if caller.OrganizationRole != constant.OrgRoleAdmin {
+		return nil, errs.ForbiddenAccess
+	}
+
+	members, err := s.organizationRepo.GetOrganizationMembers(caller.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]*dto.OrganizationMemberResponse, 0, len(members))
+	for _, member := range members {
+		resp = append(resp, toOrganizationMemberResponse(member))
+	}
+	return resp, nil
+}
+
+// UpdateMemberRole promotes or demotes an account of the caller's
+// organization. The new role is picked up when the account next refreshes its
+// tokens.
+func (s *organizationService) UpdateMemberRole(caller *dto.Caller, accountId *uuid.UUID, input *dto.UpdateOrganizationMemberBody) (*dto.OrganizationMemberResponse, error) {
+	if !caller.IsOrganizationAdmin() {
+		return nil, errs.ForbiddenAccess
+	}
+
+	member, err := s.organizationRepo.UpdateOrganizationMemberRole(caller.OrganizationID, *accountId, input.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("organizationService", "Organization member role updated", map[string]string{
+		"organization_id": caller.OrganizationID.String(),
+		"account_id":      accountId.String(),
+		"role":            member.OrganizationRole,
+	})
+	return toOrganizationMemberResponse(member), nil
+}
+
+// GetFarms lists every farm of the organization, including the ones the
+// admin is not a member of.
+func (s *organizationService) GetFarms(caller *dto.Caller) ([]*dto.FarmResponse, error) {
+	if caller.OrganizationRole != constant.OrgRoleAdmin {
+		return nil, errs.ForbiddenAccess
+	}
+
+	farms, err := s.farmRepo.GetFarmsByOrganizationId(&caller.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp []*dto.FarmResponse
+	for _, farm := range farms {
+		if !caller.CanAccessFarm(farm.ID) {
+			continue
+		}
+		resp = append(resp, &dto.FarmResponse{
+			ID:      farm.ID,
+			Name:    farm.Name,
+			Address: farm.Address,
+		})
+	}
+
+	logger.Info("organizationService", "Fetched farms of organization", map[string]string{
+		"organization_id": caller.OrganizationID.String(),
+		"count":           strconv.Itoa(len(resp)),
+	})
+	return resp, nil
+}
+
+// GetSystemUnits lists the system units of every farm of the organization,
+// optionally filtered by farm.
+func (s *organizationService) GetSystemUnits(caller *dto.Caller, filter *dto.SystemUnitFilter) ([]*dto.SystemUnitResponse, error) {
+	if caller.OrganizationRole != constant.OrgRoleAdmin {
+		return nil, errs.ForbiddenAccess
+	}
+
+	var rawFarmIds string
+	if filter != nil {
+		rawFarmIds = filter.FarmIds
+	}
+
+	farmIds, err := parseFarmIds(rawFarmIds)
+	if err != nil {
+		return nil, errs.InvalidFarmIDParam
+	}
+
+	units, err := s.systemUnitRepo.GetSystemUnitsByOrganizationId(&caller.OrganizationID, farmIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp []*dto.SystemUnitResponse
+	for _, unit := range units {
+		if !caller.CanAccessFarm(unit.FarmId) {
+			continue
+		}
+		resp = append(resp, &dto.SystemUnitResponse{
+			ID:          unit.ID,
+			UnitKey:     unit.UnitKey,
+			FarmID:      unit.FarmId,
+			FarmName:    unit.FarmName,
+			TankVolume:  unit.TankVolume,
+			TankAVolume: unit.TankAVolume,
+			TankBVolume: unit.TankBVolume,
+		})
+	}
+
+	logger.Info("organizationService", "Fetched system units of organization", map[string]string{
+		"organization_id": caller.OrganizationID.String(),
+		"count":           strconv.Itoa(len(resp)),
+	})
+	return resp, nil
+}
+
+func toOrganizationResponse(organization *model.Organization, role string) *dto.OrganizationResponse {
+	return &dto.OrganizationResponse{
+		ID:        organization.ID,
+		Name:      organization.Name,
+		Role:      role,
+		CreatedAt: organization.CreatedAt,
+	}
+}
+
+func toOrganizationMemberResponse(member *model.OrganizationMember) *dto.OrganizationMemberResponse {
+	return &dto.OrganizationMemberResponse{
+		AccountID: member.AccountId,
+		Username:  member.Username,
+		Email:     member.Email,
+		Role:      member.OrganizationRole,
+		CreatedAt: member.CreatedAt,
+	}
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"errors"
 	"strconv"
 
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/dto"
@@ -13,11 +14,11 @@ import (
 )
 
 type ProfileService interface {
-	CreateProfile(input *dto.CreateProfile) (*dto.ProfileResponse, error)
-	GetProfiles() ([]*dto.ProfileResponse, error)
-	GetProfileDetails(profileId *uuid.UUID) (*dto.ProfileResponse, error)
-	UpdateProfile(profileId *uuid.UUID, profileData *dto.UpdateProfile) (*dto.ProfileResponse, error)
-	DeleteProfile(profileId *uuid.UUID) (*dto.ProfileResponse, error)
+	CreateProfile(caller *dto.Caller, input *dto.CreateProfile) (*dto.ProfileResponse, error)
+	GetProfiles(caller *dto.Caller) ([]*dto.ProfileResponse, error)
+	GetProfileDetails(caller *dto.Caller, profileId *uuid.UUID) (*dto.ProfileResponse, error)
+	UpdateProfile(caller *dto.Caller, profileId *uuid.UUID, profileData *dto.UpdateProfile) (*dto.ProfileResponse, error)
+	DeleteProfile(caller *dto.Caller, profileId *uuid.UUID) (*dto.ProfileResponse, error)
 }
 
 type profileService struct {
@@ -37,23 +38,29 @@ func NewProfileService(config ProfileServiceConfig) ProfileService {
 	}
 }
 
-func (s *profileService) CreateProfile(input *dto.CreateProfile) (*dto.ProfileResponse, error) {
+func (s *profileService) CreateProfile(caller *dto.Caller, input *dto.CreateProfile) (*dto.ProfileResponse, error) {
 	logger.Info("profileService", "Creating profile", map[string]string{
-		"accountId": input.AccountID.String(),
+		"accountId": caller.AccountID.String(),
 	})
 
-	user, err := s.accountRepo.GetUserById(input.AccountID)
+	// read API tokens and read only impersonation may look but not change
+	if caller.ReadOnly {
+		return nil, errs.ForbiddenAccess
+	}
+
+	user, err := s.accountRepo.GetUserById(caller.AccountID)
 	if err != nil || user == nil {
 		logger.Error("profileService", "Invalid Account ID", map[string]string{
-			"error": err.Error(),
+			"accountId": caller.AccountID.String(),
 		})
 		return nil, errs.InvalidAccountId
 	}
 
 	checkedProfile, err := s.profileRepo.CheckCreatedProfileByAccountId(&model.Profile{
-		AccountId: input.AccountID,
+		AccountId:      caller.AccountID,
+		OrganizationId: caller.OrganizationID,
 	})
-	if err != nil {
+	if err != nil && !errors.Is(err, errs.InvalidAccountId) {
 		logger.Error("profileService", "Error checking profile", map[string]string{
 			"error": err.Error(),
 		})
@@ -68,9 +75,10 @@ func (s *profileService) CreateProfile(input *dto.CreateProfile) (*dto.ProfileRe
 	}
 
 	createdProfile, err := s.profileRepo.CreateProfile(&model.Profile{
-		AccountId: input.AccountID,
-		Name:      input.Name,
-		Address:   input.Address,
+		AccountId:      caller.AccountID,
+		OrganizationId: caller.OrganizationID,
+		Name:           input.Name,
+		Address:        input.Address,
 	})
 	if err != nil {
 		logger.Error("profileService", "Error creating profile", map[string]string{
@@ -91,12 +99,14 @@ func (s *profileService) CreateProfile(input *dto.CreateProfile) (*dto.ProfileRe
 	return respBody, err
 }
 
-func (s *profileService) GetProfiles() ([]*dto.ProfileResponse, error) {
-	logger.Info("profileService", "Fetching all profiles", nil)
+func (s *profileService) GetProfiles(caller *dto.Caller) ([]*dto.ProfileResponse, error) {
+	logger.Info("profileService", "Fetching profiles of caller", map[string]string{
+		"accountId": caller.AccountID.String(),
+	})
 
 	var profilesRes []*dto.ProfileResponse
 
-	res, err := s.profileRepo.GetProfiles()
+	res, err := s.profileRepo.GetProfilesByAccountId(&model.Profile{AccountId: caller.AccountID, OrganizationId: caller.OrganizationID})
 	if err != nil {
 		logger.Error("profileService", "Error fetching profiles", map[string]string{
 			"error": err.Error(),
@@ -117,12 +127,12 @@ func (s *profileService) GetProfiles() ([]*dto.ProfileResponse, error) {
 	return profilesRes, err
 }
 
-func (s *profileService) GetProfileDetails(profileId *uuid.UUID) (*dto.ProfileResponse, error) {
+func (s *profileService) GetProfileDetails(caller *dto.Caller, profileId *uuid.UUID) (*dto.ProfileResponse, error) {
 	logger.Info("profileService", "Fetching profile details", map[string]string{
 		"profileId": profileId.String(),
 	})
 
-	res, err := s.profileRepo.GetProfileById(&model.Profile{ID: *profileId})
+	res, err := s.profileRepo.GetProfileById(&model.Profile{ID: *profileId, AccountId: caller.AccountID, OrganizationId: caller.OrganizationID})
 	if err != nil {
 		logger.Error("profileService", "Error fetching profile details", map[string]string{
 			"error": err.Error(),
@@ -140,12 +150,16 @@ func (s *profileService) GetProfileDetails(profileId *uuid.UUID) (*dto.ProfileRe
 	}, err
 }
 
-func (s *profileService) UpdateProfile(profileId *uuid.UUID, profileData *dto.UpdateProfile) (*dto.ProfileResponse, error) {
+func (s *profileService) UpdateProfile(caller *dto.Caller, profileId *uuid.UUID, profileData *dto.UpdateProfile) (*dto.ProfileResponse, error) {
 	logger.Info("profileService", "Updating profile", map[string]string{
 		"profileId": profileId.String(),
 	})
 
-	res, err := s.profileRepo.UpdateProfile(&model.Profile{ID: *profileId, Name: profileData.Name, Address: profileData.Address})
+	if caller.ReadOnly {
+		return nil, errs.ForbiddenAccess
+	}
+
+	res, err := s.profileRepo.UpdateProfile(&model.Profile{ID: *profileId, AccountId: caller.AccountID, OrganizationId: caller.OrganizationID, Name: profileData.Name, Address: profileData.Address})
 	if err != nil {
 		logger.Error("profileService", "Error updating profile", map[string]string{
 			"error": err.Error(),
@@ -163,12 +177,16 @@ func (s *profileService) UpdateProfile(profileId *uuid.UUID, profileData *dto.Up
 	}, err
 }
 
-func (s *profileService) DeleteProfile(profileId *uuid.UUID) (*dto.ProfileResponse, error) {
+func (s *profileService) DeleteProfile(caller *dto.Caller, profileId *uuid.UUID) (*dto.ProfileResponse, error) {
 	logger.Info("profileService", "Deleting profile", map[string]string{
 		"profileId": profileId.String(),
 	})
 
-	res, err := s.profileRepo.DeleteProfile(&model.Profile{ID: *profileId})
+	if caller.ReadOnly {
+		return nil, errs.ForbiddenAccess
+	}
+
+	res, err := s.profileRepo.DeleteProfile(&model.Profile{ID: *profileId, AccountId: caller.AccountID, OrganizationId: caller.OrganizationID})
 	if err != nil {
 		logger.Error("profileService", "Error deleting profile", map[string]string{
 			"error": err.Error(),
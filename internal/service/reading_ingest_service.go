@@ -0,0 +1,423 @@
+package service
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/dto"
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/repository"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+	"github.com/google/uuid"
+)
+
+// Bulk upload formats. Each NDJSON line or CSV record is one sample.
+const (
+	BulkFormatNDJSON = "ndjson"
+	BulkFormatCSV    = "csv"
+)
+
+// Outcome of a row in the bulk ingestion report.
+const (
+	BulkRowAccepted = "accepted"
+	BulkRowRejected = "rejected"
+)
+
+const (
+	// bulkMaxRows bounds the samples of one upload.
+	bulkMaxRows = 10000
+	// bulkMaxLineSize bounds a single NDJSON line.
+	bulkMaxLineSize = 1 << 20
+)
+
+// ReadingIngestService takes batches of samples across farms and system units,
+// as uploaded by gateways that buffer readings.
+type ReadingIngestService interface {
+	IngestReadings(caller *dto.Caller, format string, body io.Reader) (*dto.BulkIngestResponse, error)
+	IngestLineProtocol(caller *dto.Caller, precision string, body io.Reader) (*dto.BulkIngestResponse, error)
+}
+
+type readingIngestService struct {
+	growthHistRepo repository.GrowthHistRepository
+	metricRepo     repository.MetricRepository
+	farmMemberRepo repository.FarmMemberRepository
+	systemUnitRepo repository.SystemUnitRepository
+	skewPolicy     ClockSkewPolicy
+}
+
+type ReadingIngestServiceConfig struct {
+	GrowthHistRepo repository.GrowthHistRepository
+	MetricRepo     repository.MetricRepository
+	FarmMemberRepo repository.FarmMemberRepository
+	SystemUnitRepo repository.SystemUnitRepository
+	SkewPolicy     ClockSkewPolicy
+}
+
+func NewReadingIngestService(config ReadingIngestServiceConfig) ReadingIngestService {
+	return &readingIngestService{
+		growthHistRepo: config.GrowthHistRepo,
+		metricRepo:     config.MetricRepo,
+		farmMemberRepo: config.FarmMemberRepo,
+		systemUnitRepo: config.SystemUnitRepo,
+		skewPolicy:     config.SkewPolicy,
+	}
+}
+
+// bulkRow is a parsed sample, or the reason it could not be parsed. Line
+// protocol rows may name their system unit by unit key instead of by farm and
+// system id.
+type bulkRow struct {
+	line       int
+	farmId     uuid.UUID
+	systemId   uuid.UUID
+	unitKey    uuid.UUID
+	readings   map[string]float64
+	measuredAt *time.Time
+	err        error
+}
+
+type systemUnitKey struct {
+	farmId   uuid.UUID
+	systemId uuid.UUID
+}
+
+// IngestReadings validates every row and stores the valid ones. Rows are
+// rejected individually; only a malformed upload as a whole fails the call.
+func (s *readingIngestService) IngestReadings(caller *dto.Caller, format string, body io.Reader) (*dto.BulkIngestResponse, error) {
+	logger.Info("readingIngestService", "Ingesting readings", map[string]string{
+		"format":    format,
+		"accountId": caller.AccountID.String(),
+	})
+
+	catalog, err := loadMetricCatalog(s.metricRepo)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []*bulkRow
+	switch format {
+	case BulkFormatNDJSON:
+		rows, err = parseNDJSONRows(body)
+	case BulkFormatCSV:
+		rows, err = parseCSVRows(body, catalog)
+	default:
+		return nil, errs.InvalidBulkFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, errs.EmptyBulkBody
+	}
+
+	return s.storeRows(caller, catalog, rows)
+}
+
+// IngestLineProtocol takes the InfluxDB line protocol written by Telegraf and
+// similar loggers, with timestamps in the given precision. Rows are rejected
+// individually as for IngestReadings.
+func (s *readingIngestService) IngestLineProtocol(caller *dto.Caller, precision string, body io.Reader) (*dto.BulkIngestResponse, error) {
+	logger.Info("readingIngestService", "Ingesting line protocol", map[string]string{
+		"precision": precision,
+		"accountId": caller.AccountID.String(),
+	})
+
+	unit, ok := linePrecisions[precision]
+	if !ok {
+		return nil, errs.InvalidLinePrecision
+	}
+
+	catalog, err := loadMetricCatalog(s.metricRepo)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := parseLineProtocolRows(body, unit, catalog)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, errs.EmptyBulkBody
+	}
+
+	// each unit key or system id is looked up once, not once per line
+	units := make(map[lineTarget]*model.SystemUnit)
+	lookups := make(map[lineTarget]error)
+	for _, row := range rows {
+		if row.err != nil {
+			continue
+		}
+
+		key := lineTarget{unitKey: row.unitKey, systemId: row.systemId}
+		lookupErr, ok := lookups[key]
+		if !ok {
+			units[key], lookupErr = s.findSystemUnit(caller, row.unitKey, row.systemId)
+			lookups[key] = lookupErr
+		}
+		if errors.Is(lookupErr, errs.ErrorIngestingReadings) {
+			return nil, lookupErr
+		}
+		if lookupErr != nil {
+			row.err = lookupErr
+			continue
+		}
+		row.farmId = units[key].FarmId
+		row.systemId = units[key].ID
+	}
+
+	return s.storeRows(caller, catalog, rows)
+}
+
+// findSystemUnit resolves the tags of a line protocol row. A unit key alone
+// must name a single system unit the caller can see.
+func (s *readingIngestService) findSystemUnit(caller *dto.Caller, unitKey uuid.UUID, systemId uuid.UUID) (*model.SystemUnit, error) {
+	if systemId != uuid.Nil {
+		systemUnit, err := s.systemUnitRepo.GetSystemUnitByIdAndScope(&model.SystemUnit{ID: systemId}, scopeOf(caller))
+		if err != nil || (unitKey != uuid.Nil && systemUnit.UnitKey != unitKey) {
+			return nil, errs.InvalidSystemUnitID
+		}
+		return systemUnit, nil
+	}
+
+	systemUnits, err := s.systemUnitRepo.GetSystemUnitsByUnitKeyAndScope(unitKey, scopeOf(caller))
+	if err != nil {
+		// the lookup failed, not the row
+		return nil, errs.ErrorIngestingReadings
+	}
+	switch len(systemUnits) {
+	case 0:
+		return nil, errs.InvalidSystemUnitID
+	case 1:
+		return systemUnits[0], nil
+	}
+	return nil, errs.AmbiguousUnitKey
+}
+
+// storeRows checks the caller's access and the readings of every parsed row,
+// then stores the valid ones at once.
+func (s *readingIngestService) storeRows(caller *dto.Caller, catalog map[string]*model.Metric, rows []*bulkRow) (*dto.BulkIngestResponse, error) {
+	var err error
+
+	// the whole upload is received at once; buffered samples keep their
+	// own measured_at
+	receivedAt := time.Now()
+
+	// each farm and system unit is checked once, not once per row
+	access := make(map[systemUnitKey]error)
+	resp := &dto.BulkIngestResponse{Rows: make([]*dto.BulkRowResult, 0, len(rows))}
+	var readings []*model.Reading
+	for _, row := range rows {
+		result := &dto.BulkRowResult{Line: row.line, Status: BulkRowRejected}
+		resp.Rows = append(resp.Rows, result)
+
+		err := row.err
+		if err == nil {
+			key := systemUnitKey{farmId: row.farmId, systemId: row.systemId}
+			accessErr, ok := access[key]
+			if !ok {
+				_, _, accessErr = resolveSystemUnit(s.farmMemberRepo, s.systemUnitRepo, caller, row.farmId, row.systemId, farmWriters)
+				access[key] = accessErr
+			}
+			err = accessErr
+		}
+		if err == nil {
+			err = checkReadings(catalog, row.readings)
+		}
+		var measuredAt time.Time
+		var skewFlagged bool
+		if err == nil {
+			measuredAt, skewFlagged, err = s.skewPolicy.apply(row.measuredAt, receivedAt)
+		}
+		if err != nil {
+			result.Reason = err.Error()
+			resp.Rejected++
+			continue
+		}
+
+		sampleId := uuid.New()
+		for metric, value := range row.readings {
+			readings = append(readings, &model.Reading{
+				SampleId:    sampleId,
+				FarmId:      row.farmId,
+				SystemId:    row.systemId,
+				Metric:      metric,
+				Value:       value,
+				MeasuredAt:  measuredAt,
+				ReceivedAt:  receivedAt,
+				SkewFlagged: skewFlagged,
+			})
+		}
+		result.Status = BulkRowAccepted
+		result.SampleId = &sampleId
+		result.SkewFlagged = skewFlagged
+		resp.Accepted++
+	}
+
+	if len(readings) > 0 {
+		resp.Readings, err = s.growthHistRepo.CreateReadingsBulk(readings)
+		if err != nil {
+			logger.Error("readingIngestService", "Error storing bulk readings", map[string]string{
+				"error": err.Error(),
+			})
+			return nil, errs.ErrorIngestingReadings
+		}
+	}
+
+	logger.Info("readingIngestService", "Readings ingested", map[string]string{
+		"accepted": strconv.Itoa(resp.Accepted),
+		"rejected": strconv.Itoa(resp.Rejected),
+	})
+	return resp, nil
+}
+
+func parseNDJSONRows(body io.Reader) ([]*bulkRow, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), bulkMaxLineSize)
+
+	var rows []*bulkRow
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		if len(rows) == bulkMaxRows {
+			return nil, errs.TooManyBulkRows
+		}
+
+		row := &bulkRow{line: line}
+		rows = append(rows, row)
+
+		var input dto.BulkReadingRow
+		err := json.Unmarshal([]byte(text), &input)
+		if err != nil || input.FarmId == uuid.Nil || input.SystemId == uuid.Nil {
+			row.err = errs.InvalidBulkRow
+			continue
+		}
+		row.farmId = input.FarmId
+		row.systemId = input.SystemId
+		row.readings = input.Readings
+		row.measuredAt = input.MeasuredAt
+	}
+
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, errs.InvalidBulkRow
+		}
+		return nil, errs.InvalidRequestBody
+	}
+	return rows, nil
+}
+
+// parseCSVRows reads a header of farm_id, system_id, an optional RFC 3339
+// measured_at and one column per metric followed by one sample per record.
+// Empty metric cells are left out of the sample.
+func parseCSVRows(body io.Reader, catalog map[string]*model.Metric) ([]*bulkRow, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errs.EmptyBulkBody
+	}
+	if err != nil {
+		return nil, errs.InvalidBulkCsvHeader
+	}
+
+	farmColumn, systemColumn, measuredColumn := -1, -1, -1
+	metricColumns := make(map[int]string)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "farm_id":
+			farmColumn = i
+		case "system_id":
+			systemColumn = i
+		case "measured_at":
+			measuredColumn = i
+		default:
+			if _, ok := catalog[name]; !ok {
+				return nil, errs.InvalidMetric
+			}
+			metricColumns[i] = name
+		}
+	}
+	if farmColumn < 0 || systemColumn < 0 || len(metricColumns) == 0 {
+		return nil, errs.InvalidBulkCsvHeader
+	}
+
+	var rows []*bulkRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if len(rows) == bulkMaxRows {
+			return nil, errs.TooManyBulkRows
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			rows = append(rows, &bulkRow{line: parseErr.StartLine, err: errs.InvalidBulkRow})
+			continue
+		}
+		if err != nil {
+			return nil, errs.InvalidRequestBody
+		}
+
+		line, _ := reader.FieldPos(0)
+		rows = append(rows, parseCSVRecord(line, record, farmColumn, systemColumn, measuredColumn, metricColumns))
+	}
+	return rows, nil
+}
+
+func parseCSVRecord(line int, record []string, farmColumn int, systemColumn int, measuredColumn int, metricColumns map[int]string) *bulkRow {
+	row := &bulkRow{line: line, readings: make(map[string]float64, len(metricColumns))}
+
+	farmId, err := uuid.Parse(strings.TrimSpace(record[farmColumn]))
+	if err != nil {
+		row.err = errs.InvalidFarmID
+		return row
+	}
+	systemId, err := uuid.Parse(strings.TrimSpace(record[systemColumn]))
+	if err != nil {
+		row.err = errs.InvalidSystemUnitID
+		return row
+	}
+	row.farmId = farmId
+	row.systemId = systemId
+
+	if measuredColumn >= 0 {
+		if cell := strings.TrimSpace(record[measuredColumn]); cell != "" {
+			measuredAt, err := time.Parse(time.RFC3339, cell)
+			if err != nil {
+				row.err = errs.InvalidBulkRow
+				return row
+			}
+			row.measuredAt = &measuredAt
+		}
+	}
+
+	for i, metric := range metricColumns {
+		cell := strings.TrimSpace(record[i])
+		if cell == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(cell, 64)
+		if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+			row.err = errs.InvalidBulkRow
+			return row
+		}
+		row.readings[metric] = value
+	}
+	return row
+}
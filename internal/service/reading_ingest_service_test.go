@@ -0,0 +1,200 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/google/uuid"
+)
+
+const testFarmId = "3d7a9c1e-5b2f-4a8d-9e6c-1f0b2a4c6e85"
+
+// wantRow is what a test expects of a parsed bulkRow. Readings are only
+// compared for rows without an error.
+type wantRow struct {
+	line       int
+	readings   map[string]float64
+	measuredAt *time.Time
+	err        error
+}
+
+func checkRows(t *testing.T, rows []*bulkRow, want []wantRow) {
+	t.Helper()
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(want))
+	}
+
+	farmId := uuid.MustParse(testFarmId)
+	systemId := uuid.MustParse(testSystemId)
+	for i, w := range want {
+		row := rows[i]
+		if row.line != w.line || !errors.Is(row.err, w.err) {
+			t.Errorf("row %d = line %d error %v, want line %d error %v", i, row.line, row.err, w.line, w.err)
+			continue
+		}
+		if w.err != nil {
+			continue
+		}
+		if row.farmId != farmId || row.systemId != systemId {
+			t.Errorf("row %d system unit = (%s, %s), want (%s, %s)", i, row.farmId, row.systemId, farmId, systemId)
+		}
+		if !equalReadings(row.readings, w.readings) {
+			t.Errorf("row %d readings = %v, want %v", i, row.readings, w.readings)
+		}
+		switch {
+		case w.measuredAt == nil && row.measuredAt != nil:
+			t.Errorf("row %d measuredAt = %v, want none", i, *row.measuredAt)
+		case w.measuredAt != nil && (row.measuredAt == nil || !row.measuredAt.Equal(*w.measuredAt)):
+			t.Errorf("row %d measuredAt = %v, want %v", i, row.measuredAt, *w.measuredAt)
+		}
+	}
+}
+
+func TestParseNDJSONRows(t *testing.T) {
+	unit := `"farm_id":"` + testFarmId + `","system_id":"` + testSystemId + `"`
+
+	tests := []struct {
+		name    string
+		body    string
+		want    []wantRow
+		wantErr error
+	}{
+		{
+			name: "samples and blank lines",
+			body: "{" + unit + `,"readings":{"ppm":800,"ph":6.1}}` + "\n\n" +
+				"{" + unit + `,"readings":{"ph":6.2},"measured_at":"2024-05-01T10:00:00Z"}` + "\n",
+			want: []wantRow{
+				{line: 1, readings: map[string]float64{"ppm": 800, "ph": 6.1}},
+				{line: 3, readings: map[string]float64{"ph": 6.2}, measuredAt: timePtr(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC))},
+			},
+		},
+		{
+			name: "bad rows are reported by line",
+			body: "not json\n" +
+				`{"system_id":"` + testSystemId + `","readings":{"ppm":1}}` + "\n" +
+				"{" + unit + `,"readings":{"ppm":NaN}}` + "\n" +
+				"{" + unit + `,"readings":{"ppm":"800"}}` + "\n" +
+				"{" + unit + `,"readings":{"ppm":1}}`,
+			want: []wantRow{
+				{line: 1, err: errs.InvalidBulkRow},
+				{line: 2, err: errs.InvalidBulkRow},
+				{line: 3, err: errs.InvalidBulkRow},
+				{line: 4, err: errs.InvalidBulkRow},
+				{line: 5, readings: map[string]float64{"ppm": 1}},
+			},
+		},
+		{
+			name:    "too many rows",
+			body:    strings.Repeat("{}\n", bulkMaxRows+1),
+			wantErr: errs.TooManyBulkRows,
+		},
+		{
+			name:    "line too long",
+			body:    "{" + unit + `,"readings":{"ppm":1` + strings.Repeat("0", bulkMaxLineSize) + "}}",
+			wantErr: errs.InvalidBulkRow,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := parseNDJSONRows(strings.NewReader(tt.body))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("parseNDJSONRows() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil {
+				checkRows(t, rows, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCSVRows(t *testing.T) {
+	unit := testFarmId + "," + testSystemId
+
+	tests := []struct {
+		name    string
+		body    string
+		want    []wantRow
+		wantErr error
+	}{
+		{
+			name: "samples with and without measured_at",
+			body: "farm_id,system_id,measured_at,ppm,ph\n" +
+				unit + ",2024-05-01T10:00:00Z,800,6.1\n" +
+				unit + ",,812.5,\n",
+			want: []wantRow{
+				{line: 2, readings: map[string]float64{"ppm": 800, "ph": 6.1}, measuredAt: timePtr(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC))},
+				{line: 3, readings: map[string]float64{"ppm": 812.5}},
+			},
+		},
+		{
+			name: "header in any order and case",
+			body: " PH , System_ID, farm_id\n6.3," + testSystemId + ", " + testFarmId + "\n",
+			want: []wantRow{
+				{line: 2, readings: map[string]float64{"ph": 6.3}},
+			},
+		},
+		{
+			name: "bad rows are reported by line",
+			body: "farm_id,system_id,measured_at,ppm\n" +
+				"farm," + testSystemId + ",,1\n" +
+				testFarmId + ",system,,1\n" +
+				unit + ",yesterday,1\n" +
+				unit + ",,high\n" +
+				unit + ",,NaN\n" +
+				unit + ",,-Inf\n" +
+				unit + ",,1e400\n" +
+				unit + ",1\n" +
+				unit + `,,"1` + "\n",
+			want: []wantRow{
+				{line: 2, err: errs.InvalidFarmID},
+				{line: 3, err: errs.InvalidSystemUnitID},
+				{line: 4, err: errs.InvalidBulkRow},
+				{line: 5, err: errs.InvalidBulkRow},
+				{line: 6, err: errs.InvalidBulkRow},
+				{line: 7, err: errs.InvalidBulkRow},
+				{line: 8, err: errs.InvalidBulkRow},
+				{line: 9, err: errs.InvalidBulkRow},
+				{line: 10, err: errs.InvalidBulkRow},
+			},
+		},
+		{
+			name:    "empty body",
+			body:    "",
+			wantErr: errs.EmptyBulkBody,
+		},
+		{
+			name:    "unknown metric column",
+			body:    "farm_id,system_id,co2\n",
+			wantErr: errs.InvalidMetric,
+		},
+		{
+			name:    "no metric column",
+			body:    "farm_id,system_id,measured_at\n",
+			wantErr: errs.InvalidBulkCsvHeader,
+		},
+		{
+			name:    "no system_id column",
+			body:    "farm_id,ppm\n",
+			wantErr: errs.InvalidBulkCsvHeader,
+		},
+		{
+			name:    "too many rows",
+			body:    "farm_id,system_id,ppm\n" + strings.Repeat(unit+",1\n", bulkMaxRows+1),
+			wantErr: errs.TooManyBulkRows,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := parseCSVRows(strings.NewReader(tt.body), testCatalog)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("parseCSVRows() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil {
+				checkRows(t, rows, tt.want)
+			}
+		})
+	}
+}
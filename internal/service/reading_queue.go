@@ -0,0 +1,290 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/dto"
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/repository"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+)
+
+const (
+	// flushAttempts bounds the retries of a batch the database rejected.
+	flushAttempts = 3
+	// flushRetryDelay grows with each attempt.
+	flushRetryDelay = 500 * time.Millisecond
+)
+
+// ReadingQueuePolicy sizes the write queue. Capacity is the number of samples
+// waiting to be stored; a full queue turns writers away instead of growing.
+type ReadingQueuePolicy struct {
+	Capacity      int
+	Workers       int
+	BatchSize     int
+	FlushInterval time.Duration
+}
+
+func DefaultReadingQueuePolicy() ReadingQueuePolicy {
+	return ReadingQueuePolicy{
+		Capacity:      10000,
+		Workers:       2,
+		BatchSize:     500,
+		FlushInterval: time.Second,
+	}
+}
+
+// ReadingQueue buffers samples in memory and stores them in batches, so
+// frequent single samples do not each cost a round trip to the database.
+// Queued samples are lost if the process dies before a flush; Close drains
+// the queue on a regular shutdown.
+type ReadingQueue interface {
+	Enqueue(sample *model.ReadingSample) ([]*model.IngestReceipt, error)
+	Pending(receipt *model.IngestReceipt) []*model.IngestReceipt
+	Stats() *dto.ReadingQueueStats
+	Close()
+}
+
+type readingQueue struct {
+	growthHistRepo repository.GrowthHistRepository
+	policy         ReadingQueuePolicy
+	samples        chan *queuedSample
+	workers        sync.WaitGroup
+
+	mu      sync.Mutex
+	closed  bool
+	pending map[string]*model.IngestReceipt
+	stats   dto.ReadingQueueStats
+	flushed time.Duration
+}
+
+type ReadingQueueConfig struct {
+	GrowthHistRepo repository.GrowthHistRepository
+	Policy         ReadingQueuePolicy
+}
+
+type queuedSample struct {
+	sample     *model.ReadingSample
+	enqueuedAt time.Time
+}
+
+// NewReadingQueue starts the flush workers of the queue.
+func NewReadingQueue(config ReadingQueueConfig) ReadingQueue {
+	policy := config.Policy
+	policy.Workers = max(policy.Workers, 1)
+	policy.BatchSize = max(policy.BatchSize, 1)
+	if policy.FlushInterval <= 0 {
+		policy.FlushInterval = DefaultReadingQueuePolicy().FlushInterval
+	}
+
+	q := &readingQueue{
+		growthHistRepo: config.GrowthHistRepo,
+		policy:         policy,
+		samples:        make(chan *queuedSample, policy.Capacity),
+		pending:        make(map[string]*model.IngestReceipt),
+	}
+	q.stats.Capacity = policy.Capacity
+	q.stats.Workers = policy.Workers
+
+	for i := 0; i < policy.Workers; i++ {
+		q.workers.Add(1)
+		go q.work()
+	}
+
+	logger.Info("readingQueue", "Reading queue started", map[string]string{
+		"capacity":  strconv.Itoa(policy.Capacity),
+		"workers":   strconv.Itoa(policy.Workers),
+		"batchSize": strconv.Itoa(policy.BatchSize),
+	})
+	return q
+}
+
+// Enqueue accepts a sample for storage. When a sample with the same retry
+// keys is still queued, the sample is dropped and the queued receipts are
+// returned so the caller can answer as for a replay.
+func (q *readingQueue) Enqueue(sample *model.ReadingSample) ([]*model.IngestReceipt, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return nil, errs.ReadingQueueClosed
+	}
+
+	if sample.Receipt != nil {
+		pending := q.pendingLocked(sample.Receipt)
+		if len(pending) > 0 {
+			return pending, nil
+		}
+	}
+
+	select {
+	case q.samples <- &queuedSample{sample: sample, enqueuedAt: time.Now()}:
+	default:
+		q.stats.Rejected++
+		return nil, errs.ReadingQueueFull
+	}
+
+	if sample.Receipt != nil {
+		for _, key := range receiptKeys(sample.Receipt) {
+			q.pending[key] = sample.Receipt
+		}
+	}
+	q.stats.Enqueued++
+	return nil, nil
+}
+
+// Pending returns the queued receipts that share a key with receipt.
+func (q *readingQueue) Pending(receipt *model.IngestReceipt) []*model.IngestReceipt {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.pendingLocked(receipt)
+}
+
+func (q *readingQueue) pendingLocked(receipt *model.IngestReceipt) []*model.IngestReceipt {
+	var pending []*model.IngestReceipt
+	for _, key := range receiptKeys(receipt) {
+		queued, ok := q.pending[key]
+		if ok && (len(pending) == 0 || pending[0] != queued) {
+			pending = append(pending, queued)
+		}
+	}
+	return pending
+}
+
+func (q *readingQueue) Stats() *dto.ReadingQueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	stats := q.stats
+	stats.Depth = len(q.samples)
+	stats.Unflushed = stats.Enqueued - stats.Stored - stats.Failed
+	if stats.Batches > 0 {
+		stats.AvgFlushMs = q.flushed.Milliseconds() / stats.Batches
+	}
+	return &stats
+}
+
+// Close stops accepting samples and returns once every queued sample has been
+// flushed.
+func (q *readingQueue) Close() {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return
+	}
+	q.closed = true
+	close(q.samples)
+	q.mu.Unlock()
+
+	logger.Info("readingQueue", "Draining reading queue", map[string]string{
+		"depth": strconv.Itoa(len(q.samples)),
+	})
+	q.workers.Wait()
+	logger.Info("readingQueue", "Reading queue drained", nil)
+}
+
+// work collects samples until the batch is full or the flush interval
+// passes, and flushes what is left when the queue closes.
+func (q *readingQueue) work() {
+	defer q.workers.Done()
+
+	ticker := time.NewTicker(q.policy.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*queuedSample, 0, q.policy.BatchSize)
+	for {
+		select {
+		case sample, ok := <-q.samples:
+			if !ok {
+				q.flush(batch)
+				return
+			}
+			batch = append(batch, sample)
+			if len(batch) < q.policy.BatchSize {
+				continue
+			}
+		case <-ticker.C:
+		}
+
+		q.flush(batch)
+		batch = batch[:0]
+	}
+}
+
+func (q *readingQueue) flush(batch []*queuedSample) {
+	if len(batch) == 0 {
+		return
+	}
+
+	samples := make([]*model.ReadingSample, 0, len(batch))
+	for _, queued := range batch {
+		samples = append(samples, queued.sample)
+	}
+
+	start := time.Now()
+	var err error
+	for attempt := 1; attempt <= flushAttempts; attempt++ {
+		_, err = q.growthHistRepo.CreateReadingSamples(samples)
+		if err == nil {
+			break
+		}
+		if attempt < flushAttempts {
+			logger.Warn("readingQueue", "Retrying failed flush", map[string]string{
+				"attempt": strconv.Itoa(attempt),
+				"error":   err.Error(),
+			})
+			time.Sleep(time.Duration(attempt) * flushRetryDelay)
+		}
+	}
+	elapsed := time.Since(start)
+
+	q.mu.Lock()
+	for _, sample := range samples {
+		if sample.Receipt == nil {
+			continue
+		}
+		for _, key := range receiptKeys(sample.Receipt) {
+			if q.pending[key] == sample.Receipt {
+				delete(q.pending, key)
+			}
+		}
+	}
+	count := int64(len(samples))
+	if err != nil {
+		q.stats.Failed += count
+	} else {
+		q.stats.Stored += count
+	}
+	q.stats.Batches++
+	q.flushed += elapsed
+	q.stats.LastFlushMs = elapsed.Milliseconds()
+	q.stats.MaxFlushMs = max(q.stats.MaxFlushMs, q.stats.LastFlushMs)
+	// the oldest sample of the batch waited longest for its readings to land
+	q.stats.LastLatencyMs = time.Since(batch[0].enqueuedAt).Milliseconds()
+	q.mu.Unlock()
+
+	if err != nil {
+		logger.Error("readingQueue", "Dropping samples after failed flushes", map[string]string{
+			"count": strconv.FormatInt(count, 10),
+			"error": err.Error(),
+		})
+	}
+}
+
+// receiptKeys names the unique keys of a receipt, as in the ingest_receipts
+// constraints.
+func receiptKeys(receipt *model.IngestReceipt) []string {
+	var keys []string
+	if receipt.MessageId != nil {
+		keys = append(keys, fmt.Sprintf("%s/message/%s", receipt.SystemId, *receipt.MessageId))
+	}
+	if receipt.Sequence != nil {
+		keys = append(keys, fmt.Sprintf("%s/sequence/%d", receipt.SystemId, *receipt.Sequence))
+	}
+	return keys
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/repository"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+	"github.com/google/uuid"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "service-test")
+	if err != nil {
+		panic(err)
+	}
+	err = logger.Init(filepath.Join(dir, "app.log"))
+	if err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// fakeSampleStore records the batches flushed by a reading queue. When hold
+// is set, every flush waits until it is closed.
+type fakeSampleStore struct {
+	repository.GrowthHistRepository
+
+	hold     chan struct{}
+	flushing chan struct{}
+
+	mu      sync.Mutex
+	batches []int
+	stored  []*model.ReadingSample
+}
+
+func newFakeSampleStore(hold bool) *fakeSampleStore {
+	f := &fakeSampleStore{flushing: make(chan struct{}, 100)}
+	if hold {
+		f.hold = make(chan struct{})
+	}
+	return f
+}
+
+func (f *fakeSampleStore) CreateReadingSamples(samples []*model.ReadingSample) (int64, error) {
+	f.flushing <- struct{}{}
+	if f.hold != nil {
+		<-f.hold
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.batches = append(f.batches, len(samples))
+	f.stored = append(f.stored, samples...)
+	return int64(len(samples)), nil
+}
+
+func (f *fakeSampleStore) storedCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.stored)
+}
+
+// waitFlushing waits until a worker is inside a flush.
+func (f *fakeSampleStore) waitFlushing(t *testing.T) {
+	t.Helper()
+	select {
+	case <-f.flushing:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no flush started")
+	}
+}
+
+func testSample(messageId string) *model.ReadingSample {
+	sample := &model.ReadingSample{
+		Readings: []*model.Reading{{Metric: "ppm", Value: 800}},
+	}
+	if messageId != "" {
+		sample.Receipt = &model.IngestReceipt{SystemId: uuid.MustParse(testSystemId), MessageId: &messageId}
+	}
+	return sample
+}
+
+func TestReadingQueueFull(t *testing.T) {
+	store := newFakeSampleStore(true)
+	queue := NewReadingQueue(ReadingQueueConfig{
+		GrowthHistRepo: store,
+		Policy:         ReadingQueuePolicy{Capacity: 2, Workers: 1, BatchSize: 1, FlushInterval: time.Hour},
+	})
+
+	// the worker holds the first sample while two more fill the queue
+	if _, err := queue.Enqueue(testSample("")); err != nil {
+		t.Fatal(err)
+	}
+	store.waitFlushing(t)
+	for i := 0; i < 2; i++ {
+		if _, err := queue.Enqueue(testSample("")); err != nil {
+			t.Fatalf("Enqueue() %d error = %v", i, err)
+		}
+	}
+
+	_, err := queue.Enqueue(testSample(""))
+	if !errors.Is(err, errs.ReadingQueueFull) {
+		t.Fatalf("Enqueue() on a full queue error = %v, want %v", err, errs.ReadingQueueFull)
+	}
+
+	stats := queue.Stats()
+	if stats.Depth != 2 || stats.Enqueued != 3 || stats.Rejected != 1 || stats.Unflushed != 3 {
+		t.Errorf("Stats() = %+v, want depth 2, enqueued 3, rejected 1, unflushed 3", *stats)
+	}
+
+	close(store.hold)
+	queue.Close()
+	if got := store.storedCount(); got != 3 {
+		t.Errorf("stored %d samples, want 3", got)
+	}
+}
+
+func TestReadingQueueCloseDrains(t *testing.T) {
+	store := newFakeSampleStore(false)
+	queue := NewReadingQueue(ReadingQueueConfig{
+		GrowthHistRepo: store,
+		Policy:         ReadingQueuePolicy{Capacity: 100, Workers: 1, BatchSize: 10, FlushInterval: time.Hour},
+	})
+
+	for i := 0; i < 25; i++ {
+		if _, err := queue.Enqueue(testSample("")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	queue.Close()
+
+	// full batches are flushed as they fill, the rest when the queue closes
+	if len(store.batches) != 3 || store.batches[0] != 10 || store.batches[1] != 10 || store.batches[2] != 5 {
+		t.Errorf("flushed batches %v, want [10 10 5]", store.batches)
+	}
+
+	stats := queue.Stats()
+	if stats.Depth != 0 || stats.Stored != 25 || stats.Unflushed != 0 || stats.Batches != 3 {
+		t.Errorf("Stats() = %+v, want depth 0, stored 25, unflushed 0, batches 3", *stats)
+	}
+
+	_, err := queue.Enqueue(testSample(""))
+	if !errors.Is(err, errs.ReadingQueueClosed) {
+		t.Errorf("Enqueue() after Close() error = %v, want %v", err, errs.ReadingQueueClosed)
+	}
+
+	// a second Close is a no-op
+	queue.Close()
+}
+
+func TestReadingQueueFlushInterval(t *testing.T) {
+	store := newFakeSampleStore(false)
+	queue := NewReadingQueue(ReadingQueueConfig{
+		GrowthHistRepo: store,
+		Policy:         ReadingQueuePolicy{Capacity: 10, Workers: 1, BatchSize: 10, FlushInterval: 10 * time.Millisecond},
+	})
+	defer queue.Close()
+
+	if _, err := queue.Enqueue(testSample("")); err != nil {
+		t.Fatal(err)
+	}
+	store.waitFlushing(t)
+}
+
+func TestReadingQueuePending(t *testing.T) {
+	store := newFakeSampleStore(true)
+	queue := NewReadingQueue(ReadingQueueConfig{
+		GrowthHistRepo: store,
+		Policy:         ReadingQueuePolicy{Capacity: 10, Workers: 1, BatchSize: 1, FlushInterval: time.Hour},
+	})
+
+	first := testSample("msg-1")
+	if _, err := queue.Enqueue(first); err != nil {
+		t.Fatal(err)
+	}
+	store.waitFlushing(t)
+
+	// a retry of a sample still in flight is answered from the queued receipt
+	pending, err := queue.Enqueue(testSample("msg-1"))
+	if err != nil || len(pending) != 1 || pending[0] != first.Receipt {
+		t.Fatalf("Enqueue() of a retry = (%v, %v), want the queued receipt", pending, err)
+	}
+	if pending := queue.Pending(testSample("msg-2").Receipt); len(pending) != 0 {
+		t.Errorf("Pending() of another message = %v, want none", pending)
+	}
+
+	close(store.hold)
+	queue.Close()
+	if pending := queue.Pending(first.Receipt); len(pending) != 0 {
+		t.Errorf("Pending() after the flush = %v, want none", pending)
+	}
+	if got := store.storedCount(); got != 1 {
+		t.Errorf("stored %d samples, want 1", got)
+	}
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"slices"
+
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/constant"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/dto"
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/repository"
+	"github.com/google/uuid"
+)
+
+// Farm member roles allowed for each kind of farm operation. Operators log
+// readings and dosing, viewers such as agronomists only read.
+var (
+	farmReaders = constant.Roles
+	farmWriters = []string{constant.RoleOwner, constant.RoleOperator}
+	farmOwners  = []string{constant.RoleOwner}
+)
+
+// scopeOf limits repository queries to the caller's own organization.
+func scopeOf(caller *dto.Caller) *repository.Scope {
+	return &repository.Scope{AccountID: caller.AccountID, OrganizationID: caller.OrganizationID}
+}
+
+// authorizeFarm checks that the caller is a member of the farm with one of the
+// given roles. Callers that are not members get the same not found error as
+// for a missing farm; members with a weaker role are forbidden.
+func authorizeFarm(farmMemberRepo repository.FarmMemberRepository, caller *dto.Caller, farmId uuid.UUID, roles []string) (*model.FarmMember, error) {
+	if !caller.CanAccessFarm(farmId) {
+		return nil, errs.InvalidFarmID
+	}
+
+	member, err := farmMemberRepo.GetFarmMember(farmId, scopeOf(caller))
+	if err != nil || member == nil {
+		return nil, errs.InvalidFarmID
+	}
+
+	if !slices.Contains(roles, caller.FarmRole(member.Role)) {
+		return nil, errs.ForbiddenAccess
+	}
+
+	return member, nil
+}
+
+// resolveSystemUnit checks the caller's role on a farm and loads one of its
+// system units. Missing resources and resources owned by another tenant
+// produce the same not found errors so callers cannot probe for foreign ids.
+func resolveSystemUnit(farmMemberRepo repository.FarmMemberRepository, systemUnitRepo repository.SystemUnitRepository, caller *dto.Caller, farmId uuid.UUID, systemId uuid.UUID, roles []string) (*model.FarmMember, *model.SystemUnit, error) {
+	member, err := authorizeFarm(farmMemberRepo, caller, farmId, roles)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	systemUnit, err := systemUnitRepo.GetSystemUnitByIdAndScope(&model.SystemUnit{ID: systemId}, scopeOf(caller))
+	if err != nil || systemUnit == nil || systemUnit.FarmId != farmId {
+		return nil, nil, errs.InvalidSystemUnitID
+	}
+
+	return member, systemUnit, nil
+}
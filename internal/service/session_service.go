@@ -0,0 +1,117 @@
+package service
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/dto"
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/repository"
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+	"github.com/google/uuid"
+)
+
+// lastSeenResolution limits how often an authenticated request writes the
+// session's last seen time back to the database.
+const lastSeenResolution = time.Minute
+
+type SessionService interface {
+	ValidateSession(sessionId *uuid.UUID, accountId *uuid.UUID, ipAddress string) error
+	GetActiveSessions(accountId *uuid.UUID, currentSessionId *uuid.UUID) ([]*dto.SessionResponse, error)
+	Logout(sessionId *uuid.UUID, accountId *uuid.UUID) (*dto.LogoutResponse, error)
+	LogoutAll(accountId *uuid.UUID) (*dto.LogoutResponse, error)
+}
+
+type sessionService struct {
+	sessionRepo repository.SessionRepository
+}
+
+type SessionServiceConfig struct {
+	SessionRepo repository.SessionRepository
+}
+
+func NewSessionService(config SessionServiceConfig) SessionService {
+	return &sessionService{
+		sessionRepo: config.SessionRepo,
+	}
+}
+
+func (s *sessionService) ValidateSession(sessionId *uuid.UUID, accountId *uuid.UUID, ipAddress string) error {
+	session, err := s.sessionRepo.GetActiveSessionById(&model.Session{
+		ID:        *sessionId,
+		AccountId: *accountId,
+	})
+	if err != nil {
+		return errs.InvalidSession
+	}
+
+	if time.Since(session.LastSeenAt) > lastSeenResolution || session.IpAddress != ipAddress {
+		err = s.sessionRepo.TouchSession(&model.Session{ID: session.ID, IpAddress: ipAddress})
+		if err != nil {
+			logger.Warn("sessionService", "Failed to update session last seen", map[string]string{
+				"session_id": session.ID.String(),
+				"error":      err.Error(),
+			})
+		}
+	}
+
+	return nil
+}
+
+func (s *sessionService) GetActiveSessions(accountId *uuid.UUID, currentSessionId *uuid.UUID) ([]*dto.SessionResponse, error) {
+	logger.Info("sessionService", "Fetching active sessions", map[string]string{
+		"account_id": accountId.String(),
+	})
+
+	res, err := s.sessionRepo.GetActiveSessionsByAccountId(&model.Session{AccountId: *accountId})
+	if err != nil {
+		logger.Error("sessionService", "Failed to fetch active sessions", map[string]string{
+			"error": err.Error(),
+		})
+		return nil, errs.ErrorOnGettingSessions
+	}
+
+	sessionsRes := []*dto.SessionResponse{}
+	for _, session := range res {
+		sessionsRes = append(sessionsRes, &dto.SessionResponse{
+			ID:         session.ID,
+			UserAgent:  session.UserAgent,
+			IpAddress:  session.IpAddress,
+			LastSeenAt: session.LastSeenAt,
+			CreatedAt:  session.CreatedAt,
+			Current:    session.ID == *currentSessionId,
+		})
+	}
+
+	logger.Info("sessionService", "Fetched active sessions successfully", map[string]string{
+		"count": strconv.Itoa(len(sessionsRes)),
+	})
+	return sessionsRes, nil
+}
+
+func (s *sessionService) Logout(sessionId *uuid.UUID, accountId *uuid.UUID) (*dto.LogoutResponse, error) {
+	logger.Info("sessionService", "Logging out session", map[string]string{
+		"session_id": sessionId.String(),
+	})
+
+	revoked, err := s.sessionRepo.RevokeSession(&model.Session{ID: *sessionId, AccountId: *accountId})
+	if err != nil {
+		return nil, errs.ErrorOnRevokingSession
+	}
+
+	return &dto.LogoutResponse{RevokedSessions: revoked}, nil
+}
+
+func (s *sessionService) LogoutAll(accountId *uuid.UUID) (*dto.LogoutResponse, error) {
+	logger.Info("sessionService", "Logging out all sessions", map[string]string{
+		"account_id": accountId.String(),
+	})
+
+	revoked, err := s.sessionRepo.RevokeSessionsByAccountId(&model.Session{AccountId: *accountId})
+	if err != nil {
+		return nil, errs.ErrorOnRevokingSession
+	}
+
+	return &dto.LogoutResponse{RevokedSessions: revoked}, nil
+}
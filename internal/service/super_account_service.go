@@ -1,6 +1,7 @@
 package service
 
 import (
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/constant"
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/dto"
 	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
@@ -8,22 +9,28 @@ import (
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/hasher"
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/tokenprovider"
+	"github.com/google/uuid"
 )
 
 type SuperAccountService interface {
 	CreateSuperUser(input *dto.RegisterSuperUserBody) (*dto.RegisterSuperUserResponse, error)
+	Login(input *dto.LoginBody) (*dto.SuperLoginResponse, error)
+	UnlockLogin(input *dto.UnlockLoginBody) (*dto.UnlockLoginResponse, error)
+	StepUp(accountId *uuid.UUID, sessionId *uuid.UUID, input *dto.StepupBody) (*dto.StepupResponse, error)
 }
 
 type superAccountService struct {
 	superAccountRepo repository.SuperAccountRepository
 	hasher           hasher.Hasher
 	jwtProvider      tokenprovider.JWTTokenProvider
+	loginThrottle    LoginThrottleService
 }
 
 type SuperAccountServiceConfig struct {
 	SuperAccountRepo repository.SuperAccountRepository
 	Hasher           hasher.Hasher
 	JwtProvider      tokenprovider.JWTTokenProvider
+	LoginThrottle    LoginThrottleService
 }
 
 func NewSuperAccountService(config SuperAccountServiceConfig) SuperAccountService {
@@ -31,6 +38,7 @@ func NewSuperAccountService(config SuperAccountServiceConfig) SuperAccountServic
 		superAccountRepo: config.SuperAccountRepo,
 		hasher:           config.Hasher,
 		jwtProvider:      config.JwtProvider,
+		loginThrottle:    config.LoginThrottle,
 	}
 }
 
@@ -67,3 +75,97 @@ func (s *superAccountService) CreateSuperUser(input *dto.RegisterSuperUserBody)
 		Username: res.Username,
 	}, nil
 }
+
+// Login issues a super admin access token. The token is signed by the super
+// admin provider, so its issuer and audience differ from farmer tokens.
+func (s *superAccountService) Login(input *dto.LoginBody) (*dto.SuperLoginResponse, error) {
+	logger.Info("superAccountService", "Super User login", map[string]string{
+		"username": input.Username,
+	})
+
+	superUser, err := s.superAccountRepo.GetSuperUserByName(&input.Username)
+	if err != nil {
+		return nil, err
+	}
+	if superUser == nil {
+		return nil, errs.UsernamePasswordIncorrect
+	}
+
+	passwordOk, err := s.hasher.IsEqual(superUser.Password, input.Password)
+	if err != nil {
+		logger.Error("superAccountService", "hasher isEqual", map[string]string{
+			"error": err.Error(),
+		})
+		return nil, err
+	}
+	if !passwordOk {
+		return nil, errs.UsernamePasswordIncorrect
+	}
+
+	// upgrade hashes made by an older hasher configuration
+	if s.hasher.NeedsRehash(superUser.Password) {
+		hashed, err := s.hasher.Hash(input.Password)
+		if err == nil {
+			err = s.superAccountRepo.UpdateSuperUserPasswordHash(superUser.ID, superUser.Password, hashed)
+		}
+		if err != nil {
+			logger.Error("superAccountService", "Failed to rehash password", map[string]string{
+				"userId": superUser.ID.String(),
+				"error":  err.Error(),
+			})
+		}
+	}
+
+	accessToken, err := s.jwtProvider.GenerateAccessToken(&model.User{
+		ID:       superUser.ID,
+		Username: superUser.Username,
+		Role:     constant.RoleSuperAdmin,
+	}, uuid.New())
+	if err != nil {
+		logger.Error("superAccountService", "Error generating access token", map[string]string{
+			"error": err.Error(),
+		})
+		return nil, errs.ErrorGeneratingToken
+	}
+
+	logger.Info("superAccountService", "Super User logged in", map[string]string{
+		"userId": superUser.ID.String(),
+	})
+	return &dto.SuperLoginResponse{AccesToken: accessToken}, nil
+}
+
+// UnlockLogin clears the failed login counters and lockouts of a farmer
+// username and/or an IP address.
+func (s *superAccountService) UnlockLogin(input *dto.UnlockLoginBody) (*dto.UnlockLoginResponse, error) {
+	logger.Info("superAccountService", "Unlocking login", map[string]string{
+		"username":   input.Username,
+		"ip_address": input.IpAddress,
+	})
+
+	return s.loginThrottle.Unlock(input)
+}
+
+// StepUp re-checks the super admin password and issues a step-up token bound
+// to the current access token.
+func (s *superAccountService) StepUp(accountId *uuid.UUID, sessionId *uuid.UUID, input *dto.StepupBody) (*dto.StepupResponse, error) {
+	superUser, err := s.superAccountRepo.GetSuperUserById(*accountId)
+	if err != nil || superUser == nil {
+		return nil, errs.InvalidAccountId
+	}
+
+	passwordOk, err := s.hasher.IsEqual(superUser.Password, input.Password)
+	if err != nil || !passwordOk {
+		return nil, errs.PasswordDoesntMatch
+	}
+
+	stepupToken, err := s.jwtProvider.GenerateStepupToken(&model.User{
+		ID:       superUser.ID,
+		Username: superUser.Username,
+		Role:     constant.RoleSuperAdmin,
+	}, *sessionId)
+	if err != nil {
+		return nil, errs.ErrorGeneratingStepup
+	}
+
+	return &dto.StepupResponse{StepupToken: stepupToken}, nil
+}
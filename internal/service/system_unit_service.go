@@ -1,7 +1,9 @@
 package service
 
 import (
+	"errors"
 	"strconv"
+	"strings"
 
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/dto"
 	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
@@ -13,52 +15,49 @@ import (
 )
 
 type SystemUnitService interface {
-	CreateSystemUnit(input *dto.CreateSystemUnit) (*dto.CreateSystemUnitResponse, error)
-	GetSystemUnits(farm_ids *dto.SystemUnitFilter) ([]*dto.SystemUnitResponse, error)
-	UpdateSystemUnit(systemUnitId *uuid.UUID, systemUnitData *dto.CreateSystemUnit) (*dto.SystemUnitResponse, error)
-	DeleteSystemUnitById(unitId *uuid.UUID) (*dto.CreateSystemUnitResponse, error)
+	CreateSystemUnit(caller *dto.Caller, input *dto.CreateSystemUnit) (*dto.CreateSystemUnitResponse, error)
+	GetSystemUnits(caller *dto.Caller, farm_ids *dto.SystemUnitFilter) ([]*dto.SystemUnitResponse, error)
+	UpdateSystemUnit(caller *dto.Caller, systemUnitId *uuid.UUID, systemUnitData *dto.CreateSystemUnit) (*dto.SystemUnitResponse, error)
+	DeleteSystemUnitById(caller *dto.Caller, unitId *uuid.UUID) (*dto.CreateSystemUnitResponse, error)
+	RotateDeviceCredential(caller *dto.Caller, systemUnitId *uuid.UUID) (*dto.DeviceCredentialResponse, error)
+	GetSequenceGaps(caller *dto.Caller, systemUnitId *uuid.UUID) (*dto.SequenceGapReport, error)
 }
 
 type systemUnitService struct {
-	systemUnitRepo repository.SystemUnitRepository
-	farmRepo       repository.FarmRepository
-	unitKeyRepo    repository.UnitIdRepository
+	systemUnitRepo    repository.SystemUnitRepository
+	farmMemberRepo    repository.FarmMemberRepository
+	unitKeyRepo       repository.UnitIdRepository
+	ingestReceiptRepo repository.IngestReceiptRepository
+	deviceService     DeviceService
 }
 
 type SystemUnitServiceConfig struct {
-	SystemUnitRepo repository.SystemUnitRepository
-	FarmRepo       repository.FarmRepository
-	UnitKeyRepo    repository.UnitIdRepository
+	SystemUnitRepo    repository.SystemUnitRepository
+	FarmMemberRepo    repository.FarmMemberRepository
+	UnitKeyRepo       repository.UnitIdRepository
+	IngestReceiptRepo repository.IngestReceiptRepository
+	DeviceService     DeviceService
 }
 
 func NewSystemUnitService(config SystemUnitServiceConfig) SystemUnitService {
 	return &systemUnitService{
-		systemUnitRepo: config.SystemUnitRepo,
-		farmRepo:       config.FarmRepo,
-		unitKeyRepo:    config.UnitKeyRepo,
+		systemUnitRepo:    config.SystemUnitRepo,
+		farmMemberRepo:    config.FarmMemberRepo,
+		unitKeyRepo:       config.UnitKeyRepo,
+		ingestReceiptRepo: config.IngestReceiptRepo,
+		deviceService:     config.DeviceService,
 	}
 }
 
-func (s *systemUnitService) CreateSystemUnit(input *dto.CreateSystemUnit) (*dto.CreateSystemUnitResponse, error) {
+func (s *systemUnitService) CreateSystemUnit(caller *dto.Caller, input *dto.CreateSystemUnit) (*dto.CreateSystemUnitResponse, error) {
 	logger.Info("systemUnitService", "Creating a new system unit", map[string]string{
 		"farm_id":  input.FarmID.String(),
 		"unit_key": input.UnitKey.String(),
 	})
 
-	farm, err := s.farmRepo.GetFarmById(&model.Farm{ID: input.FarmID})
-	if err != nil || farm == nil {
-		logger.Error("systemUnitService", "Invalid farm ID", map[string]string{
-			"error": err.Error(),
-		})
-		return nil, errs.InvalidFarmID
-	}
-
-	unitKey, err := s.unitKeyRepo.GetUnitIdById(&model.UnitId{ID: input.UnitKey})
-	if err != nil || unitKey == nil {
-		logger.Error("systemUnitService", "Invalid unit key", map[string]string{
-			"error": err.Error(),
-		})
-		return nil, errs.InvalidUnitKey
+	err := s.checkSystemUnitInput(caller, input)
+	if err != nil {
+		return nil, err
 	}
 
 	createdSystemUnit, err := s.systemUnitRepo.CreateSystemUnit(&model.SystemUnit{
@@ -79,29 +78,47 @@ func (s *systemUnitService) CreateSystemUnit(input *dto.CreateSystemUnit) (*dto.
 		"unit_id": createdSystemUnit.ID.String(),
 	})
 
+	// A unit without a credential can still be provisioned later through
+	// RotateDeviceCredential, so this does not fail the creation.
+	credential, err := s.deviceService.ProvisionCredential(createdSystemUnit.ID)
+	if err != nil {
+		logger.Error("systemUnitService", "Failed to provision device credential", map[string]string{
+			"unit_id": createdSystemUnit.ID.String(),
+			"error":   err.Error(),
+		})
+	}
+
 	return &dto.CreateSystemUnitResponse{
-		ID:          createdSystemUnit.ID,
-		TankVolume:  createdSystemUnit.TankVolume,
-		TankAVolume: createdSystemUnit.TankAVolume,
-		TankBVolume: createdSystemUnit.TankBVolume,
+		ID:               createdSystemUnit.ID,
+		TankVolume:       createdSystemUnit.TankVolume,
+		TankAVolume:      createdSystemUnit.TankAVolume,
+		TankBVolume:      createdSystemUnit.TankBVolume,
+		DeviceCredential: credential,
 	}, nil
 }
 
-func (s *systemUnitService) GetSystemUnits(farm_ids *dto.SystemUnitFilter) ([]*dto.SystemUnitResponse, error) {
+func (s *systemUnitService) GetSystemUnits(caller *dto.Caller, farm_ids *dto.SystemUnitFilter) ([]*dto.SystemUnitResponse, error) {
+	var rawFarmIds string
+	if farm_ids != nil {
+		rawFarmIds = farm_ids.FarmIds
+	}
+
 	logger.Info("systemUnitService", "Fetching system units", map[string]string{
-		"farm_ids": farm_ids.FarmIds,
+		"account_id": caller.AccountID.String(),
+		"farm_ids":   rawFarmIds,
 	})
 
 	var systemUnitRes []*dto.SystemUnitResponse
-	var id string
 
-	if farm_ids == nil {
-		id = ""
-	} else {
-		id = "AND su.farm_id IN (" + farm_ids.FarmIds + ")"
+	farmIds, err := parseFarmIds(rawFarmIds)
+	if err != nil {
+		logger.Error("systemUnitService", "Invalid farm ID filter", map[string]string{
+			"farm_ids": rawFarmIds,
+		})
+		return nil, errs.InvalidFarmIDParam
 	}
 
-	res, err := s.systemUnitRepo.GetSystemUnits(&id)
+	res, err := s.systemUnitRepo.GetSystemUnitsByScope(scopeOf(caller), farmIds)
 	if err != nil {
 		logger.Error("systemUnitService", "Error fetching system units", map[string]string{
 			"error": err.Error(),
@@ -110,6 +127,9 @@ func (s *systemUnitService) GetSystemUnits(farm_ids *dto.SystemUnitFilter) ([]*d
 	}
 
 	for _, resIdx := range res {
+		if !caller.CanAccessFarm(resIdx.FarmId) {
+			continue
+		}
 		systemUnitRes = append(systemUnitRes, &dto.SystemUnitResponse{
 			ID:          resIdx.ID,
 			UnitKey:     resIdx.UnitKey,
@@ -127,11 +147,21 @@ func (s *systemUnitService) GetSystemUnits(farm_ids *dto.SystemUnitFilter) ([]*d
 	return systemUnitRes, nil
 }
 
-func (s *systemUnitService) UpdateSystemUnit(systemUnitId *uuid.UUID, systemUnitData *dto.CreateSystemUnit) (*dto.SystemUnitResponse, error) {
+func (s *systemUnitService) UpdateSystemUnit(caller *dto.Caller, systemUnitId *uuid.UUID, systemUnitData *dto.CreateSystemUnit) (*dto.SystemUnitResponse, error) {
 	logger.Info("systemUnitService", "Updating system unit", map[string]string{
 		"unit_id": systemUnitId.String(),
 	})
 
+	_, err := s.checkSystemUnitAccess(caller, systemUnitId, farmOwners)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.checkSystemUnitInput(caller, systemUnitData)
+	if err != nil {
+		return nil, err
+	}
+
 	res, err := s.systemUnitRepo.UpdateSystemUnit(&model.SystemUnit{
 		ID:          *systemUnitId,
 		FarmId:      systemUnitData.FarmID,
@@ -139,7 +169,7 @@ func (s *systemUnitService) UpdateSystemUnit(systemUnitId *uuid.UUID, systemUnit
 		TankVolume:  systemUnitData.TankVolume,
 		TankAVolume: systemUnitData.TankAVolume,
 		TankBVolume: systemUnitData.TankBVolume,
-	})
+	}, scopeOf(caller))
 	if err != nil {
 		logger.Error("systemUnitService", "Error updating system unit", map[string]string{
 			"error": err.Error(),
@@ -160,12 +190,17 @@ func (s *systemUnitService) UpdateSystemUnit(systemUnitId *uuid.UUID, systemUnit
 	}, nil
 }
 
-func (s *systemUnitService) DeleteSystemUnitById(unitId *uuid.UUID) (*dto.CreateSystemUnitResponse, error) {
+func (s *systemUnitService) DeleteSystemUnitById(caller *dto.Caller, unitId *uuid.UUID) (*dto.CreateSystemUnitResponse, error) {
 	logger.Info("systemUnitService", "Deleting system unit", map[string]string{
 		"unit_id": unitId.String(),
 	})
 
-	res, err := s.systemUnitRepo.DeleteSystemUnitById(&model.SystemUnit{ID: *unitId})
+	_, err := s.checkSystemUnitAccess(caller, unitId, farmOwners)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.systemUnitRepo.DeleteSystemUnitById(&model.SystemUnit{ID: *unitId}, scopeOf(caller))
 	if err != nil {
 		logger.Error("systemUnitService", "Error deleting system unit", map[string]string{
 			"error": err.Error(),
@@ -180,3 +215,114 @@ func (s *systemUnitService) DeleteSystemUnitById(unitId *uuid.UUID) (*dto.Create
 		ID: res.ID,
 	}, nil
 }
+
+// RotateDeviceCredential issues a new device secret for a system unit and
+// revokes the old one.
+func (s *systemUnitService) RotateDeviceCredential(caller *dto.Caller, systemUnitId *uuid.UUID) (*dto.DeviceCredentialResponse, error) {
+	systemUnit, err := s.checkSystemUnitAccess(caller, systemUnitId, farmOwners)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, err := s.deviceService.ProvisionCredential(systemUnit.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("systemUnitService", "Device credential rotated", map[string]string{
+		"unit_id": systemUnit.ID.String(),
+		"key_id":  credential.KeyId,
+	})
+	return credential, nil
+}
+
+// GetSequenceGaps reports the sequence numbers a system unit never delivered,
+// so a gateway can resend them from its buffer.
+func (s *systemUnitService) GetSequenceGaps(caller *dto.Caller, systemUnitId *uuid.UUID) (*dto.SequenceGapReport, error) {
+	systemUnit, err := s.checkSystemUnitAccess(caller, systemUnitId, farmReaders)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := s.ingestReceiptRepo.GetSequenceStats(systemUnit.ID)
+	if err != nil {
+		return nil, errs.ErrorOnGettingSequenceGaps
+	}
+	gaps, err := s.ingestReceiptRepo.GetSequenceGaps(systemUnit.ID)
+	if err != nil {
+		return nil, errs.ErrorOnGettingSequenceGaps
+	}
+
+	report := &dto.SequenceGapReport{
+		SystemId:      systemUnit.ID,
+		FirstSequence: stats.First,
+		LastSequence:  stats.Last,
+		Received:      stats.Received,
+		Gaps:          gaps,
+	}
+	for _, gap := range gaps {
+		report.Missing += gap.To - gap.From + 1
+	}
+	return report, nil
+}
+
+// checkSystemUnitInput verifies that the caller owns the target farm and that
+// the unit key exists.
+func (s *systemUnitService) checkSystemUnitInput(caller *dto.Caller, input *dto.CreateSystemUnit) error {
+	_, err := authorizeFarm(s.farmMemberRepo, caller, input.FarmID, farmOwners)
+	if err != nil {
+		logger.Error("systemUnitService", "Farm not accessible", map[string]string{
+			"farm_id": input.FarmID.String(),
+			"error":   err.Error(),
+		})
+		return err
+	}
+
+	unitKey, err := s.unitKeyRepo.GetUnitIdById(&model.UnitId{ID: input.UnitKey})
+	if err != nil || unitKey == nil {
+		logger.Error("systemUnitService", "Invalid unit key", map[string]string{
+			"unit_key": input.UnitKey.String(),
+		})
+		return errs.InvalidUnitKey
+	}
+
+	return nil
+}
+
+// checkSystemUnitAccess loads a system unit if the caller holds one of roles
+// on the farm the unit belongs to. Changes take the owner role.
+func (s *systemUnitService) checkSystemUnitAccess(caller *dto.Caller, systemUnitId *uuid.UUID, roles []string) (*model.SystemUnit, error) {
+	systemUnit, err := s.systemUnitRepo.GetSystemUnitByIdAndScope(&model.SystemUnit{ID: *systemUnitId}, scopeOf(caller))
+	if err != nil || systemUnit == nil {
+		return nil, errs.InvalidSystemUnitID
+	}
+
+	_, err = authorizeFarm(s.farmMemberRepo, caller, systemUnit.FarmId, roles)
+	if errors.Is(err, errs.InvalidFarmID) {
+		return nil, errs.InvalidSystemUnitID
+	}
+	if err != nil {
+		return nil, err
+	}
+	return systemUnit, nil
+}
+
+// parseFarmIds parses a comma separated list of farm ids. An empty list means
+// no filter.
+func parseFarmIds(raw string) ([]uuid.UUID, error) {
+	var farmIds []uuid.UUID
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.Trim(strings.TrimSpace(part), "'")
+		if part == "" {
+			continue
+		}
+
+		id, err := uuid.Parse(part)
+		if err != nil {
+			return nil, err
+		}
+		farmIds = append(farmIds, id)
+	}
+
+	return farmIds, nil
+}
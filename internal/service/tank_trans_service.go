@@ -1,76 +1,128 @@
 package service
 
 import (
+	"encoding/json"
+
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/dto"
 	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
 
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/repository"
+	"github.com/google/uuid"
 )
 
 type TankTransService interface {
-	CreateTankTrans(input *dto.TankTransaction) (*dto.TankTransactionResponse, error)
+	CreateTankTrans(caller *dto.Caller, input *dto.TankTransaction) (*dto.TankTransactionResponse, error)
+	CreateDeviceTankTrans(device *dto.DeviceAuth, input *dto.DeviceTankTransaction) (*dto.TankTransactionResponse, error)
 }
 
 type tankTransService struct {
-	tankTransRepo  repository.TankTransRepository
-	farmRepo       repository.FarmRepository
-	systemUnitRepo repository.SystemUnitRepository
+	tankTransRepo     repository.TankTransRepository
+	ingestReceiptRepo repository.IngestReceiptRepository
+	farmMemberRepo    repository.FarmMemberRepository
+	systemUnitRepo    repository.SystemUnitRepository
 }
 
 type TankTransServiceConfig struct {
-	TankTransRepo  repository.TankTransRepository
-	FarmRepo       repository.FarmRepository
-	SystemUnitRepo repository.SystemUnitRepository
+	TankTransRepo     repository.TankTransRepository
+	IngestReceiptRepo repository.IngestReceiptRepository
+	FarmMemberRepo    repository.FarmMemberRepository
+	SystemUnitRepo    repository.SystemUnitRepository
 }
 
 func NewTankTransService(config TankTransServiceConfig) TankTransService {
 	return &tankTransService{
-		tankTransRepo:  config.TankTransRepo,
-		farmRepo:       config.FarmRepo,
-		systemUnitRepo: config.SystemUnitRepo,
+		tankTransRepo:     config.TankTransRepo,
+		ingestReceiptRepo: config.IngestReceiptRepo,
+		farmMemberRepo:    config.FarmMemberRepo,
+		systemUnitRepo:    config.SystemUnitRepo,
 	}
 }
 
-func (s *tankTransService) CreateTankTrans(input *dto.TankTransaction) (*dto.TankTransactionResponse, error) {
+func (s *tankTransService) CreateTankTrans(caller *dto.Caller, input *dto.TankTransaction) (*dto.TankTransactionResponse, error) {
 	logger.Info("tankTransService", "Creating tank transaction", map[string]string{
 		"farm_id":   input.FarmId.String(),
 		"system_id": input.SystemId.String(),
 	})
 
-	farm, err := s.farmRepo.GetFarmById(&model.Farm{
-		ID: input.FarmId,
-	})
-	if err != nil || farm == nil {
-		logger.Error("tankTransService", "Invalid farm ID", map[string]string{
-			"farm_id": input.FarmId.String(),
-		})
-		return nil, errs.InvalidFarmID
-	}
-
-	systemUnit, err := s.systemUnitRepo.GetSystemUnitById(&model.SystemUnit{
-		ID: input.SystemId,
-	})
-	if err != nil || systemUnit == nil {
-		logger.Error("tankTransService", "Invalid system unit ID", map[string]string{
+	_, _, err := resolveSystemUnit(s.farmMemberRepo, s.systemUnitRepo, caller, input.FarmId, input.SystemId, farmWriters)
+	if err != nil {
+		logger.Error("tankTransService", "Farm or system unit not accessible", map[string]string{
+			"farm_id":   input.FarmId.String(),
 			"system_id": input.SystemId.String(),
 		})
-		return nil, errs.InvalidSystemUnitID
+		return nil, err
 	}
 
-	tankTrans, err := s.tankTransRepo.CreateTankTransaction(&model.TankTran{
+	return s.createTankTrans(&model.TankTran{
 		FarmId:      input.FarmId,
 		SystemId:    input.SystemId,
 		WaterVolume: input.WaterVolume,
 		AVolume:     input.AVolume,
 		BVolume:     input.BVolume,
+	}, newIngestReceipt(input.SystemId, receiptKindTankTrans, input.IngestKey, input))
+}
+
+// CreateDeviceTankTrans stores a dosing record sent by a signed device
+// request. The farm and system unit come from the device credential.
+func (s *tankTransService) CreateDeviceTankTrans(device *dto.DeviceAuth, input *dto.DeviceTankTransaction) (*dto.TankTransactionResponse, error) {
+	logger.Info("tankTransService", "Creating tank transaction from device", map[string]string{
+		"key_id":    device.KeyId,
+		"system_id": device.SystemUnitID.String(),
 	})
+
+	return s.createTankTrans(&model.TankTran{
+		FarmId:      device.FarmID,
+		SystemId:    device.SystemUnitID,
+		WaterVolume: input.WaterVolume,
+		AVolume:     input.AVolume,
+		BVolume:     input.BVolume,
+	}, newIngestReceipt(device.SystemUnitID, receiptKindTankTrans, input.IngestKey, input))
+}
+
+// createTankTrans stores a tank transaction. A retry of a transaction stored
+// with a receipt gets the original response instead of a second transaction.
+func (s *tankTransService) createTankTrans(inputModel *model.TankTran, receipt *model.IngestReceipt) (*dto.TankTransactionResponse, error) {
+	if receipt != nil {
+		replay, err := s.replayTankTrans(receipt)
+		if err != nil || replay != nil {
+			return replay, err
+		}
+	}
+
+	// the id is known up front so the receipt can hold the response
+	inputModel.ID = uuid.New()
+	respBody := &dto.TankTransactionResponse{
+		ID:          inputModel.ID,
+		FarmId:      inputModel.FarmId,
+		SystemId:    inputModel.SystemId,
+		WaterVolume: inputModel.WaterVolume,
+		AVolume:     inputModel.AVolume,
+		BVolume:     inputModel.BVolume,
+	}
+
+	var err error
+	if receipt != nil {
+		receipt.Response, err = json.Marshal(respBody)
+		if err != nil {
+			return nil, errs.ErrorOnCreatingNewTankTrans
+		}
+	}
+
+	tankTrans, err := s.tankTransRepo.CreateTankTransaction(inputModel, receipt)
 	if err != nil {
 		logger.Error("tankTransService", "Error creating new tank transaction", map[string]string{
-			"farm_id":   input.FarmId.String(),
-			"system_id": input.SystemId.String(),
+			"farm_id":   inputModel.FarmId.String(),
+			"system_id": inputModel.SystemId.String(),
 		})
+		// a concurrent retry may have stored the same transaction first
+		if receipt != nil {
+			replay, replayErr := s.replayTankTrans(receipt)
+			if replayErr != nil || replay != nil {
+				return replay, replayErr
+			}
+		}
 		return nil, errs.ErrorOnCreatingNewTankTrans
 	}
 
@@ -78,14 +130,16 @@ func (s *tankTransService) CreateTankTrans(input *dto.TankTransaction) (*dto.Tan
 		"transaction_id": tankTrans.ID.String(),
 	})
 
-	respBody := &dto.TankTransactionResponse{
-		ID:          tankTrans.ID,
-		FarmId:      tankTrans.FarmId,
-		SystemId:    tankTrans.SystemId,
-		WaterVolume: tankTrans.WaterVolume,
-		AVolume:     tankTrans.AVolume,
-		BVolume:     tankTrans.BVolume,
-	}
+	return respBody, nil
+}
 
-	return respBody, err
+// replayTankTrans returns the response of an earlier transaction stored with
+// the same receipt keys, or nil for a new one.
+func (s *tankTransService) replayTankTrans(receipt *model.IngestReceipt) (*dto.TankTransactionResponse, error) {
+	replay := &dto.TankTransactionResponse{}
+	found, err := findReplay(s.ingestReceiptRepo, receipt, replay)
+	if err != nil || !found {
+		return nil, err
+	}
+	return replay, nil
 }
@@ -0,0 +1,138 @@
+package hasher
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testArgon2idParams keeps the tests fast; the format is the same as with
+// the default parameters.
+var testArgon2idParams = Argon2idParams{
+	Memory:      1024,
+	Iterations:  2,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+func TestArgon2idHashFormat(t *testing.T) {
+	hashed, err := NewArgon2id(testArgon2idParams).Hash("s3cret-Passw0rd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(hashed, "$argon2id$v=19$m=1024,t=2,p=1$") {
+		t.Fatalf("Hash() = %q, want a PHC string with the configured parameters", hashed)
+	}
+
+	params, salt, key, err := decodeArgon2id(hashed)
+	if err != nil {
+		t.Fatalf("decodeArgon2id() error = %v", err)
+	}
+	if *params != testArgon2idParams {
+		t.Errorf("decoded params = %+v, want %+v", *params, testArgon2idParams)
+	}
+	if len(salt) != 16 || len(key) != 32 {
+		t.Errorf("decoded salt and key lengths = %d, %d, want 16, 32", len(salt), len(key))
+	}
+
+	other, err := NewArgon2id(testArgon2idParams).Hash("s3cret-Passw0rd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == hashed {
+		t.Errorf("two hashes of the same value share a salt")
+	}
+}
+
+func TestArgon2idIsEqual(t *testing.T) {
+	h := NewArgon2id(testArgon2idParams)
+	hashed, err := h.Hash("s3cret-Passw0rd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		hashed  string
+		raw     string
+		want    bool
+		wantErr error
+	}{
+		{"same value", hashed, "s3cret-Passw0rd", true, nil},
+		{"other value", hashed, "s3cret-Passw0rd!", false, nil},
+		{"empty value", hashed, "", false, nil},
+		{"bad version", strings.Replace(hashed, "v=19", "v=16", 1), "s3cret-Passw0rd", false, ErrUnknownHashFormat},
+		{"bad params", strings.Replace(hashed, "t=2", "t=0", 1), "s3cret-Passw0rd", false, ErrUnknownHashFormat},
+		{"missing key", hashed[:strings.LastIndex(hashed, "$")], "s3cret-Passw0rd", false, ErrUnknownHashFormat},
+		{"bad salt", "$argon2id$v=19$m=1024,t=1,p=1$!!$AAAA", "s3cret-Passw0rd", false, ErrUnknownHashFormat},
+		{"unknown format", "plain", "plain", false, ErrUnknownHashFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := h.IsEqual(tt.hashed, tt.raw)
+			if got != tt.want || !errors.Is(err, tt.wantErr) {
+				t.Errorf("IsEqual() = (%v, %v), want (%v, %v)", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestArgon2idNeedsRehash(t *testing.T) {
+	h := NewArgon2id(testArgon2idParams)
+
+	hash := func(params Argon2idParams) string {
+		hashed, err := NewArgon2id(params).Hash("s3cret-Passw0rd")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hashed
+	}
+	with := func(change func(*Argon2idParams)) Argon2idParams {
+		params := testArgon2idParams
+		change(&params)
+		return params
+	}
+
+	bcryptHashed, err := NewBcrypt(4).Hash("s3cret-Passw0rd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		hashed string
+		want   bool
+	}{
+		{"same params", hash(testArgon2idParams), false},
+		{"stronger params", hash(with(func(p *Argon2idParams) { p.Memory, p.Iterations = 2048, 3 })), false},
+		{"less memory", hash(with(func(p *Argon2idParams) { p.Memory = 512 })), true},
+		{"fewer iterations", hash(with(func(p *Argon2idParams) { p.Iterations = 1 })), true},
+		{"other parallelism", hash(with(func(p *Argon2idParams) { p.Parallelism = 2 })), true},
+		{"shorter salt", hash(with(func(p *Argon2idParams) { p.SaltLength = 8 })), true},
+		{"shorter key", hash(with(func(p *Argon2idParams) { p.KeyLength = 16 })), true},
+		{"bcrypt", bcryptHashed, true},
+		{"unknown format", "plain", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := h.NeedsRehash(tt.hashed); got != tt.want {
+				t.Errorf("NeedsRehash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestArgon2idReadsBcrypt(t *testing.T) {
+	// accounts hashed before the switch keep logging in until rehashed
+	bcryptHashed, err := NewBcrypt(4).Hash("s3cret-Passw0rd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok, err := NewArgon2id(testArgon2idParams).IsEqual(bcryptHashed, "s3cret-Passw0rd")
+	if err != nil || !ok {
+		t.Errorf("IsEqual() on a bcrypt hash = (%v, %v), want (true, nil)", ok, err)
+	}
+}
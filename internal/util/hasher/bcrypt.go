@@ -2,6 +2,7 @@ package hasher
 
 import (
 	"errors"
+	"strings"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -25,7 +26,27 @@ func (h bcryptHash) Hash(value string) (string, error) {
 }
 
 func (h bcryptHash) IsEqual(hashed string, rawValue string) (bool, error) {
+	return compare(hashed, rawValue)
+}
+
+func (h bcryptHash) NeedsRehash(hashed string) bool {
+	if !isBcryptHash(hashed) {
+		return true
+	}
+
+	cost, err := bcrypt.Cost([]byte(hashed))
+	return err != nil || cost < h.cost
+}
 
+func (h bcryptHash) test() {
+	println("test")
+}
+
+func isBcryptHash(hashed string) bool {
+	return strings.HasPrefix(hashed, "$2a$") || strings.HasPrefix(hashed, "$2b$") || strings.HasPrefix(hashed, "$2y$")
+}
+
+func compareBcrypt(hashed string, rawValue string) (bool, error) {
 	err := bcrypt.CompareHashAndPassword([]byte(hashed), []byte(rawValue))
 
 	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
@@ -37,7 +58,3 @@ func (h bcryptHash) IsEqual(hashed string, rawValue string) (bool, error) {
 
 	return true, nil
 }
-
-func (h bcryptHash) test() {
-	println("test")
-}
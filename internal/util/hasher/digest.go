@@ -0,0 +1,13 @@
+package hasher
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// TokenDigest returns the hex encoded SHA-256 of a high entropy token so it
+// can be stored and looked up without keeping the raw value in the database.
+func TokenDigest(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,6 +1,29 @@
 package hasher
 
+import (
+	"errors"
+	"strings"
+)
+
+var ErrUnknownHashFormat = errors.New("unknown password hash format")
+
 type Hasher interface {
 	Hash(string) (string, error)
 	IsEqual(hashed string, rawValue string) (bool, error)
+	// NeedsRehash reports whether a stored hash was made by another algorithm
+	// or with weaker parameters than the hasher is configured with.
+	NeedsRehash(hashed string) bool
+}
+
+// compare checks a password against a stored hash of any supported format,
+// so accounts keep working while their hashes are migrated.
+func compare(hashed string, rawValue string) (bool, error) {
+	switch {
+	case strings.HasPrefix(hashed, argon2idPrefix):
+		return compareArgon2id(hashed, rawValue)
+	case isBcryptHash(hashed):
+		return compareBcrypt(hashed, rawValue)
+	default:
+		return false, ErrUnknownHashFormat
+	}
 }
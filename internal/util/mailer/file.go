@@ -0,0 +1,41 @@
+package mailer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// fileMailer writes messages to a file, or to stdout when no path is given,
+// so mail flows can be exercised locally without a mail server.
+type fileMailer struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFile(path string) Mailer {
+	return &fileMailer{
+		path: path,
+	}
+}
+
+func (m *fileMailer) Send(message *Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var out io.Writer = os.Stdout
+	if m.path != "" {
+		file, err := os.OpenFile(m.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+
+	_, err := fmt.Fprintf(out, "--- %s ---\nTo: %s\nSubject: %s\n\n%s\n\n",
+		time.Now().Format(time.RFC3339), message.To, message.Subject, message.Body)
+	return err
+}
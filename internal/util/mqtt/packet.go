@@ -0,0 +1,231 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Control packet types of MQTT 3.1.1.
+const (
+	packetConnect     = 1
+	packetConnack     = 2
+	packetPublish     = 3
+	packetPuback      = 4
+	packetPubrec      = 5
+	packetPubrel      = 6
+	packetPubcomp     = 7
+	packetSubscribe   = 8
+	packetSuback      = 9
+	packetUnsubscribe = 10
+	packetUnsuback    = 11
+	packetPingreq     = 12
+	packetPingresp    = 13
+	packetDisconnect  = 14
+)
+
+// CONNACK return codes.
+const (
+	connackAccepted           = 0x00
+	connackBadProtocol        = 0x01
+	connackIdentifierRejected = 0x02
+	connackServerUnavailable  = 0x03
+	connackBadCredentials     = 0x04
+)
+
+// subackFailure refuses a subscription; the broker only takes messages in.
+const subackFailure = 0x80
+
+var (
+	errMalformedPacket = errors.New("malformed mqtt packet")
+	errPacketTooLarge  = errors.New("mqtt packet too large")
+)
+
+type packet struct {
+	kind  byte
+	flags byte
+	body  []byte
+}
+
+type connectPacket struct {
+	protocolName  string
+	protocolLevel byte
+	cleanSession  bool
+	keepAlive     uint16
+	clientId      string
+	username      string
+	password      []byte
+}
+
+type publishPacket struct {
+	dup      bool
+	qos      byte
+	topic    string
+	packetId uint16
+	payload  []byte
+}
+
+// readPacket reads one control packet, refusing any larger than maxSize.
+func readPacket(r *bufio.Reader, maxSize int) (*packet, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	length := 0
+	for shift := 0; ; shift += 7 {
+		if shift > 21 {
+			return nil, errMalformedPacket
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		length |= int(b&0x7f) << shift
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	if length > maxSize {
+		return nil, errPacketTooLarge
+	}
+
+	body := make([]byte, length)
+	_, err = io.ReadFull(r, body)
+	if err != nil {
+		return nil, err
+	}
+	return &packet{kind: header >> 4, flags: header & 0x0f, body: body}, nil
+}
+
+func writePacket(w io.Writer, kind byte, flags byte, body []byte) error {
+	buf := []byte{kind<<4 | flags}
+	length := len(body)
+	for {
+		b := byte(length & 0x7f)
+		length >>= 7
+		if length > 0 {
+			b |= 0x80
+		}
+		buf = append(buf, b)
+		if length == 0 {
+			break
+		}
+	}
+	_, err := w.Write(append(buf, body...))
+	return err
+}
+
+func writePacketId(w io.Writer, kind byte, flags byte, packetId uint16) error {
+	return writePacket(w, kind, flags, binary.BigEndian.AppendUint16(nil, packetId))
+}
+
+// reader walks the variable header and payload of a packet.
+type reader struct {
+	buf []byte
+	err error
+}
+
+func (r *reader) readBytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if len(r.buf) < n {
+		r.err = errMalformedPacket
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *reader) readByte() byte {
+	b := r.readBytes(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (r *reader) readUint16() uint16 {
+	b := r.readBytes(2)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint16(b)
+}
+
+// readBinary reads a length prefixed field; strings are UTF-8 in this encoding.
+func (r *reader) readBinary() []byte {
+	return r.readBytes(int(r.readUint16()))
+}
+
+func (r *reader) readString() string {
+	return string(r.readBinary())
+}
+
+func parseConnect(body []byte) (*connectPacket, error) {
+	r := &reader{buf: body}
+	connect := &connectPacket{
+		protocolName:  r.readString(),
+		protocolLevel: r.readByte(),
+	}
+	flags := r.readByte()
+	connect.keepAlive = r.readUint16()
+	if r.err != nil || flags&0x01 != 0 {
+		return nil, errMalformedPacket
+	}
+	connect.cleanSession = flags&0x02 != 0
+
+	connect.clientId = r.readString()
+	if flags&0x04 != 0 {
+		// the will is accepted but never published
+		r.readString()
+		r.readBinary()
+	}
+	if flags&0x80 != 0 {
+		connect.username = r.readString()
+	}
+	if flags&0x40 != 0 {
+		connect.password = r.readBinary()
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return connect, nil
+}
+
+func parsePublish(flags byte, body []byte) (*publishPacket, error) {
+	r := &reader{buf: body}
+	publish := &publishPacket{
+		dup:   flags&0x08 != 0,
+		qos:   (flags >> 1) & 0x03,
+		topic: r.readString(),
+	}
+	if publish.qos > 0 {
+		publish.packetId = r.readUint16()
+	}
+	if r.err != nil || publish.qos > 2 || publish.topic == "" {
+		return nil, errMalformedPacket
+	}
+	publish.payload = r.buf
+	return publish, nil
+}
+
+// countSubscriptions returns the packet id of a SUBSCRIBE and how many topic
+// filters it carries.
+func countSubscriptions(body []byte) (uint16, int, error) {
+	r := &reader{buf: body}
+	packetId := r.readUint16()
+	count := 0
+	for r.err == nil && len(r.buf) > 0 {
+		r.readString()
+		r.readByte()
+		count++
+	}
+	if r.err != nil || count == 0 {
+		return 0, 0, errMalformedPacket
+	}
+	return packetId, count, nil
+}
@@ -0,0 +1,203 @@
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestReadPacket(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []byte
+		maxSize  int
+		wantKind byte
+		wantFlag byte
+		wantLen  int
+		wantErr  error
+	}{
+		{"empty body", []byte{0xc0, 0x00}, 16, packetPingreq, 0, 0, nil},
+		{"one byte length", append([]byte{0x32, 0x03}, 1, 2, 3), 16, packetPublish, 0x02, 3, nil},
+		{"two byte length", append([]byte{0x30, 0x80, 0x01}, make([]byte, 128)...), 256, packetPublish, 0, 128, nil},
+		{"length at max size", append([]byte{0x30, 0x10}, make([]byte, 16)...), 16, packetPublish, 0, 16, nil},
+		{"length above max size", append([]byte{0x30, 0x11}, make([]byte, 17)...), 16, 0, 0, 0, errPacketTooLarge},
+		{"four byte length above max size", []byte{0x30, 0xff, 0xff, 0xff, 0x7f}, 1 << 20, 0, 0, 0, errPacketTooLarge},
+		{"five byte length", []byte{0x30, 0x80, 0x80, 0x80, 0x80, 0x01}, 1 << 30, 0, 0, 0, errMalformedPacket},
+		{"no header", nil, 16, 0, 0, 0, io.EOF},
+		{"truncated length", []byte{0x30, 0x80}, 16, 0, 0, 0, io.EOF},
+		{"truncated body", []byte{0x30, 0x05, 1, 2}, 16, 0, 0, 0, io.ErrUnexpectedEOF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := readPacket(bufio.NewReader(bytes.NewReader(tt.input)), tt.maxSize)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("readPacket() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if p.kind != tt.wantKind || p.flags != tt.wantFlag || len(p.body) != tt.wantLen {
+				t.Errorf("readPacket() = kind %d flags %#x body %d bytes, want kind %d flags %#x body %d bytes",
+					p.kind, p.flags, len(p.body), tt.wantKind, tt.wantFlag, tt.wantLen)
+			}
+		})
+	}
+}
+
+func TestWritePacketRoundTrip(t *testing.T) {
+	for _, size := range []int{0, 1, 127, 128, 16383, 16384, 2097151, 2097152} {
+		var buf bytes.Buffer
+		body := bytes.Repeat([]byte{0xab}, size)
+		err := writePacket(&buf, packetPublish, 0x02, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		p, err := readPacket(bufio.NewReader(&buf), size)
+		if err != nil {
+			t.Fatalf("size %d: readPacket() error = %v", size, err)
+		}
+		if p.kind != packetPublish || p.flags != 0x02 || !bytes.Equal(p.body, body) {
+			t.Errorf("size %d: packet did not survive the round trip", size)
+		}
+	}
+}
+
+// connectBody builds the variable header and payload of a CONNECT.
+func connectBody(protocol string, level byte, flags byte, keepAlive uint16, fields ...string) []byte {
+	body := appendString(nil, protocol)
+	body = append(body, level, flags, byte(keepAlive>>8), byte(keepAlive))
+	for _, field := range fields {
+		body = appendString(body, field)
+	}
+	return body
+}
+
+func appendString(b []byte, s string) []byte {
+	b = append(b, byte(len(s)>>8), byte(len(s)))
+	return append(b, s...)
+}
+
+func TestParseConnect(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    []byte
+		want    connectPacket
+		wantErr bool
+	}{
+		{
+			name: "credentials",
+			body: connectBody("MQTT", 4, 0xc2, 60, "device-1", "unit", "secret"),
+			want: connectPacket{protocolName: "MQTT", protocolLevel: 4, cleanSession: true, keepAlive: 60, clientId: "device-1", username: "unit", password: []byte("secret")},
+		},
+		{
+			name: "will is skipped",
+			body: connectBody("MQTT", 4, 0xc6, 30, "device-1", "will/topic", "bye", "unit", "secret"),
+			want: connectPacket{protocolName: "MQTT", protocolLevel: 4, cleanSession: true, keepAlive: 30, clientId: "device-1", username: "unit", password: []byte("secret")},
+		},
+		{
+			name: "no credentials",
+			body: connectBody("MQTT", 4, 0x00, 0, "device-1"),
+			want: connectPacket{protocolName: "MQTT", protocolLevel: 4, clientId: "device-1"},
+		},
+		{name: "reserved flag", body: connectBody("MQTT", 4, 0x01, 0, "device-1"), wantErr: true},
+		{name: "missing password", body: connectBody("MQTT", 4, 0xc2, 60, "device-1", "unit"), wantErr: true},
+		{name: "truncated string", body: connectBody("MQTT", 4, 0x02, 60, "device-1")[:14], wantErr: true},
+		{name: "truncated header", body: []byte{0x00, 0x04, 'M', 'Q'}, wantErr: true},
+		{name: "empty", body: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseConnect(tt.body)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseConnect() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.protocolName != tt.want.protocolName || got.protocolLevel != tt.want.protocolLevel ||
+				got.cleanSession != tt.want.cleanSession || got.keepAlive != tt.want.keepAlive ||
+				got.clientId != tt.want.clientId || got.username != tt.want.username ||
+				!bytes.Equal(got.password, tt.want.password) {
+				t.Errorf("parseConnect() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePublish(t *testing.T) {
+	tests := []struct {
+		name    string
+		flags   byte
+		body    []byte
+		want    publishPacket
+		wantErr bool
+	}{
+		{
+			name:  "qos 0",
+			flags: 0x00,
+			body:  append(appendString(nil, "farms/a/readings"), `{"ppm":1}`...),
+			want:  publishPacket{topic: "farms/a/readings", payload: []byte(`{"ppm":1}`)},
+		},
+		{
+			name:  "qos 1 with packet id",
+			flags: 0x02,
+			body:  append(appendString(nil, "farms/a/tank"), 0x01, 0x02, 'x'),
+			want:  publishPacket{qos: 1, topic: "farms/a/tank", packetId: 0x0102, payload: []byte("x")},
+		},
+		{
+			name:  "qos 2 duplicate",
+			flags: 0x0c,
+			body:  append(appendString(nil, "t"), 0x00, 0x07),
+			want:  publishPacket{dup: true, qos: 2, topic: "t", packetId: 7, payload: []byte{}},
+		},
+		{name: "qos 3", flags: 0x06, body: append(appendString(nil, "t"), 0x00, 0x01), wantErr: true},
+		{name: "empty topic", flags: 0x00, body: appendString(nil, ""), wantErr: true},
+		{name: "missing packet id", flags: 0x02, body: appendString(nil, "t"), wantErr: true},
+		{name: "truncated topic", flags: 0x00, body: []byte{0x00, 0x05, 'f'}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePublish(tt.flags, tt.body)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePublish() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.dup != tt.want.dup || got.qos != tt.want.qos || got.topic != tt.want.topic ||
+				got.packetId != tt.want.packetId || !bytes.Equal(got.payload, tt.want.payload) {
+				t.Errorf("parsePublish() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountSubscriptions(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         []byte
+		wantPacketId uint16
+		wantCount    int
+		wantErr      bool
+	}{
+		{"one filter", append(appendString([]byte{0x00, 0x0a}, "farms/#"), 1), 10, 1, false},
+		{"two filters", append(appendString(append(appendString([]byte{0x00, 0x0b}, "a"), 0), "b"), 2), 11, 2, false},
+		{"no filter", []byte{0x00, 0x0a}, 0, 0, true},
+		{"missing qos", appendString([]byte{0x00, 0x0a}, "a"), 0, 0, true},
+		{"truncated filter", []byte{0x00, 0x0a, 0x00, 0x09, 'a'}, 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			packetId, count, err := countSubscriptions(tt.body)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("countSubscriptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if packetId != tt.wantPacketId || count != tt.wantCount {
+				t.Errorf("countSubscriptions() = (%d, %d), want (%d, %d)", packetId, count, tt.wantPacketId, tt.wantCount)
+			}
+		})
+	}
+}
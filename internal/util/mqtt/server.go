@@ -0,0 +1,290 @@
+// Package mqtt is a minimal MQTT 3.1.1 broker for devices that publish
+// readings. It authenticates each connection, hands every PUBLISH to a
+// handler and acknowledges it once handled. Subscriptions are refused and
+// messages are never routed to other clients, retained or persisted.
+package mqtt
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+)
+
+const (
+	// connectTimeout bounds the wait for the CONNECT of a new connection.
+	connectTimeout = 10 * time.Second
+	// defaultMaxPacketSize bounds a single packet unless configured.
+	defaultMaxPacketSize = 256 << 10
+)
+
+// ErrBadCredentials makes the broker refuse a CONNECT with "bad user name or
+// password". Any other Authenticator error answers "server unavailable".
+var ErrBadCredentials = errors.New("bad mqtt credentials")
+
+// Authenticator checks the credentials of a CONNECT. Its result identifies
+// the client in every Handler call of the connection.
+type Authenticator func(clientId string, username string, password []byte) (interface{}, error)
+
+// Handler processes an application message. An error closes the connection
+// without acknowledging the message, so the client sends it again once it
+// reconnects.
+type Handler func(client interface{}, topic string, payload []byte) error
+
+type Config struct {
+	Authenticate  Authenticator
+	Handle        Handler
+	MaxPacketSize int
+}
+
+type Server struct {
+	config Config
+
+	mu        sync.Mutex
+	closed    bool
+	listeners map[net.Listener]bool
+	conns     map[net.Conn]bool
+	wg        sync.WaitGroup
+}
+
+func NewServer(config Config) *Server {
+	if config.MaxPacketSize <= 0 {
+		config.MaxPacketSize = defaultMaxPacketSize
+	}
+	return &Server{
+		config:    config,
+		listeners: make(map[net.Listener]bool),
+		conns:     make(map[net.Conn]bool),
+	}
+}
+
+// Serve accepts connections until the listener fails or the server is
+// closed. It returns nil after Close.
+func (s *Server) Serve(listener net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		listener.Close()
+		return nil
+	}
+	s.listeners[listener] = true
+	s.mu.Unlock()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			delete(s.listeners, listener)
+			s.mu.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return nil
+		}
+		s.conns[conn] = true
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go s.serveConn(conn)
+	}
+}
+
+// Close stops accepting connections, closes the open ones and waits for
+// messages being handled to finish.
+func (s *Server) Close() {
+	s.mu.Lock()
+	s.closed = true
+	for listener := range s.listeners {
+		listener.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer func() {
+		conn.Close()
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		s.wg.Done()
+	}()
+
+	remote := conn.RemoteAddr().String()
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+
+	conn.SetReadDeadline(time.Now().Add(connectTimeout))
+	client, keepAlive, err := s.connect(r, w)
+	if err != nil {
+		logger.Warn("mqtt", "Connection refused", map[string]string{
+			"remote": remote,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	// clients ping within their keep alive, allow half of it again
+	var timeout time.Duration
+	if keepAlive > 0 {
+		timeout = time.Duration(keepAlive) * time.Second * 3 / 2
+	}
+
+	// QoS 2 messages handled but not yet released, so a resent PUBLISH is
+	// not handled twice
+	awaitingRelease := make(map[uint16]bool)
+	for {
+		if timeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(timeout))
+		} else {
+			conn.SetReadDeadline(time.Time{})
+		}
+
+		p, err := readPacket(r, s.config.MaxPacketSize)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				logger.Warn("mqtt", "Closing connection", map[string]string{
+					"remote": remote,
+					"error":  err.Error(),
+				})
+			}
+			return
+		}
+
+		switch p.kind {
+		case packetPublish:
+			var publish *publishPacket
+			publish, err = parsePublish(p.flags, p.body)
+			if err != nil || strings.ContainsAny(publish.topic, "+#") {
+				return
+			}
+			if publish.qos == 2 && awaitingRelease[publish.packetId] {
+				err = writePacketId(w, packetPubrec, 0, publish.packetId)
+				break
+			}
+
+			err = s.config.Handle(client, publish.topic, publish.payload)
+			if err != nil {
+				logger.Warn("mqtt", "Message not handled, closing connection", map[string]string{
+					"remote": remote,
+					"topic":  publish.topic,
+					"error":  err.Error(),
+				})
+				return
+			}
+
+			switch publish.qos {
+			case 1:
+				err = writePacketId(w, packetPuback, 0, publish.packetId)
+			case 2:
+				awaitingRelease[publish.packetId] = true
+				err = writePacketId(w, packetPubrec, 0, publish.packetId)
+			}
+		case packetPubrel:
+			body := &reader{buf: p.body}
+			packetId := body.readUint16()
+			if body.err != nil || p.flags != 0x02 {
+				return
+			}
+			delete(awaitingRelease, packetId)
+			err = writePacketId(w, packetPubcomp, 0, packetId)
+		case packetSubscribe:
+			packetId, count, parseErr := countSubscriptions(p.body)
+			if parseErr != nil || p.flags != 0x02 {
+				return
+			}
+			codes := make([]byte, count)
+			for i := range codes {
+				codes[i] = subackFailure
+			}
+			err = writePacket(w, packetSuback, 0, append([]byte{byte(packetId >> 8), byte(packetId)}, codes...))
+		case packetUnsubscribe:
+			body := &reader{buf: p.body}
+			packetId := body.readUint16()
+			if body.err != nil || p.flags != 0x02 {
+				return
+			}
+			err = writePacketId(w, packetUnsuback, 0, packetId)
+		case packetPingreq:
+			err = writePacket(w, packetPingresp, 0, nil)
+		case packetDisconnect:
+			return
+		default:
+			// a second CONNECT or a packet only a broker sends
+			return
+		}
+
+		if err == nil {
+			err = w.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// connect reads the CONNECT of a connection and answers it. Only protocol
+// level 4, MQTT 3.1.1, is accepted.
+func (s *Server) connect(r *bufio.Reader, w *bufio.Writer) (interface{}, uint16, error) {
+	p, err := readPacket(r, s.config.MaxPacketSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	if p.kind != packetConnect {
+		return nil, 0, errMalformedPacket
+	}
+
+	connect, err := parseConnect(p.body)
+	if err != nil {
+		return nil, 0, err
+	}
+	if connect.protocolName != "MQTT" || connect.protocolLevel != 4 {
+		return nil, 0, refuse(w, connackBadProtocol, errors.New("unsupported mqtt protocol"))
+	}
+	if connect.clientId == "" && !connect.cleanSession {
+		return nil, 0, refuse(w, connackIdentifierRejected, errors.New("empty client id without clean session"))
+	}
+
+	client, err := s.config.Authenticate(connect.clientId, connect.username, connect.password)
+	if errors.Is(err, ErrBadCredentials) {
+		return nil, 0, refuse(w, connackBadCredentials, err)
+	}
+	if err != nil {
+		return nil, 0, refuse(w, connackServerUnavailable, err)
+	}
+
+	// sessions are never kept, so session present is always 0
+	err = writePacket(w, packetConnack, 0, []byte{0, connackAccepted})
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	return client, connect.keepAlive, nil
+}
+
+func refuse(w *bufio.Writer, code byte, reason error) error {
+	err := writePacket(w, packetConnack, 0, []byte{0, code})
+	if err == nil {
+		w.Flush()
+	}
+	return reason
+}
@@ -0,0 +1,287 @@
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/util/logger"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "mqtt-test")
+	if err != nil {
+		panic(err)
+	}
+	err = logger.Init(filepath.Join(dir, "app.log"))
+	if err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+type message struct {
+	client  interface{}
+	topic   string
+	payload string
+}
+
+// testBroker serves a broker on a local port. The password "secret" is
+// accepted for any user name, and messages to the topic "fail" are refused.
+type testBroker struct {
+	server *Server
+	addr   string
+
+	mu       sync.Mutex
+	messages []message
+}
+
+func newTestBroker(t *testing.T, maxPacketSize int) *testBroker {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := &testBroker{addr: listener.Addr().String()}
+	b.server = NewServer(Config{
+		Authenticate: func(clientId string, username string, password []byte) (interface{}, error) {
+			switch string(password) {
+			case "secret":
+				return username, nil
+			case "unavailable":
+				return nil, errors.New("store is down")
+			}
+			return nil, ErrBadCredentials
+		},
+		Handle: func(client interface{}, topic string, payload []byte) error {
+			if topic == "fail" {
+				return errors.New("not handled")
+			}
+			b.mu.Lock()
+			b.messages = append(b.messages, message{client, topic, string(payload)})
+			b.mu.Unlock()
+			return nil
+		},
+		MaxPacketSize: maxPacketSize,
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- b.server.Serve(listener) }()
+	t.Cleanup(func() {
+		b.server.Close()
+		if err := <-done; err != nil {
+			t.Errorf("Serve() error = %v", err)
+		}
+	})
+	return b
+}
+
+func (b *testBroker) received() []message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]message(nil), b.messages...)
+}
+
+type testClient struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func (b *testBroker) dial(t *testing.T) *testClient {
+	conn, err := net.Dial("tcp", b.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return &testClient{t: t, conn: conn, r: bufio.NewReader(conn)}
+}
+
+func (c *testClient) send(kind byte, flags byte, body []byte) {
+	if err := writePacket(c.conn, kind, flags, body); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *testClient) expect(kind byte, body []byte) {
+	p, err := readPacket(c.r, defaultMaxPacketSize)
+	if err != nil {
+		c.t.Fatalf("reading packet %d: %v", kind, err)
+	}
+	if p.kind != kind || !bytes.Equal(p.body, body) {
+		c.t.Fatalf("got packet %d %x, want %d %x", p.kind, p.body, kind, body)
+	}
+}
+
+// expectClosed waits for the broker to close the connection.
+func (c *testClient) expectClosed() {
+	_, err := c.r.ReadByte()
+	if !errors.Is(err, io.EOF) {
+		c.t.Fatalf("connection not closed: %v", err)
+	}
+}
+
+func (c *testClient) connect(password string) {
+	c.send(packetConnect, 0, connectBody("MQTT", 4, 0xc2, 60, "device-1", "unit-1", password))
+	c.expect(packetConnack, []byte{0, connackAccepted})
+}
+
+func publishBody(topic string, packetId uint16, payload string) []byte {
+	body := appendString(nil, topic)
+	if packetId != 0 {
+		body = append(body, byte(packetId>>8), byte(packetId))
+	}
+	return append(body, payload...)
+}
+
+func TestServerConnect(t *testing.T) {
+	b := newTestBroker(t, 0)
+
+	tests := []struct {
+		name string
+		body []byte
+		code byte
+	}{
+		{"accepted", connectBody("MQTT", 4, 0xc2, 60, "device-1", "unit-1", "secret"), connackAccepted},
+		{"bad password", connectBody("MQTT", 4, 0xc2, 60, "device-1", "unit-1", "wrong"), connackBadCredentials},
+		{"authenticator failure", connectBody("MQTT", 4, 0xc2, 60, "device-1", "unit-1", "unavailable"), connackServerUnavailable},
+		{"mqtt 3.1", connectBody("MQIsdp", 3, 0xc2, 60, "device-1", "unit-1", "secret"), connackBadProtocol},
+		{"empty client id without clean session", connectBody("MQTT", 4, 0xc0, 60, "", "unit-1", "secret"), connackIdentifierRejected},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := b.dial(t)
+			c.send(packetConnect, 0, tt.body)
+			c.expect(packetConnack, []byte{0, tt.code})
+			if tt.code != connackAccepted {
+				c.expectClosed()
+			}
+		})
+	}
+}
+
+func TestServerRefusesPacketBeforeConnect(t *testing.T) {
+	b := newTestBroker(t, 0)
+
+	c := b.dial(t)
+	c.send(packetPingreq, 0, nil)
+	c.expectClosed()
+}
+
+func TestServerPublish(t *testing.T) {
+	b := newTestBroker(t, 0)
+
+	c := b.dial(t)
+	c.connect("secret")
+
+	c.send(packetPublish, 0x00, publishBody("farms/a/readings", 0, "qos0"))
+	c.send(packetPublish, 0x02, publishBody("farms/a/readings", 1, "qos1"))
+	c.expect(packetPuback, []byte{0, 1})
+
+	// a resent QoS 2 message is acknowledged again but handled once
+	c.send(packetPublish, 0x04, publishBody("farms/a/tank", 2, "qos2"))
+	c.expect(packetPubrec, []byte{0, 2})
+	c.send(packetPublish, 0x0c, publishBody("farms/a/tank", 2, "qos2"))
+	c.expect(packetPubrec, []byte{0, 2})
+	c.send(packetPubrel, 0x02, []byte{0, 2})
+	c.expect(packetPubcomp, []byte{0, 2})
+
+	c.send(packetSubscribe, 0x02, append(appendString([]byte{0, 3}, "farms/#"), 1))
+	c.expect(packetSuback, []byte{0, 3, subackFailure})
+	c.send(packetPingreq, 0, nil)
+	c.expect(packetPingresp, nil)
+
+	want := []message{
+		{"unit-1", "farms/a/readings", "qos0"},
+		{"unit-1", "farms/a/readings", "qos1"},
+		{"unit-1", "farms/a/tank", "qos2"},
+	}
+	got := b.received()
+	if len(got) != len(want) {
+		t.Fatalf("handled %d messages, want %d: %v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("message %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestServerClosesConnection(t *testing.T) {
+	tests := []struct {
+		name   string
+		kind   byte
+		flags  byte
+		body   []byte
+		header []byte
+	}{
+		{name: "handler error", kind: packetPublish, flags: 0x02, body: publishBody("fail", 1, "x")},
+		{name: "wildcard topic", kind: packetPublish, flags: 0x00, body: publishBody("farms/+/readings", 0, "x")},
+		{name: "second connect", kind: packetConnect, body: connectBody("MQTT", 4, 0xc2, 60, "device-1", "unit-1", "secret")},
+		{name: "oversized packet", kind: packetPublish, body: publishBody("farms/a/readings", 0, string(make([]byte, 64)))},
+		{name: "malformed remaining length", header: []byte{0x30, 0x80, 0x80, 0x80, 0x80, 0x01}},
+		{name: "disconnect", kind: packetDisconnect},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newTestBroker(t, 64)
+
+			c := b.dial(t)
+			c.connect("secret")
+			if tt.header != nil {
+				if _, err := c.conn.Write(tt.header); err != nil {
+					t.Fatal(err)
+				}
+			} else {
+				c.send(tt.kind, tt.flags, tt.body)
+			}
+			c.expectClosed()
+
+			if got := b.received(); len(got) != 0 {
+				t.Errorf("handled %v, want no message", got)
+			}
+		})
+	}
+}
+
+func TestServerClose(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(Config{
+		Authenticate: func(string, string, []byte) (interface{}, error) { return nil, nil },
+		Handle:       func(interface{}, string, []byte) error { return nil },
+	})
+	done := make(chan error, 1)
+	go func() { done <- server.Serve(listener) }()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	c := &testClient{t: t, conn: conn, r: bufio.NewReader(conn)}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	c.connect("secret")
+
+	server.Close()
+	c.expectClosed()
+	if err := <-done; err != nil {
+		t.Errorf("Serve() after Close() = %v, want nil", err)
+	}
+	if err := server.Serve(listener); err != nil {
+		t.Errorf("Serve() on a closed server = %v, want nil", err)
+	}
+}
@@ -0,0 +1,87 @@
+package passwordpolicy
+
+import (
+	"bufio"
+	_ "embed"
+	"strings"
+	"unicode"
+
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+)
+
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+var commonPasswords = loadCommonPasswords(commonPasswordList)
+
+const (
+	DefaultMinLength      = 8
+	DefaultMinCharClasses = 3
+	DefaultHistorySize    = 5
+)
+
+// Policy describes the rules a new password has to satisfy. Character classes
+// are lower case letters, upper case letters, digits and symbols.
+type Policy struct {
+	MinLength      int
+	MinCharClasses int
+	HistorySize    int
+}
+
+func NewDefault() *Policy {
+	return &Policy{
+		MinLength:      DefaultMinLength,
+		MinCharClasses: DefaultMinCharClasses,
+		HistorySize:    DefaultHistorySize,
+	}
+}
+
+func (p *Policy) Validate(username string, password string) error {
+	if len([]rune(password)) < p.MinLength {
+		return errs.PasswordTooShort
+	}
+
+	if countCharClasses(password) < p.MinCharClasses {
+		return errs.PasswordTooWeak
+	}
+
+	lowered := strings.ToLower(password)
+	if username != "" && strings.Contains(lowered, strings.ToLower(username)) {
+		return errs.PasswordContainUsername
+	}
+
+	if _, ok := commonPasswords[lowered]; ok {
+		return errs.PasswordTooCommon
+	}
+
+	return nil
+}
+
+func countCharClasses(password string) int {
+	var lower, upper, digit, symbol int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+	return lower + upper + digit + symbol
+}
+
+func loadCommonPasswords(list string) map[string]struct{} {
+	passwords := map[string]struct{}{}
+	scanner := bufio.NewScanner(strings.NewReader(list))
+	for scanner.Scan() {
+		line := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if line != "" {
+			passwords[line] = struct{}{}
+		}
+	}
+	return passwords
+}
@@ -0,0 +1,79 @@
+package secretcipher
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+)
+
+// MinKeyLength is the shortest key accepted. An empty or short key, such as
+// an unset variable, would make the stored secrets readable by anyone.
+const MinKeyLength = 16
+
+var (
+	errInvalidCiphertext = errors.New("invalid ciphertext")
+	ErrKeyTooShort       = errors.New("encryption key must be at least 16 characters")
+)
+
+// Cipher encrypts secrets that the server has to read back later, such as
+// TOTP seeds and device HMAC keys, so they are not stored in clear text.
+type Cipher interface {
+	Encrypt(plaintext string) (string, error)
+	Decrypt(ciphertext string) (string, error)
+}
+
+type aesGCMCipher struct {
+	aead cipher.AEAD
+}
+
+// NewAESGCM derives a 256 bit AES key from key.
+func NewAESGCM(key string) (Cipher, error) {
+	if len(key) < MinKeyLength {
+		return nil, ErrKeyTooShort
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &aesGCMCipher{aead: aead}, nil
+}
+
+func (c *aesGCMCipher) Encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (c *aesGCMCipher) Decrypt(ciphertext string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", err
+	}
+
+	nonceSize := c.aead.NonceSize()
+	if len(raw) < nonceSize {
+		return "", errInvalidCiphertext
+	}
+
+	plaintext, err := c.aead.Open(nil, raw[:nonceSize], raw[nonceSize:], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
@@ -0,0 +1,127 @@
+package secretcipher
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const testKey = "0123456789abcdef-test"
+
+func TestNewAESGCMKeyLength(t *testing.T) {
+	tests := []struct {
+		key     string
+		wantErr error
+	}{
+		{"", ErrKeyTooShort},
+		{strings.Repeat("k", MinKeyLength-1), ErrKeyTooShort},
+		{strings.Repeat("k", MinKeyLength), nil},
+		{strings.Repeat("k", 100), nil},
+	}
+	for _, tt := range tests {
+		_, err := NewAESGCM(tt.key)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("NewAESGCM with a %d character key = %v, want %v", len(tt.key), err, tt.wantErr)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	cipher, err := NewAESGCM(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, plaintext := range []string{"", "JBSWY3DPEHPK3PXP", "device secret ✓", strings.Repeat("x", 4096)} {
+		ciphertext, err := cipher.Encrypt(plaintext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if plaintext != "" && strings.Contains(ciphertext, plaintext) {
+			t.Errorf("ciphertext %q holds the plaintext", ciphertext)
+		}
+
+		got, err := cipher.Decrypt(ciphertext)
+		if err != nil {
+			t.Fatalf("Decrypt(Encrypt(%q)): %v", plaintext, err)
+		}
+		if got != plaintext {
+			t.Errorf("Decrypt(Encrypt(%q)) = %q", plaintext, got)
+		}
+	}
+}
+
+func TestEncryptUsesFreshNonce(t *testing.T) {
+	cipher, err := NewAESGCM(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := cipher.Encrypt("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := cipher.Encrypt("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Error("encrypting the same secret twice gave the same ciphertext")
+	}
+}
+
+func TestDecryptRejects(t *testing.T) {
+	cipher, err := NewAESGCM(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherCipher, err := NewAESGCM(testKey + "-other")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ciphertext, err := cipher.Encrypt("device secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// flip returns the ciphertext with one bit changed at i
+	flip := func(i int) string {
+		tampered := append([]byte(nil), raw...)
+		tampered[i] ^= 0x01
+		return base64.StdEncoding.EncodeToString(tampered)
+	}
+	truncate := func(n int) string {
+		return base64.StdEncoding.EncodeToString(raw[:n])
+	}
+
+	tests := []struct {
+		name       string
+		cipher     Cipher
+		ciphertext string
+	}{
+		{"wrong key", otherCipher, ciphertext},
+		{"tampered nonce", cipher, flip(0)},
+		{"tampered sealed text", cipher, flip(len(raw) / 2)},
+		{"tampered tag", cipher, flip(len(raw) - 1)},
+		{"truncated tag", cipher, truncate(len(raw) - 1)},
+		{"nonce only", cipher, truncate(12)},
+		{"shorter than the nonce", cipher, truncate(5)},
+		{"empty", cipher, ""},
+		{"not base64", cipher, "not base64!"},
+		{"stored in clear text", cipher, "device secret"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cipher.Decrypt(tt.ciphertext)
+			if err == nil {
+				t.Errorf("Decrypt succeeded with %q, want an error", got)
+			}
+		})
+	}
+}
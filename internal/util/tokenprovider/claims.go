@@ -6,9 +6,20 @@ type UserClaims struct {
 	UserID   string `json:"user_id"`
 	Username string `json:"username"`
 	Role     string `json:"role"`
+	// OrganizationID is the tenant the account belongs to.
+	OrganizationID   string `json:"organization_id"`
+	OrganizationRole string `json:"organization_role"`
+	// EmailVerified is false until the account confirms its email address.
+	EmailVerified bool `json:"email_verified"`
+	// ActorID is the super admin acting as UserID. It is only set on
+	// impersonation tokens.
+	ActorID string `json:"actor_id,omitempty"`
+	// ReadOnly limits the token to GET requests.
+	ReadOnly bool `json:"read_only,omitempty"`
 }
 
 type JwtClaims struct {
 	jwt.RegisteredClaims
 	UserClaims
+	TokenType string `json:"token_type"`
 }
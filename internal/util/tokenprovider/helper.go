@@ -16,6 +16,6 @@ func GetProvider() JWTTokenProvider {
 	refreshTokenDuration, _ := strconv.Atoi(refreshTokenDurationString)
 	accessTokenDuration, _ := strconv.Atoi(accessTokenDurationString)
 
-	jwtProvider := NewJWT(issuer, secret, refreshTokenDuration, accessTokenDuration)
+	jwtProvider := NewJWT(issuer, constant.AudienceUser, NewHMACKeySet(secret), refreshTokenDuration, accessTokenDuration)
 	return jwtProvider
 }
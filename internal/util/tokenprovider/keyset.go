@@ -0,0 +1,259 @@
+package tokenprovider
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmEdDSA = "EdDSA"
+	AlgorithmRS256 = "RS256"
+)
+
+// KeySet signs tokens with a single key and verifies them with any of the
+// keys it knows about, which allows keys to be rotated without logging every
+// user out.
+type KeySet interface {
+	Sign(claims jwt.Claims) (string, error)
+	Keyfunc(token *jwt.Token) (interface{}, error)
+	JWKS() *JWKS
+}
+
+// JWK is the public part of a verification key as described in RFC 7517.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+type KeySetConfig struct {
+	// Algorithm is HS256, EdDSA or RS256.
+	Algorithm string
+	// Secret is the HS256 key. With an asymmetric algorithm it is only used to
+	// verify tokens issued before the switch, see AcceptLegacyHS256.
+	Secret string
+	// KeysDir holds one PEM file per key, named <kid>.pem. Private keys can
+	// sign and verify, public keys only verify.
+	KeysDir string
+	// SigningKeyId is the kid of the private key used for new tokens.
+	SigningKeyId      string
+	AcceptLegacyHS256 bool
+}
+
+func NewKeySet(config KeySetConfig) (KeySet, error) {
+	switch config.Algorithm {
+	case "", AlgorithmHS256:
+		return NewHMACKeySet(config.Secret), nil
+	case AlgorithmEdDSA:
+		return loadAsymmetricKeySet(jwt.SigningMethodEdDSA, config)
+	case AlgorithmRS256:
+		return loadAsymmetricKeySet(jwt.SigningMethodRS256, config)
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", config.Algorithm)
+	}
+}
+
+type hmacKeySet struct {
+	secret []byte
+}
+
+// NewHMACKeySet signs and verifies with a shared secret. It publishes no keys.
+func NewHMACKeySet(secret string) KeySet {
+	return &hmacKeySet{secret: []byte(secret)}
+}
+
+func (k *hmacKeySet) Sign(claims jwt.Claims) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(k.secret)
+}
+
+func (k *hmacKeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	if token.Method != jwt.SigningMethodHS256 {
+		return nil, errs.InvalidToken
+	}
+	return k.secret, nil
+}
+
+func (k *hmacKeySet) JWKS() *JWKS {
+	return &JWKS{Keys: []JWK{}}
+}
+
+type asymmetricKeySet struct {
+	method       jwt.SigningMethod
+	signingKeyId string
+	signingKey   crypto.Signer
+	publicKeys   map[string]crypto.PublicKey
+	legacySecret []byte
+}
+
+func loadAsymmetricKeySet(method jwt.SigningMethod, config KeySetConfig) (KeySet, error) {
+	if config.KeysDir == "" || config.SigningKeyId == "" {
+		return nil, errors.New("JWT keys directory and signing key id are required for " + method.Alg())
+	}
+
+	files, err := filepath.Glob(filepath.Join(config.KeysDir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	keySet := &asymmetricKeySet{
+		method:       method,
+		signingKeyId: config.SigningKeyId,
+		publicKeys:   map[string]crypto.PublicKey{},
+	}
+	if config.AcceptLegacyHS256 && config.Secret != "" {
+		keySet.legacySecret = []byte(config.Secret)
+	}
+
+	for _, file := range files {
+		kid := strings.TrimSuffix(filepath.Base(file), ".pem")
+
+		privateKey, publicKey, err := readPEMKey(file)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", kid, err)
+		}
+		if !keyMatchesMethod(publicKey, method) {
+			return nil, fmt.Errorf("key %s cannot be used with %s", kid, method.Alg())
+		}
+
+		keySet.publicKeys[kid] = publicKey
+		if kid == config.SigningKeyId {
+			if privateKey == nil {
+				return nil, fmt.Errorf("signing key %s is not a private key", kid)
+			}
+			keySet.signingKey = privateKey
+		}
+	}
+
+	if keySet.signingKey == nil {
+		return nil, fmt.Errorf("signing key %s not found in %s", config.SigningKeyId, config.KeysDir)
+	}
+
+	return keySet, nil
+}
+
+func (k *asymmetricKeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.method, claims)
+	token.Header["kid"] = k.signingKeyId
+	return token.SignedString(k.signingKey)
+}
+
+func (k *asymmetricKeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	if token.Method == jwt.SigningMethodHS256 && kid == "" && k.legacySecret != nil {
+		return k.legacySecret, nil
+	}
+
+	if token.Method.Alg() != k.method.Alg() {
+		return nil, errs.InvalidToken
+	}
+
+	publicKey, ok := k.publicKeys[kid]
+	if !ok {
+		return nil, errs.InvalidToken
+	}
+	return publicKey, nil
+}
+
+func (k *asymmetricKeySet) JWKS() *JWKS {
+	kids := make([]string, 0, len(k.publicKeys))
+	for kid := range k.publicKeys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	jwks := &JWKS{Keys: make([]JWK, 0, len(kids))}
+	for _, kid := range kids {
+		jwk := JWK{Kid: kid, Use: "sig", Alg: k.method.Alg()}
+
+		switch key := k.publicKeys[kid].(type) {
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(key)
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+		}
+
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	return jwks
+}
+
+// readPEMKey returns the private key, if the file holds one, and the public
+// key of the file.
+func readPEMKey(file string) (crypto.Signer, crypto.PublicKey, error) {
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, nil, errors.New("no PEM block found")
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, nil, errors.New("unsupported private key type")
+		}
+		return signer, signer.Public(), nil
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		return key, key.Public(), nil
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, key, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+}
+
+func keyMatchesMethod(publicKey crypto.PublicKey, method jwt.SigningMethod) bool {
+	switch key := publicKey.(type) {
+	case ed25519.PublicKey:
+		return method == jwt.SigningMethodEdDSA
+	case *rsa.PublicKey:
+		return method == jwt.SigningMethodRS256 && key.N.BitLen() >= 2048
+	default:
+		return false
+	}
+}
@@ -0,0 +1,429 @@
+package tokenprovider
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const testLegacySecret = "legacy-secret"
+
+// writePrivateKey stores key as <kid>.pem in dir in PKCS #8 form.
+func writePrivateKey(t *testing.T, dir string, kid string, key crypto.Signer) {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, dir, kid, "PRIVATE KEY", der)
+}
+
+// writePublicKey stores only the public half of key, as a retired key is kept.
+func writePublicKey(t *testing.T, dir string, kid string, key crypto.Signer) {
+	t.Helper()
+	writePEM(t, dir, kid, "PUBLIC KEY", publicKeyDER(t, key))
+}
+
+func writePEM(t *testing.T, dir string, kid string, blockType string, der []byte) {
+	t.Helper()
+	raw := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	err := os.WriteFile(filepath.Join(dir, kid+".pem"), raw, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func publicKeyDER(t *testing.T, key crypto.Signer) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func newEd25519Key(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func newRSAKey(t *testing.T, bits int) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func testClaims() jwt.Claims {
+	return jwt.RegisteredClaims{
+		Subject:   "user",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}
+}
+
+// signHS256 signs testClaims with secret, setting kid when it is not empty.
+func signHS256(t *testing.T, secret []byte, kid string) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims())
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func verifies(keySet KeySet, token string) bool {
+	parsed, err := jwt.Parse(token, keySet.Keyfunc)
+	return err == nil && parsed.Valid
+}
+
+func TestNewKeySet(t *testing.T) {
+	edKey := newEd25519Key(t)
+	rsaKey := newRSAKey(t, 2048)
+	shortRSAKey := newRSAKey(t, 1024)
+
+	tests := []struct {
+		name    string
+		config  KeySetConfig
+		keys    func(dir string)
+		wantErr bool
+	}{
+		{
+			name:   "HS256 by default",
+			config: KeySetConfig{Secret: "secret"},
+		},
+		{
+			name:    "unsupported algorithm",
+			config:  KeySetConfig{Algorithm: "ES256"},
+			wantErr: true,
+		},
+		{
+			name:    "no signing key id",
+			config:  KeySetConfig{Algorithm: AlgorithmEdDSA},
+			keys:    func(dir string) { writePrivateKey(t, dir, "current", edKey) },
+			wantErr: true,
+		},
+		{
+			name:   "EdDSA",
+			config: KeySetConfig{Algorithm: AlgorithmEdDSA, SigningKeyId: "current"},
+			keys:   func(dir string) { writePrivateKey(t, dir, "current", edKey) },
+		},
+		{
+			name:   "RS256",
+			config: KeySetConfig{Algorithm: AlgorithmRS256, SigningKeyId: "current"},
+			keys:   func(dir string) { writePrivateKey(t, dir, "current", rsaKey) },
+		},
+		{
+			name:    "signing key missing",
+			config:  KeySetConfig{Algorithm: AlgorithmEdDSA, SigningKeyId: "next"},
+			keys:    func(dir string) { writePrivateKey(t, dir, "current", edKey) },
+			wantErr: true,
+		},
+		{
+			name:    "signing key is public only",
+			config:  KeySetConfig{Algorithm: AlgorithmEdDSA, SigningKeyId: "current"},
+			keys:    func(dir string) { writePublicKey(t, dir, "current", edKey) },
+			wantErr: true,
+		},
+		{
+			name:   "RSA key under 2048 bits",
+			config: KeySetConfig{Algorithm: AlgorithmRS256, SigningKeyId: "current"},
+			keys: func(dir string) {
+				writePrivateKey(t, dir, "current", rsaKey)
+				writePublicKey(t, dir, "old", shortRSAKey)
+			},
+			wantErr: true,
+		},
+		{
+			name:    "Ed25519 key for RS256",
+			config:  KeySetConfig{Algorithm: AlgorithmRS256, SigningKeyId: "current"},
+			keys:    func(dir string) { writePrivateKey(t, dir, "current", edKey) },
+			wantErr: true,
+		},
+		{
+			name:    "RSA key for EdDSA",
+			config:  KeySetConfig{Algorithm: AlgorithmEdDSA, SigningKeyId: "current"},
+			keys:    func(dir string) { writePrivateKey(t, dir, "current", rsaKey) },
+			wantErr: true,
+		},
+		{
+			name:   "not a PEM file",
+			config: KeySetConfig{Algorithm: AlgorithmEdDSA, SigningKeyId: "current"},
+			keys: func(dir string) {
+				writePrivateKey(t, dir, "current", edKey)
+				os.WriteFile(filepath.Join(dir, "broken.pem"), []byte("not a key"), 0o600)
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.keys != nil {
+				tt.config.KeysDir = t.TempDir()
+				tt.keys(tt.config.KeysDir)
+			}
+
+			keySet, err := NewKeySet(tt.config)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("NewKeySet succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if keySet == nil {
+				t.Fatal("NewKeySet returned no key set")
+			}
+		})
+	}
+}
+
+func TestAsymmetricKeySetRoundTrip(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		newKey    func() crypto.Signer
+	}{
+		{AlgorithmEdDSA, func() crypto.Signer { return newEd25519Key(t) }},
+		{AlgorithmRS256, func() crypto.Signer { return newRSAKey(t, 2048) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			oldKey, currentKey := tt.newKey(), tt.newKey()
+
+			oldDir := t.TempDir()
+			writePrivateKey(t, oldDir, "old", oldKey)
+			oldSet, err := NewKeySet(KeySetConfig{Algorithm: tt.algorithm, KeysDir: oldDir, SigningKeyId: "old"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// after the rotation only the public half of the old key is kept
+			dir := t.TempDir()
+			writePublicKey(t, dir, "old", oldKey)
+			writePrivateKey(t, dir, "current", currentKey)
+			keySet, err := NewKeySet(KeySetConfig{Algorithm: tt.algorithm, KeysDir: dir, SigningKeyId: "current"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			token, err := keySet.Sign(testClaims())
+			if err != nil {
+				t.Fatal(err)
+			}
+			parsed, err := jwt.Parse(token, keySet.Keyfunc)
+			if err != nil || !parsed.Valid {
+				t.Fatalf("token signed by the key set does not verify: %v", err)
+			}
+			if parsed.Header["kid"] != "current" || parsed.Method.Alg() != tt.algorithm {
+				t.Errorf("header = %v, want kid current and alg %s", parsed.Header, tt.algorithm)
+			}
+
+			oldToken, err := oldSet.Sign(testClaims())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !verifies(keySet, oldToken) {
+				t.Error("token signed with the retired key does not verify")
+			}
+			if verifies(oldSet, token) {
+				t.Error("key set without the current key verified its token")
+			}
+		})
+	}
+}
+
+func TestAsymmetricKeyfunc(t *testing.T) {
+	edKey := newEd25519Key(t)
+	dir := t.TempDir()
+	writePrivateKey(t, dir, "current", edKey)
+
+	strict, err := NewKeySet(KeySetConfig{
+		Algorithm:    AlgorithmEdDSA,
+		Secret:       testLegacySecret,
+		KeysDir:      dir,
+		SigningKeyId: "current",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy, err := NewKeySet(KeySetConfig{
+		Algorithm:         AlgorithmEdDSA,
+		Secret:            testLegacySecret,
+		KeysDir:           dir,
+		SigningKeyId:      "current",
+		AcceptLegacyHS256: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unknownKid := jwt.NewWithClaims(jwt.SigningMethodEdDSA, testClaims())
+	unknownKid.Header["kid"] = "unknown"
+	unknownKidToken, err := unknownKid.SignedString(edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	noKid, err := jwt.NewWithClaims(jwt.SigningMethodEdDSA, testClaims()).SignedString(edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherKey := newEd25519Key(t)
+	forged := jwt.NewWithClaims(jwt.SigningMethodEdDSA, testClaims())
+	forged.Header["kid"] = "current"
+	forgedToken, err := forged.SignedString(otherKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsaDir := t.TempDir()
+	writePrivateKey(t, rsaDir, "current", newRSAKey(t, 2048))
+	rsaSet, err := NewKeySet(KeySetConfig{Algorithm: AlgorithmRS256, KeysDir: rsaDir, SigningKeyId: "current"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaToken, err := rsaSet.Sign(testClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// an alg confusion attack signs HS256 with the published public key
+	publicKey := edKey.Public().(ed25519.PublicKey)
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyDER(t, edKey)})
+
+	tests := []struct {
+		name       string
+		token      string
+		wantStrict bool
+		wantLegacy bool
+	}{
+		{"unknown kid", unknownKidToken, false, false},
+		{"no kid", noKid, false, false},
+		{"signed with another key", forgedToken, false, false},
+		{"RS256 token for an EdDSA key set", rsaToken, false, false},
+		{"HS256 with the public key and kid", signHS256(t, publicKey, "current"), false, false},
+		{"HS256 with the public key without kid", signHS256(t, publicKey, ""), false, false},
+		{"HS256 with the public PEM and kid", signHS256(t, publicPEM, "current"), false, false},
+		{"HS256 with the public PEM without kid", signHS256(t, publicPEM, ""), false, false},
+		{"HS256 with the legacy secret and kid", signHS256(t, []byte(testLegacySecret), "current"), false, false},
+		{"HS256 with the legacy secret without kid", signHS256(t, []byte(testLegacySecret), ""), false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := verifies(strict, tt.token); got != tt.wantStrict {
+				t.Errorf("verified without legacy HS256 = %v, want %v", got, tt.wantStrict)
+			}
+			if got := verifies(legacy, tt.token); got != tt.wantLegacy {
+				t.Errorf("verified with legacy HS256 = %v, want %v", got, tt.wantLegacy)
+			}
+		})
+	}
+
+	_, err = strict.Keyfunc(&jwt.Token{Method: jwt.SigningMethodHS256, Header: map[string]interface{}{}})
+	if !errors.Is(err, errs.InvalidToken) {
+		t.Errorf("Keyfunc for HS256 = %v, want %v", err, errs.InvalidToken)
+	}
+}
+
+func TestHMACKeySet(t *testing.T) {
+	keySet := NewHMACKeySet("secret")
+
+	token, err := keySet.Sign(testClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verifies(keySet, token) {
+		t.Error("token signed by the key set does not verify")
+	}
+	if verifies(NewHMACKeySet("other"), token) {
+		t.Error("token verified with another secret")
+	}
+
+	none, err := jwt.NewWithClaims(jwt.SigningMethodNone, testClaims()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verifies(keySet, none) {
+		t.Error("unsigned token verified")
+	}
+
+	if jwks := keySet.JWKS(); jwks.Keys == nil || len(jwks.Keys) != 0 {
+		t.Errorf("JWKS = %+v, want an empty key list", jwks)
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	edCurrent, edOld := newEd25519Key(t), newEd25519Key(t)
+	rsaKey := newRSAKey(t, 2048)
+
+	edDir := t.TempDir()
+	writePrivateKey(t, edDir, "b-current", edCurrent)
+	writePublicKey(t, edDir, "a-old", edOld)
+	rsaDir := t.TempDir()
+	writePrivateKey(t, rsaDir, "current", rsaKey)
+
+	b64 := base64.RawURLEncoding.EncodeToString
+	tests := []struct {
+		name   string
+		config KeySetConfig
+		want   []JWK
+	}{
+		{
+			name:   "EdDSA",
+			config: KeySetConfig{Algorithm: AlgorithmEdDSA, KeysDir: edDir, SigningKeyId: "b-current"},
+			want: []JWK{
+				{Kty: "OKP", Kid: "a-old", Use: "sig", Alg: "EdDSA", Crv: "Ed25519", X: b64(edOld.Public().(ed25519.PublicKey))},
+				{Kty: "OKP", Kid: "b-current", Use: "sig", Alg: "EdDSA", Crv: "Ed25519", X: b64(edCurrent.Public().(ed25519.PublicKey))},
+			},
+		},
+		{
+			name:   "RS256",
+			config: KeySetConfig{Algorithm: AlgorithmRS256, KeysDir: rsaDir, SigningKeyId: "current"},
+			want: []JWK{
+				{Kty: "RSA", Kid: "current", Use: "sig", Alg: "RS256", N: b64(rsaKey.N.Bytes()), E: "AQAB"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keySet, err := NewKeySet(tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := keySet.JWKS().Keys
+			if len(got) != len(tt.want) {
+				t.Fatalf("JWKS has %d keys, want %d", len(got), len(tt.want))
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("key %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/constant"
 	errs "github.com/Ayasibp/be-smart-farming-hydroponic/internal/errors"
 	"github.com/Ayasibp/be-smart-farming-hydroponic/internal/model"
 	"github.com/golang-jwt/jwt/v4"
@@ -12,53 +13,106 @@ import (
 )
 
 type JWTTokenProvider interface {
-	GenerateRefreshToken(user *model.User) (string, error)
-	GenerateAccessToken(user *model.User) (string, error)
+	GenerateRefreshToken(user *model.User, tokenId uuid.UUID) (string, error)
+	GenerateAccessToken(user *model.User, sessionId uuid.UUID) (string, error)
 	ValidateToken(token string) (*JwtClaims, error)
+	ValidateRefreshToken(token string) (*JwtClaims, error)
 	ExtractToken(authHeader string) (string, error)
-	RenewAccessToken(refreshTokenString string) (*string, error)
+	GenerateMfaToken(user *model.User, challengeId uuid.UUID) (string, error)
+	ValidateMfaToken(token string) (*JwtClaims, error)
+	GenerateStepupToken(user *model.User, bindingId uuid.UUID) (string, error)
+	ValidateStepupToken(token string) (*JwtClaims, error)
+	GenerateImpersonationToken(user *model.User, actorId uuid.UUID, tokenId uuid.UUID, readOnly bool, expiresIn time.Duration) (string, error)
+	ValidateImpersonationToken(token string) (*JwtClaims, error)
+	RefreshTokenDuration() time.Duration
 }
 
+const (
+	// mfaTokenDuration bounds the time between the password step and the
+	// second factor of a login.
+	mfaTokenDuration = 5 * time.Minute
+	// stepupTokenDuration bounds how long a re-authentication unlocks
+	// destructive endpoints.
+	stepupTokenDuration = 5 * time.Minute
+)
+
 type jwtTokenProvider struct {
 	issuer               string
-	secret               string
+	audience             string
+	keys                 KeySet
 	refreshTokenDuration int
 	accessTokenDuration  int
 }
 
-func NewJWT(issuer string, secret string, refreshTokenDuration int, accessTokenDuration int) JWTTokenProvider {
+func NewJWT(issuer string, audience string, keys KeySet, refreshTokenDuration int, accessTokenDuration int) JWTTokenProvider {
 	return &jwtTokenProvider{
 		issuer:               issuer,
-		secret:               secret,
+		audience:             audience,
+		keys:                 keys,
 		refreshTokenDuration: refreshTokenDuration,
 		accessTokenDuration:  accessTokenDuration,
 	}
 }
 
-func (p *jwtTokenProvider) GenerateAccessToken(user *model.User) (string, error) {
-	return p.generateToken(user, time.Duration(p.accessTokenDuration)*time.Minute)
+func (p *jwtTokenProvider) GenerateAccessToken(user *model.User, sessionId uuid.UUID) (string, error) {
+	return p.generateToken(user, sessionId.String(), constant.TypeUserClaim, time.Duration(p.accessTokenDuration)*time.Minute)
 }
 
-func (p *jwtTokenProvider) GenerateRefreshToken(user *model.User) (string, error) {
-	return p.generateToken(user, time.Duration(p.refreshTokenDuration)*time.Minute)
+func (p *jwtTokenProvider) GenerateRefreshToken(user *model.User, tokenId uuid.UUID) (string, error) {
+	return p.generateToken(user, tokenId.String(), constant.TypeRefreshClaim, p.RefreshTokenDuration())
+}
+
+func (p *jwtTokenProvider) GenerateMfaToken(user *model.User, challengeId uuid.UUID) (string, error) {
+	return p.generateToken(user, challengeId.String(), constant.TypeMfaClaim, mfaTokenDuration)
+}
+
+// GenerateStepupToken issues a short lived token bound to bindingId, the jti
+// of the access token it was requested with.
+func (p *jwtTokenProvider) GenerateStepupToken(user *model.User, bindingId uuid.UUID) (string, error) {
+	return p.generateToken(user, bindingId.String(), constant.TypeStepupClaim, stepupTokenDuration)
+}
+
+// GenerateImpersonationToken issues an access token for user on behalf of the
+// super admin actorId. tokenId identifies the grant in the system log.
+func (p *jwtTokenProvider) GenerateImpersonationToken(user *model.User, actorId uuid.UUID, tokenId uuid.UUID, readOnly bool, expiresIn time.Duration) (string, error) {
+	claims := p.newClaims(user, tokenId.String(), constant.TypeImpersonationClaim, expiresIn)
+	claims.ActorID = actorId.String()
+	claims.ReadOnly = readOnly
+
+	return p.sign(claims)
 }
 
-func (p *jwtTokenProvider) generateToken(user *model.User, expiresIn time.Duration) (string, error) {
-	claims := JwtClaims{
+func (p *jwtTokenProvider) RefreshTokenDuration() time.Duration {
+	return time.Duration(p.refreshTokenDuration) * time.Minute
+}
+
+func (p *jwtTokenProvider) generateToken(user *model.User, tokenId string, tokenType string, expiresIn time.Duration) (string, error) {
+	return p.sign(p.newClaims(user, tokenId, tokenType, expiresIn))
+}
+
+func (p *jwtTokenProvider) newClaims(user *model.User, tokenId string, tokenType string, expiresIn time.Duration) JwtClaims {
+	return JwtClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenId,
 			Issuer:    p.issuer,
+			Audience:  jwt.ClaimStrings{p.audience},
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 		UserClaims: UserClaims{
-			UserID:   user.ID.String(),
-			Username: user.Username,
-			Role:     user.Role,
+			UserID:           user.ID.String(),
+			Username:         user.Username,
+			Role:             user.Role,
+			OrganizationID:   user.OrganizationId.String(),
+			OrganizationRole: user.OrganizationRole,
+			EmailVerified:    user.EmailVerifiedAt != nil,
 		},
+		TokenType: tokenType,
 	}
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenStr, err := token.SignedString([]byte(p.secret))
+func (p *jwtTokenProvider) sign(claims JwtClaims) (string, error) {
+	tokenStr, err := p.keys.Sign(claims)
 	if err != nil {
 		log.Println(err)
 		return "", err
@@ -68,11 +122,31 @@ func (p *jwtTokenProvider) generateToken(user *model.User, expiresIn time.Durati
 }
 
 func (p *jwtTokenProvider) ValidateToken(token string) (*JwtClaims, error) {
+	return p.parseToken(token, constant.TypeUserClaim)
+}
+
+func (p *jwtTokenProvider) ValidateRefreshToken(token string) (*JwtClaims, error) {
+	return p.parseToken(token, constant.TypeRefreshClaim)
+}
+
+func (p *jwtTokenProvider) ValidateMfaToken(token string) (*JwtClaims, error) {
+	return p.parseToken(token, constant.TypeMfaClaim)
+}
+
+func (p *jwtTokenProvider) ValidateStepupToken(token string) (*JwtClaims, error) {
+	return p.parseToken(token, constant.TypeStepupClaim)
+}
+
+func (p *jwtTokenProvider) ValidateImpersonationToken(token string) (*JwtClaims, error) {
+	return p.parseToken(token, constant.TypeImpersonationClaim)
+}
+
+func (p *jwtTokenProvider) parseToken(token string, tokenType string) (*JwtClaims, error) {
 	claims := JwtClaims{}
 
-	jwtToken, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (interface{}, error) {
-		return []byte(p.secret), nil
-	})
+	// The key set rejects tokens whose alg does not match the configured
+	// signing method, so an HS256 token cannot be verified with a public key.
+	jwtToken, err := jwt.ParseWithClaims(token, &claims, p.keys.Keyfunc)
 
 	if jwtToken == nil || !jwtToken.Valid {
 		return nil, errs.InvalidToken
@@ -86,38 +160,15 @@ func (p *jwtTokenProvider) ValidateToken(token string) (*JwtClaims, error) {
 		return nil, errs.InvalidIssuer
 	}
 
-	return &claims, nil
-}
-
-func (p *jwtTokenProvider) RenewAccessToken(refreshTokenString string) (*string, error) {
-	// Parse and verify the refresh token
-	refreshToken, err := jwt.Parse(refreshTokenString, func(t *jwt.Token) (interface{}, error) {
-		return []byte(p.secret), nil
-	})
-
-	if err != nil || !refreshToken.Valid {
-		return nil, errs.InvalidToken
+	if !claims.VerifyAudience(p.audience, true) {
+		return nil, errs.InvalidAudience
 	}
 
-	// Generate a new access token if refresh token is valid
-	if claims, ok := refreshToken.Claims.(jwt.MapClaims); ok && refreshToken.Valid {
-		username := claims["username"].(string)
-		userId := claims["user_id"].(string)
-
-		parsedUUID, err := uuid.Parse(userId)
-		if err != nil {
-			return nil, errs.ParseUUIDError
-		}
-
-		newAccessTokenString, err := p.GenerateAccessToken(&model.User{Username: username, ID: parsedUUID})
-		if err != nil {
-			return nil, err
-		}
-
-		return &newAccessTokenString, nil
-	} else {
+	if claims.TokenType != tokenType {
 		return nil, errs.InvalidToken
 	}
+
+	return &claims, nil
 }
 
 func (p *jwtTokenProvider) ExtractToken(authHeader string) (string, error) {
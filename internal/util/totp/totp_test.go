@@ -0,0 +1,91 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 seed of the RFC 6238 appendix B test vectors,
+// "12345678901234567890" in base32.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestGenerateCodeRFC6238(t *testing.T) {
+	key, err := encoding.DecodeString(rfcSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the appendix lists 8 digit codes, these are their last 6 digits
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		counter := uint64(tt.unix / int64(Period.Seconds()))
+		if got := generateCode(key, counter); got != tt.code {
+			t.Errorf("generateCode at %d = %s, want %s", tt.unix, got, tt.code)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := now.Unix() / int64(Period.Seconds())
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		at       time.Time
+		wantStep int64
+		wantOk   bool
+	}{
+		{"current step", rfcSecret, "050471", now, step, true},
+		{"lower case secret", "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", "050471", now, step, true},
+		{"surrounding spaces", rfcSecret, " 050471 ", now, step, true},
+		{"one step behind", rfcSecret, "050471", now.Add(Period), step, true},
+		{"one step ahead", rfcSecret, "050471", now.Add(-Period), step, true},
+		{"beyond skew", rfcSecret, "050471", now.Add(2 * Period), 0, false},
+		{"wrong code", rfcSecret, "050472", now, 0, false},
+		{"too short", rfcSecret, "05047", now, 0, false},
+		{"too long", rfcSecret, "0504711", now, 0, false},
+		{"empty", rfcSecret, "", now, 0, false},
+		{"invalid secret", "not base32!", "050471", now, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, gotOk := Match(tt.secret, tt.code, tt.at)
+			if gotStep != tt.wantStep || gotOk != tt.wantOk {
+				t.Errorf("Match() = (%d, %v), want (%d, %v)", gotStep, gotOk, tt.wantStep, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestGenerateSecretRoundTrip(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := encoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("secret %q is not base32: %v", secret, err)
+	}
+	if len(key) != 20 {
+		t.Fatalf("secret has %d bytes, want 20", len(key))
+	}
+
+	now := time.Now()
+	code := generateCode(key, uint64(now.Unix()/int64(Period.Seconds())))
+	if _, ok := Match(secret, code, now); !ok {
+		t.Errorf("Match() refused the code of a generated secret")
+	}
+}